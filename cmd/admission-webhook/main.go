@@ -9,6 +9,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	collectionwebhook "github.com/kabanero-io/kabanero-operator/pkg/webhook/collection"
 	kabanerowebhookv1alpha1 "github.com/kabanero-io/kabanero-operator/pkg/webhook/kabanero/v1alpha1"
 	kabanerowebhookv1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/webhook/kabanero/v1alpha2"
@@ -19,6 +20,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 )
 
 var log = logf.Log.WithName("cmd")
@@ -71,6 +73,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Vault credentials are validated against the Vault settings of the operator.
+	if err := cutils.LoadCredentialSettings(); err != nil {
+		log.Error(err, "Failed to get the credential provider settings")
+		os.Exit(1)
+	}
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -105,6 +113,7 @@ func main() {
 	hookServer.Register("/validate-kabaneros/v1alpha2", kabanerowebhookv1alpha2.BuildValidatingWebhook(&mgr))
 	hookServer.Register("/validate-stacks", stackwebhook.BuildValidatingWebhook(&mgr))
 	hookServer.Register("/mutate-stacks", stackwebhook.BuildMutatingWebhook(&mgr))
+	hookServer.Register("/convert", &conversion.Webhook{})
 
 	log.Info("Starting the Cmd.")
 
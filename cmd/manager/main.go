@@ -13,6 +13,7 @@ import (
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 
 	knsapis "github.com/knative/serving-operator/pkg/apis"
 	appsv1 "github.com/openshift/api/apps/v1"
@@ -21,6 +22,7 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	tektonapis "github.com/tektoncd/operator/pkg/apis"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
@@ -103,6 +105,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Featured stack indexes are retrieved using the same credential providers as the stack controller.
+	if err := cutils.LoadCredentialSettings(); err != nil {
+		log.Error(err, "Failed to get the credential provider settings")
+		os.Exit(1)
+	}
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -168,6 +176,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err := networkingv1beta1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	if err := operatorv1.AddToScheme(mgr.GetScheme()); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strconv"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/kabanero-io/kabanero-operator/pkg/apis"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -26,6 +28,10 @@ var (
 )
 var log = logf.Log.WithName("cmd")
 
+// The number of stacks reconciled at the same time, unless MAX_CONCURRENT_RECONCILES is set.
+// A slow pipeline archive download then only holds up the stack which needs it.
+const defaultMaxConcurrentReconciles = 4
+
 // These variables are injected during the build using ldflags
 var GitTag string
 var GitCommit string
@@ -65,6 +71,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	stack.MaxConcurrentReconciles, err = getMaxConcurrentReconciles()
+	if err != nil {
+		log.Error(err, "Failed to get the number of concurrent reconciles")
+		os.Exit(1)
+	}
+	log.Info(fmt.Sprintf("Reconciling up to %v stacks concurrently", stack.MaxConcurrentReconciles))
+
+	if err := cutils.LoadCredentialSettings(); err != nil {
+		log.Error(err, "Failed to get the credential provider settings")
+		os.Exit(1)
+	}
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -117,3 +135,18 @@ func getStackControllerNamespace() (string, error) {
 	}
 	return ns, nil
 }
+
+// Returns the number of stacks reconciled at the same time.  This can be set
+// using the MAX_CONCURRENT_RECONCILES environment variable.
+func getMaxConcurrentReconciles() (int, error) {
+	value, found := os.LookupEnv("MAX_CONCURRENT_RECONCILES")
+	if !found || len(value) == 0 {
+		return defaultMaxConcurrentReconciles, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("MAX_CONCURRENT_RECONCILES must be a positive integer, but was %v", value)
+	}
+	return n, nil
+}
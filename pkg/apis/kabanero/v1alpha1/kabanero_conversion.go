@@ -0,0 +1,407 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// The annotations which hold the fields that the other version of Kabanero
+// cannot represent, so that converting to the other version and back does
+// not lose them.
+const (
+	// Set on a v1alpha2 Kabanero which was converted from v1alpha1
+	V1alpha1DataAnnotation = "kabanero.io/v1alpha1-data"
+
+	// Set on a v1alpha1 Kabanero which was converted from v1alpha2
+	V1alpha2DataAnnotation = "kabanero.io/v1alpha2-data"
+)
+
+// The v1alpha1 fields which have no v1alpha2 equivalent
+type v1alpha1Data struct {
+	// The names of the repositories which activate their default collections
+	ActivateDefaultCollections []string                `json:"activateDefaultCollections,omitempty"`
+	Tekton                     TektonCustomizationSpec `json:"tekton,omitempty"`
+	KabaneroChe                KabaneroCheSpec         `json:"kabaneroChe,omitempty"`
+	KnativeEventing            KnativeEventingStatus   `json:"knativeEventing,omitempty"`
+	Che                        *CheStatus              `json:"che,omitempty"`
+}
+
+// The v1alpha2 spec, recorded when v1alpha1 cannot represent it.  The status
+// is not recorded: it is only written by the controller, through the status
+// subresource, so a v1alpha1 client cannot change it and it is regenerated on
+// the next reconcile.  Leaving it out also keeps the annotation well below the
+// size limit for annotations.
+type v1alpha2Data struct {
+	Spec v1alpha2.KabaneroSpec `json:"spec,omitempty"`
+}
+
+var _ conversion.Convertible = &Kabanero{}
+
+// ConvertTo converts this Kabanero to the v1alpha2 hub version.  Collection
+// repositories become stack repositories, and Che becomes CodeReady Workspaces.
+func (src *Kabanero) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1alpha2.Kabanero)
+	if !ok {
+		return fmt.Errorf("Unable to convert Kabanero to %T", dstRaw)
+	}
+
+	// Start from the v1alpha2 fields recorded by an earlier conversion, if any.
+	restored := v1alpha2Data{}
+	if data, ok := src.Annotations[V1alpha2DataAnnotation]; ok {
+		err := json.Unmarshal([]byte(data), &restored)
+		if err != nil {
+			return fmt.Errorf("Unable to read annotation %v: %v", V1alpha2DataAnnotation, err)
+		}
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = restored.Spec
+	src.convertSpecTo(&dst.Spec)
+	src.convertStatusTo(&dst.Status)
+
+	// Record the fields that v1alpha2 cannot represent.
+	data := v1alpha1Data{
+		Tekton:          src.Spec.Tekton,
+		KabaneroChe:     src.Spec.Che.KabaneroChe,
+		KnativeEventing: src.Status.KnativeEventing,
+		Che:             src.Status.Che,
+	}
+	for _, repo := range src.Spec.Collections.Repositories {
+		if repo.ActivateDefaultCollections {
+			data.ActivateDefaultCollections = append(data.ActivateDefaultCollections, repo.Name)
+		}
+	}
+
+	delete(dst.Annotations, V1alpha2DataAnnotation)
+	if !equality.Semantic.DeepEqual(data, v1alpha1Data{}) {
+		err := setDataAnnotation(&dst.ObjectMeta.Annotations, V1alpha1DataAnnotation, data)
+		if err != nil {
+			return err
+		}
+	}
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	return nil
+}
+
+// ConvertFrom converts the v1alpha2 hub version to this Kabanero.  Stack
+// repositories become collection repositories.
+func (dst *Kabanero) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1alpha2.Kabanero)
+	if !ok {
+		return fmt.Errorf("Unable to convert %T to Kabanero", srcRaw)
+	}
+
+	// Restore the v1alpha1 fields recorded by an earlier conversion, if any.
+	restored := v1alpha1Data{}
+	if data, ok := src.Annotations[V1alpha1DataAnnotation]; ok {
+		err := json.Unmarshal([]byte(data), &restored)
+		if err != nil {
+			return fmt.Errorf("Unable to read annotation %v: %v", V1alpha1DataAnnotation, err)
+		}
+	}
+
+	src.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	dst.Spec = KabaneroSpec{
+		Version:          src.Spec.Version,
+		TargetNamespaces: src.Spec.TargetNamespaces,
+		Github: GithubConfig{
+			Organization: src.Spec.Github.Organization,
+			Teams:        src.Spec.Github.Teams,
+			ApiUrl:       src.Spec.Github.ApiUrl,
+		},
+		Tekton: restored.Tekton,
+		CliServices: KabaneroCliServicesCustomizationSpec{
+			Version:                  src.Spec.CliServices.Version,
+			Image:                    src.Spec.CliServices.Image,
+			Repository:               src.Spec.CliServices.Repository,
+			Tag:                      src.Spec.CliServices.Tag,
+			SessionExpirationSeconds: src.Spec.CliServices.SessionExpirationSeconds,
+		},
+		Landing: KabaneroLandingCustomizationSpec{
+			Enable:  src.Spec.Landing.Enable,
+			Version: src.Spec.Landing.Version,
+		},
+		Che: CheCustomizationSpec{
+			Enable: src.Spec.CodereadyWorkspaces.Enable,
+			CheOperatorInstance: CheOperatorInstanceSpec{
+				CheWorkspaceClusterRole: src.Spec.CodereadyWorkspaces.Operator.CustomResourceInstance.CheWorkspaceClusterRole,
+			},
+			KabaneroChe: restored.KabaneroChe,
+		},
+		Events: EventsCustomizationSpec{
+			Enable:     src.Spec.Events.Enable,
+			Version:    src.Spec.Events.Version,
+			Image:      src.Spec.Events.Image,
+			Repository: src.Spec.Events.Repository,
+			Tag:        src.Spec.Events.Tag,
+		},
+		CollectionController:       CollectionControllerSpec(src.Spec.CollectionController),
+		AdmissionControllerWebhook: AdmissionControllerWebhookCustomizationSpec(src.Spec.AdmissionControllerWebhook),
+	}
+	for _, repo := range src.Spec.Stacks.Repositories {
+		dst.Spec.Collections.Repositories = append(dst.Spec.Collections.Repositories, RepositoryConfig{
+			Name:                       repo.Name,
+			Url:                        repo.Https.Url,
+			ActivateDefaultCollections: containsString(restored.ActivateDefaultCollections, repo.Name),
+			SkipCertVerification:       repo.Https.SkipCertVerification,
+		})
+	}
+
+	dst.Status = KabaneroStatus{
+		KabaneroInstance: KabaneroInstanceStatus{
+			Ready:        src.Status.KabaneroInstance.Ready,
+			ErrorMessage: src.Status.KabaneroInstance.Message,
+			Version:      src.Status.KabaneroInstance.Version,
+		},
+		KnativeEventing: restored.KnativeEventing,
+		Serverless: ServerlessStatus{
+			Ready:        src.Status.Serverless.Ready,
+			ErrorMessage: src.Status.Serverless.Message,
+			Version:      src.Status.Serverless.Version,
+			KnativeServing: KnativeServingStatus{
+				Ready:        src.Status.Serverless.KnativeServing.Ready,
+				ErrorMessage: src.Status.Serverless.KnativeServing.Message,
+				Version:      src.Status.Serverless.KnativeServing.Version,
+			},
+		},
+		Tekton: TektonStatus{
+			Ready:        src.Status.Tekton.Ready,
+			ErrorMessage: src.Status.Tekton.Message,
+			Version:      src.Status.Tekton.Version,
+		},
+		Cli: CliStatus{
+			Ready:        src.Status.Cli.Ready,
+			ErrorMessage: src.Status.Cli.Message,
+			Hostnames:    src.Status.Cli.Hostnames,
+		},
+		Appsody: AppsodyStatus{
+			Ready:        src.Status.Appsody.Ready,
+			ErrorMessage: src.Status.Appsody.Message,
+			Version:      src.Status.Appsody.Version,
+		},
+		Che: restored.Che,
+		CollectionController: CollectionControllerStatus{
+			Ready:        src.Status.CollectionController.Ready,
+			ErrorMessage: src.Status.CollectionController.Message,
+			Version:      src.Status.CollectionController.Version,
+		},
+		AdmissionControllerWebhook: AdmissionControllerWebhookStatus{
+			Ready:        src.Status.AdmissionControllerWebhook.Ready,
+			ErrorMessage: src.Status.AdmissionControllerWebhook.Message,
+		},
+	}
+	if src.Status.Landing != nil {
+		dst.Status.Landing = &KabaneroLandingPageStatus{
+			Ready:        src.Status.Landing.Ready,
+			ErrorMessage: src.Status.Landing.Message,
+			Version:      src.Status.Landing.Version,
+		}
+	}
+	if src.Status.Kappnav != nil {
+		dst.Status.Kappnav = &KappnavStatus{
+			Ready:        src.Status.Kappnav.Ready,
+			ErrorMessage: src.Status.Kappnav.Message,
+			UiLocations:  src.Status.Kappnav.UiLocations,
+			ApiLocations: src.Status.Kappnav.ApiLocations,
+		}
+	}
+	if src.Status.Events != nil {
+		dst.Status.Events = &EventsStatus{
+			Ready:        src.Status.Events.Ready,
+			ErrorMessage: src.Status.Events.Message,
+			Hostnames:    src.Status.Events.Hostnames,
+		}
+	}
+
+	// Record the v1alpha2 spec when converting back would not reproduce it.
+	delete(dst.Annotations, V1alpha1DataAnnotation)
+	converted := v1alpha2Data{}
+	dst.convertSpecTo(&converted.Spec)
+	if !equality.Semantic.DeepEqual(converted, v1alpha2Data{Spec: src.Spec}) {
+		err := setDataAnnotation(&dst.ObjectMeta.Annotations, V1alpha2DataAnnotation, v1alpha2Data{Spec: src.Spec})
+		if err != nil {
+			return err
+		}
+	}
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	return nil
+}
+
+// MigrateCollectionRepositories adds the collection repositories of this
+// Kabanero, which were kept in a v1alpha2 instance because its CRD preserved
+// unknown fields, to the v1alpha2 Kabanero as stack repositories.  Collection
+// repositories with the name of an existing stack repository are left out.
+// The repositories which activate their default collections are recorded in
+// the v1alpha1 data annotation, as ConvertTo does.
+func (src *Kabanero) MigrateCollectionRepositories(dst *v1alpha2.Kabanero) error {
+	restored := v1alpha1Data{}
+	if data, ok := dst.Annotations[V1alpha1DataAnnotation]; ok {
+		err := json.Unmarshal([]byte(data), &restored)
+		if err != nil {
+			return fmt.Errorf("Unable to read annotation %v: %v", V1alpha1DataAnnotation, err)
+		}
+	}
+
+	for _, collectionRepo := range src.Spec.Collections.Repositories {
+		found := false
+		for _, stackRepo := range dst.Spec.Stacks.Repositories {
+			if stackRepo.Name == collectionRepo.Name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+
+		dst.Spec.Stacks.Repositories = append(dst.Spec.Stacks.Repositories, v1alpha2.RepositoryConfig{
+			Name:  collectionRepo.Name,
+			Https: v1alpha2.HttpsProtocolFile{Url: collectionRepo.Url, SkipCertVerification: collectionRepo.SkipCertVerification},
+		})
+		if collectionRepo.ActivateDefaultCollections && !containsString(restored.ActivateDefaultCollections, collectionRepo.Name) {
+			restored.ActivateDefaultCollections = append(restored.ActivateDefaultCollections, collectionRepo.Name)
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(restored, v1alpha1Data{}) {
+		return setDataAnnotation(&dst.ObjectMeta.Annotations, V1alpha1DataAnnotation, restored)
+	}
+	return nil
+}
+
+// Sets the v1alpha2 spec fields which v1alpha1 can represent.  Stack
+// repositories are matched to collection repositories by name, so that
+// their pipelines and Git release settings are kept.
+func (src *Kabanero) convertSpecTo(dst *v1alpha2.KabaneroSpec) {
+	dst.Version = src.Spec.Version
+	dst.TargetNamespaces = src.Spec.TargetNamespaces
+	dst.Github.Organization = src.Spec.Github.Organization
+	dst.Github.Teams = src.Spec.Github.Teams
+	dst.Github.ApiUrl = src.Spec.Github.ApiUrl
+	dst.CliServices.Version = src.Spec.CliServices.Version
+	dst.CliServices.Image = src.Spec.CliServices.Image
+	dst.CliServices.Repository = src.Spec.CliServices.Repository
+	dst.CliServices.Tag = src.Spec.CliServices.Tag
+	dst.CliServices.SessionExpirationSeconds = src.Spec.CliServices.SessionExpirationSeconds
+	dst.Landing.Enable = src.Spec.Landing.Enable
+	dst.Landing.Version = src.Spec.Landing.Version
+	dst.CodereadyWorkspaces.Enable = src.Spec.Che.Enable
+	dst.CodereadyWorkspaces.Operator.CustomResourceInstance.CheWorkspaceClusterRole = src.Spec.Che.CheOperatorInstance.CheWorkspaceClusterRole
+	dst.Events.Enable = src.Spec.Events.Enable
+	dst.Events.Version = src.Spec.Events.Version
+	dst.Events.Image = src.Spec.Events.Image
+	dst.Events.Repository = src.Spec.Events.Repository
+	dst.Events.Tag = src.Spec.Events.Tag
+	dst.CollectionController = v1alpha2.CollectionControllerSpec(src.Spec.CollectionController)
+	dst.AdmissionControllerWebhook = v1alpha2.AdmissionControllerWebhookCustomizationSpec(src.Spec.AdmissionControllerWebhook)
+
+	var repositories []v1alpha2.RepositoryConfig
+	for _, collectionRepo := range src.Spec.Collections.Repositories {
+		stackRepo := v1alpha2.RepositoryConfig{}
+		for _, r := range dst.Stacks.Repositories {
+			if r.Name == collectionRepo.Name {
+				stackRepo = r
+				break
+			}
+		}
+		stackRepo.Name = collectionRepo.Name
+		stackRepo.Https = v1alpha2.HttpsProtocolFile{Url: collectionRepo.Url, SkipCertVerification: collectionRepo.SkipCertVerification}
+		repositories = append(repositories, stackRepo)
+	}
+	dst.Stacks.Repositories = repositories
+}
+
+// Sets the v1alpha2 status fields which v1alpha1 can represent.
+func (src *Kabanero) convertStatusTo(dst *v1alpha2.KabaneroStatus) {
+	dst.KabaneroInstance = v1alpha2.KabaneroInstanceStatus{
+		Ready:   src.Status.KabaneroInstance.Ready,
+		Message: src.Status.KabaneroInstance.ErrorMessage,
+		Version: src.Status.KabaneroInstance.Version,
+	}
+	dst.Serverless.Ready = src.Status.Serverless.Ready
+	dst.Serverless.Message = src.Status.Serverless.ErrorMessage
+	dst.Serverless.Version = src.Status.Serverless.Version
+	dst.Serverless.KnativeServing = v1alpha2.KnativeServingStatus{
+		Ready:   src.Status.Serverless.KnativeServing.Ready,
+		Message: src.Status.Serverless.KnativeServing.ErrorMessage,
+		Version: src.Status.Serverless.KnativeServing.Version,
+	}
+	dst.Tekton.Ready = src.Status.Tekton.Ready
+	dst.Tekton.Message = src.Status.Tekton.ErrorMessage
+	dst.Tekton.Version = src.Status.Tekton.Version
+	dst.Cli = v1alpha2.CliStatus{
+		Ready:     src.Status.Cli.Ready,
+		Message:   src.Status.Cli.ErrorMessage,
+		Hostnames: src.Status.Cli.Hostnames,
+	}
+	dst.Appsody.Ready = src.Status.Appsody.Ready
+	dst.Appsody.Message = src.Status.Appsody.ErrorMessage
+	dst.Appsody.Version = src.Status.Appsody.Version
+	dst.CollectionController = v1alpha2.CollectionControllerStatus{
+		Ready:   src.Status.CollectionController.Ready,
+		Message: src.Status.CollectionController.ErrorMessage,
+		Version: src.Status.CollectionController.Version,
+	}
+	dst.AdmissionControllerWebhook = v1alpha2.AdmissionControllerWebhookStatus{
+		Ready:   src.Status.AdmissionControllerWebhook.Ready,
+		Message: src.Status.AdmissionControllerWebhook.ErrorMessage,
+	}
+
+	dst.Landing = nil
+	if src.Status.Landing != nil {
+		dst.Landing = &v1alpha2.KabaneroLandingPageStatus{
+			Ready:   src.Status.Landing.Ready,
+			Message: src.Status.Landing.ErrorMessage,
+			Version: src.Status.Landing.Version,
+		}
+	}
+	dst.Kappnav = nil
+	if src.Status.Kappnav != nil {
+		dst.Kappnav = &v1alpha2.KappnavStatus{
+			Ready:        src.Status.Kappnav.Ready,
+			Message:      src.Status.Kappnav.ErrorMessage,
+			UiLocations:  src.Status.Kappnav.UiLocations,
+			ApiLocations: src.Status.Kappnav.ApiLocations,
+		}
+	}
+	dst.Events = nil
+	if src.Status.Events != nil {
+		dst.Events = &v1alpha2.EventsStatus{
+			Ready:     src.Status.Events.Ready,
+			Message:   src.Status.Events.ErrorMessage,
+			Hostnames: src.Status.Events.Hostnames,
+		}
+	}
+}
+
+// Records data which the other version cannot represent in an annotation.
+func setDataAnnotation(annotations *map[string]string, name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("Unable to write annotation %v: %v", name, err)
+	}
+	if *annotations == nil {
+		*annotations = map[string]string{}
+	}
+	(*annotations)[name] = string(b)
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var enable = true
+
+// Verifies v1alpha1 instances survive conversion to v1alpha2 and back
+func TestKabaneroRoundTripFromV1alpha1(t *testing.T) {
+	tests := []struct {
+		name     string
+		kabanero Kabanero
+	}{
+		{"empty", Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}},
+		{"collections", Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", Annotations: map[string]string{"owner": "me"}},
+			Spec: KabaneroSpec{
+				Version:          "0.6.0",
+				TargetNamespaces: []string{"dev", "test"},
+				Github:           GithubConfig{Organization: "kabanero-io", Teams: []string{"admins"}, ApiUrl: "https://api.github.com"},
+				Collections: InstanceCollectionConfig{Repositories: []RepositoryConfig{
+					{Name: "central", Url: "https://github.com/kabanero-io/collections/releases/download/0.5.0/kabanero-index.yaml", ActivateDefaultCollections: true},
+					{Name: "incubator", Url: "https://example.com/index.yaml", SkipCertVerification: true},
+				}},
+			},
+		}},
+		{"customizations", Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+			Spec: KabaneroSpec{
+				Tekton:      TektonCustomizationSpec{Disabled: true, Version: "0.5.2"},
+				CliServices: KabaneroCliServicesCustomizationSpec{Version: "0.6.0", Image: "kabanero/cli", Repository: "repo", Tag: "tag", SessionExpirationSeconds: "60m"},
+				Landing:     KabaneroLandingCustomizationSpec{Enable: &enable, Version: "0.6.0"},
+				Che: CheCustomizationSpec{
+					Enable:              &enable,
+					CheOperatorInstance: CheOperatorInstanceSpec{CheWorkspaceClusterRole: "eclipse-codewind"},
+					KabaneroChe:         KabaneroCheSpec{Version: "0.6.0", Image: "kabanero/kabanero-che", Repository: "repo", Tag: "tag"},
+				},
+				Events:                     EventsCustomizationSpec{Enable: true, Version: "0.1.0", Image: "kabanero/events"},
+				CollectionController:       CollectionControllerSpec{Version: "0.6.0", Repository: "repo", Tag: "tag"},
+				AdmissionControllerWebhook: AdmissionControllerWebhookCustomizationSpec{Version: "0.6.0", Image: "kabanero/webhook"},
+			},
+		}},
+		{"status", Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+			Status: KabaneroStatus{
+				KabaneroInstance:           KabaneroInstanceStatus{Ready: "False", ErrorMessage: "failed", Version: "0.6.0"},
+				KnativeEventing:            KnativeEventingStatus{Ready: "True", Version: "0.7.0"},
+				Serverless:                 ServerlessStatus{Ready: "True", Version: "1.4.0", KnativeServing: KnativeServingStatus{Ready: "True", Version: "0.10.0"}},
+				Tekton:                     TektonStatus{Ready: "True", Version: "0.10.0"},
+				Cli:                        CliStatus{Ready: "True", Hostnames: []string{"cli.example.com"}},
+				Landing:                    &KabaneroLandingPageStatus{Ready: "True", Version: "0.6.0"},
+				Appsody:                    AppsodyStatus{Ready: "False", ErrorMessage: "missing"},
+				Kappnav:                    &KappnavStatus{Ready: "True", UiLocations: []string{"ui"}, ApiLocations: []string{"api"}},
+				Che:                        &CheStatus{Ready: "True", CheOperator: CheOperatorStatus{Version: "7.3.0"}},
+				Events:                     &EventsStatus{Ready: "True", Hostnames: []string{"events.example.com"}},
+				CollectionController:       CollectionControllerStatus{Ready: "True", Version: "0.6.0"},
+				AdmissionControllerWebhook: AdmissionControllerWebhookStatus{Ready: "True"},
+			},
+		}},
+	}
+
+	for _, test := range tests {
+		original := test.kabanero.DeepCopy()
+
+		hub := &v1alpha2.Kabanero{}
+		err := test.kabanero.ConvertTo(hub)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		converted := &Kabanero{}
+		err = converted.ConvertFrom(hub)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		if !equality.Semantic.DeepEqual(original, converted) {
+			t.Fatalf("%v: expected %#v but got %#v", test.name, original, converted)
+		}
+		if !equality.Semantic.DeepEqual(original, &test.kabanero) {
+			t.Fatalf("%v: the source object was modified", test.name)
+		}
+	}
+}
+
+// Verifies v1alpha2 instances survive conversion to v1alpha1 and back
+func TestKabaneroRoundTripFromV1alpha2(t *testing.T) {
+	tests := []struct {
+		name     string
+		kabanero v1alpha2.Kabanero
+	}{
+		{"empty", v1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}},
+		{"stacks", v1alpha2.Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+			Spec: v1alpha2.KabaneroSpec{
+				Version: "0.7.0",
+				Stacks: v1alpha2.InstanceStackConfig{
+					Repositories: []v1alpha2.RepositoryConfig{
+						{Name: "central", Https: v1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/stacks/releases/download/0.6.0/kabanero-index.yaml"}},
+						{
+							Name:       "git",
+							Pipelines:  []v1alpha2.PipelineSpec{{Id: "default", Sha256: "abc", Https: v1alpha2.HttpsProtocolFile{Url: "https://example.com/pipelines.tar.gz"}}},
+							GitRelease: v1alpha2.GitReleaseSpec{Hostname: "github.com", Organization: "kabanero-io", Project: "stacks", Release: "0.6.0", AssetName: "kabanero-index.yaml"},
+						},
+					},
+					Pipelines:   []v1alpha2.PipelineSpec{{Id: "default", Sha256: "def", Https: v1alpha2.HttpsProtocolFile{Url: "https://example.com/default.tar.gz"}}},
+					ImagePolicy: v1alpha2.StackImagePolicySpec{AllowedImages: []string{"docker.io/kabanero"}},
+				},
+				Triggers: []v1alpha2.TriggerSpec{{Id: "incubator", Sha256: "123", Https: v1alpha2.HttpsProtocolFile{Url: "https://example.com/triggers.tar.gz"}}},
+			},
+		}},
+		{"customizations", v1alpha2.Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", Annotations: map[string]string{"owner": "me"}},
+			Spec: v1alpha2.KabaneroSpec{
+				Landing: v1alpha2.KabaneroLandingCustomizationSpec{Enable: &enable, Version: "0.6.0", Image: "kabanero/landing", Tag: "latest"},
+				CodereadyWorkspaces: v1alpha2.CRWCustomizationSpec{
+					Enable: &enable,
+					Operator: v1alpha2.CRWOperatorSpec{CustomResourceInstance: v1alpha2.CRWOperatorCRInstanceSpec{
+						DevFileRegistryImage:    v1alpha2.CWRCustomResourceDevFileRegImage{Version: "0.6.0"},
+						CheWorkspaceClusterRole: "eclipse-codewind",
+						TLSSupport:              &enable,
+					}},
+				},
+				StackController: v1alpha2.StackControllerSpec{Version: "0.7.0"},
+				Sso:             v1alpha2.SsoCustomizationSpec{Enable: true, AdminSecretName: "sso-admin"},
+				CliServices:     v1alpha2.KabaneroCliServicesCustomizationSpec{Version: "0.7.0", Ingress: v1alpha2.ComponentIngressSpec{Host: "cli.example.com"}},
+				Events:          v1alpha2.EventsCustomizationSpec{Enable: true, Ingress: v1alpha2.ComponentIngressSpec{TlsSecretName: "events-tls"}},
+				Ingress:         v1alpha2.IngressSpec{Domain: "apps.example.com", Class: "nginx"},
+				Github: v1alpha2.GithubConfig{
+					Organization: "kabanero-io",
+					Webhook:      v1alpha2.GithubWebhookSpec{Enable: true, Repositories: []string{"app"}, TokenSecretRef: v1alpha2.SecretKeyRef{Name: "github-token"}},
+				},
+			},
+		}},
+		{"status", v1alpha2.Kabanero{
+			ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+			Status: v1alpha2.KabaneroStatus{
+				KabaneroInstance: v1alpha2.KabaneroInstanceStatus{Ready: "True", Version: "0.7.0"},
+				Tekton:           v1alpha2.TektonStatus{Ready: "True", Version: "0.10.0"},
+				Landing:          &v1alpha2.KabaneroLandingPageStatus{Ready: "True"},
+			},
+		}},
+	}
+
+	for _, test := range tests {
+		original := test.kabanero.DeepCopy()
+
+		spoke := &Kabanero{}
+		err := spoke.ConvertFrom(&test.kabanero)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		converted := &v1alpha2.Kabanero{}
+		err = spoke.ConvertTo(converted)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		if !equality.Semantic.DeepEqual(original, converted) {
+			t.Fatalf("%v: expected %#v but got %#v", test.name, original, converted)
+		}
+		if !equality.Semantic.DeepEqual(original, &test.kabanero) {
+			t.Fatalf("%v: the source object was modified", test.name)
+		}
+	}
+}
+
+// Verifies collection repositories become stack repositories, and changes made
+// to a converted v1alpha1 instance are applied over the recorded v1alpha2 fields
+func TestKabaneroConvertCollectionRepositories(t *testing.T) {
+	hub := &v1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: v1alpha2.KabaneroSpec{
+			Stacks: v1alpha2.InstanceStackConfig{
+				Repositories: []v1alpha2.RepositoryConfig{{
+					Name:      "central",
+					Https:     v1alpha2.HttpsProtocolFile{Url: "https://example.com/index.yaml"},
+					Pipelines: []v1alpha2.PipelineSpec{{Id: "default", Sha256: "abc"}},
+				}},
+			},
+			Triggers: []v1alpha2.TriggerSpec{{Id: "incubator", Sha256: "123"}},
+		},
+	}
+
+	spoke := &Kabanero{}
+	err := spoke.ConvertFrom(hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spoke.Spec.Collections.Repositories) != 1 || spoke.Spec.Collections.Repositories[0].Url != "https://example.com/index.yaml" {
+		t.Fatalf("Unexpected collection repositories: %v", spoke.Spec.Collections.Repositories)
+	}
+	if _, ok := spoke.Annotations[V1alpha2DataAnnotation]; !ok {
+		t.Fatal("Expected the pipelines and triggers to be recorded in an annotation")
+	}
+
+	// A v1alpha1 client changes the repository URL and adds a repository
+	spoke.Spec.Collections.Repositories[0].Url = "https://example.com/new-index.yaml"
+	spoke.Spec.Collections.Repositories = append(spoke.Spec.Collections.Repositories, RepositoryConfig{Name: "incubator", Url: "https://example.com/incubator.yaml"})
+
+	converted := &v1alpha2.Kabanero{}
+	err = spoke.ConvertTo(converted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := converted.Spec.Stacks.Repositories
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 stack repositories: %v", repos)
+	}
+	if repos[0].Https.Url != "https://example.com/new-index.yaml" || len(repos[0].Pipelines) != 1 {
+		t.Fatalf("Expected the repository URL to change and its pipelines to be kept: %v", repos[0])
+	}
+	if repos[1].Name != "incubator" || repos[1].Https.Url != "https://example.com/incubator.yaml" {
+		t.Fatalf("Unexpected new repository: %v", repos[1])
+	}
+	if len(converted.Spec.Triggers) != 1 {
+		t.Fatalf("Expected the triggers to be kept: %v", converted.Spec.Triggers)
+	}
+	if len(converted.Annotations) != 0 {
+		t.Fatalf("Expected no conversion annotations: %v", converted.Annotations)
+	}
+}
+
+// Verifies the v1alpha2 status is not recorded in the conversion annotation
+func TestKabaneroConvertFromDoesNotRecordStatus(t *testing.T) {
+	hub := &v1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Status: v1alpha2.KabaneroStatus{
+			RejectedStacks:  []v1alpha2.RejectedStackStatus{{Name: "java-microprofile", Version: "0.2.19", Message: "rejected"}},
+			StackController: v1alpha2.StackControllerStatus{Ready: "True", Version: "0.7.0"},
+		},
+	}
+
+	spoke := &Kabanero{}
+	err := spoke.ConvertFrom(hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := spoke.Annotations[V1alpha2DataAnnotation]; ok {
+		t.Fatalf("Expected the status not to be recorded, but got annotation %v", data)
+	}
+}
+
+// Verifies leftover collection repositories are added as stack repositories,
+// and the repositories which activate their default collections are recorded
+func TestKabaneroMigrateCollectionRepositories(t *testing.T) {
+	legacy := &Kabanero{
+		Spec: KabaneroSpec{
+			Collections: InstanceCollectionConfig{
+				Repositories: []RepositoryConfig{
+					{Name: "central", Url: "https://example.com/collections.yaml", ActivateDefaultCollections: true, SkipCertVerification: true},
+					{Name: "incubator", Url: "https://example.com/old-incubator.yaml"},
+				},
+			},
+		},
+	}
+	hub := &v1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: v1alpha2.KabaneroSpec{
+			Stacks: v1alpha2.InstanceStackConfig{
+				Repositories: []v1alpha2.RepositoryConfig{{Name: "incubator", Https: v1alpha2.HttpsProtocolFile{Url: "https://example.com/incubator.yaml"}}},
+			},
+		},
+	}
+
+	err := legacy.MigrateCollectionRepositories(hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := hub.Spec.Stacks.Repositories
+	if len(repos) != 2 || repos[0].Https.Url != "https://example.com/incubator.yaml" {
+		t.Fatalf("Expected the existing stack repository to be kept: %v", repos)
+	}
+	if repos[1].Name != "central" || repos[1].Https.Url != "https://example.com/collections.yaml" || !repos[1].Https.SkipCertVerification {
+		t.Fatalf("Expected the collection repository to become a stack repository: %v", repos[1])
+	}
+
+	// Converting back to v1alpha1 restores the default collection activation
+	spoke := &Kabanero{}
+	err = spoke.ConvertFrom(hub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, repo := range spoke.Spec.Collections.Repositories {
+		if repo.ActivateDefaultCollections != (repo.Name == "central") {
+			t.Fatalf("Unexpected default collection activation: %v", spoke.Spec.Collections.Repositories)
+		}
+	}
+}
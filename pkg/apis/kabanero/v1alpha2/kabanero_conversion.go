@@ -0,0 +1,5 @@
+package v1alpha2
+
+// Hub marks v1alpha2 as the version which other versions of Kabanero are
+// converted to and from.
+func (*Kabanero) Hub() {}
@@ -44,6 +44,16 @@ type KabaneroSpec struct {
 	AdmissionControllerWebhook AdmissionControllerWebhookCustomizationSpec `json:"admissionControllerWebhook,omitempty"`
 
 	Sso SsoCustomizationSpec `json:"sso,omitempty"`
+
+	VersionCatalog VersionCatalogSpec `json:"versionCatalog,omitempty"`
+
+	Subscriptions PrerequisiteSubscriptionsSpec `json:"subscriptions,omitempty"`
+
+	DigestPinning DigestPinningSpec `json:"digestPinning,omitempty"`
+
+	SecretRotation SecretRotationSpec `json:"secretRotation,omitempty"`
+
+	Ingress IngressSpec `json:"ingress,omitempty"`
 }
 
 // InstanceStackConfig defines the customization entries for a set of stacks.
@@ -53,6 +63,50 @@ type InstanceStackConfig struct {
 
 	// +listType=set
 	Pipelines []PipelineSpec `json:"pipelines,omitempty"`
+
+	ImagePolicy StackImagePolicySpec `json:"imagePolicy,omitempty"`
+
+	// The Tekton API version which stack pipeline assets are applied with.  When
+	// v1beta1 is requested, and served by the cluster, v1alpha1 Tasks and Pipelines
+	// are converted to v1beta1 before they are applied.  When empty, assets are
+	// applied as they are found in the pipeline archive.
+	TektonApiVersion string `json:"tektonApiVersion,omitempty"`
+
+	// What the stack controller does with pipeline assets whose owning stack no
+	// longer exists.  Report, the default, logs them.  Delete deletes them.
+	OrphanedAssetPolicy string `json:"orphanedAssetPolicy,omitempty"`
+
+	Timeouts StackTimeoutsSpec `json:"timeouts,omitempty"`
+}
+
+// StackTimeoutsSpec limits the time spent retrieving stacks, using durations such as 30s or 2m.
+// Download limits each download of a stack index or pipeline archive.  IndexResolution limits
+// the retrieval of the index of a stack repository.  Reconcile limits a single reconcile of a Stack.
+type StackTimeoutsSpec struct {
+	Download        string `json:"download,omitempty"`
+	IndexResolution string `json:"indexResolution,omitempty"`
+	Reconcile       string `json:"reconcile,omitempty"`
+}
+
+const (
+	// OrphanedAssetPolicyReport logs stack assets whose owning stack no longer exists.
+	OrphanedAssetPolicyReport = "Report"
+
+	// OrphanedAssetPolicyDelete deletes stack assets whose owning stack no longer exists.
+	// Assets which may still be used by a stack in another namespace are only reported.
+	OrphanedAssetPolicyDelete = "Delete"
+)
+
+// StackImagePolicySpec defines the container images which stacks in the namespace may use.
+// AllowedImages lists registry or repository prefixes, such as quay.io or docker.io/kabanero.
+// When it is empty, images from any registry are allowed.  RequireSignatures requires that
+// the image at the tag matching the stack version has a cosign signature which verifies with
+// one of the PEM encoded public keys in the SignatureKeys ConfigMap.  The key defaults to cosign.pub.
+type StackImagePolicySpec struct {
+	// +listType=set
+	AllowedImages     []string        `json:"allowedImages,omitempty"`
+	RequireSignatures bool            `json:"requireSignatures,omitempty"`
+	SignatureKeys     ConfigMapKeyRef `json:"signatureKeys,omitempty"`
 }
 
 // PipelineSpec defines the sets of default pipelines for the stacks.
@@ -63,10 +117,38 @@ type PipelineSpec struct {
 	GitRelease GitReleaseSpec    `json:"gitRelease,omitempty"`
 }
 
-// HttpsProtocolFile defines how to retrieve a file over https
+// HttpsProtocolFile defines how to retrieve a file over https.  The server can be
+// authenticated to using a bearer token from Credentials, or using the Secret
+// identified by Auth, but not both.  The certificate authorities in CaBundle are
+// trusted in addition to the system ones.
 type HttpsProtocolFile struct {
-	Url                  string `json:"url,omitempty"`
-	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+	Url                  string            `json:"url,omitempty"`
+	SkipCertVerification bool              `json:"skipCertVerification,omitempty"`
+	Credentials          CredentialsSource `json:"credentials,omitempty"`
+	Auth                 HttpsAuthSpec     `json:"auth,omitempty"`
+	CaBundle             ConfigMapKeyRef   `json:"caBundle,omitempty"`
+}
+
+// Types of HTTPS authentication
+const (
+	// The Secret contains keys username and password
+	HttpsAuthTypeBasic = "basic"
+	// The Secret contains key token
+	HttpsAuthTypeBearer = "bearer"
+	// The Secret contains keys tls.crt and tls.key, such as a Secret of type kubernetes.io/tls
+	HttpsAuthTypeClientCert = "clientCert"
+)
+
+// HttpsAuthSpec identifies the Secret used to authenticate to an HTTPS server.
+type HttpsAuthSpec struct {
+	Type       string `json:"type,omitempty"`
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ConfigMapKeyRef identifies a key in a ConfigMap.  The key defaults to "ca-bundle.crt".
+type ConfigMapKeyRef struct {
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key,omitempty"`
 }
 
 // TriggerSpec defines the sets of default triggers for the stacks
@@ -86,6 +168,30 @@ type GithubConfig struct {
 	// +listType=set
 	Teams  []string `json:"teams,omitempty"`
 	ApiUrl string   `json:"apiUrl,omitempty"`
+
+	// Registers the webhook of the events service with the organization
+	Webhook GithubWebhookSpec `json:"webhook,omitempty"`
+}
+
+// GithubWebhookSpec defines the webhook which the operator registers with the
+// GitHub organization, or some of its repositories, once the events service has
+// been assigned a hostname.  The webhook is removed when it is disabled, or when
+// the events service or Kabanero instance is removed.
+type GithubWebhookSpec struct {
+	Enable bool `json:"enable,omitempty"`
+
+	// The repositories of the organization with which the webhook is registered.
+	// When empty, an organization webhook is registered.
+	// +listType=set
+	Repositories []string `json:"repositories,omitempty"`
+
+	// The events sent to the webhook.  Defaults to push and pull_request.
+	// +listType=set
+	Events []string `json:"events,omitempty"`
+
+	// The Secret key holding a GitHub token allowed to manage the webhooks of the
+	// organization or repositories.  The key defaults to "password".
+	TokenSecretRef SecretKeyRef `json:"tokenSecretRef,omitempty"`
 }
 
 // RepositoryConfig defines customization entries for a stack.
@@ -103,8 +209,40 @@ type GitReleaseSpec struct {
 	Organization         string `json:"organization,omitempty"`
 	Project              string `json:"project,omitempty"`
 	Release              string `json:"release,omitempty"`
-	AssetName            string `json:"assetName,omitempty"`
-	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+	AssetName            string            `json:"assetName,omitempty"`
+	SkipCertVerification bool              `json:"skipCertVerification,omitempty"`
+	Credentials          CredentialsSource `json:"credentials,omitempty"`
+}
+
+// CredentialsSource identifies where the token used to retrieve a file is found.
+// At most one source can be specified.  When none is specified, the Secrets in the
+// namespace are searched for one annotated with the URL of the host.
+type CredentialsSource struct {
+	// A Secret in the namespace of the instance
+	SecretRef SecretKeyRef `json:"secretRef,omitempty"`
+	// A file mounted in the operator, such as by a CSI secrets store driver
+	File string `json:"file,omitempty"`
+	// A secret in a HashiCorp Vault KV secrets engine
+	Vault VaultSecretSource `json:"vault,omitempty"`
+}
+
+// SecretKeyRef identifies a key in a Secret.  The key defaults to "password".
+type SecretKeyRef struct {
+	Name string `json:"name,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// VaultSecretSource identifies a key in a Vault KV secret, such as
+// "secret/data/kabanero/github".  The operator logs in to Vault using the
+// Kubernetes auth method.  The Vault server, the auth method mount and the roles
+// which may be used are configured for the operator, not by the instance.  Address,
+// when specified, must be the configured server, and Role must be one of the
+// configured roles.  Role defaults to the first configured role.
+type VaultSecretSource struct {
+	Address string `json:"address,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Key     string `json:"key,omitempty"`
+	Role    string `json:"role,omitempty"`
 }
 
 // KabaneroCliServicesCustomizationSpec defines customization entries for the Kabanero CLI.
@@ -115,6 +253,7 @@ type KabaneroCliServicesCustomizationSpec struct {
 	Repository               string `json:"repository,omitempty"`
 	Tag                      string `json:"tag,omitempty"`
 	SessionExpirationSeconds string `json:"sessionExpirationSeconds,omitempty"`
+	Ingress                  ComponentIngressSpec `json:"ingress,omitempty"`
 }
 
 // KabaneroLandingCustomizationSpec defines customization entries for Kabanero landing page.
@@ -124,8 +263,49 @@ type KabaneroLandingCustomizationSpec struct {
 	Image                    string `json:"image,omitempty"`
 	Repository               string `json:"repository,omitempty"`
 	Tag                      string `json:"tag,omitempty"`
+	Ingress                  ComponentIngressSpec `json:"ingress,omitempty"`
+
+	// The links added to the OpenShift web console.  When none are listed, links to
+	// the landing page, docs and guides are added.
+	// +listType=set
+	ConsoleLinks []ConsoleLinkSpec `json:"consoleLinks,omitempty"`
+}
+
+// ConsoleLinkSpec defines a link added to the OpenShift web console.  The href and
+// imageUrl are templates, rendered with the landingURL, and the name and namespace
+// of the Kabanero instance, such as "{{ .landingURL }}/docs".
+type ConsoleLinkSpec struct {
+	// Identifies the link within the Kabanero instance.  The name of the ConsoleLink
+	// is derived from it and the namespace and name of the instance.
+	Name string `json:"name"`
+
+	// ApplicationMenu, HelpMenu, UserMenu or NamespaceDashboard
+	Location string `json:"location"`
+
+	Text string `json:"text"`
+
+	Href string `json:"href"`
+
+	// The application menu section of an ApplicationMenu link.  Defaults to Kabanero.
+	Section string `json:"section,omitempty"`
+
+	// The icon of an ApplicationMenu link
+	ImageUrl string `json:"imageUrl,omitempty"`
+
+	// The namespaces whose dashboards show a NamespaceDashboard link.  Defaults to the
+	// namespace of the Kabanero instance and its target namespaces.
+	// +listType=set
+	Namespaces []string `json:"namespaces,omitempty"`
 }
 
+// Locations of console links
+const (
+	ConsoleLinkLocationApplicationMenu    = "ApplicationMenu"
+	ConsoleLinkLocationHelpMenu           = "HelpMenu"
+	ConsoleLinkLocationUserMenu           = "UserMenu"
+	ConsoleLinkLocationNamespaceDashboard = "NamespaceDashboard"
+)
+
 // CRWCustomizationSpec defines customization entries for codeready-workspaces.
 type CRWCustomizationSpec struct {
 	Enable   *bool           `json:"enable,omitempty"`
@@ -155,11 +335,92 @@ type CWRCustomResourceDevFileRegImage struct {
 }
 
 type EventsCustomizationSpec struct {
-	Enable     bool   `json:"enable,omitempty"`
-	Version    string `json:"version,omitempty"`
-	Image      string `json:"image,omitempty"`
-	Repository string `json:"repository,omitempty"`
-	Tag        string `json:"tag,omitempty"`
+	Enable     bool                 `json:"enable,omitempty"`
+	Version    string               `json:"version,omitempty"`
+	Image      string               `json:"image,omitempty"`
+	Repository string               `json:"repository,omitempty"`
+	Tag        string               `json:"tag,omitempty"`
+	Ingress    ComponentIngressSpec `json:"ingress,omitempty"`
+
+	// The sources of the webhooks accepted by the events service
+	// +listType=set
+	WebhookSources []EventsWebhookSourceSpec `json:"webhookSources,omitempty"`
+
+	// Maps the repositories of the webhook sources to the stacks and pipelines which
+	// process their events
+	// +listType=set
+	Mappings []EventsMappingSpec `json:"mappings,omitempty"`
+
+	// The destinations to which processed events are sent
+	// +listType=set
+	Destinations []EventsDestinationSpec `json:"destinations,omitempty"`
+}
+
+// EventsWebhookSourceSpec defines a GitHub organization, or some of its repositories,
+// whose webhooks are accepted by the events service at /webhook/<name>.
+type EventsWebhookSourceSpec struct {
+	Name string `json:"name"`
+
+	Organization string `json:"organization"`
+
+	// The repositories of the organization whose webhooks are accepted.  When empty,
+	// the webhooks of all repositories are accepted.
+	// +listType=set
+	Repositories []string `json:"repositories,omitempty"`
+
+	// The Secret whose "secret" key holds the webhook secret.  Defaults to the
+	// default-events-secret generated by the operator.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// EventsMappingSpec maps the repositories of a webhook source to the stack and
+// pipeline which process their events.
+type EventsMappingSpec struct {
+	// The name of the webhook source
+	Source string `json:"source"`
+
+	// The repositories mapped.  When empty, all repositories of the source are mapped.
+	// +listType=set
+	Repositories []string `json:"repositories,omitempty"`
+
+	// The stack id, and the pipeline of the stack, which process the events
+	Stack    string `json:"stack"`
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// The name of the destination to which the processed events are sent
+	Destination string `json:"destination,omitempty"`
+}
+
+// EventsDestinationSpec defines a destination, such as a Tekton EventListener, to
+// which processed events are sent.
+type EventsDestinationSpec struct {
+	Name string `json:"name"`
+
+	Url string `json:"url"`
+
+	// Whether the certificate of the destination is not verified
+	SkipCertVerification bool `json:"skipCertVerification,omitempty"`
+}
+
+// IngressSpec defines how the landing page, CLI and events are exposed on clusters
+// which do not serve OpenShift Routes.  Each is exposed using an Ingress instead.
+type IngressSpec struct {
+	// The domain under which hosts are generated, as <name>-<namespace>.<domain>, for
+	// components which do not specify a host.  When empty, hosts are not generated and
+	// the address assigned by the ingress controller is used.
+	Domain string `json:"domain,omitempty"`
+
+	// The ingress class, set using the kubernetes.io/ingress.class annotation.
+	Class string `json:"class,omitempty"`
+
+	// The TLS Secret used for hosts of components which do not specify one.
+	TlsSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// ComponentIngressSpec defines the host and TLS Secret of the Ingress exposing a component.
+type ComponentIngressSpec struct {
+	Host          string `json:"host,omitempty"`
+	TlsSecretName string `json:"tlsSecretName,omitempty"`
 }
 
 // CollectionControllerSpec defines customization entried for the Kabanero collection controller.
@@ -189,6 +450,83 @@ type SsoCustomizationSpec struct {
 	Enable          bool   `json:"enable,omitempty"`
 	Provider        string `json:"provider,omitempty"`
 	AdminSecretName string `json:"adminSecretName,omitempty"`
+	// The name of a Secret containing the clientId and clientSecret of a GitHub
+	// OAuth application.  When specified, users can log in to the Kabanero realm
+	// with their GitHub identity.
+	GithubSecretName string `json:"githubSecretName,omitempty"`
+	// An existing OpenID Connect provider to use instead of the bundled SSO
+	// server.  When specified, the bundled SSO server is not deployed.
+	External *ExternalSsoSpec `json:"external,omitempty"`
+}
+
+// ExternalSsoSpec identifies an external OpenID Connect provider, and the
+// client the Kabanero components use to log users in with it.
+type ExternalSsoSpec struct {
+	// The issuer URL of the provider.  The provider configuration is
+	// discovered from <issuerUrl>/.well-known/openid-configuration.
+	IssuerUrl string `json:"issuerUrl,omitempty"`
+	// The name of a Secret containing the clientId and clientSecret of the
+	// client registered with the provider.
+	ClientSecretName string `json:"clientSecretName,omitempty"`
+	// The scopes requested when logging in.  Defaults to openid, profile and email.
+	// +listType=set
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// VersionCatalogSpec identifies a versions.yaml document which is merged over
+// the catalogue embedded in the operator.  Either a ConfigMap in the Kabanero
+// namespace or a URL may be specified.
+type VersionCatalogSpec struct {
+	ConfigMapName        string `json:"configMapName,omitempty"`
+	ConfigMapKey         string `json:"configMapKey,omitempty"`
+	Url                  string `json:"url,omitempty"`
+	SkipCertVerification bool   `json:"skipCertVerification,omitempty"`
+}
+
+// PrerequisiteSubscriptionsSpec selects the prerequisite operators whose OLM
+// subscriptions are managed by the Kabanero instance.
+type PrerequisiteSubscriptionsSpec struct {
+	Tekton              SubscriptionCustomizationSpec `json:"tekton,omitempty"`
+	Serverless          SubscriptionCustomizationSpec `json:"serverless,omitempty"`
+	Appsody             SubscriptionCustomizationSpec `json:"appsody,omitempty"`
+	CodereadyWorkspaces SubscriptionCustomizationSpec `json:"codeReadyWorkspaces,omitempty"`
+	Kappnav             SubscriptionCustomizationSpec `json:"kappnav,omitempty"`
+}
+
+// SubscriptionCustomizationSpec enables management of an OLM subscription.  The
+// remaining fields override the values in the version catalogue.
+type SubscriptionCustomizationSpec struct {
+	Enable          bool   `json:"enable,omitempty"`
+	Channel         string `json:"channel,omitempty"`
+	Source          string `json:"source,omitempty"`
+	SourceNamespace string `json:"sourceNamespace,omitempty"`
+	Approval        string `json:"approval,omitempty"`
+	StartingCSV     string `json:"startingCSV,omitempty"`
+}
+
+// DigestPinningSpec defines how the images of Kabanero components and stacks are resolved to digests.
+// When enabled, image tags are resolved to digests and reported in status.  When deploy is also
+// set, the Kabanero components are deployed using the digest pinned image references.  Pull
+// secrets name docker config secrets in the Kabanero namespace; when none are specified, the
+// image pull secrets of the namespace's default service account are used.
+type DigestPinningSpec struct {
+	Enable bool `json:"enable,omitempty"`
+	Deploy bool `json:"deploy,omitempty"`
+	// +listType=set
+	PullSecrets []string `json:"pullSecrets,omitempty"`
+}
+
+// SecretRotationSpec defines how often the secrets generated by the operator are
+// regenerated.  Each interval is a duration, such as 720h.  A secret is not rotated
+// automatically when its interval is not specified, but may be rotated at any time
+// by adding the kabanero.io/rotate-now annotation to it.
+type SecretRotationSpec struct {
+	// The interval between rotations of the SSO database credentials
+	SsoDatabase string `json:"ssoDatabase,omitempty"`
+	// The interval between rotations of the default events secret
+	Events string `json:"events,omitempty"`
+	// The interval between rotations of the CLI AES encryption key
+	CliEncryptionKey string `json:"cliEncryptionKey,omitempty"`
 }
 
 // KabaneroStatus defines the observed state of the Kabanero instance.
@@ -232,6 +570,28 @@ type KabaneroStatus struct {
 
 	// SSO server status
 	Sso SsoStatus `json:"sso,omitempty"`
+
+	// Active version catalogue status
+	VersionCatalog VersionCatalogStatus `json:"versionCatalog,omitempty"`
+
+	// Kabanero platform version upgrade status
+	Upgrade *KabaneroUpgradeStatus `json:"upgrade,omitempty"`
+
+	// Managed prerequisite operator subscription status
+	// +listType=set
+	Subscriptions []SubscriptionStatus `json:"subscriptions,omitempty"`
+
+	// Resolved image digests of the Kabanero components
+	// +listType=set
+	Images []ComponentImageStatus `json:"images,omitempty"`
+
+	// Featured stack versions which were not created because they violate the stack image policy
+	// +listType=set
+	RejectedStacks []RejectedStackStatus `json:"rejectedStacks,omitempty"`
+
+	// Generated secrets which could not be rotated, or whose workloads could not be restarted after rotation
+	// +listType=set
+	SecretRotationFailures []SecretRotationFailureStatus `json:"secretRotationFailures,omitempty"`
 }
 
 // KabaneroInstanceStatus defines the observed status details of Kabanero operator instance
@@ -242,17 +602,21 @@ type KabaneroInstanceStatus struct {
 }
 
 // TektonStatus defines the observed status details of Tekton.
+// Compatible reports whether the installed version satisfies the range required by the Kabanero version.
 type TektonStatus struct {
-	Ready   string `json:"ready,omitempty"`
-	Message string `json:"message,omitempty"`
-	Version string `json:"version,omitempty"`
+	Ready      string `json:"ready,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Compatible string `json:"compatible,omitempty"`
 }
 
 // ServerlessStatus defines the observed status details of Open Shift serverless.
+// Compatible reports whether the installed version satisfies the range required by the Kabanero version.
 type ServerlessStatus struct {
 	Ready          string               `json:"ready,omitempty"`
 	Message        string               `json:"message,omitempty"`
 	Version        string               `json:"version,omitempty"`
+	Compatible     string               `json:"compatible,omitempty"`
 	KnativeServing KnativeServingStatus `json:"knativeServing,omitempty"`
 }
 
@@ -279,10 +643,12 @@ type KabaneroLandingPageStatus struct {
 }
 
 // AppsodyStatus defines the observed status details of Appsody.
+// Compatible reports whether the installed version satisfies the range required by the Kabanero version.
 type AppsodyStatus struct {
-	Ready   string `json:"ready,omitempty"`
-	Message string `json:"message,omitempty"`
-	Version string `json:"version,omitempty"`
+	Ready      string `json:"ready,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Compatible string `json:"compatible,omitempty"`
 }
 
 // KappnavStatus defines the observed status details of Kubernetes Application Navigator.
@@ -303,9 +669,11 @@ type CRWStatus struct {
 }
 
 // CRWOperatorStatus defines the observed status details of the codeready-workspaces operator.
+// Compatible reports whether the installed version satisfies the range required by the Kabanero version.
 type CRWOperatorStatus struct {
-	Version  string            `json:"version,omitempty"`
-	Instance CRWInstanceStatus `json:"instance,omitempty"`
+	Version    string            `json:"version,omitempty"`
+	Compatible string            `json:"compatible,omitempty"`
+	Instance   CRWInstanceStatus `json:"instance,omitempty"`
 }
 
 // CRWInstanceStatus defines the observed status details of the codeready-workspaces operator custom resource.
@@ -323,6 +691,9 @@ type EventsStatus struct {
 	Message string `json:"message,omitempty"`
 	// +listType=set
 	Hostnames []string `json:"hostnames,omitempty"`
+	// The URLs at which the webhooks of each source are accepted
+	// +listType=set
+	WebhookEndpoints []string `json:"webhookEndpoints,omitempty"`
 }
 
 // CollectionControllerStatus defines the observed status details of the Kabanero collection controller.
@@ -350,6 +721,83 @@ type SsoStatus struct {
 	Configured string `json:"configured,omitempty"`
 	Ready      string `json:"ready,omitempty"`
 	Message    string `json:"message,omitempty"`
+	// The issuer of the external OpenID Connect provider, if one is used
+	Issuer string `json:"issuer,omitempty"`
+	// Whether the discovery document of the external provider could be retrieved
+	DiscoveryReachable string `json:"discoveryReachable,omitempty"`
+	// Whether the signing keys (JWKS) of the external provider could be retrieved
+	JwksReachable string `json:"jwksReachable,omitempty"`
+}
+
+// VersionCatalogStatus defines the observed status details of the active version catalogue.
+type VersionCatalogStatus struct {
+	Ready          string `json:"ready,omitempty"`
+	Message        string `json:"message,omitempty"`
+	Source         string `json:"source,omitempty"`
+	DefaultVersion string `json:"defaultVersion,omitempty"`
+	// +listType=set
+	Versions []string `json:"versions,omitempty"`
+}
+
+// KabaneroUpgradeStatus defines the observed status details of a Kabanero platform version upgrade.
+type KabaneroUpgradeStatus struct {
+	Phase       string `json:"phase,omitempty"`
+	Message     string `json:"message,omitempty"`
+	FromVersion string `json:"fromVersion,omitempty"`
+	ToVersion   string `json:"toVersion,omitempty"`
+	// The generation of the Kabanero instance when the upgrade was last attempted
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +listType=set
+	Components []ComponentUpgradeStatus `json:"components,omitempty"`
+}
+
+// ComponentUpgradeStatus defines the observed upgrade status details of a single software component.
+type ComponentUpgradeStatus struct {
+	Name        string       `json:"name,omitempty"`
+	FromVersion string       `json:"fromVersion,omitempty"`
+	ToVersion   string       `json:"toVersion,omitempty"`
+	Phase       string       `json:"phase,omitempty"`
+	StartTime   *metav1.Time `json:"startTime,omitempty"`
+}
+
+// SubscriptionStatus defines the observed status details of a managed OLM subscription.
+type SubscriptionStatus struct {
+	Name                string `json:"name,omitempty"`
+	Namespace           string `json:"namespace,omitempty"`
+	Package             string `json:"package,omitempty"`
+	Channel             string `json:"channel,omitempty"`
+	InstalledCSV        string `json:"installedCSV,omitempty"`
+	InstallPlan         string `json:"installPlan,omitempty"`
+	InstallPlanPhase    string `json:"installPlanPhase,omitempty"`
+	InstallPlanApproval string `json:"installPlanApproval,omitempty"`
+	InstallPlanApproved string `json:"installPlanApproved,omitempty"`
+	Ready               string `json:"ready,omitempty"`
+	Message             string `json:"message,omitempty"`
+}
+
+// ComponentImageStatus defines the resolved digest of the image used by a Kabanero component.
+// PreviousDigest is set when the image tag was found to have moved to a new digest,
+// at MovedTime, and is cleared a day later.
+type ComponentImageStatus struct {
+	Component      string       `json:"component,omitempty"`
+	Image          string       `json:"image,omitempty"`
+	Digest         string       `json:"digest,omitempty"`
+	PreviousDigest string       `json:"previousDigest,omitempty"`
+	MovedTime      *metav1.Time `json:"movedTime,omitempty"`
+	Message        string       `json:"message,omitempty"`
+}
+
+// SecretRotationFailureStatus defines a generated secret whose last rotation failed.
+type SecretRotationFailureStatus struct {
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// RejectedStackStatus defines a featured stack version which violates the stack image policy.
+type RejectedStackStatus struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Message string `json:"message,omitempty"`
 }
 
 // Kabanero is the Schema for the kabaneros API
@@ -33,7 +33,28 @@ type StackVersion struct {
 	DesiredState         string         `json:"desiredState,omitempty"`
 	SkipCertVerification bool           `json:"skipCertVerification,omitempty"`
 	// +listType=set
-	Images []Image `json:"images,omitempty"`
+	Images   []Image           `json:"images,omitempty"`
+	Requires StackRequirements `json:"requires,omitempty"`
+}
+
+// StackRequirements defines what a stack version needs from the platform before its assets
+// are applied.  Kabanero is a semantic version range the Kabanero version must satisfy, such
+// as ">=0.7.0".  TektonApiVersions lists the Tekton API versions, such as v1beta1, which the
+// cluster must serve.  Stacks lists other stacks, whose pipelines this stack version uses,
+// which must have an active version.
+type StackRequirements struct {
+	Kabanero string `json:"kabanero,omitempty"`
+	// +listType=set
+	TektonApiVersions []string `json:"tektonApiVersions,omitempty"`
+	// +listType=set
+	Stacks []StackDependency `json:"stacks,omitempty"`
+}
+
+// StackDependency identifies a stack which must have an active version.  Version is an
+// optional semantic version range the active version must satisfy.
+type StackDependency struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
 }
 
 // PipelineStatus defines the observed state of the assets located within a single pipeline .tar.gz.
@@ -56,6 +77,12 @@ type RepositoryAssetStatus struct {
 	Digest        string `json:"assetDigest,omitempty"`
 	Status        string `json:"status,omitempty"`
 	StatusMessage string `json:"statusMessage,omitempty"`
+	// The number of consecutive failed attempts to apply the asset
+	Attempts int `json:"attempts,omitempty"`
+	// The error from the last failed attempt
+	LastError string `json:"lastError,omitempty"`
+	// When a failed asset will next be retried
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
 }
 
 // StackStatus defines the observed state of a stack
@@ -64,6 +91,16 @@ type StackStatus struct {
 	StatusMessage string `json:"statusMessage,omitempty"`
 	// +listType=set
 	Versions []StackVersionStatus `json:"versions,omitempty"`
+	Assets   *StackAssetSummary   `json:"assets,omitempty"`
+}
+
+// StackAssetSummary summarizes the assets created for the active versions of a stack.
+// The selector matches the labels on the assets, and can be used to list them, for
+// example: kubectl get tasks -l <selector>
+type StackAssetSummary struct {
+	Selector string `json:"selector,omitempty"`
+	Active   int    `json:"active,omitempty"`
+	Failed   int    `json:"failed,omitempty"`
 }
 
 // StackVersionStatus defines the observed state of a specific stack version.
@@ -75,7 +112,7 @@ type StackVersionStatus struct {
 	Status        string           `json:"status,omitempty"`
 	StatusMessage string           `json:"statusMessage,omitempty"`
 	// +listType=set
-	Images []Image `json:"images,omitempty"`
+	Images []ImageStatus `json:"images,omitempty"`
 }
 
 // Image defines a container image used by a stack
@@ -84,6 +121,19 @@ type Image struct {
 	Image string `json:"image,omitempty"`
 }
 
+// ImageStatus defines the observed state of a container image used by a stack version.
+// The digest is that of the image tagged with the stack version.  PreviousDigest is set
+// when the tag was found to have moved to a new digest, at MovedTime, and is cleared
+// a day later.
+type ImageStatus struct {
+	Id             string       `json:"id,omitempty"`
+	Image          string       `json:"image,omitempty"`
+	Digest         string       `json:"digest,omitempty"`
+	PreviousDigest string       `json:"previousDigest,omitempty"`
+	MovedTime      *metav1.Time `json:"movedTime,omitempty"`
+	Message        string       `json:"message,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // Stack is the Schema for the stack API
@@ -246,9 +246,161 @@ func (in *CollectionControllerStatus) DeepCopy() *CollectionControllerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentImageStatus) DeepCopyInto(out *ComponentImageStatus) {
+	*out = *in
+	if in.MovedTime != nil {
+		in, out := &in.MovedTime, &out.MovedTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentImageStatus.
+func (in *ComponentImageStatus) DeepCopy() *ComponentImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentIngressSpec) DeepCopyInto(out *ComponentIngressSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentIngressSpec.
+func (in *ComponentIngressSpec) DeepCopy() *ComponentIngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentIngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentUpgradeStatus) DeepCopyInto(out *ComponentUpgradeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentUpgradeStatus.
+func (in *ComponentUpgradeStatus) DeepCopy() *ComponentUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeyRef) DeepCopyInto(out *ConfigMapKeyRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeyRef.
+func (in *ConfigMapKeyRef) DeepCopy() *ConfigMapKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleLinkSpec) DeepCopyInto(out *ConsoleLinkSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleLinkSpec.
+func (in *ConsoleLinkSpec) DeepCopy() *ConsoleLinkSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleLinkSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialsSource) DeepCopyInto(out *CredentialsSource) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+	out.Vault = in.Vault
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialsSource.
+func (in *CredentialsSource) DeepCopy() *CredentialsSource {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialsSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DigestPinningSpec) DeepCopyInto(out *DigestPinningSpec) {
+	*out = *in
+	if in.PullSecrets != nil {
+		in, out := &in.PullSecrets, &out.PullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DigestPinningSpec.
+func (in *DigestPinningSpec) DeepCopy() *DigestPinningSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DigestPinningSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventsCustomizationSpec) DeepCopyInto(out *EventsCustomizationSpec) {
 	*out = *in
+	out.Ingress = in.Ingress
+	if in.WebhookSources != nil {
+		in, out := &in.WebhookSources, &out.WebhookSources
+		*out = make([]EventsWebhookSourceSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Mappings != nil {
+		in, out := &in.Mappings, &out.Mappings
+		*out = make([]EventsMappingSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]EventsDestinationSpec, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -262,6 +414,43 @@ func (in *EventsCustomizationSpec) DeepCopy() *EventsCustomizationSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventsDestinationSpec) DeepCopyInto(out *EventsDestinationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventsDestinationSpec.
+func (in *EventsDestinationSpec) DeepCopy() *EventsDestinationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EventsDestinationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventsMappingSpec) DeepCopyInto(out *EventsMappingSpec) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventsMappingSpec.
+func (in *EventsMappingSpec) DeepCopy() *EventsMappingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EventsMappingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EventsStatus) DeepCopyInto(out *EventsStatus) {
 	*out = *in
@@ -270,6 +459,11 @@ func (in *EventsStatus) DeepCopyInto(out *EventsStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.WebhookEndpoints != nil {
+		in, out := &in.WebhookEndpoints, &out.WebhookEndpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -283,9 +477,52 @@ func (in *EventsStatus) DeepCopy() *EventsStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EventsWebhookSourceSpec) DeepCopyInto(out *EventsWebhookSourceSpec) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EventsWebhookSourceSpec.
+func (in *EventsWebhookSourceSpec) DeepCopy() *EventsWebhookSourceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(EventsWebhookSourceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalSsoSpec) DeepCopyInto(out *ExternalSsoSpec) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalSsoSpec.
+func (in *ExternalSsoSpec) DeepCopy() *ExternalSsoSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalSsoSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitReleaseSpec) DeepCopyInto(out *GitReleaseSpec) {
 	*out = *in
+	out.Credentials = in.Credentials
 	return
 }
 
@@ -307,6 +544,7 @@ func (in *GithubConfig) DeepCopyInto(out *GithubConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	in.Webhook.DeepCopyInto(&out.Webhook)
 	return
 }
 
@@ -320,9 +558,55 @@ func (in *GithubConfig) DeepCopy() *GithubConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GithubWebhookSpec) DeepCopyInto(out *GithubWebhookSpec) {
+	*out = *in
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.TokenSecretRef = in.TokenSecretRef
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GithubWebhookSpec.
+func (in *GithubWebhookSpec) DeepCopy() *GithubWebhookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GithubWebhookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpsAuthSpec) DeepCopyInto(out *HttpsAuthSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpsAuthSpec.
+func (in *HttpsAuthSpec) DeepCopy() *HttpsAuthSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpsAuthSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HttpsProtocolFile) DeepCopyInto(out *HttpsProtocolFile) {
 	*out = *in
+	out.Credentials = in.Credentials
+	out.Auth = in.Auth
+	out.CaBundle = in.CaBundle
 	return
 }
 
@@ -352,6 +636,42 @@ func (in *Image) DeepCopy() *Image {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageStatus) DeepCopyInto(out *ImageStatus) {
+	*out = *in
+	if in.MovedTime != nil {
+		in, out := &in.MovedTime, &out.MovedTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageStatus.
+func (in *ImageStatus) DeepCopy() *ImageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InstanceStackConfig) DeepCopyInto(out *InstanceStackConfig) {
 	*out = *in
@@ -367,6 +687,8 @@ func (in *InstanceStackConfig) DeepCopyInto(out *InstanceStackConfig) {
 		*out = make([]PipelineSpec, len(*in))
 		copy(*out, *in)
 	}
+	in.ImagePolicy.DeepCopyInto(&out.ImagePolicy)
+	out.Timeouts = in.Timeouts
 	return
 }
 
@@ -411,6 +733,7 @@ func (in *Kabanero) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KabaneroCliServicesCustomizationSpec) DeepCopyInto(out *KabaneroCliServicesCustomizationSpec) {
 	*out = *in
+	out.Ingress = in.Ingress
 	return
 }
 
@@ -448,6 +771,14 @@ func (in *KabaneroLandingCustomizationSpec) DeepCopyInto(out *KabaneroLandingCus
 		*out = new(bool)
 		**out = **in
 	}
+	out.Ingress = in.Ingress
+	if in.ConsoleLinks != nil {
+		in, out := &in.ConsoleLinks, &out.ConsoleLinks
+		*out = make([]ConsoleLinkSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -528,11 +859,16 @@ func (in *KabaneroSpec) DeepCopyInto(out *KabaneroSpec) {
 	out.CliServices = in.CliServices
 	in.Landing.DeepCopyInto(&out.Landing)
 	in.CodereadyWorkspaces.DeepCopyInto(&out.CodereadyWorkspaces)
-	out.Events = in.Events
+	in.Events.DeepCopyInto(&out.Events)
 	out.CollectionController = in.CollectionController
 	out.StackController = in.StackController
 	out.AdmissionControllerWebhook = in.AdmissionControllerWebhook
-	out.Sso = in.Sso
+	in.Sso.DeepCopyInto(&out.Sso)
+	out.VersionCatalog = in.VersionCatalog
+	out.Subscriptions = in.Subscriptions
+	in.DigestPinning.DeepCopyInto(&out.DigestPinning)
+	out.SecretRotation = in.SecretRotation
+	out.Ingress = in.Ingress
 	return
 }
 
@@ -578,6 +914,34 @@ func (in *KabaneroStatus) DeepCopyInto(out *KabaneroStatus) {
 	out.StackController = in.StackController
 	out.AdmissionControllerWebhook = in.AdmissionControllerWebhook
 	out.Sso = in.Sso
+	in.VersionCatalog.DeepCopyInto(&out.VersionCatalog)
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(KabaneroUpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Subscriptions != nil {
+		in, out := &in.Subscriptions, &out.Subscriptions
+		*out = make([]SubscriptionStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ComponentImageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RejectedStacks != nil {
+		in, out := &in.RejectedStacks, &out.RejectedStacks
+		*out = make([]RejectedStackStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecretRotationFailures != nil {
+		in, out := &in.SecretRotationFailures, &out.SecretRotationFailures
+		*out = make([]SecretRotationFailureStatus, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -591,6 +955,29 @@ func (in *KabaneroStatus) DeepCopy() *KabaneroStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KabaneroUpgradeStatus) DeepCopyInto(out *KabaneroUpgradeStatus) {
+	*out = *in
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]ComponentUpgradeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KabaneroUpgradeStatus.
+func (in *KabaneroUpgradeStatus) DeepCopy() *KabaneroUpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KabaneroUpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KappnavStatus) DeepCopyInto(out *KappnavStatus) {
 	*out = *in
@@ -658,7 +1045,9 @@ func (in *PipelineStatus) DeepCopyInto(out *PipelineStatus) {
 	if in.ActiveAssets != nil {
 		in, out := &in.ActiveAssets, &out.ActiveAssets
 		*out = make([]RepositoryAssetStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -673,9 +1062,50 @@ func (in *PipelineStatus) DeepCopy() *PipelineStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrerequisiteSubscriptionsSpec) DeepCopyInto(out *PrerequisiteSubscriptionsSpec) {
+	*out = *in
+	out.Tekton = in.Tekton
+	out.Serverless = in.Serverless
+	out.Appsody = in.Appsody
+	out.CodereadyWorkspaces = in.CodereadyWorkspaces
+	out.Kappnav = in.Kappnav
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrerequisiteSubscriptionsSpec.
+func (in *PrerequisiteSubscriptionsSpec) DeepCopy() *PrerequisiteSubscriptionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PrerequisiteSubscriptionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RejectedStackStatus) DeepCopyInto(out *RejectedStackStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RejectedStackStatus.
+func (in *RejectedStackStatus) DeepCopy() *RejectedStackStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RejectedStackStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RepositoryAssetStatus) DeepCopyInto(out *RepositoryAssetStatus) {
 	*out = *in
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -712,6 +1142,54 @@ func (in *RepositoryConfig) DeepCopy() *RepositoryConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretKeyRef) DeepCopyInto(out *SecretKeyRef) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyRef.
+func (in *SecretKeyRef) DeepCopy() *SecretKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotationFailureStatus) DeepCopyInto(out *SecretRotationFailureStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotationFailureStatus.
+func (in *SecretRotationFailureStatus) DeepCopy() *SecretRotationFailureStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotationFailureStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretRotationSpec) DeepCopyInto(out *SecretRotationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretRotationSpec.
+func (in *SecretRotationSpec) DeepCopy() *SecretRotationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretRotationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerlessStatus) DeepCopyInto(out *ServerlessStatus) {
 	*out = *in
@@ -732,6 +1210,11 @@ func (in *ServerlessStatus) DeepCopy() *ServerlessStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SsoCustomizationSpec) DeepCopyInto(out *SsoCustomizationSpec) {
 	*out = *in
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(ExternalSsoSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -789,6 +1272,22 @@ func (in *Stack) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackAssetSummary) DeepCopyInto(out *StackAssetSummary) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackAssetSummary.
+func (in *StackAssetSummary) DeepCopy() *StackAssetSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(StackAssetSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackControllerSpec) DeepCopyInto(out *StackControllerSpec) {
 	*out = *in
@@ -821,6 +1320,44 @@ func (in *StackControllerStatus) DeepCopy() *StackControllerStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackDependency) DeepCopyInto(out *StackDependency) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackDependency.
+func (in *StackDependency) DeepCopy() *StackDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(StackDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackImagePolicySpec) DeepCopyInto(out *StackImagePolicySpec) {
+	*out = *in
+	if in.AllowedImages != nil {
+		in, out := &in.AllowedImages, &out.AllowedImages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	out.SignatureKeys = in.SignatureKeys
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackImagePolicySpec.
+func (in *StackImagePolicySpec) DeepCopy() *StackImagePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StackImagePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackList) DeepCopyInto(out *StackList) {
 	*out = *in
@@ -854,6 +1391,32 @@ func (in *StackList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackRequirements) DeepCopyInto(out *StackRequirements) {
+	*out = *in
+	if in.TektonApiVersions != nil {
+		in, out := &in.TektonApiVersions, &out.TektonApiVersions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stacks != nil {
+		in, out := &in.Stacks, &out.Stacks
+		*out = make([]StackDependency, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackRequirements.
+func (in *StackRequirements) DeepCopy() *StackRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(StackRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackSpec) DeepCopyInto(out *StackSpec) {
 	*out = *in
@@ -887,6 +1450,11 @@ func (in *StackStatus) DeepCopyInto(out *StackStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Assets != nil {
+		in, out := &in.Assets, &out.Assets
+		*out = new(StackAssetSummary)
+		**out = **in
+	}
 	return
 }
 
@@ -900,6 +1468,22 @@ func (in *StackStatus) DeepCopy() *StackStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StackTimeoutsSpec) DeepCopyInto(out *StackTimeoutsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StackTimeoutsSpec.
+func (in *StackTimeoutsSpec) DeepCopy() *StackTimeoutsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StackTimeoutsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StackVersion) DeepCopyInto(out *StackVersion) {
 	*out = *in
@@ -913,6 +1497,7 @@ func (in *StackVersion) DeepCopyInto(out *StackVersion) {
 		*out = make([]Image, len(*in))
 		copy(*out, *in)
 	}
+	in.Requires.DeepCopyInto(&out.Requires)
 	return
 }
 
@@ -938,8 +1523,10 @@ func (in *StackVersionStatus) DeepCopyInto(out *StackVersionStatus) {
 	}
 	if in.Images != nil {
 		in, out := &in.Images, &out.Images
-		*out = make([]Image, len(*in))
-		copy(*out, *in)
+		*out = make([]ImageStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -954,6 +1541,38 @@ func (in *StackVersionStatus) DeepCopy() *StackVersionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubscriptionCustomizationSpec) DeepCopyInto(out *SubscriptionCustomizationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubscriptionCustomizationSpec.
+func (in *SubscriptionCustomizationSpec) DeepCopy() *SubscriptionCustomizationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SubscriptionCustomizationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SubscriptionStatus) DeepCopyInto(out *SubscriptionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubscriptionStatus.
+func (in *SubscriptionStatus) DeepCopy() *SubscriptionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SubscriptionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TektonStatus) DeepCopyInto(out *TektonStatus) {
 	*out = *in
@@ -987,3 +1606,56 @@ func (in *TriggerSpec) DeepCopy() *TriggerSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VaultSecretSource) DeepCopyInto(out *VaultSecretSource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VaultSecretSource.
+func (in *VaultSecretSource) DeepCopy() *VaultSecretSource {
+	if in == nil {
+		return nil
+	}
+	out := new(VaultSecretSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionCatalogSpec) DeepCopyInto(out *VersionCatalogSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionCatalogSpec.
+func (in *VersionCatalogSpec) DeepCopy() *VersionCatalogSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionCatalogSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionCatalogStatus) DeepCopyInto(out *VersionCatalogStatus) {
+	*out = *in
+	if in.Versions != nil {
+		in, out := &in.Versions, &out.Versions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionCatalogStatus.
+func (in *VersionCatalogStatus) DeepCopy() *VersionCatalogStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionCatalogStatus)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -8,6 +8,7 @@ import (
 	kabanerov1alpha1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha1"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	pipelinev1alpha1 "github.com/tektoncd/pipeline/pkg/apis/pipeline/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -168,6 +169,12 @@ func (r *ReconcileCollection) Reconcile(request reconcile.Request) (reconcile.Re
 		return reconcile.Result{}, nil
 	}
 
+	// Trust the certificate authorities injected into the trusted CA bundle ConfigMap.
+	err = cutils.LoadTrustedCABundle(ctx, r.client, request.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "Unable to load the trusted CA bundle")
+	}
+
 	// The collection is not being deleted. Migrate the collection to a stack.
 	collectionName := instance.ObjectMeta.Name
 	collectionNamespace := instance.ObjectMeta.Namespace
@@ -1,12 +1,13 @@
 package collection
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
+
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	rlog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -60,11 +61,8 @@ func getFromCache(url string, skipCertVerify bool) ([]byte, error) {
 	}
 
 	// Drive the request. Certificate validation is not disabled by default.
-	transport := &http.Transport{DisableCompression: true}
-	if skipCertVerify {
-		config := &tls.Config{InsecureSkipVerify: skipCertVerify}
-		transport.TLSClientConfig = config
-	}
+	transport := cutils.NewTransport(skipCertVerify)
+	transport.DisableCompression = true
 
 	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
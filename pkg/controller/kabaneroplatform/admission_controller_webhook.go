@@ -73,7 +73,7 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 	if err != nil {
 		return err
 	}
-	templateContext["image"] = image
+	templateContext["image"] = resolveComponentImage(ctx, k, c, "admission-webhook", image)
 
 	f, err := rev.OpenOrchestration("kabanero-operator-admission-webhook.yaml")
 	if err != nil {
@@ -94,6 +94,7 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
 	}
+	transforms = append(transforms, vaultSettingsTransforms()...)
 
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
@@ -154,6 +155,12 @@ func reconcileAdmissionControllerWebhook(ctx context.Context, k *kabanerov1alpha
 		if err != nil {
 			return err
 		}
+
+		// Convert between Kabanero versions using the same webhook server.
+		err = reconcileKabaneroConversionWebhook(ctx, k, c, encoded, reqLogger)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
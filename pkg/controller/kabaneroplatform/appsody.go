@@ -24,6 +24,7 @@ const (
 // Retrieves the Appsody deployment status.
 func getAppsodyStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
 	ready := false
+	k.Status.Appsody.Compatible = ""
 
 	// Get the appsody version.
 	csvVersion, err := getAppsodyOperatorVersion(k, c)
@@ -36,6 +37,12 @@ func getAppsodyStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger l
 	}
 	k.Status.Appsody.Version = csvVersion
 
+	// Installed, but possibly at a version the Kabanero version cannot use.
+	status := &k.Status.Appsody
+	if !checkComponentCompatibility(k, "appsody", csvVersion, &status.Compatible, &status.Ready, &status.Message) {
+		return false, nil
+	}
+
 	// Get the Appsody operator deployment.
 	u := &unstructured.Unstructured{}
 	u.SetGroupVersionKind(schema.GroupVersionKind{
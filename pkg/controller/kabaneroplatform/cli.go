@@ -1,31 +1,34 @@
 package kabaneroplatform
 
 import (
-	"bytes"
 	"context"
-	"crypto/rand"
 	"fmt"
-	"math/big"
 	"net/url"
-	"regexp"
 	"strings"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	mf "github.com/manifestival/manifestival"
 	mfc "github.com/manifestival/controller-runtime-client"
 	routev1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) error {
+	// An invalid session expiration is reported in the status, and the CLI is
+	// left as it is until the value is corrected.
+	err := validateCliSessionExpiration(k)
+	if err != nil {
+		return err
+	}
+
 	// Create the AES encryption key secret, if we don't already have one
-	err := createEncryptionKeySecret(k, cl, reqLogger)
+	err = createEncryptionKeySecret(k, cl, reqLogger)
 	if err != nil {
 		return err
 	}
@@ -43,7 +46,7 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 	if err != nil {
 		return err
 	}
-	templateContext["image"] = image
+	templateContext["image"] = resolveComponentImage(ctx, k, cl, "cli-services", image)
 
 	f, err := rev.OpenOrchestration("kabanero-cli.yaml")
 	if err != nil {
@@ -60,10 +63,11 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 		return err
 	}
 
-	transforms := []mf.Transformer{
+	transforms := ingressTransforms(k)
+	transforms = append(transforms,
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
-	}
+	)
 
 	// The CLI wants to know the Github organization name, if it was provided
 	if len(k.Spec.Github.Organization) > 0 {
@@ -86,35 +90,25 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 	// Export the github API URL, if it's set.  This is used by the security portion of the microservice.
 	if len(k.Spec.Github.ApiUrl) > 0 {
 		apiUrlString := k.Spec.Github.ApiUrl
-		apiUrl, err := url.Parse(apiUrlString)
+		apiUrl, err := cutils.ParseGithubApiUrl(apiUrlString)
 
 		if err != nil {
 			reqLogger.Error(err, "Could not parse Github API url %v, assuming api.github.com", apiUrlString)
 			apiUrl, _ = url.Parse("https://api.github.com")
-		} else if len(apiUrl.Scheme) == 0 {
-			apiUrl.Scheme = "https"
 		}
 		transforms = append(transforms, kabTransforms.AddEnvVariable("github.api.url", apiUrl.String()))
 	}
 
-	// Set JwtExpiration for login duration/timeout
-	// Specify a positive integer followed by a unit of time, which can be hours (h), minutes (m), or seconds (s).
+	// Set JwtExpiration for login duration/timeout.  The format was checked above.
 	if len(k.Spec.CliServices.SessionExpirationSeconds) > 0 {
-		// If the format is incorrect, set the default
-		matched, err := regexp.MatchString(`^\d+[smh]{1}$`, k.Spec.CliServices.SessionExpirationSeconds)
-		if err != nil {
-			return err
-		}
-		if !matched {
-			reqLogger.Info(fmt.Sprintf("Kabanero Spec.CliServices.SessionExpirationSeconds must specify a positive integer followed by a unit of time, which can be hours (h), minutes (m), or seconds (s). Defaulting to 1440m."))
-			transforms = append(transforms, kabTransforms.AddEnvVariable("JwtExpiration", "1440m"))
-		} else {
-			transforms = append(transforms, kabTransforms.AddEnvVariable("JwtExpiration", k.Spec.CliServices.SessionExpirationSeconds))
-		}
+		transforms = append(transforms, kabTransforms.AddEnvVariable("JwtExpiration", k.Spec.CliServices.SessionExpirationSeconds))
 	} else {
 		transforms = append(transforms, kabTransforms.AddEnvVariable("JwtExpiration", "1440m"))
 	}
 
+	// Log users in with the external OpenID Connect provider, if one is used
+	transforms = append(transforms, externalSsoTransforms(k)...)
+
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
 		return err
@@ -134,8 +128,45 @@ func reconcileKabaneroCli(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl
 	return nil
 }
 
+// Checks the format of the CLI session expiration, if one is specified.
+func validateCliSessionExpiration(k *kabanerov1alpha2.Kabanero) error {
+	value := k.Spec.CliServices.SessionExpirationSeconds
+	if len(value) == 0 {
+		return nil
+	}
+
+	err := cutils.ValidateSessionExpiration(value)
+	if err != nil {
+		return fmt.Errorf("The CLI session expiration %v is not valid: it %v", value, err)
+	}
+	return nil
+}
+
 // Tries to see if the CLI route has been assigned a hostname.
 func getCliRouteStatus(k *kabanerov1alpha2.Kabanero, reqLogger logr.Logger, c client.Client) (bool, error) {
+	// The CLI is not deployed while its session expiration is not valid.
+	if err := validateCliSessionExpiration(k); err != nil {
+		k.Status.Cli.Ready = "False"
+		k.Status.Cli.Message = err.Error()
+		return false, err
+	}
+
+	// Without Routes, the CLI is exposed using an Ingress.
+	if !routesServed {
+		hostnames, message, _, err := getIngressHostnames(context.TODO(), c, k.ObjectMeta.Namespace, "kabanero-cli", "CLI")
+		k.Status.Cli.Hostnames = hostnames
+		if len(hostnames) == 0 {
+			if err != nil {
+				reqLogger.Error(err, message)
+			}
+			k.Status.Cli.Ready = "False"
+			k.Status.Cli.Message = message
+			return false, err
+		}
+		k.Status.Cli.Ready = "True"
+		k.Status.Cli.Message = ""
+		return true, nil
+	}
 
 	// Check that the route is accepted
 	cliRoute := &routev1.Route{}
@@ -208,51 +239,5 @@ func destroyRoleBindingConfigMap(k *kabanerov1alpha2.Kabanero, c client.Client,
 
 // Creates the secret containing the AES encryption key used by the CLI.
 func createEncryptionKeySecret(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
-	secretName := "kabanero-cli-aes-encryption-key-secret"
-
-	// Check if the Secret already exists.
-	secretInstance := &corev1.Secret{}
-	err := c.Get(context.Background(), types.NamespacedName{
-		Name:      secretName,
-		Namespace: k.ObjectMeta.Namespace}, secretInstance)
-
-	if err != nil {
-		if errors.IsNotFound(err) == false {
-			return err
-		}
-
-		// Not found.  Make a new one.
-		var ownerRef metav1.OwnerReference
-		ownerRef, err = getOwnerReference(k, c, reqLogger)
-		if err != nil {
-			return err
-		}
-
-		secretInstance := &corev1.Secret{}
-		secretInstance.ObjectMeta.Name = secretName
-		secretInstance.ObjectMeta.Namespace = k.ObjectMeta.Namespace
-		secretInstance.ObjectMeta.OwnerReferences = append(secretInstance.ObjectMeta.OwnerReferences, ownerRef)
-
-		// Generate a 64 character random value
-		possibleChars := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-=_+")
-		maxVal := big.NewInt(int64(len(possibleChars)))
-		var buf bytes.Buffer
-		for i := 0; i < 64; i++ {
-			curInt, randErr := rand.Int(rand.Reader, maxVal)
-			if randErr != nil {
-				return randErr
-			}
-			// Convert int to char
-			buf.WriteByte(possibleChars[curInt.Int64()])
-		}
-
-		secretMap := make(map[string]string)
-		secretMap["AESEncryptionKey"] = buf.String()
-		secretInstance.StringData = secretMap
-
-		reqLogger.Info(fmt.Sprintf("Attempting to create the CLI AES Encryption key secret"))
-		err = c.Create(context.TODO(), secretInstance)
-	}
-
-	return err
+	return createGeneratedSecret(context.Background(), k, c, cliEncryptionKeySecretName, generateCliEncryptionKeyData, reqLogger)
 }
@@ -0,0 +1,31 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Verifies an invalid session expiration is reported in the CLI status instead of being replaced
+func TestCliSessionExpirationInvalid(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			CliServices: kabanerov1alpha2.KabaneroCliServicesCustomizationSpec{SessionExpirationSeconds: "0s"},
+		},
+	}
+	cl := newTestClient(t, k)
+
+	if err := reconcileKabaneroCli(context.Background(), k, cl, logf.NullLogger{}); err == nil {
+		t.Fatal("Expected the invalid session expiration to be rejected")
+	}
+
+	ready, _ := getCliRouteStatus(k, logf.NullLogger{}, cl)
+	if ready || k.Status.Cli.Ready != "False" || !strings.Contains(k.Status.Cli.Message, "0s") {
+		t.Fatalf("Expected the invalid session expiration to be reported: %v", k.Status.Cli)
+	}
+}
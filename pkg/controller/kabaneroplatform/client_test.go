@@ -0,0 +1,55 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	consolev1 "github.com/openshift/api/console/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	olmapiv1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	tektoncdv1alpha1 "github.com/tektoncd/operator/pkg/apis/operator/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Builds a scheme with the types the Kabanero controller works with.  The
+// OpenShift console and OLM objects are only handled as unstructured objects,
+// which the fake client can only list when their kinds are registered as
+// unstructured.
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		kabanerov1alpha2.SchemeBuilder.AddToScheme,
+		routev1.AddToScheme,
+		openshiftappsv1.Install,
+		tektoncdv1alpha1.SchemeBuilder.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	olmGroupVersion := schema.GroupVersion{Group: olmapiv1alpha1.GroupName, Version: olmapiv1alpha1.GroupVersion}
+	for _, gvk := range []schema.GroupVersionKind{
+		consolev1.GroupVersion.WithKind("ConsoleLink"),
+		olmGroupVersion.WithKind(olmapiv1alpha1.SubscriptionKind),
+		olmGroupVersion.WithKind(olmapiv1alpha1.InstallPlanKind),
+		olmGroupVersion.WithKind(olmapiv1alpha1.ClusterServiceVersionKind),
+	} {
+		scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+		scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+	}
+
+	return scheme
+}
+
+// Creates a fake client holding the input objects.
+func newTestClient(t *testing.T, objs ...runtime.Object) client.Client {
+	return fake.NewFakeClientWithScheme(newTestScheme(t), objs...)
+}
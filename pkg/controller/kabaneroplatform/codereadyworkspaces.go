@@ -384,6 +384,12 @@ func getCRWStatus(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 
 	k.Status.CodereadyWorkspaces.Operator.Version = crwOperatorVersion
 
+	// Installed, but possibly at a version the Kabanero version cannot use.
+	status := k.Status.CodereadyWorkspaces
+	if !checkComponentCompatibility(k, "codeready-workspaces", crwOperatorVersion, &status.Operator.Compatible, &status.Ready, &status.Message) {
+		return false, nil
+	}
+
 	// Get the codeready-workspaces instance.
 	crwInst, err := getCRWInstance(ctx, k, c)
 	if err != nil {
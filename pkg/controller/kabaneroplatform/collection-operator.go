@@ -42,7 +42,7 @@ func reconcileCollectionController(ctx context.Context, k *kabanerov1alpha2.Kaba
 		logger.Error(err, "Kabanero collection controller deployment failed. Unable to process image overrides.")
 		return err
 	}
-	templateCtx["image"] = image
+	templateCtx["image"] = resolveComponentImage(ctx, k, c, "collection-controller", image)
 
 	f, err := rev.OpenOrchestration(ccOrchestrationFileName)
 	if err != nil {
@@ -63,6 +63,7 @@ func reconcileCollectionController(ctx context.Context, k *kabanerov1alpha2.Kaba
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
 	}
+	transforms = append(transforms, outboundProxyTransforms()...)
 
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
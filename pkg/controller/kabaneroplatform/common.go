@@ -86,6 +86,9 @@ func renderOrchestration(r io.Reader, context map[string]interface{}) (string, e
 func resolveSoftwareRevision(k *kabanerov1alpha2.Kabanero, softwareComponent string, softwareVersionOverride string) (versioning.SoftwareRevision, error) {
 	v, kabaneroVersion := resolveKabaneroVersion(k)
 
+	// Components which have not been upgraded yet stay at the installed version.
+	kabaneroVersion = componentKabaneroVersion(k, softwareComponent, kabaneroVersion)
+
 	kabaneroRevision := v.KabaneroRevision(kabaneroVersion)
 	if kabaneroRevision == nil {
 		return versioning.SoftwareRevision{}, fmt.Errorf("Data related to the Kabanero release identifier `%v` cannot be found", kabaneroVersion)
@@ -112,7 +115,7 @@ func resolveSoftwareRevision(k *kabanerov1alpha2.Kabanero, softwareComponent str
 
 // Resolves the version of the Kabanero instance.
 func resolveKabaneroVersion(k *kabanerov1alpha2.Kabanero) (versioning.VersionDocument, string) {
-	v := versioning.Data
+	v := activeVersionCatalog(k)
 	kabaneroVersion := k.Spec.Version
 	if kabaneroVersion == "" {
 		kabaneroVersion = v.DefaultKabaneroRevision
@@ -0,0 +1,253 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	consolev1 "github.com/openshift/api/console/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The links added when the Kabanero instance does not list any
+var defaultConsoleLinks = []kabanerov1alpha2.ConsoleLinkSpec{
+	{
+		Name:     "app-menu-link",
+		Location: kabanerov1alpha2.ConsoleLinkLocationApplicationMenu,
+		Text:     "Landing Page",
+		Href:     "{{ .landingURL }}",
+		ImageUrl: "{{ .landingURL }}/img/favicon/favicon-16x16.png",
+	},
+	{
+		Name:     "help-menu-docs",
+		Location: kabanerov1alpha2.ConsoleLinkLocationHelpMenu,
+		Text:     "Kabanero Docs",
+		Href:     "{{ .landingURL }}/docs",
+	},
+	{
+		Name:     "help-menu-guides",
+		Location: kabanerov1alpha2.ConsoleLinkLocationHelpMenu,
+		Text:     "Kabanero Guides",
+		Href:     "{{ .landingURL }}/guides",
+	},
+}
+
+// The ConsoleLinks created by earlier versions, whose names were shared by all instances
+var legacyConsoleLinkNames = []string{"kabanero-app-menu-link", "kabanero-help-menu-docs", "kabanero-help-menu-guides"}
+
+// Adds customizations to the OpenShift web console.  The ConsoleLinks of the Kabanero
+// instance are created or updated, and those no longer listed are deleted.
+func customizeWebConsole(k *kabanerov1alpha2.Kabanero, c client.Client, landingURL string) error {
+	// There is no OpenShift web console to customize.
+	if !consoleLinksServed {
+		return nil
+	}
+
+	links := k.Spec.Landing.ConsoleLinks
+	if len(links) == 0 {
+		links = defaultConsoleLinks
+	}
+
+	desiredNames := make(map[string]bool)
+	for _, link := range links {
+		desired, err := newConsoleLink(k, link, landingURL)
+		if err != nil {
+			return err
+		}
+		desiredNames[desired.GetName()] = true
+
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+		err = c.Get(context.TODO(), types.NamespacedName{Name: desired.GetName()}, existing)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			kllog.Info(fmt.Sprintf("Creating ConsoleLink %v", desired.GetName()))
+			err = c.Create(context.TODO(), desired)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !isManagedConsoleLink(k, existing) {
+			return fmt.Errorf("ConsoleLink %v already exists and is not managed by Kabanero instance %v in namespace %v", desired.GetName(), k.GetName(), k.GetNamespace())
+		}
+
+		if !reflect.DeepEqual(existing.Object["spec"], desired.Object["spec"]) {
+			existing.Object["spec"] = desired.Object["spec"]
+			err = c.Update(context.TODO(), existing)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Delete the links which are no longer listed.
+	existingLinks, err := listConsoleLinks(k, c)
+	if err != nil {
+		return err
+	}
+	for _, existing := range existingLinks {
+		if !desiredNames[existing.GetName()] {
+			kllog.Info(fmt.Sprintf("Deleting ConsoleLink %v", existing.GetName()))
+			err = c.Delete(context.TODO(), &existing)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return removeLegacyConsoleLinks(c)
+}
+
+// Removes customizations from the openshift console.
+func removeWebConsoleCustomization(k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	if !consoleLinksServed {
+		return nil
+	}
+
+	// Since these are cluster level objects, they cannot set a namespace-level owner and must be
+	// removed manually.
+	existingLinks, err := listConsoleLinks(k, c)
+	if err != nil {
+		return err
+	}
+	for _, existing := range existingLinks {
+		err = c.Delete(context.TODO(), &existing)
+		if err != nil && !apierrors.IsNotFound(err) {
+			kllog.Error(err, "Unable to delete ConsoleLink")
+		}
+	}
+
+	return removeLegacyConsoleLinks(c)
+}
+
+// Builds the ConsoleLink object for a link of the Kabanero instance.
+func newConsoleLink(k *kabanerov1alpha2.Kabanero, link kabanerov1alpha2.ConsoleLinkSpec, landingURL string) (*unstructured.Unstructured, error) {
+	templateContext := map[string]interface{}{
+		"landingURL": landingURL,
+		"name":       k.GetName(),
+		"namespace":  k.GetNamespace(),
+	}
+
+	href, err := renderConsoleLinkTemplate(link.Href, templateContext)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to render the href of console link %v: %v", link.Name, err)
+	}
+
+	spec := map[string]interface{}{
+		"location": link.Location,
+		"text":     link.Text,
+		"href":     href,
+	}
+
+	switch link.Location {
+	case kabanerov1alpha2.ConsoleLinkLocationApplicationMenu:
+		section := link.Section
+		if len(section) == 0 {
+			section = "Kabanero"
+		}
+		applicationMenu := map[string]interface{}{"section": section}
+		if len(link.ImageUrl) != 0 {
+			imageURL, err := renderConsoleLinkTemplate(link.ImageUrl, templateContext)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to render the imageUrl of console link %v: %v", link.Name, err)
+			}
+			applicationMenu["imageURL"] = imageURL
+		}
+		spec["applicationMenu"] = applicationMenu
+	case kabanerov1alpha2.ConsoleLinkLocationNamespaceDashboard:
+		namespaces := link.Namespaces
+		if len(namespaces) == 0 {
+			namespaces = append([]string{k.GetNamespace()}, k.Spec.TargetNamespaces...)
+		}
+		namespaceList := []interface{}{}
+		for _, namespace := range namespaces {
+			namespaceList = append(namespaceList, namespace)
+		}
+		spec["namespaceDashboard"] = map[string]interface{}{"namespaces": namespaceList}
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+	u.SetName(consoleLinkName(k, link))
+	u.SetLabels(ownerLabels(k))
+	u.Object["spec"] = spec
+
+	return u, nil
+}
+
+// Returns the name of the ConsoleLink for a link of the Kabanero instance, which is
+// unique across instances.
+func consoleLinkName(k *kabanerov1alpha2.Kabanero, link kabanerov1alpha2.ConsoleLinkSpec) string {
+	return fmt.Sprintf("%v-%v-%v", k.GetNamespace(), k.GetName(), link.Name)
+}
+
+// Renders the href or imageUrl of a console link.
+func renderConsoleLinkTemplate(text string, templateContext map[string]interface{}) (string, error) {
+	t, err := template.New("consoleLink").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var wr strings.Builder
+	err = t.Execute(&wr, templateContext)
+	if err != nil {
+		return "", err
+	}
+	return wr.String(), nil
+}
+
+// Determines if the ConsoleLink was created by the Kabanero instance.  Owner
+// references cannot be used because ConsoleLinks are cluster scoped.
+func isManagedConsoleLink(k *kabanerov1alpha2.Kabanero, link *unstructured.Unstructured) bool {
+	return isLabelledOwner(k, link)
+}
+
+// Lists the ConsoleLinks created by the Kabanero instance.
+func listConsoleLinks(k *kabanerov1alpha2.Kabanero, c client.Client) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLinkList"))
+	err := c.List(context.TODO(), list, client.MatchingLabels(ownerLabels(k)))
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// Deletes the ConsoleLinks created by earlier versions.  Links which have since been
+// labelled by another Kabanero instance are left alone.
+func removeLegacyConsoleLinks(c client.Client) error {
+	for _, name := range legacyConsoleLinkNames {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+		err := c.Get(context.TODO(), types.NamespacedName{Name: name}, existing)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		if len(existing.GetLabels()[ownerNamespaceLabel]) != 0 {
+			continue
+		}
+
+		kllog.Info(fmt.Sprintf("Deleting ConsoleLink %v created by an earlier version", name))
+		err = c.Delete(context.TODO(), existing)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	consolev1 "github.com/openshift/api/console/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func getTestConsoleLink(t *testing.T, c client.Client, name string) *unstructured.Unstructured {
+	link := &unstructured.Unstructured{}
+	link.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+	err := c.Get(context.Background(), types.NamespacedName{Name: name}, link)
+	if err != nil {
+		t.Fatalf("Unable to get ConsoleLink %v: %v", name, err)
+	}
+	return link
+}
+
+func countTestConsoleLinks(t *testing.T, c client.Client) int {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLinkList"))
+	err := c.List(context.Background(), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(list.Items)
+}
+
+// Verifies the ConsoleLinks of a Kabanero instance are reconciled with the spec
+func TestCustomizeWebConsole(t *testing.T) {
+	// A link created by an earlier version, and one created by another instance
+	legacy := &unstructured.Unstructured{}
+	legacy.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+	legacy.SetName("kabanero-help-menu-docs")
+	other := &unstructured.Unstructured{}
+	other.SetGroupVersionKind(consolev1.GroupVersion.WithKind("ConsoleLink"))
+	other.SetName("other-kabanero-help-menu-docs")
+	other.SetLabels(map[string]string{ownerNamespaceLabel: "other", ownerNameLabel: "kabanero"})
+	cl := newTestClient(t, legacy, other)
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{TargetNamespaces: []string{"dev"}},
+	}
+
+	// The default links are created.
+	err := customizeWebConsole(k, cl, "https://landing.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countTestConsoleLinks(t, cl); n != 4 {
+		t.Fatalf("Expected the 3 default links and the link of the other instance, but found %v links", n)
+	}
+	link := getTestConsoleLink(t, cl, "kabanero-kabanero-app-menu-link")
+	if href, _, _ := unstructured.NestedString(link.Object, "spec", "href"); href != "https://landing.example.com" {
+		t.Fatalf("Expected href https://landing.example.com, but got %v", href)
+	}
+	if section, _, _ := unstructured.NestedString(link.Object, "spec", "applicationMenu", "section"); section != "Kabanero" {
+		t.Fatalf("Expected section Kabanero, but got %v", section)
+	}
+	link = getTestConsoleLink(t, cl, "kabanero-kabanero-help-menu-docs")
+	if href, _, _ := unstructured.NestedString(link.Object, "spec", "href"); href != "https://landing.example.com/docs" {
+		t.Fatalf("Expected href https://landing.example.com/docs, but got %v", href)
+	}
+
+	// Links removed from the spec are deleted, and the others updated.
+	k.Spec.Landing.ConsoleLinks = []kabanerov1alpha2.ConsoleLinkSpec{
+		{Name: "help-menu-docs", Location: kabanerov1alpha2.ConsoleLinkLocationHelpMenu, Text: "Docs", Href: "https://docs.example.com/{{ .namespace }}"},
+		{Name: "dashboard", Location: kabanerov1alpha2.ConsoleLinkLocationNamespaceDashboard, Text: "Kabanero", Href: "{{ .landingURL }}"},
+	}
+	err = customizeWebConsole(k, cl, "https://landing.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countTestConsoleLinks(t, cl); n != 3 {
+		t.Fatalf("Expected 2 links and the link of the other instance, but found %v links", n)
+	}
+	link = getTestConsoleLink(t, cl, "kabanero-kabanero-help-menu-docs")
+	if href, _, _ := unstructured.NestedString(link.Object, "spec", "href"); href != "https://docs.example.com/kabanero" {
+		t.Fatalf("Expected href https://docs.example.com/kabanero, but got %v", href)
+	}
+	link = getTestConsoleLink(t, cl, "kabanero-kabanero-dashboard")
+	namespaces, _, _ := unstructured.NestedStringSlice(link.Object, "spec", "namespaceDashboard", "namespaces")
+	if len(namespaces) != 2 || namespaces[0] != "kabanero" || namespaces[1] != "dev" {
+		t.Fatalf("Expected the dashboard link in namespaces kabanero and dev, but got %v", namespaces)
+	}
+
+	// A template which cannot be rendered is reported.
+	k.Spec.Landing.ConsoleLinks[0].Href = "{{ .unknown }}"
+	err = customizeWebConsole(k, cl, "https://landing.example.com")
+	if err == nil {
+		t.Fatal("Expected an error rendering the href")
+	}
+
+	// Only the links of the instance are removed.
+	err = removeWebConsoleCustomization(k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countTestConsoleLinks(t, cl); n != 1 {
+		t.Fatalf("Expected only the link of the other instance, but found %v links", n)
+	}
+}
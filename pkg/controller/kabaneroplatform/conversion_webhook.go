@@ -0,0 +1,228 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha1"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The name of the Kabanero custom resource definition
+const kabaneroCRDName = "kabaneros.kabanero.io"
+
+// The path at which the admission webhook server converts between Kabanero versions
+const kabaneroConversionPath = "/convert"
+
+// The name of the service in front of the admission webhook server
+const admissionWebhookServiceName = "kabanero-operator-admission-webhook"
+
+var crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"}
+
+var kabaneroGVK = schema.GroupVersionKind{Group: kabanerov1alpha2.SchemeGroupVersion.Group, Version: kabanerov1alpha2.SchemeGroupVersion.Version, Kind: "Kabanero"}
+
+// Migrates the Kabanero instances which are stored as v1alpha2 but still hold
+// the v1alpha1 collection repositories, which were kept because the CRD
+// preserved unknown fields.  The migration runs once, when the operator
+// starts, and is retried with a backoff until every instance is migrated.
+// The conversion webhook is not configured until then, so that pruning
+// unknown fields does not drop the collection repositories.
+type collectionRepositoryMigrator struct {
+	client client.Client
+	// Reads directly from the API server, since the Kabanero instances are
+	// read as unstructured objects, which are not in the cache.
+	reader client.Reader
+}
+
+// Start implements manager.Runnable.
+func (m *collectionRepositoryMigrator) Start(stop <-chan struct{}) error {
+	for attempts := 1; ; attempts++ {
+		err := migrateCollectionRepositories(context.Background(), m.reader, m.client, log)
+		if err == nil {
+			return nil
+		}
+
+		delay := cutils.DefaultBackoff.Delay(attempts)
+		log.Error(err, fmt.Sprintf("Unable to convert collection repositories to stack repositories. Retrying in %v", delay.Round(time.Second)))
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// Converts the collection repositories of every Kabanero instance which still
+// holds them to stack repositories.  Returns the last error, after trying to
+// migrate every instance.
+func migrateCollectionRepositories(ctx context.Context, reader client.Reader, c client.Client, reqLogger logr.Logger) error {
+	kabaneros := &unstructured.UnstructuredList{}
+	kabaneros.SetGroupVersionKind(kabaneroGVK.GroupVersion().WithKind("KabaneroList"))
+	err := reader.List(ctx, kabaneros)
+	if err != nil {
+		return fmt.Errorf("Unable to list Kabanero instances: %v", err)
+	}
+
+	var lastErr error
+	for i := range kabaneros.Items {
+		err = migrateKabaneroCollectionRepositories(ctx, &kabaneros.Items[i], c, reqLogger)
+		if err != nil {
+			reqLogger.Error(err, "Unable to convert collection repositories")
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// Converts the collection repositories of a Kabanero instance to stack
+// repositories, keeping every field of the collection repositories.
+func migrateKabaneroCollectionRepositories(ctx context.Context, kabInstanceUnstructured *unstructured.Unstructured, c client.Client, reqLogger logr.Logger) error {
+	if !hasCollectionRepositories(kabInstanceUnstructured) {
+		return nil
+	}
+
+	name := types.NamespacedName{Name: kabInstanceUnstructured.GetName(), Namespace: kabInstanceUnstructured.GetNamespace()}
+	data, err := kabInstanceUnstructured.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("Unable to read Kabanero instance %v for conversion: %v", name, err)
+	}
+
+	// Populate a v1alpha1 object for the collections, and a v1alpha2 object
+	// which keeps the common fields.
+	kabInstanceV1 := &kabanerov1alpha1.Kabanero{}
+	err = json.Unmarshal(data, kabInstanceV1)
+	if err != nil {
+		return fmt.Errorf("Unable to read Kabanero instance %v as v1alpha1: %v", name, err)
+	}
+	kabInstanceV2 := &kabanerov1alpha2.Kabanero{}
+	err = json.Unmarshal(data, kabInstanceV2)
+	if err != nil {
+		return fmt.Errorf("Unable to read Kabanero instance %v as v1alpha2: %v", name, err)
+	}
+
+	err = kabInstanceV1.MigrateCollectionRepositories(kabInstanceV2)
+	if err != nil {
+		return fmt.Errorf("Unable to convert Kabanero instance %v to v1alpha2: %v", name, err)
+	}
+
+	// Writing the typed object back drops the collections.
+	reqLogger.Info(fmt.Sprintf("Converting the collection repositories of Kabanero instance %v to stack repositories", name))
+	err = c.Update(ctx, kabInstanceV2)
+	if err != nil {
+		return fmt.Errorf("Unable to convert Kabanero instance %v to v1alpha2: %v", name, err)
+	}
+	return nil
+}
+
+// Returns true if the unstructured Kabanero instance holds v1alpha1 collection repositories.
+func hasCollectionRepositories(kabInstanceUnstructured *unstructured.Unstructured) bool {
+	_, found, _ := unstructured.NestedFieldNoCopy(kabInstanceUnstructured.Object, "spec", "collections", "repositories")
+	return found
+}
+
+// Configures the Kabanero CRD to convert between the v1alpha1 and v1alpha2
+// versions using the admission webhook server in the Kabanero namespace.  The
+// CRD is cluster scoped, so if the admission webhook server of another Kabanero
+// namespace already converts Kabanero instances, the CRD is left alone.
+// Converting requires the CRD to prune unknown fields, which would drop the
+// collection repositories of instances that have not been migrated yet, so the
+// CRD is also left alone until every instance is migrated.
+func reconcileKabaneroConversionWebhook(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, caBundle string, reqLogger logr.Logger) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	err := c.Get(ctx, types.NamespacedName{Name: kabaneroCRDName}, crd)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve CRD %v: %v", kabaneroCRDName, err)
+	}
+
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	namespace, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "service", "namespace")
+	if strategy == "Webhook" && namespace != k.GetNamespace() {
+		reqLogger.Info(fmt.Sprintf("Kabanero version conversion is served by the admission webhook in namespace %v", namespace))
+		return nil
+	}
+
+	kabaneros := &unstructured.UnstructuredList{}
+	kabaneros.SetGroupVersionKind(kabaneroGVK.GroupVersion().WithKind("KabaneroList"))
+	err = c.List(ctx, kabaneros)
+	if err != nil {
+		return fmt.Errorf("Unable to list Kabanero instances: %v", err)
+	}
+	for _, kabInstanceUnstructured := range kabaneros.Items {
+		if hasCollectionRepositories(&kabInstanceUnstructured) {
+			reqLogger.Info(fmt.Sprintf("Kabanero version conversion is not configured until the collection repositories of Kabanero instance %v/%v are converted", kabInstanceUnstructured.GetNamespace(), kabInstanceUnstructured.GetName()))
+			return nil
+		}
+	}
+
+	conversion := map[string]interface{}{
+		"strategy": "Webhook",
+		"webhookClientConfig": map[string]interface{}{
+			"caBundle": caBundle,
+			"service": map[string]interface{}{
+				"name":      admissionWebhookServiceName,
+				"namespace": k.GetNamespace(),
+				"path":      kabaneroConversionPath,
+			},
+		},
+		"conversionReviewVersions": []interface{}{"v1beta1"},
+	}
+
+	// Conversion webhooks require unknown fields to be pruned.
+	current, _, _ := unstructured.NestedMap(crd.Object, "spec", "conversion")
+	preserveUnknownFields, found, _ := unstructured.NestedBool(crd.Object, "spec", "preserveUnknownFields")
+	if reflect.DeepEqual(current, conversion) && found && !preserveUnknownFields {
+		return nil
+	}
+
+	err = unstructured.SetNestedField(crd.Object, conversion, "spec", "conversion")
+	if err != nil {
+		return err
+	}
+	err = unstructured.SetNestedField(crd.Object, false, "spec", "preserveUnknownFields")
+	if err != nil {
+		return err
+	}
+
+	reqLogger.Info("Configuring the Kabanero version conversion webhook")
+	return c.Update(ctx, crd)
+}
+
+// Stops the Kabanero CRD from using the admission webhook server in the
+// Kabanero namespace, which is being removed, to convert between versions.
+func cleanupKabaneroConversionWebhook(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	err := c.Get(ctx, types.NamespacedName{Name: kabaneroCRDName}, crd)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("Unable to retrieve CRD %v: %v", kabaneroCRDName, err)
+	}
+
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	namespace, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "service", "namespace")
+	if strategy != "Webhook" || namespace != k.GetNamespace() {
+		return nil
+	}
+
+	err = unstructured.SetNestedField(crd.Object, map[string]interface{}{"strategy": "None"}, "spec", "conversion")
+	if err != nil {
+		return err
+	}
+
+	reqLogger.Info("Removing the Kabanero version conversion webhook")
+	return c.Update(ctx, crd)
+}
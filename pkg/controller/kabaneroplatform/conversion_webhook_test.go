@@ -0,0 +1,181 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha1"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newKabaneroCRD() *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	crd.SetName(kabaneroCRDName)
+	unstructured.SetNestedField(crd.Object, "kabanero.io", "spec", "group")
+	return crd
+}
+
+// Returns an unstructured Kabanero instance stored as v1alpha2 which still
+// holds the v1alpha1 collection repositories
+func newLegacyKabanero(namespace string) *unstructured.Unstructured {
+	legacy := &unstructured.Unstructured{}
+	legacy.SetGroupVersionKind(kabaneroGVK)
+	legacy.SetName("kabanero")
+	legacy.SetNamespace(namespace)
+	unstructured.SetNestedSlice(legacy.Object, []interface{}{
+		map[string]interface{}{"name": "central", "url": "https://example.com/collections.yaml", "skipCertVerification": true},
+		map[string]interface{}{"name": "incubator", "url": "https://example.com/old-incubator.yaml"},
+	}, "spec", "collections", "repositories")
+	unstructured.SetNestedSlice(legacy.Object, []interface{}{
+		map[string]interface{}{"name": "incubator", "https": map[string]interface{}{"url": "https://example.com/incubator.yaml"}},
+	}, "spec", "stacks", "repositories")
+	return legacy
+}
+
+func getKabaneroCRD(t *testing.T, c client.Client) *unstructured.Unstructured {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	err := c.Get(context.Background(), types.NamespacedName{Name: kabaneroCRDName}, crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return crd
+}
+
+// Verifies the Kabanero CRD is pointed at the conversion webhook, and released on cleanup
+func TestReconcileKabaneroConversionWebhook(t *testing.T) {
+	ctx := context.Background()
+	cl := newTestClient(t, newKabaneroCRD())
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+
+	err := reconcileKabaneroConversionWebhook(ctx, k, cl, "Y2E=", log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crd := getKabaneroCRD(t, cl)
+	strategy, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	namespace, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "service", "namespace")
+	path, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "service", "path")
+	caBundle, _, _ := unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "caBundle")
+	preserve, found, _ := unstructured.NestedBool(crd.Object, "spec", "preserveUnknownFields")
+	if strategy != "Webhook" || namespace != "kabanero" || path != "/convert" || caBundle != "Y2E=" {
+		t.Fatalf("Unexpected conversion configuration: %v", crd.Object["spec"])
+	}
+	if !found || preserve {
+		t.Fatal("Expected unknown fields to be pruned")
+	}
+
+	// A Kabanero instance in another namespace does not take over conversion
+	other := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "other"}}
+	err = reconcileKabaneroConversionWebhook(ctx, other, cl, "b3RoZXI=", log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cleanupKabaneroConversionWebhook(ctx, other, cl, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd = getKabaneroCRD(t, cl)
+	namespace, _, _ = unstructured.NestedString(crd.Object, "spec", "conversion", "webhookClientConfig", "service", "namespace")
+	if namespace != "kabanero" {
+		t.Fatalf("Expected conversion to be served from namespace kabanero, but was %v", namespace)
+	}
+
+	// Removing the Kabanero instance removes the conversion webhook
+	err = cleanupKabaneroConversionWebhook(ctx, k, cl, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crd = getKabaneroCRD(t, cl)
+	strategy, _, _ = unstructured.NestedString(crd.Object, "spec", "conversion", "strategy")
+	if strategy != "None" {
+		t.Fatalf("Expected the conversion strategy to be None, but was %v", strategy)
+	}
+}
+
+// Verifies the Kabanero CRD keeps preserving unknown fields while an instance
+// still holds v1alpha1 collection repositories
+func TestReconcileKabaneroConversionWebhookLegacyInstance(t *testing.T) {
+	ctx := context.Background()
+	// Kabanero instances are only read as unstructured
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(kabaneroGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(kabaneroGVK.GroupVersion().WithKind("KabaneroList"), &unstructured.UnstructuredList{})
+	cl := fake.NewFakeClientWithScheme(scheme, newKabaneroCRD(), newLegacyKabanero("other"))
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+
+	err := reconcileKabaneroConversionWebhook(ctx, k, cl, "Y2E=", log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crd := getKabaneroCRD(t, cl)
+	if _, found, _ := unstructured.NestedFieldNoCopy(crd.Object, "spec", "conversion"); found {
+		t.Fatalf("Expected conversion not to be configured: %v", crd.Object["spec"])
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(crd.Object, "spec", "preserveUnknownFields"); found {
+		t.Fatalf("Expected unknown fields to be preserved: %v", crd.Object["spec"])
+	}
+}
+
+// Verifies the v1alpha1 collection repositories of an instance stored as
+// v1alpha2 become stack repositories, keeping every repository field
+func TestMigrateCollectionRepositories(t *testing.T) {
+	ctx := context.Background()
+	legacy := newLegacyKabanero("kabanero")
+	repos, _, _ := unstructured.NestedSlice(legacy.Object, "spec", "collections", "repositories")
+	repos[0].(map[string]interface{})["activateDefaultCollections"] = true
+	unstructured.SetNestedSlice(legacy.Object, repos, "spec", "collections", "repositories")
+	cl := newTestClient(t, legacy.DeepCopy())
+	name := types.NamespacedName{Name: "kabanero", Namespace: "kabanero"}
+
+	// The API server reader lists the Kabanero instances as unstructured
+	scheme := runtime.NewScheme()
+	scheme.AddKnownTypeWithName(kabaneroGVK, &unstructured.Unstructured{})
+	scheme.AddKnownTypeWithName(kabaneroGVK.GroupVersion().WithKind("KabaneroList"), &unstructured.UnstructuredList{})
+	reader := fake.NewFakeClientWithScheme(scheme, legacy)
+
+	err := migrateCollectionRepositories(ctx, reader, cl, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k := &kabanerov1alpha2.Kabanero{}
+	err = cl.Get(ctx, name, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stackRepos := k.Spec.Stacks.Repositories
+	if len(stackRepos) != 2 {
+		t.Fatalf("Expected 2 stack repositories: %v", stackRepos)
+	}
+	if stackRepos[0].Name != "incubator" || stackRepos[0].Https.Url != "https://example.com/incubator.yaml" {
+		t.Fatalf("Expected the existing stack repository to be kept: %v", stackRepos[0])
+	}
+	if stackRepos[1].Name != "central" || stackRepos[1].Https.Url != "https://example.com/collections.yaml" || !stackRepos[1].Https.SkipCertVerification {
+		t.Fatalf("Expected the collection repository to become a stack repository: %v", stackRepos[1])
+	}
+	if !strings.Contains(k.Annotations[kabanerov1alpha1.V1alpha1DataAnnotation], "central") {
+		t.Fatalf("Expected the default collection activation to be recorded: %v", k.Annotations)
+	}
+
+	// The collections are gone, so the instance is not converted again
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(kabaneroGVK)
+	err = cl.Get(ctx, name, u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasCollectionRepositories(u) {
+		t.Fatal("Expected the collection repositories to be removed")
+	}
+}
@@ -6,8 +6,10 @@ import (
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -64,3 +66,58 @@ func getDeploymentStatus(c client.Client, name string, namespace string) (bool,
 	// Did not find the condition
 	return false, fmt.Errorf("Deployment did not contains an Available status condition")
 }
+
+// Determines if the latest rollout of a Deployment has finished: the controller has
+// observed the latest spec, every replica runs the latest pod template, and the old
+// replicas are gone.  The Available condition is not enough, because the replicas
+// of the previous pod template keep the Deployment available while the new ones
+// fail to start.  A Deployment which does not exist has no rollout in progress.
+func isDeploymentRolloutComplete(ctx context.Context, c client.Client, name string, namespace string) (bool, error) {
+	d := &appsv1.Deployment{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, d)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas, nil
+}
+
+// Determines if the latest rollout of a DeploymentConfig has finished.  The
+// Progressing condition reports NewReplicationControllerAvailable once the
+// replication controller of the latest version is available.  A DeploymentConfig
+// which does not exist has no rollout in progress.
+func isDeploymentConfigRolloutComplete(ctx context.Context, c client.Client, name string, namespace string) (bool, error) {
+	dc := &openshiftappsv1.DeploymentConfig{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, dc)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if dc.Status.ObservedGeneration < dc.Generation ||
+		dc.Status.UpdatedReplicas != dc.Spec.Replicas ||
+		dc.Status.Replicas != dc.Spec.Replicas ||
+		dc.Status.AvailableReplicas != dc.Spec.Replicas {
+		return false, nil
+	}
+
+	for _, condition := range dc.Status.Conditions {
+		if condition.Type == openshiftappsv1.DeploymentProgressing {
+			return condition.Status == corev1.ConditionTrue && condition.Reason == "NewReplicationControllerAvailable", nil
+		}
+	}
+	return false, nil
+}
@@ -1,9 +1,13 @@
 package kabaneroplatform
 
 import (
-	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
@@ -12,17 +16,31 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"math/big"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
+	"time"
 )
 
+// The Secret holding the TLS certificate served by the events service.  On
+// OpenShift it is created by the service CA.  Elsewhere the operator generates
+// a self-signed certificate, unless the Secret was already created, for
+// example by cert-manager.
+const eventsServingCertSecretName = "kabanero-events-serving-cert"
+
+// How long the generated events serving certificate is valid
+const eventsServingCertValidity = 10 * 365 * 24 * time.Hour
+
 func reconcileEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client, reqLogger logr.Logger) error {
 
 	// The Events entry was not configured in the spec.  We should disable it.
 	if k.Spec.Events.Enable == false {
+		// Remove the GitHub webhooks before the secret they use is deleted.
+		err := reconcileGithubWebhooks(ctx, k, cl, reqLogger)
+		if err != nil {
+			return err
+		}
 		cleanupEvents(ctx, k, cl, reqLogger)
 		return nil
 	}
@@ -40,7 +58,7 @@ func reconcileEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl clien
 	if err != nil {
 		return err
 	}
-	templateContext["image"] = image
+	templateContext["image"] = resolveComponentImage(ctx, k, cl, "events", image)
 
 	f, err := rev.OpenOrchestration("kabanero-events.yaml")
 	if err != nil {
@@ -57,28 +75,49 @@ func reconcileEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl clien
 		return err
 	}
 
-	transforms := []mf.Transformer{
-		mf.InjectOwner(k),
-		mf.InjectNamespace(k.GetNamespace()),
+	// Create the default events secret, if we don't already have one.  The webhook
+	// sources use it unless they specify their own.
+	err = createDefaultEventsSecret(k, cl, reqLogger)
+	if err != nil {
+		return err
 	}
 
-	m, err := mOrig.Transform(transforms...)
+	// Render the webhook routing configuration consumed by the events service
+	configTransforms, err := reconcileEventsConfig(ctx, k, cl, reqLogger)
 	if err != nil {
 		return err
 	}
 
-	err = m.Apply()
+	// Without the OpenShift service CA, the serving certificate is generated.
+	if !routesServed {
+		err = createGeneratedSecret(ctx, k, cl, eventsServingCertSecretName, func() (map[string][]byte, error) {
+			return generateEventsServingCertData(k.GetNamespace())
+		}, reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	transforms := ingressTransforms(k)
+	transforms = append(transforms,
+		mf.InjectOwner(k),
+		mf.InjectNamespace(k.GetNamespace()),
+	)
+	transforms = append(transforms, configTransforms...)
+
+	m, err := mOrig.Transform(transforms...)
 	if err != nil {
 		return err
 	}
 
-	// Create the default events secret, if we don't already have one
-	err = createDefaultEventsSecret(k, cl, reqLogger)
+	err = m.Apply()
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Register the webhook with GitHub, if requested, once the events service has
+	// been assigned a hostname.
+	return reconcileGithubWebhooks(ctx, k, cl, reqLogger)
 }
 
 // Remove the events resources
@@ -111,10 +150,11 @@ func cleanupEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.
 		return err
 	}
 
-	transforms := []mf.Transformer{
+	transforms := ingressTransforms(k)
+	transforms = append(transforms,
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
-	}
+	)
 
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
@@ -126,11 +166,29 @@ func cleanupEvents(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.
 		return err
 	}
 
+	err = cleanupEventsConfig(ctx, k, cl)
+	if err != nil {
+		return err
+	}
+
+	// Delete the serving certificate, if the operator generated it.
+	servingCert := &corev1.Secret{}
+	err = cl.Get(ctx, types.NamespacedName{Name: eventsServingCertSecretName, Namespace: k.GetNamespace()}, servingCert)
+	if err != nil {
+		if errors.IsNotFound(err) == false {
+			return err
+		}
+	} else if _, ok := servingCert.GetAnnotations()[secretGeneratedAnnotation]; ok {
+		err = cl.Delete(ctx, servingCert)
+		if err != nil && errors.IsNotFound(err) == false {
+			return err
+		}
+	}
+
 	// Delete the secret.
-	// Check if the ConfigMap resource already exists.
 	secret := &corev1.Secret{}
 	err = cl.Get(context.Background(), types.NamespacedName{
-		Name:      "default-events-secret",
+		Name:      eventsSecretName,
 		Namespace: k.ObjectMeta.Namespace}, secret)
 
 	if err != nil {
@@ -161,6 +219,26 @@ func getEventsRouteStatus(k *kabanerov1alpha2.Kabanero, cl client.Client, reqLog
 	k.Status.Events = &kabanerov1alpha2.EventsStatus{}
 	k.Status.Events.Ready = "False"
 
+	// Without Routes, the events are exposed using an Ingress.
+	if !routesServed {
+		hostnames, message, tls, err := getIngressHostnames(context.TODO(), cl, k.ObjectMeta.Namespace, "kabanero-events", "events")
+		k.Status.Events.Hostnames = hostnames
+		if len(hostnames) == 0 {
+			if err != nil {
+				reqLogger.Error(err, message)
+			}
+			k.Status.Events.Message = message
+			return false, err
+		}
+		scheme := "http://"
+		if tls {
+			scheme = "https://"
+		}
+		k.Status.Events.WebhookEndpoints = getEventsWebhookEndpoints(k, scheme, hostnames)
+		k.Status.Events.Ready = "True"
+		return true, nil
+	}
+
 	// Check that the route is accepted
 	eventsRoute := &routev1.Route{}
 	eventsRouteName := types.NamespacedName{Namespace: k.ObjectMeta.Namespace, Name: "kabanero-events"}
@@ -181,6 +259,7 @@ func getEventsRouteStatus(k *kabanerov1alpha2.Kabanero, cl client.Client, reqLog
 		}
 		// If we found a hostname from an admitted route, we're done.
 		if len(k.Status.Events.Hostnames) > 0 {
+			k.Status.Events.WebhookEndpoints = getEventsWebhookEndpoints(k, "https://", k.Status.Events.Hostnames)
 			k.Status.Events.Ready = "True"
 			k.Status.Events.Message = ""
 		} else {
@@ -207,51 +286,52 @@ func getEventsRouteStatus(k *kabanerov1alpha2.Kabanero, cl client.Client, reqLog
 
 // Creates the default events secret
 func createDefaultEventsSecret(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
-	secretName := "default-events-secret"
-
-	// Check if the Secret already exists.
-	secretInstance := &corev1.Secret{}
-	err := c.Get(context.Background(), types.NamespacedName{
-		Name:      secretName,
-		Namespace: k.ObjectMeta.Namespace}, secretInstance)
+	return createGeneratedSecret(context.Background(), k, c, eventsSecretName, generateEventsSecretData, reqLogger)
+}
 
+// Generates a self-signed TLS certificate for the events service, in the keys
+// used by the OpenShift service CA.
+func generateEventsServingCertData(namespace string) (map[string][]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		if errors.IsNotFound(err) == false {
-			return err
-		}
-
-		// Not found.  Make a new one.
-		var ownerRef metav1.OwnerReference
-		ownerRef, err = getOwnerReference(k, c, reqLogger)
-		if err != nil {
-			return err
-		}
+		return nil, err
+	}
 
-		secretInstance := &corev1.Secret{}
-		secretInstance.ObjectMeta.Name = secretName
-		secretInstance.ObjectMeta.Namespace = k.ObjectMeta.Namespace
-		secretInstance.ObjectMeta.OwnerReferences = append(secretInstance.ObjectMeta.OwnerReferences, ownerRef)
-
-		// Generate a 16 character random value
-		possibleChars := []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-=_+")
-		maxVal := big.NewInt(int64(len(possibleChars)))
-		var buf bytes.Buffer
-		for i := 0; i < 16; i++ {
-			curInt, randErr := rand.Int(rand.Reader, maxVal)
-			if randErr != nil {
-				return randErr
-			}
-			// Convert int to char
-			buf.WriteByte(possibleChars[curInt.Int64()])
-		}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
 
-		secretMap := make(map[string]string)
-		secretMap["secret"] = buf.String()
-		secretInstance.StringData = secretMap
+	service := "kabanero-events"
+	hosts := []string{
+		service,
+		fmt.Sprintf("%v.%v", service, namespace),
+		fmt.Sprintf("%v.%v.svc", service, namespace),
+		fmt.Sprintf("%v.%v.svc.cluster.local", service, namespace),
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: hosts[2]},
+		DNSNames:              hosts,
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(eventsServingCertValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
 
-		reqLogger.Info(fmt.Sprintf("Attempting to create the default events secret"))
-		err = c.Create(context.TODO(), secretInstance)
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return map[string][]byte{
+		corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}, nil
 }
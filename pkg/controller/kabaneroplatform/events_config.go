@@ -0,0 +1,221 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	mf "github.com/manifestival/manifestival"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// The ConfigMap holding the webhook routing configuration of the events service
+	eventsConfigMapName = "kabanero-events-config"
+	eventsConfigKey     = "config.yaml"
+
+	// Where the configuration and webhook secrets are mounted in the events service
+	eventsConfigMountPath         = "/etc/kabanero-events"
+	eventsWebhookSecretsMountPath = "/etc/webhook-secrets"
+
+	// Set on the pod template of the events service, so that it is restarted when
+	// the configuration changes
+	eventsConfigDigestAnnotation = "kabanero.io/events-config-digest"
+)
+
+// The webhook routing configuration read by the events service
+type eventsConfig struct {
+	WebhookSources []eventsWebhookSource `yaml:"webhookSources"`
+	Mappings       []eventsMapping       `yaml:"mappings"`
+	Destinations   []eventsDestination   `yaml:"destinations"`
+}
+
+type eventsWebhookSource struct {
+	Name         string   `yaml:"name"`
+	Path         string   `yaml:"path"`
+	Organization string   `yaml:"organization"`
+	Repositories []string `yaml:"repositories,omitempty"`
+	SecretFile   string   `yaml:"secretFile"`
+}
+
+type eventsMapping struct {
+	Source       string   `yaml:"source"`
+	Repositories []string `yaml:"repositories,omitempty"`
+	Stack        string   `yaml:"stack"`
+	Pipeline     string   `yaml:"pipeline,omitempty"`
+	Destination  string   `yaml:"destination,omitempty"`
+}
+
+type eventsDestination struct {
+	Name                 string `yaml:"name"`
+	Url                  string `yaml:"url"`
+	SkipCertVerification bool   `yaml:"skipCertVerification,omitempty"`
+}
+
+// Returns the path at which the webhooks of a source are accepted.
+func eventsWebhookPath(source kabanerov1alpha2.EventsWebhookSourceSpec) string {
+	return "/webhook/" + source.Name
+}
+
+// Returns the name of the Secret holding the webhook secret of a source.
+func eventsWebhookSecretName(source kabanerov1alpha2.EventsWebhookSourceSpec) string {
+	if len(source.SecretName) == 0 {
+		return eventsSecretName
+	}
+	return source.SecretName
+}
+
+// Renders the webhook routing configuration of the Kabanero instance.
+func renderEventsConfig(k *kabanerov1alpha2.Kabanero) ([]byte, error) {
+	config := eventsConfig{
+		WebhookSources: []eventsWebhookSource{},
+		Mappings:       []eventsMapping{},
+		Destinations:   []eventsDestination{},
+	}
+
+	for _, source := range k.Spec.Events.WebhookSources {
+		config.WebhookSources = append(config.WebhookSources, eventsWebhookSource{
+			Name:         source.Name,
+			Path:         eventsWebhookPath(source),
+			Organization: source.Organization,
+			Repositories: source.Repositories,
+			SecretFile:   path.Join(eventsWebhookSecretsMountPath, eventsWebhookSecretName(source), "secret"),
+		})
+	}
+	for _, mapping := range k.Spec.Events.Mappings {
+		config.Mappings = append(config.Mappings, eventsMapping(mapping))
+	}
+	for _, destination := range k.Spec.Events.Destinations {
+		config.Destinations = append(config.Destinations, eventsDestination(destination))
+	}
+
+	return yaml.Marshal(config)
+}
+
+// Checks that the Secrets of the webhook sources exist and hold a webhook secret.
+func validateEventsWebhookSecrets(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	for _, source := range k.Spec.Events.WebhookSources {
+		secretName := eventsWebhookSecretName(source)
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: k.GetNamespace()}, secret)
+		if err != nil {
+			return fmt.Errorf("Unable to retrieve the Secret %v of webhook source %v: %v", secretName, source.Name, err)
+		}
+		if len(secret.Data["secret"]) == 0 {
+			return fmt.Errorf("The Secret %v of webhook source %v does not contain key 'secret'", secretName, source.Name)
+		}
+	}
+	return nil
+}
+
+// Creates or updates the ConfigMap holding the webhook routing configuration of the
+// events service, and returns the transforms which mount it and the webhook secrets
+// into the events service.
+func reconcileEventsConfig(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) ([]mf.Transformer, error) {
+	err := validateEventsWebhookSecrets(ctx, k, c)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := renderEventsConfig(k)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{eventsConfigKey: string(config)}
+
+	cm := &corev1.ConfigMap{}
+	err = c.Get(ctx, types.NamespacedName{Name: eventsConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+
+		ownerRef, err := getOwnerReference(k, c, reqLogger)
+		if err != nil {
+			return nil, err
+		}
+
+		cm = &corev1.ConfigMap{}
+		cm.ObjectMeta.Name = eventsConfigMapName
+		cm.ObjectMeta.Namespace = k.GetNamespace()
+		cm.ObjectMeta.OwnerReferences = append(cm.ObjectMeta.OwnerReferences, ownerRef)
+		cm.Data = data
+
+		reqLogger.Info(fmt.Sprintf("Attempting to create the events configuration ConfigMap %v", cm.Name))
+		err = c.Create(ctx, cm)
+		if err != nil {
+			return nil, err
+		}
+	} else if !reflect.DeepEqual(cm.Data, data) {
+		cm.Data = data
+		reqLogger.Info(fmt.Sprintf("Updating the events configuration ConfigMap %v", cm.Name))
+		err = c.Update(ctx, cm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	digest := sha256.Sum256(config)
+	transforms := []mf.Transformer{
+		kabTransforms.MountConfigMap(eventsConfigMapName, eventsConfigMountPath),
+		kabTransforms.AddEnvVariable("EVENTS_CONFIG_FILE", path.Join(eventsConfigMountPath, eventsConfigKey)),
+		setEventsConfigDigest(hex.EncodeToString(digest[:])),
+	}
+
+	mounted := make(map[string]bool)
+	for _, source := range k.Spec.Events.WebhookSources {
+		secretName := eventsWebhookSecretName(source)
+		if !mounted[secretName] {
+			mounted[secretName] = true
+			transforms = append(transforms, kabTransforms.MountSecret(secretName, path.Join(eventsWebhookSecretsMountPath, secretName)))
+		}
+	}
+
+	return transforms, nil
+}
+
+// Records the digest of the configuration on the pod template of the events service.
+func setEventsConfigDigest(digest string) mf.Transformer {
+	return func(u *unstructured.Unstructured) error {
+		if u.GetKind() != "Deployment" {
+			return nil
+		}
+		return unstructured.SetNestedField(u.Object, digest, "spec", "template", "metadata", "annotations", eventsConfigDigestAnnotation)
+	}
+}
+
+// Returns the URLs at which the webhooks of each source are accepted, on each of the
+// hostnames of the events service.
+func getEventsWebhookEndpoints(k *kabanerov1alpha2.Kabanero, scheme string, hostnames []string) []string {
+	var endpoints []string
+	for _, hostname := range hostnames {
+		for _, source := range k.Spec.Events.WebhookSources {
+			endpoints = append(endpoints, scheme+hostname+eventsWebhookPath(source))
+		}
+	}
+	return endpoints
+}
+
+// Deletes the ConfigMap holding the webhook routing configuration.
+func cleanupEventsConfig(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: eventsConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return c.Delete(ctx, cm)
+}
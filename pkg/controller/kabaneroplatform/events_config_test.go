@@ -0,0 +1,118 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Verifies the events configuration ConfigMap is rendered from the Kabanero instance
+func TestReconcileEventsConfig(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Events: kabanerov1alpha2.EventsCustomizationSpec{
+				Enable: true,
+				WebhookSources: []kabanerov1alpha2.EventsWebhookSourceSpec{
+					{Name: "github", Organization: "myorg"},
+					{Name: "team", Organization: "team", Repositories: []string{"app"}, SecretName: "team-webhook"},
+				},
+				Mappings: []kabanerov1alpha2.EventsMappingSpec{
+					{Source: "github", Stack: "java-openliberty", Destination: "tekton"},
+				},
+				Destinations: []kabanerov1alpha2.EventsDestinationSpec{
+					{Name: "tekton", Url: "https://el-listener.kabanero.svc:8080"},
+				},
+			},
+		},
+	}
+	defaultSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: eventsSecretName, Namespace: "kabanero"},
+		Data:       map[string][]byte{"secret": []byte("default")},
+	}
+	teamSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-webhook", Namespace: "kabanero"},
+		Data:       map[string][]byte{"token": []byte("team")},
+	}
+
+	cl := newTestClient(t, k, defaultSecret, teamSecret)
+	ctx := context.Background()
+
+	// The Secret of the team source does not hold a webhook secret.
+	_, err := reconcileEventsConfig(ctx, k, cl, logf.NullLogger{})
+	if err == nil || !strings.Contains(err.Error(), "does not contain key 'secret'") {
+		t.Fatalf("Expected an error for the Secret of the team source, but got %v", err)
+	}
+
+	teamSecret.Data["secret"] = []byte("team")
+	if err = cl.Update(ctx, teamSecret); err != nil {
+		t.Fatal(err)
+	}
+	transforms, err := reconcileEventsConfig(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The ConfigMap, environment variable, digest, and the two Secrets
+	if len(transforms) != 5 {
+		t.Fatalf("Expected 5 transforms, but got %v", len(transforms))
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = cl.Get(ctx, types.NamespacedName{Name: eventsConfigMapName, Namespace: "kabanero"}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cm.OwnerReferences) != 1 {
+		t.Fatalf("Expected the ConfigMap to be owned by the Kabanero instance: %v", cm.ObjectMeta)
+	}
+
+	config := eventsConfig{}
+	err = yaml.Unmarshal([]byte(cm.Data[eventsConfigKey]), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.WebhookSources) != 2 || len(config.Mappings) != 1 || len(config.Destinations) != 1 {
+		t.Fatalf("Unexpected configuration: %v", config)
+	}
+	if source := config.WebhookSources[0]; source.Path != "/webhook/github" || source.SecretFile != "/etc/webhook-secrets/default-events-secret/secret" {
+		t.Fatalf("Unexpected github source: %v", source)
+	}
+	if source := config.WebhookSources[1]; source.Path != "/webhook/team" || source.SecretFile != "/etc/webhook-secrets/team-webhook/secret" {
+		t.Fatalf("Unexpected team source: %v", source)
+	}
+
+	// The ConfigMap is updated when the instance changes.
+	k.Spec.Events.Mappings = nil
+	_, err = reconcileEventsConfig(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.Get(ctx, types.NamespacedName{Name: eventsConfigMapName, Namespace: "kabanero"}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(cm.Data[eventsConfigKey], "java-openliberty") {
+		t.Fatalf("Expected the mapping to be removed: %v", cm.Data[eventsConfigKey])
+	}
+
+	endpoints := getEventsWebhookEndpoints(k, "https://", []string{"events.example.com"})
+	if len(endpoints) != 2 || endpoints[0] != "https://events.example.com/webhook/github" || endpoints[1] != "https://events.example.com/webhook/team" {
+		t.Fatalf("Unexpected webhook endpoints: %v", endpoints)
+	}
+
+	err = cleanupEventsConfig(ctx, k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.Get(ctx, types.NamespacedName{Name: eventsConfigMapName, Namespace: "kabanero"}, cm)
+	if err == nil {
+		t.Fatal("Expected the ConfigMap to be deleted")
+	}
+}
@@ -0,0 +1,145 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Verifies the events orchestration is deployed with a generated serving
+// certificate when Routes are not served
+func TestReconcileEventsWithoutRoutes(t *testing.T) {
+	defer func() { routesServed = true }()
+	routesServed = false
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Ingress: kabanerov1alpha2.IngressSpec{Domain: "apps.example.com"},
+			Events:  kabanerov1alpha2.EventsCustomizationSpec{Enable: true},
+		},
+	}
+
+	cl := newTestClient(t, k)
+	ctx := context.Background()
+
+	err := reconcileEvents(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ingress := &networkingv1beta1.Ingress{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "kabanero-events", Namespace: "kabanero"}, ingress)
+	if err != nil {
+		t.Fatalf("Expected the events to be exposed using an Ingress: %v", err)
+	}
+	if ingress.Annotations[ingressBackendProtocolAnnotation] != "HTTPS" {
+		t.Fatalf("Expected the Ingress to connect to the events service using HTTPS, but got annotations %v", ingress.Annotations)
+	}
+
+	// The Secret mounted by the Deployment must exist.
+	deployment := &appsv1.Deployment{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "kabanero-events", Namespace: "kabanero"}, deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var certSecretName string
+	for _, volume := range deployment.Spec.Template.Spec.Volumes {
+		if volume.Name == "kabanero-events-serving-cert" && volume.Secret != nil {
+			certSecretName = volume.Secret.SecretName
+		}
+	}
+	if certSecretName != eventsServingCertSecretName {
+		t.Fatalf("Expected the Deployment to mount the serving certificate, but got volumes %v", deployment.Spec.Template.Spec.Volumes)
+	}
+
+	secret := &corev1.Secret{}
+	err = cl.Get(ctx, types.NamespacedName{Name: certSecretName, Namespace: "kabanero"}, secret)
+	if err != nil {
+		t.Fatalf("Expected the serving certificate to be generated: %v", err)
+	}
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		t.Fatalf("Expected a PEM encoded certificate, but got %v", string(secret.Data[corev1.TLSCertKey]))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.VerifyHostname("kabanero-events.kabanero.svc"); err != nil {
+		t.Fatal(err)
+	}
+	if keyBlock, _ := pem.Decode(secret.Data[corev1.TLSPrivateKeyKey]); keyBlock == nil {
+		t.Fatal("Expected a PEM encoded private key")
+	}
+
+	// The generated certificate is kept.
+	err = reconcileEvents(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := &corev1.Secret{}
+	err = cl.Get(ctx, types.NamespacedName{Name: certSecretName, Namespace: "kabanero"}, kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(kept.Data[corev1.TLSCertKey]) != string(secret.Data[corev1.TLSCertKey]) {
+		t.Fatal("Expected the serving certificate not to be regenerated")
+	}
+
+	// The generated certificate is removed with the events.
+	err = cleanupEvents(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cl.Get(ctx, types.NamespacedName{Name: certSecretName, Namespace: "kabanero"}, &corev1.Secret{})
+	if err == nil {
+		t.Fatal("Expected the generated serving certificate to be deleted")
+	}
+}
+
+// Verifies a serving certificate which was not generated by the operator is
+// used and kept
+func TestReconcileEventsProvidedServingCert(t *testing.T) {
+	defer func() { routesServed = true }()
+	routesServed = false
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Events: kabanerov1alpha2.EventsCustomizationSpec{Enable: true}},
+	}
+	provided := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: eventsServingCertSecretName, Namespace: "kabanero"},
+		Data:       map[string][]byte{corev1.TLSCertKey: []byte("cert"), corev1.TLSPrivateKeyKey: []byte("key")},
+	}
+
+	cl := newTestClient(t, k, provided)
+	ctx := context.Background()
+
+	err := reconcileEvents(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cleanupEvents(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{}
+	err = cl.Get(ctx, types.NamespacedName{Name: eventsServingCertSecretName, Namespace: "kabanero"}, secret)
+	if err != nil {
+		t.Fatalf("Expected the provided serving certificate to be kept: %v", err)
+	}
+	if string(secret.Data[corev1.TLSCertKey]) != "cert" {
+		t.Fatalf("Expected the provided serving certificate not to be replaced, but got %v", string(secret.Data[corev1.TLSCertKey]))
+	}
+}
@@ -2,26 +2,86 @@ package kabaneroplatform
 
 import (
 	"context"
+	"crypto"
+	"fmt"
+	"strings"
+	"time"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/stack"
 	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// The amount of time the digest of a featured stack image is remembered before
+// the registry is asked again.  Its signature is only verified again when the
+// digest or the trusted keys change.
+const featuredStackDigestCacheTTL = 10 * time.Minute
+
+// Verified featured stack image signatures
+var featuredStackSignatureCache = cutils.NewSignatureCache(featuredStackDigestCacheTTL)
+
 func reconcileFeaturedStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client) error {
 	// Resolve the stacks which are currently featured across the various indexes.
-	stackMap, err := featuredStacks(k, cl)
+	stackMap, err := featuredStacks(ctx, k, cl)
 	if err != nil {
 		return err
 	}
 
+	// Stack versions which violate the stack image policy would be rejected by the stack
+	// admission webhook.  Skip them, so that the Stack create or update does not fail on
+	// every reconcile, and report them in the instance status.
+	// When the signatures cannot be verified, the stack versions with images are
+	// skipped and reported, rather than failing the reconcile of the instance.
+	policy := k.Spec.Stacks.ImagePolicy
+	var keys []crypto.PublicKey
+	var creds cutils.RegistryCredentials
+	var signatureErr error
+	if policy.RequireSignatures {
+		keys, signatureErr = sutils.GetSignatureKeys(ctx, cl, k.GetNamespace(), policy)
+		if signatureErr == nil {
+			creds, signatureErr = cutils.GetRegistryCredentials(cl, k.GetNamespace(), k.Spec.DigestPinning.PullSecrets)
+		}
+	}
+	k.Status.RejectedStacks = nil
+
 	// Each key is a stack id.  Get that Stack CR instance and see if the versions are set correctly.
-	for key, value := range stackMap {
+	for key, versions := range stackMap {
+		var value []kabanerov1alpha2.StackVersion
+		for _, stack := range versions {
+			// Remove the tag portion of all images associated with the input stack version.
+			err := sutils.RemoveTagFromStackImages(&stack, key)
+			if err != nil {
+				return err
+			}
+
+			var violations []string
+			if signatureErr != nil && len(stack.Images) > 0 {
+				violations = []string{fmt.Sprintf("Unable to verify the signatures of the images associated with stack %v %v: %v", key, stack.Version, signatureErr)}
+			} else {
+				violations = sutils.CheckStackImagePolicy(ctx, policy, key, stack, nil, keys, creds, cutils.RegistryTransport, featuredStackSignatureCache)
+			}
+			if len(violations) > 0 {
+				k.Status.RejectedStacks = append(k.Status.RejectedStacks, kabanerov1alpha2.RejectedStackStatus{
+					Name:    key,
+					Version: stack.Version,
+					Message: strings.Join(violations, " "),
+				})
+				continue
+			}
+
+			value = append(value, stack)
+		}
+
+		if len(value) == 0 {
+			continue
+		}
+
 		updateStack := utils.Update
 		name := types.NamespacedName{
 			Name:      key,
@@ -60,14 +120,7 @@ func reconcileFeaturedStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 
 		// Add each version to the versions array if it's not already there.  If it's already there, just
 		// update the repository URL, don't touch the desired state.
-		for i, stack := range value {
-			// Remove the tag portion of all images associated with the input stack version.
-			err := sutils.RemoveTagFromStackImages(&stack, key)
-			if err != nil {
-				return err
-			}
-			value[i].Images = stack.Images
-
+		for _, stack := range value {
 			foundVersion := false
 			for j, stackVersion := range stackResource.Spec.Versions {
 				if stackVersion.Version == stack.Version {
@@ -75,6 +128,7 @@ func reconcileFeaturedStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 					stackVersion.Pipelines = stack.Pipelines
 					stackVersion.SkipCertVerification = stack.SkipCertVerification
 					stackVersion.Images = stack.Images
+					stackVersion.Requires = stack.Requires
 					stackResource.Spec.Versions[j] = stackVersion
 				}
 			}
@@ -95,7 +149,8 @@ func reconcileFeaturedStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 }
 
 // Resolves all stacks for the given Kabanero instance
-func featuredStacks(k *kabanerov1alpha2.Kabanero, cl client.Client) (map[string][]kabanerov1alpha2.StackVersion, error) {
+func featuredStacks(ctx context.Context, k *kabanerov1alpha2.Kabanero, cl client.Client) (map[string][]kabanerov1alpha2.StackVersion, error) {
+	timeouts := stack.GetTimeouts(k.Spec.Stacks.Timeouts)
 
 	stackMap := make(map[string][]kabanerov1alpha2.StackVersion)
 	for _, r := range k.Spec.Stacks.Repositories {
@@ -108,10 +163,10 @@ func featuredStacks(k *kabanerov1alpha2.Kabanero, cl client.Client) (map[string]
 
 		indexPipelines := []stack.Pipelines{}
 		for _, pipeline := range pipelines {
-			indexPipelines = append(indexPipelines, stack.Pipelines{Id: pipeline.Id, Sha256: pipeline.Sha256, Url: pipeline.Https.Url, GitRelease: pipeline.GitRelease, SkipCertVerification: pipeline.Https.SkipCertVerification})
+			indexPipelines = append(indexPipelines, stack.Pipelines{Id: pipeline.Id, Sha256: pipeline.Sha256, Url: pipeline.Https.Url, GitRelease: pipeline.GitRelease, SkipCertVerification: pipeline.Https.SkipCertVerification, Https: pipeline.Https})
 		}
 
-		index, err := stack.ResolveIndex(cl, r, k.Namespace, indexPipelines, []stack.Trigger{}, "")
+		index, err := resolveIndex(ctx, cl, r, k.Namespace, indexPipelines, timeouts)
 		if err != nil {
 			return nil, err
 		}
@@ -122,7 +177,9 @@ func featuredStacks(k *kabanerov1alpha2.Kabanero, cl client.Client) (map[string]
 			// because we provided it at the time we read the appsody stack index (in ResolveIndex).
 			pipelines := []kabanerov1alpha2.PipelineSpec{}
 			for _, pipeline := range c.Pipelines {
-				pipelineUrl := kabanerov1alpha2.HttpsProtocolFile{Url: pipeline.Url, SkipCertVerification: pipeline.SkipCertVerification}
+				pipelineUrl := pipeline.Https
+				pipelineUrl.Url = pipeline.Url
+				pipelineUrl.SkipCertVerification = pipeline.SkipCertVerification
 				pipelines = append(pipelines, kabanerov1alpha2.PipelineSpec{Id: pipeline.Id, Sha256: pipeline.Sha256, Https: pipelineUrl, GitRelease: pipeline.GitRelease})
 			}
 			// The image information will be in the stack.  Today we just support reading the legacy field from the collection hub.
@@ -131,9 +188,30 @@ func featuredStacks(k *kabanerov1alpha2.Kabanero, cl client.Client) (map[string]
 				images = append(images, kabanerov1alpha2.Image{Id: image.Id, Image: image.Image})
 			}
 
-			stackMap[c.Id] = append(stackMap[c.Id], kabanerov1alpha2.StackVersion{Pipelines: pipelines, Version: c.Version, Images: images})
+			// The requirements the platform must satisfy before the stack version is activated.
+			requires := kabanerov1alpha2.StackRequirements{Kabanero: c.Requires.Kabanero, TektonApiVersions: c.Requires.TektonApiVersions}
+			for _, required := range c.Requires.Stacks {
+				requires.Stacks = append(requires.Stacks, kabanerov1alpha2.StackDependency{Name: required.Id, Version: required.Version})
+			}
+
+			stackMap[c.Id] = append(stackMap[c.Id], kabanerov1alpha2.StackVersion{Pipelines: pipelines, Version: c.Version, Images: images, Requires: requires})
 		}
 	}
 
 	return stackMap, nil
 }
+
+// Resolves the index of a stack repository, within the index resolution timeout.
+func resolveIndex(ctx context.Context, cl client.Client, r kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []stack.Pipelines, timeouts stack.Timeouts) (*stack.Index, error) {
+	ictx, cancel := context.WithTimeout(stack.WithDownloadTimeout(ctx, timeouts.Download), timeouts.IndexResolution)
+	defer cancel()
+
+	index, err := stack.ResolveIndex(ictx, cl, r, namespace, pipelines, []stack.Trigger{}, "")
+	if err != nil && ictx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+		if _, ok := err.(*stack.TimeoutError); !ok {
+			err = &stack.TimeoutError{Operation: fmt.Sprintf("resolving the index of stack repository %v", r.Name), Timeout: timeouts.IndexResolution, Err: err}
+		}
+	}
+
+	return index, err
+}
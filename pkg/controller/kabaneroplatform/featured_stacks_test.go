@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -216,6 +217,76 @@ func TestReconcileFeaturedStacks(t *testing.T) {
 	}
 }
 
+// Test that stack versions which violate the stack image policy are skipped and reported
+func TestReconcileFeaturedStacksImagePolicy(t *testing.T) {
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := unitTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	k := createKabanero(server.URL + defaultIndexName)
+	k.Spec.Stacks.ImagePolicy.AllowedImages = []string{"docker.io/kabanero/nodejs"}
+
+	err := reconcileFeaturedStacks(ctx, k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.objs["nodejs"] == nil {
+		t.Fatal("Expected the nodejs stack to be created")
+	}
+
+	if cl.objs["java-microprofile"] != nil {
+		t.Fatal("Expected the java-microprofile stack to be skipped")
+	}
+
+	if len(k.Status.RejectedStacks) != 1 || k.Status.RejectedStacks[0].Name != "java-microprofile" {
+		t.Fatal(fmt.Sprintf("Expected the java-microprofile stack to be reported as rejected: %v", k.Status.RejectedStacks))
+	}
+
+	// Relaxing the policy clears the report
+	k.Spec.Stacks.ImagePolicy.AllowedImages = append(k.Spec.Stacks.ImagePolicy.AllowedImages, "kabanero/java-microprofile")
+	err = reconcileFeaturedStacks(ctx, k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.objs["java-microprofile"] == nil || len(k.Status.RejectedStacks) != 0 {
+		t.Fatal(fmt.Sprintf("Expected the java-microprofile stack to be created: %v", k.Status.RejectedStacks))
+	}
+}
+
+// Test that stack versions are skipped and reported, without failing the reconcile,
+// when the trusted signature keys cannot be loaded
+func TestReconcileFeaturedStacksSignatureKeysUnavailable(t *testing.T) {
+	server := httptest.NewServer(stackIndexHandler{})
+	defer server.Close()
+
+	ctx := context.Background()
+	cl := unitTestClient{make(map[string]*kabanerov1alpha2.Stack)}
+	k := createKabanero(server.URL + defaultIndexName)
+	k.Spec.Stacks.ImagePolicy.RequireSignatures = true
+	k.Spec.Stacks.ImagePolicy.SignatureKeys = kabanerov1alpha2.ConfigMapKeyRef{Name: "cosign-keys"}
+
+	err := reconcileFeaturedStacks(ctx, k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cl.objs) != 0 {
+		t.Fatal(fmt.Sprintf("Expected no stacks to be created: %v", cl.objs))
+	}
+
+	if len(k.Status.RejectedStacks) != 2 {
+		t.Fatal(fmt.Sprintf("Expected both stacks to be reported as rejected: %v", k.Status.RejectedStacks))
+	}
+	for _, rejected := range k.Status.RejectedStacks {
+		if !strings.Contains(rejected.Message, "Unable to verify the signatures") {
+			t.Fatal(fmt.Sprintf("Unexpected rejection message: %v", rejected.Message))
+		}
+	}
+}
+
 func TestReconcileFeaturedStacksTwoRepositories(t *testing.T) {
 	// The server that will host the pipeline zip
 	server := httptest.NewServer(stackIndexHandler{})
@@ -439,7 +510,7 @@ func TestResolveFeaturedStacks(t *testing.T) {
 	stack_index_url := server.URL + defaultIndexName
 	k := createKabanero(stack_index_url)
 
-	stacks, err := featuredStacks(k, nil)
+	stacks, err := featuredStacks(context.Background(), k, nil)
 	if err != nil {
 		t.Fatal("Could not resolve the featured stacks from the default index", err)
 	}
@@ -467,6 +538,12 @@ func TestResolveFeaturedStacks(t *testing.T) {
 	if len(nodejsStackVersions) != 1 {
 		t.Fatal(fmt.Sprintf("Expected one version of nodejs stack, but found %v: %v", len(nodejsStackVersions), nodejsStackVersions))
 	}
+
+	// Make sure the stack requirements were carried into the stack version
+	requires := nodejsStackVersions[0].Requires
+	if requires.Kabanero != ">=0.6.0" || len(requires.TektonApiVersions) != 1 || len(requires.Stacks) != 1 || requires.Stacks[0].Name != "java-microprofile" {
+		t.Fatal(fmt.Sprintf("Expected the nodejs stack requirements to be set, but found %#v", requires))
+	}
 }
 
 // Attempts to resolve the featured stacks from two repositories
@@ -480,7 +557,7 @@ func TestResolveFeaturedStacksTwoRepositories(t *testing.T) {
 	k := createKabanero(stack_index_url)
 	k.Spec.Stacks.Repositories = append(k.Spec.Stacks.Repositories, kabanerov1alpha2.RepositoryConfig{Name: "two", Https: kabanerov1alpha2.HttpsProtocolFile{Url: stack_index_url_two}})
 
-	stacks, err := featuredStacks(k, nil)
+	stacks, err := featuredStacks(context.Background(), k, nil)
 	if err != nil {
 		t.Fatal("Could not resolve the featured stacks from the default index", err)
 	}
@@ -0,0 +1,487 @@
+package kabaneroplatform
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	routev1 "github.com/openshift/api/route/v1"
+	"gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The ConfigMap recording the webhooks registered with GitHub, so that they can be
+// updated and removed later.  Each key identifies the organization ("org") or a
+// repository ("repo.<name>") with which a webhook is registered.
+const githubWebhooksConfigMapName = "kabanero-events-github-webhooks"
+
+// The events sent to the webhook when none are specified
+var defaultGithubWebhookEvents = []string{"push", "pull_request"}
+
+// How long to wait for a response from the GitHub API
+const githubRequestTimeout = 30 * time.Second
+
+// A webhook registered with GitHub
+type githubWebhookRecord struct {
+	// The GitHub API path of the hooks of the organization or repository
+	Path string `yaml:"path"`
+	Id   int64  `yaml:"id"`
+	// The digest of the URL, secret and events the webhook was registered with
+	Digest string `yaml:"digest"`
+}
+
+// The hook configuration sent to the GitHub API
+type githubHook struct {
+	Name   string           `json:"name,omitempty"`
+	Active bool             `json:"active"`
+	Events []string         `json:"events"`
+	Config githubHookConfig `json:"config"`
+}
+
+type githubHookConfig struct {
+	Url         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Secret      string `json:"secret"`
+	InsecureSsl string `json:"insecure_ssl"`
+}
+
+// Registers the webhook of the events service with the GitHub organization or
+// repositories, updating the webhooks already registered when the URL, secret or
+// events change, and removing those no longer wanted.
+func reconcileGithubWebhooks(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	if !k.Spec.Events.Enable || !k.Spec.Github.Webhook.Enable {
+		return removeGithubWebhooks(ctx, k, c, false, reqLogger)
+	}
+
+	// The webhook is registered once the events service has been assigned a hostname.
+	eventsURL, err := getEventsURL(ctx, k, c)
+	if err != nil {
+		return err
+	}
+	if len(eventsURL) == 0 {
+		reqLogger.Info("The events service has not been assigned a hostname. The GitHub webhook will be registered later.")
+		return nil
+	}
+
+	path, secretName := githubWebhookTarget(k)
+	secret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: k.GetNamespace()}, secret)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve the webhook Secret %v: %v", secretName, err)
+	}
+
+	events := k.Spec.Github.Webhook.Events
+	if len(events) == 0 {
+		events = defaultGithubWebhookEvents
+	}
+	hook := githubHook{
+		Name:   "web",
+		Active: true,
+		Events: events,
+		Config: githubHookConfig{
+			Url:         eventsURL + path,
+			ContentType: "json",
+			Secret:      string(secret.Data["secret"]),
+			InsecureSsl: "0",
+		},
+	}
+
+	gh, err := newGithubHooksClient(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
+	records, cm, err := getGithubWebhookRecords(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
+	// The webhooks registered are recorded even if a later request fails, so that
+	// they are not registered again.
+	changed, err := syncGithubWebhooks(ctx, gh, k, hook, records, reqLogger)
+	if changed {
+		saveErr := saveGithubWebhookRecords(ctx, k, c, cm, records, reqLogger)
+		if err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// Registers, updates and removes webhooks so that they match the desired hook,
+// updating the records.  Returns whether the records were changed.
+func syncGithubWebhooks(ctx context.Context, gh *githubHooksClient, k *kabanerov1alpha2.Kabanero, hook githubHook, records map[string]githubWebhookRecord, reqLogger logr.Logger) (bool, error) {
+	changed := false
+	digest := githubHookDigest(hook)
+	desired := githubWebhookHooksPaths(k)
+	for key, hooksPath := range desired {
+		record, found := records[key]
+		if found && record.Path == hooksPath && record.Digest == digest {
+			continue
+		}
+
+		var err error
+		// The organization has changed, so the webhook is registered with the new one.
+		if found && record.Path != hooksPath {
+			reqLogger.Info(fmt.Sprintf("Removing the GitHub webhook %v/%v", record.Path, record.Id))
+			err = gh.delete(ctx, record.Path, record.Id)
+			if err != nil {
+				return changed, err
+			}
+			delete(records, key)
+			changed = true
+			found = false
+		}
+		if found {
+			reqLogger.Info(fmt.Sprintf("Updating the GitHub webhook %v/%v", hooksPath, record.Id))
+			found, err = gh.update(ctx, hooksPath, record.Id, hook)
+			if err != nil {
+				return changed, err
+			}
+		}
+		// The webhook was never registered, or was removed from GitHub.
+		if !found {
+			reqLogger.Info(fmt.Sprintf("Registering a GitHub webhook at %v for %v", hooksPath, hook.Config.Url))
+			record.Id, err = gh.create(ctx, hooksPath, hook)
+			if err != nil {
+				return changed, err
+			}
+		}
+		record.Path = hooksPath
+		record.Digest = digest
+		records[key] = record
+		changed = true
+	}
+
+	// Remove the webhooks of repositories no longer listed, or of the organization
+	// when repositories are now listed.
+	for key, record := range records {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		reqLogger.Info(fmt.Sprintf("Removing the GitHub webhook %v/%v", record.Path, record.Id))
+		err := gh.delete(ctx, record.Path, record.Id)
+		if err != nil {
+			return changed, err
+		}
+		delete(records, key)
+		changed = true
+	}
+
+	return changed, nil
+}
+
+// Removes the webhooks registered with GitHub, and the record of them.  When
+// best effort, as when the Kabanero instance is deleted, the webhooks which
+// cannot be removed are logged and forgotten, rather than retried, since GitHub
+// may be unreachable or the token may already have been deleted.
+func removeGithubWebhooks(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, bestEffort bool, reqLogger logr.Logger) error {
+	records, cm, err := getGithubWebhookRecords(ctx, k, c)
+	if err != nil {
+		if !bestEffort || cm == nil {
+			return err
+		}
+		reqLogger.Error(err, "Unable to read the GitHub webhooks to remove. They must be removed in GitHub.")
+		records = nil
+	}
+	if cm == nil {
+		return nil
+	}
+
+	if len(records) != 0 {
+		err = deleteGithubWebhooks(ctx, k, c, records, reqLogger)
+		if err != nil {
+			if !bestEffort {
+				return err
+			}
+			reqLogger.Error(err, "Unable to remove the GitHub webhooks. They must be removed in GitHub.")
+		}
+	}
+
+	err = c.Delete(ctx, cm)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Deletes the recorded webhooks from GitHub.
+func deleteGithubWebhooks(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, records map[string]githubWebhookRecord, reqLogger logr.Logger) error {
+	gh, err := newGithubHooksClient(ctx, k, c)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		reqLogger.Info(fmt.Sprintf("Removing the GitHub webhook %v/%v", record.Path, record.Id))
+		err = gh.delete(ctx, record.Path, record.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the path of the events service and the name of the Secret holding the
+// webhook secret.  When a webhook source of the events service accepts the webhooks
+// of the organization, its path and Secret are used.
+func githubWebhookTarget(k *kabanerov1alpha2.Kabanero) (string, string) {
+	for _, source := range k.Spec.Events.WebhookSources {
+		if source.Organization == k.Spec.Github.Organization {
+			return eventsWebhookPath(source), eventsWebhookSecretName(source)
+		}
+	}
+	return "/", eventsSecretName
+}
+
+// Returns the GitHub API paths under which the webhooks are registered, by the key
+// recording them.
+func githubWebhookHooksPaths(k *kabanerov1alpha2.Kabanero) map[string]string {
+	organization := url.PathEscape(k.Spec.Github.Organization)
+	paths := make(map[string]string)
+	if len(k.Spec.Github.Webhook.Repositories) == 0 {
+		paths["org"] = fmt.Sprintf("/orgs/%v/hooks", organization)
+	}
+	for _, repository := range k.Spec.Github.Webhook.Repositories {
+		paths["repo."+repository] = fmt.Sprintf("/repos/%v/%v/hooks", organization, url.PathEscape(repository))
+	}
+	return paths
+}
+
+// Returns a digest identifying the URL, secret and events of a hook, without
+// recording the secret itself.
+func githubHookDigest(hook githubHook) string {
+	events := append([]string{}, hook.Events...)
+	sort.Strings(events)
+	digest := sha256.Sum256([]byte(strings.Join([]string{hook.Config.Url, hook.Config.Secret, strings.Join(events, ",")}, "\n")))
+	return hex.EncodeToString(digest[:])
+}
+
+// Returns the base URL of the events service, or an empty string when it has not
+// been assigned a hostname.
+func getEventsURL(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	if !routesServed {
+		hostnames, _, tls, err := getIngressHostnames(ctx, c, k.GetNamespace(), "kabanero-events", "events")
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+		if len(hostnames) == 0 {
+			return "", nil
+		}
+		if tls {
+			return "https://" + hostnames[0], nil
+		}
+		return "http://" + hostnames[0], nil
+	}
+
+	eventsRoute := &routev1.Route{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: k.GetNamespace(), Name: "kabanero-events"}, eventsRoute)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	for _, ingress := range eventsRoute.Status.Ingress {
+		for _, condition := range ingress.Conditions {
+			if condition.Type == routev1.RouteAdmitted && condition.Status == corev1.ConditionTrue && len(ingress.Host) > 0 {
+				return "https://" + ingress.Host, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// Reads the record of the webhooks registered with GitHub.  The ConfigMap is nil
+// when no webhooks have been recorded, and is returned when it cannot be read.
+func getGithubWebhookRecords(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (map[string]githubWebhookRecord, *corev1.ConfigMap, error) {
+	records := make(map[string]githubWebhookRecord)
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: githubWebhooksConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return records, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	for key, value := range cm.Data {
+		record := githubWebhookRecord{}
+		err = yaml.Unmarshal([]byte(value), &record)
+		if err != nil {
+			return nil, cm, fmt.Errorf("Unable to read the GitHub webhook %v recorded in ConfigMap %v: %v", key, githubWebhooksConfigMapName, err)
+		}
+		records[key] = record
+	}
+	return records, cm, nil
+}
+
+// Records the webhooks registered with GitHub.
+func saveGithubWebhookRecords(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, cm *corev1.ConfigMap, records map[string]githubWebhookRecord, reqLogger logr.Logger) error {
+	data := make(map[string]string)
+	for key, record := range records {
+		b, err := yaml.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data[key] = string(b)
+	}
+
+	if cm == nil {
+		ownerRef, err := getOwnerReference(k, c, reqLogger)
+		if err != nil {
+			return err
+		}
+
+		cm = &corev1.ConfigMap{}
+		cm.ObjectMeta.Name = githubWebhooksConfigMapName
+		cm.ObjectMeta.Namespace = k.GetNamespace()
+		cm.ObjectMeta.OwnerReferences = append(cm.ObjectMeta.OwnerReferences, ownerRef)
+		cm.Data = data
+		return c.Create(ctx, cm)
+	}
+
+	cm.Data = data
+	return c.Update(ctx, cm)
+}
+
+// A minimal client of the GitHub hooks API
+type githubHooksClient struct {
+	apiUrl     string
+	httpClient *http.Client
+}
+
+// Creates a client of the GitHub API of the organization, authenticated using the
+// token of the webhook.
+func newGithubHooksClient(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (*githubHooksClient, error) {
+	tokenRef := k.Spec.Github.Webhook.TokenSecretRef
+	provider := cutils.SecretRefCredentialProvider{Client: c, Namespace: k.GetNamespace(), Name: tokenRef.Name, Key: tokenRef.Key}
+
+	apiUrl, err := githubApiUrl(k)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := provider.GetToken(ctx, apiUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := cutils.GetHTTPClient(token, nil)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.Timeout = githubRequestTimeout
+	return &githubHooksClient{apiUrl: apiUrl, httpClient: httpClient}, nil
+}
+
+// Returns the base URL of the GitHub API.  GitHub Enterprise serves the API under
+// /api/v3 of the host, which is assumed when the configured URL has no path.
+func githubApiUrl(k *kabanerov1alpha2.Kabanero) (string, error) {
+	apiUrlString := k.Spec.Github.ApiUrl
+	if len(apiUrlString) == 0 {
+		return "https://api.github.com", nil
+	}
+
+	apiUrl, err := cutils.ParseGithubApiUrl(apiUrlString)
+	if err != nil {
+		return "", err
+	}
+	path := strings.TrimSuffix(apiUrl.Path, "/")
+	if len(path) == 0 && apiUrl.Hostname() != "api.github.com" {
+		path = "/api/v3"
+	}
+	return apiUrl.Scheme + "://" + apiUrl.Host + path, nil
+}
+
+// Registers a hook, returning its id.
+func (gh *githubHooksClient) create(ctx context.Context, hooksPath string, hook githubHook) (int64, error) {
+	created := struct {
+		Id int64 `json:"id"`
+	}{}
+	_, err := gh.do(ctx, http.MethodPost, hooksPath, hook, &created)
+	if err != nil {
+		return 0, err
+	}
+	return created.Id, nil
+}
+
+// Updates a hook, returning false if it no longer exists.
+func (gh *githubHooksClient) update(ctx context.Context, hooksPath string, id int64, hook githubHook) (bool, error) {
+	// The name of a hook cannot be changed.
+	hook.Name = ""
+	status, err := gh.do(ctx, http.MethodPatch, fmt.Sprintf("%v/%v", hooksPath, id), hook, nil)
+	if status == http.StatusNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Deletes a hook.  A hook which no longer exists is ignored.
+func (gh *githubHooksClient) delete(ctx context.Context, hooksPath string, id int64) error {
+	status, err := gh.do(ctx, http.MethodDelete, fmt.Sprintf("%v/%v", hooksPath, id), nil, nil)
+	if status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Sends a request to the GitHub API, returning the status code.
+func (gh *githubHooksClient) do(ctx context.Context, method string, path string, body interface{}, result interface{}) (int, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, gh.apiUrl+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := gh.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to %v GitHub webhook %v: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("Unable to %v GitHub webhook %v: %v %v", method, path, resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	if result != nil {
+		err = json.Unmarshal(b, result)
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("Unable to read the GitHub webhook %v: %v", path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
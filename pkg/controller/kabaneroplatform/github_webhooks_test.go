@@ -0,0 +1,281 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// A fake GitHub API serving the hooks of organizations and repositories
+type fakeGithubHooks struct {
+	mutex    sync.Mutex
+	nextId   int64
+	hooks    map[string]githubHook
+	requests []string
+}
+
+func (f *fakeGithubHooks) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer github-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+	f.requests = append(f.requests, r.Method+" "+path)
+	switch r.Method {
+	case http.MethodPost:
+		hook := githubHook{}
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.nextId++
+		f.hooks[fmt.Sprintf("%v/%v", path, f.nextId)] = hook
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id": %v}`, f.nextId)
+	case http.MethodPatch:
+		if _, ok := f.hooks[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		hook := githubHook{}
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.hooks[path] = hook
+		fmt.Fprint(w, `{}`)
+	case http.MethodDelete:
+		if _, ok := f.hooks[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.hooks, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// Returns the requests made since the last call.
+func (f *fakeGithubHooks) takeRequests() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+// Verifies webhooks are registered with GitHub, kept up to date and removed
+func TestReconcileGithubWebhooks(t *testing.T) {
+	github := &fakeGithubHooks{hooks: make(map[string]githubHook)}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Github: kabanerov1alpha2.GithubConfig{
+				Organization: "myorg",
+				ApiUrl:       server.URL,
+				Webhook: kabanerov1alpha2.GithubWebhookSpec{
+					Enable:         true,
+					TokenSecretRef: kabanerov1alpha2.SecretKeyRef{Name: "github-token", Key: "token"},
+				},
+			},
+			Events: kabanerov1alpha2.EventsCustomizationSpec{Enable: true},
+		},
+	}
+	eventsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: eventsSecretName, Namespace: "kabanero"},
+		Data:       map[string][]byte{"secret": []byte("first")},
+	}
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-token", Namespace: "kabanero"},
+		Data:       map[string][]byte{"token": []byte("github-token")},
+	}
+
+	cl := newTestClient(t, k, eventsSecret, tokenSecret)
+	ctx := context.Background()
+
+	reconcile := func(expected ...string) {
+		t.Helper()
+		err := reconcileGithubWebhooks(ctx, k, cl, logf.NullLogger{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests := github.takeRequests()
+		if strings.Join(requests, ",") != strings.Join(expected, ",") {
+			t.Fatalf("Expected requests %v, but got %v", expected, requests)
+		}
+	}
+
+	// The events route has not been created yet.
+	reconcile()
+
+	// An organization webhook is registered once the route is admitted.
+	route := admittedRoute("kabanero-events", "events.example.com")
+	if err := cl.Create(ctx, route); err != nil {
+		t.Fatal(err)
+	}
+	reconcile("POST /orgs/myorg/hooks")
+	hook := github.hooks["/orgs/myorg/hooks/1"]
+	if hook.Config.Url != "https://events.example.com/" || hook.Config.Secret != "first" || hook.Config.ContentType != "json" {
+		t.Fatalf("Unexpected hook configuration: %v", hook.Config)
+	}
+	if strings.Join(hook.Events, ",") != "push,pull_request" {
+		t.Fatalf("Expected the default events, but got %v", hook.Events)
+	}
+
+	// Nothing changed.
+	reconcile()
+
+	// The secret is rotated.
+	eventsSecret.Data["secret"] = []byte("second")
+	if err := cl.Update(ctx, eventsSecret); err != nil {
+		t.Fatal(err)
+	}
+	reconcile("PATCH /orgs/myorg/hooks/1")
+	if secret := github.hooks["/orgs/myorg/hooks/1"].Config.Secret; secret != "second" {
+		t.Fatalf("Expected the hook secret to be updated, but got %v", secret)
+	}
+
+	// The hostname changes, and a webhook source of the organization is configured.
+	route.Status.Ingress[0].Host = "events.apps.example.com"
+	if err := cl.Update(ctx, route); err != nil {
+		t.Fatal(err)
+	}
+	k.Spec.Events.WebhookSources = []kabanerov1alpha2.EventsWebhookSourceSpec{{Name: "github", Organization: "myorg"}}
+	reconcile("PATCH /orgs/myorg/hooks/1")
+	if url := github.hooks["/orgs/myorg/hooks/1"].Config.Url; url != "https://events.apps.example.com/webhook/github" {
+		t.Fatalf("Expected the hook URL to be updated, but got %v", url)
+	}
+
+	// The webhook was deleted in GitHub.
+	delete(github.hooks, "/orgs/myorg/hooks/1")
+	eventsSecret.Data["secret"] = []byte("third")
+	if err := cl.Update(ctx, eventsSecret); err != nil {
+		t.Fatal(err)
+	}
+	reconcile("PATCH /orgs/myorg/hooks/1", "POST /orgs/myorg/hooks")
+
+	// Repository webhooks replace the organization webhook.
+	k.Spec.Github.Webhook.Repositories = []string{"app"}
+	reconcile("POST /repos/myorg/app/hooks", "DELETE /orgs/myorg/hooks/2")
+
+	// The webhook is disabled.
+	k.Spec.Github.Webhook.Enable = false
+	reconcile("DELETE /repos/myorg/app/hooks/3")
+	if len(github.hooks) != 0 {
+		t.Fatalf("Expected all hooks to be removed, but found %v", github.hooks)
+	}
+	cm := &corev1.ConfigMap{}
+	err := cl.Get(ctx, types.NamespacedName{Name: githubWebhooksConfigMapName, Namespace: "kabanero"}, cm)
+	if err == nil {
+		t.Fatal("Expected the record of the webhooks to be deleted")
+	}
+
+	// There is nothing left to remove.
+	reconcile()
+}
+
+// Verifies the webhooks are forgotten when they cannot be removed from GitHub
+// while the instance is deleted
+func TestRemoveGithubWebhooksBestEffort(t *testing.T) {
+	github := &fakeGithubHooks{hooks: make(map[string]githubHook)}
+	server := httptest.NewServer(github)
+	defer server.Close()
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Github: kabanerov1alpha2.GithubConfig{
+				Organization: "myorg",
+				ApiUrl:       server.URL,
+				Webhook: kabanerov1alpha2.GithubWebhookSpec{
+					Enable:         true,
+					TokenSecretRef: kabanerov1alpha2.SecretKeyRef{Name: "github-token", Key: "token"},
+				},
+			},
+		},
+	}
+	records := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: githubWebhooksConfigMapName, Namespace: "kabanero"},
+		Data:       map[string]string{"org": "path: /orgs/myorg/hooks\nid: 1\ndigest: abc\n"},
+	}
+
+	ctx := context.Background()
+	recordsName := types.NamespacedName{Name: githubWebhooksConfigMapName, Namespace: "kabanero"}
+
+	// The token Secret has been deleted.
+	cl := newTestClient(t, k, records.DeepCopy())
+	if err := removeGithubWebhooks(ctx, k, cl, false, logf.NullLogger{}); err == nil {
+		t.Fatal("Expected an error removing the webhooks without a token")
+	}
+	if err := cl.Get(ctx, recordsName, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("Expected the webhooks to still be recorded: %v", err)
+	}
+	if err := removeGithubWebhooks(ctx, k, cl, true, logf.NullLogger{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Get(ctx, recordsName, &corev1.ConfigMap{}); err == nil {
+		t.Fatal("Expected the record of the webhooks to be deleted")
+	}
+
+	// GitHub cannot be reached.
+	tokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "github-token", Namespace: "kabanero"},
+		Data:       map[string][]byte{"token": []byte("github-token")},
+	}
+	cl = newTestClient(t, k, tokenSecret, records.DeepCopy())
+	server.Close()
+	if err := removeGithubWebhooks(ctx, k, cl, true, logf.NullLogger{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cl.Get(ctx, recordsName, &corev1.ConfigMap{}); err == nil {
+		t.Fatal("Expected the record of the webhooks to be deleted")
+	}
+}
+
+// Verifies the GitHub API URL is derived from the configured value
+func TestGithubApiUrl(t *testing.T) {
+	tests := map[string]string{
+		"":                                   "https://api.github.com",
+		"api.github.com":                     "https://api.github.com",
+		"https://api.github.com/":            "https://api.github.com",
+		"github.example.com":                 "https://github.example.com/api/v3",
+		"http://github.example.com":          "http://github.example.com/api/v3",
+		"https://github.example.com/api/v3":  "https://github.example.com/api/v3",
+		"https://github.example.com/api/v3/": "https://github.example.com/api/v3",
+		"https://example.com/github/api/v3":  "https://example.com/github/api/v3",
+	}
+	for apiUrl, expected := range tests {
+		k := &kabanerov1alpha2.Kabanero{Spec: kabanerov1alpha2.KabaneroSpec{Github: kabanerov1alpha2.GithubConfig{ApiUrl: apiUrl}}}
+		actual, err := githubApiUrl(k)
+		if err != nil {
+			t.Fatalf("Unexpected error for %v: %v", apiUrl, err)
+		}
+		if actual != expected {
+			t.Errorf("Expected %v for %v, but was %v", expected, apiUrl, actual)
+		}
+	}
+
+	k := &kabanerov1alpha2.Kabanero{Spec: kabanerov1alpha2.KabaneroSpec{Github: kabanerov1alpha2.GithubConfig{ApiUrl: "ftp://github.example.com"}}}
+	if _, err := githubApiUrl(k); err == nil {
+		t.Fatal("Expected an error for an ftp URL")
+	}
+}
@@ -0,0 +1,88 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The amount of time a resolved component image digest is remembered before
+// the registry is asked again.  This bounds how long a moved tag goes unnoticed.
+const componentDigestCacheTTL = 10 * time.Minute
+
+// Resolved component image digests
+var componentDigestCache = cutils.NewDigestCache(componentDigestCacheTTL)
+
+// Resolves the image of a Kabanero component to a digest when digest pinning
+// is enabled, and records the result in the instance status.  The image to
+// deploy is returned: the digest pinned reference when deployment of pinned
+// images is requested and a digest is known, otherwise the input image.
+func resolveComponentImage(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, component string, image string) string {
+	pinning := k.Spec.DigestPinning
+	if !pinning.Enable {
+		setComponentImageStatus(k, nil, component)
+		return image
+	}
+
+	prev := kabanerov1alpha2.ComponentImageStatus{}
+	for _, p := range k.Status.Images {
+		if p.Component == component && p.Image == image {
+			prev = p
+			break
+		}
+	}
+
+	status := kabanerov1alpha2.ComponentImageStatus{Component: component, Image: image}
+	creds, err := cutils.GetRegistryCredentials(c, k.GetNamespace(), pinning.PullSecrets)
+	digest := ""
+	if err == nil {
+		digest, err = componentDigestCache.Resolve(ctx, image, creds, cutils.RegistryTransport)
+	}
+
+	if err != nil {
+		// Keep using the last known digest.
+		status.Digest = prev.Digest
+		status.PreviousDigest = prev.PreviousDigest
+		status.MovedTime = prev.MovedTime
+		status.Message = fmt.Sprintf("Unable to resolve the digest of image %v: %v", image, err)
+	} else {
+		status.Digest = digest
+		status.PreviousDigest, status.MovedTime = cutils.TrackTagMove(prev.Digest, prev.PreviousDigest, prev.MovedTime, digest, time.Now())
+		if status.PreviousDigest != "" {
+			status.Message = cutils.TagMovedMessage(image, status.PreviousDigest, status.Digest)
+		}
+	}
+
+	setComponentImageStatus(k, &status, component)
+
+	if pinning.Deploy && status.Digest != "" {
+		ref, err := cutils.ParseImageReference(image)
+		if err == nil {
+			return ref.DigestReference(status.Digest)
+		}
+	}
+
+	return image
+}
+
+// Replaces the image status of a component.  A nil status removes it.
+func setComponentImageStatus(k *kabanerov1alpha2.Kabanero, status *kabanerov1alpha2.ComponentImageStatus, component string) {
+	var images []kabanerov1alpha2.ComponentImageStatus
+	found := false
+	for _, image := range k.Status.Images {
+		if image.Component != component {
+			images = append(images, image)
+		} else if status != nil && !found {
+			images = append(images, *status)
+			found = true
+		}
+	}
+	if status != nil && !found {
+		images = append(images, *status)
+	}
+	k.Status.Images = images
+}
@@ -0,0 +1,87 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Verifies component images are resolved, pinned when requested, and moved tags are reported
+func TestResolveComponentImage(t *testing.T) {
+	digests := map[string]string{"/v2/kabanero/kabanero-landing/manifests/0.7.0": "sha256:1111"}
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		digest, ok := digests[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer registry.Close()
+
+	cutils.RegistryTransport = registry.Client().Transport
+	defer func() { cutils.RegistryTransport = nil }()
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			DigestPinning: kabanerov1alpha2.DigestPinningSpec{Enable: true},
+		},
+	}
+	cl := newTestClient(t)
+	image := strings.TrimPrefix(registry.URL, "https://") + "/kabanero/kabanero-landing:0.7.0"
+
+	// Resolved and reported, but the tagged image is deployed
+	deployed := resolveComponentImage(context.Background(), k, cl, "landing", image)
+	if deployed != image {
+		t.Fatalf("Expected the tagged image to be deployed but was %v", deployed)
+	}
+	if len(k.Status.Images) != 1 || k.Status.Images[0].Digest != "sha256:1111" {
+		t.Fatalf("Unexpected image status: %v", k.Status.Images)
+	}
+
+	// The tag moves, and the pinned image is deployed
+	k.Spec.DigestPinning.Deploy = true
+	digests["/v2/kabanero/kabanero-landing/manifests/0.7.0"] = "sha256:2222"
+	componentDigestCache = cutils.NewDigestCache(componentDigestCacheTTL)
+	deployed = resolveComponentImage(context.Background(), k, cl, "landing", image)
+	if !strings.HasSuffix(deployed, "/kabanero/kabanero-landing@sha256:2222") {
+		t.Fatalf("Expected the digest pinned image to be deployed but was %v", deployed)
+	}
+	status := k.Status.Images[0]
+	if status.PreviousDigest != "sha256:1111" || !strings.Contains(status.Message, "moved") {
+		t.Fatalf("Expected the moved tag to be reported: %v", status)
+	}
+
+	// Disabling digest pinning removes the status
+	k.Spec.DigestPinning.Enable = false
+	deployed = resolveComponentImage(context.Background(), k, cl, "landing", image)
+	if deployed != image || len(k.Status.Images) != 0 {
+		t.Fatalf("Expected digest pinning to be disabled: %v %v", deployed, k.Status.Images)
+	}
+}
+
+// Verifies a named pull secret which does not exist is reported
+func TestResolveComponentImageMissingPullSecret(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			DigestPinning: kabanerov1alpha2.DigestPinningSpec{Enable: true, Deploy: true, PullSecrets: []string{"missing"}},
+		},
+	}
+
+	image := "kabanero/kabanero-landing:0.7.0"
+	deployed := resolveComponentImage(context.Background(), k, newTestClient(t), "landing", image)
+	if deployed != image {
+		t.Fatalf("Expected the tagged image to be deployed without a digest but was %v", deployed)
+	}
+	if len(k.Status.Images) != 1 || !strings.Contains(k.Status.Images[0].Message, "missing") {
+		t.Fatalf("Expected the missing pull secret to be reported: %v", k.Status.Images)
+	}
+}
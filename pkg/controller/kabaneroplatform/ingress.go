@@ -0,0 +1,199 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	mf "github.com/manifestival/manifestival"
+	consolev1 "github.com/openshift/api/console/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Whether the cluster serves the OpenShift Route and ConsoleLink APIs.  These are
+// detected when the controller is added.  On other Kubernetes clusters, components
+// are exposed using Ingresses and the web console is not customized.
+var routesServed = true
+var consoleLinksServed = true
+
+// The annotations set on the Ingresses created in place of Routes
+const ingressClassAnnotation = "kubernetes.io/ingress.class"
+const ingressBackendProtocolAnnotation = "nginx.ingress.kubernetes.io/backend-protocol"
+
+// Determines which of the OpenShift APIs used by Kabanero the cluster serves.
+func detectClusterApis(mapper meta.RESTMapper) {
+	routesServed = apiServed(mapper, schema.GroupKind{Group: routev1.GroupName, Kind: "Route"}, routev1.GroupVersion.Version)
+	consoleLinksServed = apiServed(mapper, schema.GroupKind{Group: consolev1.GroupName, Kind: "ConsoleLink"}, consolev1.GroupVersion.Version)
+
+	if !routesServed {
+		log.Info("The cluster does not serve OpenShift Routes. Components are exposed using Ingresses.")
+	}
+	if !consoleLinksServed {
+		log.Info("The cluster does not serve OpenShift ConsoleLinks. The web console is not customized.")
+	}
+}
+
+func apiServed(mapper meta.RESTMapper, gk schema.GroupKind, version string) bool {
+	_, err := mapper.RESTMapping(gk, version)
+	return err == nil
+}
+
+// Returns the transforms which replace the Routes in an orchestration with Ingresses,
+// when the cluster does not serve Routes.
+func ingressTransforms(k *kabanerov1alpha2.Kabanero) []mf.Transformer {
+	if routesServed {
+		return []mf.Transformer{}
+	}
+	return []mf.Transformer{routeToIngress(k)}
+}
+
+// Replaces a Route with an Ingress routing to the same Service.  The Services behind
+// Routes with TLS serve TLS themselves, so the ingress controller is asked to
+// connect to them using HTTPS.
+func routeToIngress(k *kabanerov1alpha2.Kabanero) mf.Transformer {
+	return func(u *unstructured.Unstructured) error {
+		if u.GroupVersionKind().GroupKind() != (schema.GroupKind{Group: routev1.GroupName, Kind: "Route"}) {
+			return nil
+		}
+
+		route := &routev1.Route{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, route)
+		if err != nil {
+			return err
+		}
+
+		component := getComponentIngressSpec(k, route.Name)
+		host := component.Host
+		if len(host) == 0 && len(k.Spec.Ingress.Domain) != 0 {
+			host = fmt.Sprintf("%v-%v.%v", route.Name, k.GetNamespace(), k.Spec.Ingress.Domain)
+		}
+		tlsSecretName := component.TlsSecretName
+		if len(tlsSecretName) == 0 {
+			tlsSecretName = k.Spec.Ingress.TlsSecretName
+		}
+
+		ingress := &networkingv1beta1.Ingress{}
+		ingress.SetGroupVersionKind(networkingv1beta1.SchemeGroupVersion.WithKind("Ingress"))
+		ingress.ObjectMeta = route.ObjectMeta
+		ingress.Annotations = map[string]string{}
+		for key, value := range route.Annotations {
+			ingress.Annotations[key] = value
+		}
+		if route.Spec.TLS != nil {
+			ingress.Annotations[ingressBackendProtocolAnnotation] = "HTTPS"
+		}
+		if len(k.Spec.Ingress.Class) != 0 {
+			ingress.Annotations[ingressClassAnnotation] = k.Spec.Ingress.Class
+		}
+
+		// The services exposed by Kabanero listen on port 443.
+		backend := networkingv1beta1.IngressBackend{ServiceName: route.Spec.To.Name, ServicePort: intstr.FromInt(443)}
+		ingress.Spec.Rules = []networkingv1beta1.IngressRule{{
+			Host: host,
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+				Paths: []networkingv1beta1.HTTPIngressPath{{Backend: backend}},
+			}},
+		}}
+		if len(tlsSecretName) != 0 {
+			tls := networkingv1beta1.IngressTLS{SecretName: tlsSecretName}
+			if len(host) != 0 {
+				tls.Hosts = []string{host}
+			}
+			ingress.Spec.TLS = []networkingv1beta1.IngressTLS{tls}
+		}
+
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ingress)
+		if err != nil {
+			return err
+		}
+		// Transformers are given a copy of the resource sharing its map, so the
+		// contents of the map are replaced rather than the map itself.
+		for key := range u.Object {
+			delete(u.Object, key)
+		}
+		for key, value := range obj {
+			// The status is set by the ingress controller.
+			if key != "status" {
+				u.Object[key] = value
+			}
+		}
+		return nil
+	}
+}
+
+// Returns the Ingress customization of the component exposed by a Route.
+func getComponentIngressSpec(k *kabanerov1alpha2.Kabanero, routeName string) kabanerov1alpha2.ComponentIngressSpec {
+	switch routeName {
+	case "kabanero-landing":
+		return k.Spec.Landing.Ingress
+	case "kabanero-cli":
+		return k.Spec.CliServices.Ingress
+	case "kabanero-events":
+		return k.Spec.Events.Ingress
+	}
+	return kabanerov1alpha2.ComponentIngressSpec{}
+}
+
+// Retrieves the hostnames of an Ingress which the ingress controller has assigned an
+// address.  When the Ingress does not specify hosts, the assigned address is returned.
+// Also returns a message describing why there are no hostnames, and whether the
+// hostnames are served over TLS.
+func getIngressHostnames(ctx context.Context, c client.Client, namespace string, name string, component string) ([]string, string, bool, error) {
+	ingress := &networkingv1beta1.Ingress{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, ingress)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Sprintf("The Ingress object for the %v was not found: %v", component, err), false, err
+		}
+		return nil, fmt.Sprintf("An error occurred retrieving the Ingress object for the %v: %v", component, err), false, err
+	}
+
+	if len(ingress.Status.LoadBalancer.Ingress) == 0 {
+		return nil, fmt.Sprintf("The Ingress object for the %v has not been assigned an address", component), false, nil
+	}
+
+	var hostnames []string
+	for _, rule := range ingress.Spec.Rules {
+		if len(rule.Host) != 0 {
+			hostnames = append(hostnames, rule.Host)
+		}
+	}
+	if len(hostnames) == 0 {
+		for _, address := range ingress.Status.LoadBalancer.Ingress {
+			if len(address.Hostname) != 0 {
+				hostnames = append(hostnames, address.Hostname)
+			} else if len(address.IP) != 0 {
+				hostnames = append(hostnames, address.IP)
+			}
+		}
+	}
+
+	tls := len(ingress.Spec.TLS) != 0
+	return hostnames, "", tls, nil
+}
+
+// Returns the URL at which a component exposed by an Ingress is served.
+func getIngressURL(ctx context.Context, c client.Client, namespace string, name string, component string) (string, error) {
+	hostnames, message, tls, err := getIngressHostnames(ctx, c, namespace, name, component)
+	if err != nil {
+		return "", err
+	}
+	if len(hostnames) == 0 {
+		return "", fmt.Errorf("%v", message)
+	}
+
+	scheme := "http://"
+	if tls {
+		scheme = "https://"
+	}
+	return scheme + hostnames[0], nil
+}
@@ -0,0 +1,194 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	mf "github.com/manifestival/manifestival"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const routeOrchestration = `apiVersion: v1
+kind: Service
+metadata:
+  name: kabanero-landing
+spec:
+  ports:
+  - port: 443
+    targetPort: 9443
+---
+apiVersion: route.openshift.io/v1
+kind: Route
+metadata:
+  name: kabanero-landing
+spec:
+  to:
+    kind: Service
+    name: kabanero-landing
+  tls:
+    termination: passthrough
+`
+
+// Verifies the OpenShift APIs are detected using the REST mapper
+func TestDetectClusterApis(t *testing.T) {
+	defer func() { routesServed, consoleLinksServed = true, true }()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"}, meta.RESTScopeNamespace)
+	detectClusterApis(mapper)
+	if !routesServed || consoleLinksServed {
+		t.Fatalf("Expected Routes but not ConsoleLinks to be served, but got %v and %v", routesServed, consoleLinksServed)
+	}
+
+	detectClusterApis(meta.NewDefaultRESTMapper(nil))
+	if routesServed || consoleLinksServed {
+		t.Fatalf("Expected neither Routes nor ConsoleLinks to be served, but got %v and %v", routesServed, consoleLinksServed)
+	}
+}
+
+// Verifies Routes in an orchestration are replaced with Ingresses when Routes are not served
+func TestRouteToIngress(t *testing.T) {
+	defer func() { routesServed = true }()
+
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Ingress: kabanerov1alpha2.IngressSpec{Domain: "apps.example.com", Class: "nginx", TlsSecretName: "kabanero-tls"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		landing kabanerov1alpha2.ComponentIngressSpec
+		host    string
+		secret  string
+	}{
+		{"generated host", kabanerov1alpha2.ComponentIngressSpec{}, "kabanero-landing-kabanero.apps.example.com", "kabanero-tls"},
+		{"component host", kabanerov1alpha2.ComponentIngressSpec{Host: "kabanero.example.com", TlsSecretName: "landing-tls"}, "kabanero.example.com", "landing-tls"},
+	}
+
+	for _, test := range tests {
+		k.Spec.Landing.Ingress = test.landing
+
+		routesServed = true
+		m, err := mf.ManifestFrom(mf.Reader(strings.NewReader(routeOrchestration)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		transformed, err := m.Transform(ingressTransforms(k)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind := transformed.Resources()[1].GetKind(); kind != "Route" {
+			t.Fatalf("%v: expected the Route to be kept when Routes are served, but got a %v", test.name, kind)
+		}
+
+		routesServed = false
+		m, err = mf.ManifestFrom(mf.Reader(strings.NewReader(routeOrchestration)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		transformed, err = m.Transform(ingressTransforms(k)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind := transformed.Resources()[0].GetKind(); kind != "Service" {
+			t.Fatalf("%v: expected the Service to be kept, but got a %v", test.name, kind)
+		}
+
+		ingress := &networkingv1beta1.Ingress{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(transformed.Resources()[1].Object, ingress)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ingress.Kind != "Ingress" || ingress.APIVersion != "networking.k8s.io/v1beta1" || ingress.Name != "kabanero-landing" {
+			t.Fatalf("%v: expected the Route to be replaced with Ingress kabanero-landing, but got %v %v %v", test.name, ingress.APIVersion, ingress.Kind, ingress.Name)
+		}
+		if ingress.Annotations[ingressClassAnnotation] != "nginx" || ingress.Annotations[ingressBackendProtocolAnnotation] != "HTTPS" {
+			t.Fatalf("%v: unexpected annotations %v", test.name, ingress.Annotations)
+		}
+		if len(ingress.Spec.Rules) != 1 || ingress.Spec.Rules[0].Host != test.host {
+			t.Fatalf("%v: expected a rule for host %v, but got %v", test.name, test.host, ingress.Spec.Rules)
+		}
+		backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend
+		if backend.ServiceName != "kabanero-landing" || backend.ServicePort.IntValue() != 443 {
+			t.Fatalf("%v: unexpected backend %v", test.name, backend)
+		}
+		if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].SecretName != test.secret || ingress.Spec.TLS[0].Hosts[0] != test.host {
+			t.Fatalf("%v: expected TLS using Secret %v for host %v, but got %v", test.name, test.secret, test.host, ingress.Spec.TLS)
+		}
+	}
+}
+
+// Verifies hostnames are read from the Ingress status when Routes are not served
+func TestIngressStatus(t *testing.T) {
+	defer func() { routesServed = true }()
+	routesServed = false
+
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"}}
+	cliIngress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero-cli", Namespace: "kabanero"},
+		Spec:       networkingv1beta1.IngressSpec{Rules: []networkingv1beta1.IngressRule{{Host: "kabanero-cli.example.com"}}},
+	}
+	landingIngress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero-landing", Namespace: "kabanero"},
+		Spec: networkingv1beta1.IngressSpec{
+			Rules: []networkingv1beta1.IngressRule{{}},
+			TLS:   []networkingv1beta1.IngressTLS{{SecretName: "kabanero-tls"}},
+		},
+		Status: networkingv1beta1.IngressStatus{LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}}}},
+	}
+	cl := newTestClient(t, cliIngress, landingIngress)
+
+	// The CLI Ingress has not been assigned an address.
+	ready, _ := getCliRouteStatus(k, logf.NullLogger{}, cl)
+	if ready || k.Status.Cli.Ready != "False" || len(k.Status.Cli.Hostnames) != 0 {
+		t.Fatalf("Expected the CLI not to be ready: %v", k.Status.Cli)
+	}
+
+	cliIngress.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+	err := cl.Update(context.Background(), cliIngress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ready, _ = getCliRouteStatus(k, logf.NullLogger{}, cl)
+	if !ready || k.Status.Cli.Ready != "True" || len(k.Status.Cli.Hostnames) != 1 || k.Status.Cli.Hostnames[0] != "kabanero-cli.example.com" {
+		t.Fatalf("Expected the CLI to be ready at its host: %v", k.Status.Cli)
+	}
+
+	// Without a host, the address assigned by the ingress controller is used.
+	landingURL, err := getLandingURL(k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if landingURL != "https://192.0.2.10" {
+		t.Fatalf("Expected landing URL https://192.0.2.10, but got %v", landingURL)
+	}
+
+	// The events Ingress does not exist.
+	k.Spec.Events.Enable = true
+	ready, _ = getEventsRouteStatus(k, cl, logf.NullLogger{})
+	if ready || k.Status.Events.Ready != "False" || !strings.Contains(k.Status.Events.Message, "was not found") {
+		t.Fatalf("Expected the events not to be ready: %v", k.Status.Events)
+	}
+
+	// There are no ConsoleLinks to create.
+	consoleLinksServed = false
+	defer func() { consoleLinksServed = true }()
+	err = customizeWebConsole(k, cl, landingURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = removeWebConsoleCustomization(k, cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
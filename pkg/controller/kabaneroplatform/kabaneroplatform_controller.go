@@ -2,20 +2,17 @@ package kabaneroplatform
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
-	kabanerov1alpha1 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha1"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	kutils "github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 
 	"k8s.io/apimachinery/pkg/api/errors"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -39,6 +36,7 @@ type reconcileFuncType struct {
 }
 
 var reconcileFuncs = []reconcileFuncType{
+	{name: "prerequisite subscriptions", function: reconcileSubscriptions},
 	{name: "collection controller", function: reconcileCollectionController},
 	{name: "stack controller", function: reconcileStackController},
 	{name: "landing page", function: deployLandingPage},
@@ -57,9 +55,9 @@ func Add(mgr manager.Manager) error {
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
 	return &ReconcileKabanero{
-		client:          mgr.GetClient(),
-		scheme:          mgr.GetScheme(),
-		requeueDelayMap: make(map[string]RequeueData)}
+		client:  mgr.GetClient(),
+		scheme:  mgr.GetScheme(),
+		retries: cutils.NewRetryTracker(cutils.DefaultBackoff)}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -71,6 +69,9 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 	ctrlr = c
 
+	// Expose components using Ingresses on clusters without OpenShift Routes.
+	detectClusterApis(mgr.GetRESTMapper())
+
 	// Watch for changes to primary resource Kabanero
 	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Kabanero{}}, &handler.EnqueueRequestForObject{})
 	if err != nil {
@@ -105,6 +106,24 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	if err != nil {
 		return err
 	}
+
+	// Convert any collection repositories left over from v1alpha1 when the operator starts.
+	err = mgr.Add(&collectionRepositoryMigrator{client: mgr.GetClient(), reader: mgr.GetAPIReader()})
+	if err != nil {
+		return err
+	}
+
+	// Watch ConfigMaps which may contain a version catalogue overlay.
+	err = watchVersionCatalog(c, mgr.GetClient())
+	if err != nil {
+		return err
+	}
+
+	// Watch the generated secrets for requests to rotate them.
+	err = watchSecretRotation(c)
+	if err != nil {
+		return err
+	}
 	
 	return nil
 }
@@ -115,103 +134,48 @@ var _ reconcile.Reconciler = &ReconcileKabanero{}
 type ReconcileKabanero struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
-	client          client.Client
-	scheme          *runtime.Scheme
-	requeueDelayMap map[string]RequeueData
-}
-
-// RequeueData stores information that enables reconcile operations to be retried.
-type RequeueData struct {
-	delay      int
-	futureTime time.Time
+	client  client.Client
+	scheme  *runtime.Scheme
+	retries *cutils.RetryTracker
 }
 
-// Determine if requeue is needed or not.
-// If requeue is required set RequeueAfter to 60 seconds the first time.
-// After the first time increase RequeueAfter by 60 seconds up to a max of 15 minutes.
-func (r *ReconcileKabanero) determineHowToRequeue(ctx context.Context, request reconcile.Request, instance *kabanerov1alpha2.Kabanero, errorMessage string, requeueDelayMap map[string]RequeueData, reqLogger logr.Logger) (reconcile.Result, error) {
-	var requeueDelay int
-	var localFutureTime time.Time
-	requeueData, ok := requeueDelayMap[request.Namespace]
-	if !ok {
-		requeueDelay = 0
-		localFutureTime = time.Now()
-		requeueData := RequeueData{requeueDelay, localFutureTime}
-		requeueDelayMap[request.Namespace] = requeueData
-	} else {
-		requeueDelay = requeueData.delay
-		localFutureTime = requeueData.futureTime
-	}
-	currentTime := time.Now()
-	// if first time or current time is after the requeue time we requested previously, request a requeue
-	if requeueDelay == 0 || currentTime.After(localFutureTime) {
-		// only update status if error message changed
-		if strings.Compare(errorMessage, instance.Status.KabaneroInstance.Message) != 0 {
-			instance.Status.KabaneroInstance.Message = errorMessage
-			instance.Status.KabaneroInstance.Ready = "False"
-			// Update the kabanero instance status.
-			err := r.client.Status().Update(ctx, instance)
-			if err != nil {
-				reqLogger.Error(err, "Error updating Kabanero status.")
-			}
-		}
-		// increase delay by 60 seconds
-		requeueDelay = requeueDelay + 60
-		// do not go over 15 minutes
-		if requeueDelay >= 900 {
-			requeueDelay = 900
+// Determine how to requeue after the featured stacks could not be reconciled.
+// The retries of each instance back off exponentially, up to a maximum, and
+// are shared with reconciles triggered by watches in the meantime.
+func (r *ReconcileKabanero) determineHowToRequeue(ctx context.Context, request reconcile.Request, instance *kabanerov1alpha2.Kabanero, errorMessage string, reqLogger logr.Logger) (reconcile.Result, error) {
+	// only update status if error message changed
+	if strings.Compare(errorMessage, instance.Status.KabaneroInstance.Message) != 0 {
+		instance.Status.KabaneroInstance.Message = errorMessage
+		instance.Status.KabaneroInstance.Ready = "False"
+		// Update the kabanero instance status.
+		err := r.client.Status().Update(ctx, instance)
+		if err != nil {
+			reqLogger.Error(err, "Error updating Kabanero status.")
 		}
-		localFutureTime = currentTime.Add(time.Duration(requeueDelay) * time.Second)
-		requeueDelayMap[request.Namespace] = RequeueData{requeueDelay, localFutureTime}
-		reqLogger.Info(fmt.Sprintf("Reconciling Kabanero requesting requeue in %d seconds", requeueDelay))
-
-		return reconcile.Result{Requeue: true, RequeueAfter: time.Duration(requeueDelay) * time.Second}, nil
 	}
 
-	// No requeue
-	return reconcile.Result{}, nil
+	retryKey := request.NamespacedName.String()
+	requeueDelay := r.retries.Failed(retryKey)
+	reqLogger.Info(fmt.Sprintf("Reconciling Kabanero requesting requeue in %v after %d failed attempts", requeueDelay.Round(time.Second), r.retries.Attempts(retryKey)))
 
+	return reconcile.Result{Requeue: true, RequeueAfter: requeueDelay}, nil
 }
 
-// Convert a v1alpha1 Kabanero CR instance, with collection repositories, to v1alpha2
-func (r *ReconcileKabanero) convertTo_v1alpha2(kabInstanceUnstructured *unstructured.Unstructured, reqLogger logr.Logger) {
-	// First populate a v1alpha1 object from the unstructured
-	data, err := kabInstanceUnstructured.MarshalJSON()
-	if err != nil {
-		reqLogger.Error(err, "Error marshalling unstructured data: ")
-		return 
-	}
-
-	kabInstanceV1 := &kabanerov1alpha1.Kabanero{}
-	err = json.Unmarshal(data, kabInstanceV1)
-	if err != nil {
-		reqLogger.Error(err, "Error unmarshalling unstructured data to Kabanero v1alpha1: ")
-		return
+// Handles a request to retry failed work now.  The annotation is removed, so
+// that it can be set again later.
+func (r *ReconcileKabanero) processRetryNow(ctx context.Context, request reconcile.Request, instance *kabanerov1alpha2.Kabanero, reqLogger logr.Logger) error {
+	if !cutils.RetryNowRequested(instance) {
+		return nil
 	}
 
-	// Next populate a v1alpha2 object from the unstructured.  We'll keep the common fields.
-	kabInstanceV2 := &kabanerov1alpha2.Kabanero{}
-	err = json.Unmarshal(data, kabInstanceV2)
-	if err != nil {
-		reqLogger.Error(err, "Error unmarshalling unstructured data to Kabanero v1alpha2: ")
-		return
-	}
+	reqLogger.Info("Retrying now, as requested by the " + cutils.RetryNowAnnotation + " annotation")
+	r.retries.Reset(request.NamespacedName.String())
+	r.retries.Reset(upgradePreflightRetryKey(request))
 
-	// Now convert the collections to stacks
-	for _, collectionRepoConfig := range kabInstanceV1.Spec.Collections.Repositories {
-		httpsConfig := kabanerov1alpha2.HttpsProtocolFile{Url: collectionRepoConfig.Url, SkipCertVerification: collectionRepoConfig.SkipCertVerification}
-		stackRepoConfig := kabanerov1alpha2.RepositoryConfig{Name: collectionRepoConfig.Name, Https: httpsConfig}
-		// TODO: Pipelines?
-		kabInstanceV2.Spec.Stacks.Repositories = append(kabInstanceV2.Spec.Stacks.Repositories, stackRepoConfig)
-	}
-
-	// TODO: Triggers?
-
-	// Write the object back.
-	err = r.client.Update(context.TODO(), kabInstanceV2)
-	if err != nil {
-		reqLogger.Error(err, "Error converting to Kabanero v1alpha2: ")
-	}
+	annotations := instance.GetAnnotations()
+	delete(annotations, cutils.RetryNowAnnotation)
+	instance.SetAnnotations(annotations)
+	return r.client.Update(ctx, instance)
 }
 
 // Reconcile reads that state of the cluster for a Kabanero object and makes changes based on the state read
@@ -225,42 +189,9 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
 	reqLogger.Info("Reconciling Kabanero")
 
-	// TODO: Retrieve kabanero as unstructured and see if there is a collection hub defined.  If so,
-	// convert it, update, and retry.
-	kabInstanceUnstructured := &unstructured.Unstructured{}
-	kabInstanceUnstructured.SetGroupVersionKind(schema.GroupVersionKind{
-		Kind:    "Kabanero",
-		Group:   kabanerov1alpha2.SchemeGroupVersion.Group,
-		Version: kabanerov1alpha2.SchemeGroupVersion.Version,
-	})
-
-	err := r.client.Get(context.TODO(), request.NamespacedName, kabInstanceUnstructured)
-	if err != nil {
-		if errors.IsNotFound(err) {
-			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected.
-			// Return and don't requeue
-			return reconcile.Result{}, nil
-		}
-		// Error reading the object - requeue the request.
-		return reconcile.Result{}, err
-	}
-
-	// See about the collection hub...
-	_, found, err := unstructured.NestedSlice(kabInstanceUnstructured.Object, "spec", "collections", "repositories")
-	if err != nil {
-		reqLogger.Error(err, "Unable to parse Kabanero instance for conversion")
-	} else {
-		if found {
-			// Do the conversion
-			r.convertTo_v1alpha2(kabInstanceUnstructured, reqLogger)
-			return reconcile.Result{}, nil // Will run again since we changed the object
-		}
-	}
-	
 	// Fetch the Kabanero instance
 	instance := &kabanerov1alpha2.Kabanero{}
-	err = r.client.Get(context.TODO(), request.NamespacedName, instance)
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
@@ -285,6 +216,34 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{}, nil
 	}
 
+	err = r.processRetryNow(ctx, request, instance, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Unable to remove the "+cutils.RetryNowAnnotation+" annotation")
+		return reconcile.Result{}, err
+	}
+
+	// Load the proxy and trusted certificate authorities before anything is retrieved.
+	err = reconcileOutboundConfig(ctx, instance, r.client, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Error reconciling the outbound HTTP configuration")
+		return reconcile.Result{}, err
+	}
+
+	// Load the version catalogue before anything resolves component versions.
+	err = reconcileVersionCatalog(ctx, instance, r.client, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Error reconciling the version catalogue")
+		return reconcile.Result{}, err
+	}
+
+	// Advance any Kabanero version upgrade before the components are reconciled.
+	err = reconcileUpgrade(ctx, instance, r.client, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Error reconciling the Kabanero version upgrade")
+		processStatus(ctx, request, instance, r.client, reqLogger)
+		return reconcile.Result{}, err
+	}
+
 	// Reconcile the admission controller webhook
 	err = reconcileAdmissionControllerWebhook(ctx, instance, r.client, reqLogger)
 	if err != nil {
@@ -300,6 +259,11 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{Requeue: true, RequeueAfter: 10 * time.Second}, nil
 	}
 	
+	// Rotate the generated secrets which are due before the components which
+	// use them, such as the events GitHub webhooks, are reconciled.  Failures
+	// are reported in the status.
+	rotationRequeueAfter := reconcileSecretRotation(ctx, instance, r.client, reqLogger)
+
 	// Iterate the components and try to reconcile.  If something goes wrong,
 	// update the status and try again later.
 	for _, component := range reconcileFuncs {
@@ -316,11 +280,11 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 	if err != nil {
 		reqLogger.Error(err, "Error reconciling featured stacks.")
 		processStatus(ctx, request, instance, r.client, reqLogger)
-		return r.determineHowToRequeue(ctx, request, instance, err.Error(), r.requeueDelayMap, reqLogger)
+		return r.determineHowToRequeue(ctx, request, instance, err.Error(), reqLogger)
 	}
 
 	// things worked reset requeue data
-	r.requeueDelayMap[request.Namespace] = RequeueData{0, time.Now()}
+	r.retries.Reset(request.NamespacedName.String())
 	
 	// Determine the status of the kabanero operator instance and set it.
 	isReady, err := processStatus(ctx, request, instance, r.client, reqLogger)
@@ -329,11 +293,29 @@ func (r *ReconcileKabanero) Reconcile(request reconcile.Request) (reconcile.Resu
 		return reconcile.Result{}, err
 	}
 
+	// Check on an upgrade in progress sooner than the usual interval.
+	if upgradeInProgress(instance) {
+		return reconcile.Result{Requeue: true, RequeueAfter: upgradeRequeueInterval}, nil
+	}
+
+	// Check the prerequisites of a pending upgrade again, backing off while they are missing.
+	preflightKey := upgradePreflightRetryKey(request)
+	if upgradePreflightFailed(instance) {
+		return reconcile.Result{Requeue: true, RequeueAfter: r.retries.Failed(preflightKey)}, nil
+	}
+	r.retries.Reset(preflightKey)
+
 	// If all resource dependencies are not in the ready state, reconcile again in 60 seconds.
 	if !isReady {
 		return reconcile.Result{Requeue: true, RequeueAfter: 60 * time.Second}, err
 	}
 
+	// Poll the version catalogue if it is retrieved from a URL, and come back
+	// when the next generated secret is due to be rotated.
+	if requeueAfter := minRequeueAfter(versionCatalogRequeueAfter(instance), rotationRequeueAfter); requeueAfter > 0 {
+		return reconcile.Result{Requeue: true, RequeueAfter: requeueAfter}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
@@ -410,8 +392,22 @@ func cleanup(ctx context.Context, k *kabanerov1alpha2.Kabanero, client client.Cl
 		}
 	}
 
+	// Remove the webhooks registered with GitHub, which are outside the cluster.
+	// This is best effort, so that the instance can be deleted when GitHub cannot
+	// be reached.
+	err := removeGithubWebhooks(ctx, k, client, true, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	// Stop converting Kabanero versions before the webhook server is removed.
+	err = cleanupKabaneroConversionWebhook(ctx, k, client, reqLogger)
+	if err != nil {
+		return err
+	}
+
 	// Remove the webhook configurations and friends.
-	err := cleanupAdmissionControllerWebhook(k, client, reqLogger)
+	err = cleanupAdmissionControllerWebhook(k, client, reqLogger)
 	if err != nil {
 		return err
 	}
@@ -434,6 +430,15 @@ func cleanup(ctx context.Context, k *kabanerov1alpha2.Kabanero, client client.Cl
 		return err
 	}
 
+	// Remove the prerequisite operator subscriptions this instance manages.
+	err = cleanupSubscriptions(ctx, k, client, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	// Forget the version catalogue overlay for this instance.
+	setActiveVersionCatalog(k, nil, "")
+
 	return nil
 }
 
@@ -452,8 +457,6 @@ func isFinalizerInList(k *kabanerov1alpha2.Kabanero, finalizer string) bool {
 // is set to true. Otherwise, it is set to false.
 func processStatus(ctx context.Context, request reconcile.Request, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
 	errorMessage := "One or more resource dependencies are not ready."
-	_, instanceVersion := resolveKabaneroVersion(k)
-	k.Status.KabaneroInstance.Version = instanceVersion
 
 	k.Status.KabaneroInstance.Ready = "False"
 
@@ -470,6 +473,9 @@ func processStatus(ctx context.Context, request reconcile.Request, k *kabanerov1
 	isEventsRouteReady, _ := getEventsRouteStatus(k, c, reqLogger)
 	isAdmissionControllerWebhookReady, _ := getAdmissionControllerWebhookStatus(k, c, reqLogger)
 	isSsoReady, _ := getSsoStatus(k, c, reqLogger)
+	isVersionCatalogReady, _ := getVersionCatalogStatus(k)
+	isUpgradeReady, _ := getUpgradeStatus(k)
+	isSubscriptionsReady, _ := getSubscriptionsStatus(k, c)
 	
 	// Set the overall status.
 	isKabaneroReady := isCollectionControllerReady &&
@@ -483,7 +489,10 @@ func processStatus(ctx context.Context, request reconcile.Request, k *kabanerov1
 		isCRWReady &&
 		isEventsRouteReady &&
 		isAdmissionControllerWebhookReady &&
-		isSsoReady
+		isSsoReady &&
+		isVersionCatalogReady &&
+		isUpgradeReady &&
+		isSubscriptionsReady
 
 	if isKabaneroReady {
 		k.Status.KabaneroInstance.Message = ""
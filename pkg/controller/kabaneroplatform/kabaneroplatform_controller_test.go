@@ -0,0 +1,52 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Verifies the retries of instances in the same namespace back off independently
+func TestDetermineHowToRequeue(t *testing.T) {
+	first := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "kabanero"}}
+	second := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "kabanero"}}
+
+	scheme := newTestScheme(t)
+	r := &ReconcileKabanero{
+		client:  fake.NewFakeClientWithScheme(scheme, first, second),
+		scheme:  scheme,
+		retries: cutils.NewRetryTracker(cutils.DefaultBackoff),
+	}
+	ctx := context.Background()
+
+	firstRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "kabanero", Name: "first"}}
+	secondRequest := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "kabanero", Name: "second"}}
+
+	result, err := r.determineHowToRequeue(ctx, firstRequest, first, "failed", logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Requeue || result.RequeueAfter <= 0 {
+		t.Fatalf("Expected a requeue after a delay, but got %v", result)
+	}
+
+	if attempts := r.retries.Attempts(firstRequest.NamespacedName.String()); attempts != 1 {
+		t.Fatalf("Expected 1 failed attempt of the first instance, but got %v", attempts)
+	}
+	if attempts := r.retries.Attempts(secondRequest.NamespacedName.String()); attempts != 0 {
+		t.Fatalf("Expected no failed attempts of the second instance, but got %v", attempts)
+	}
+
+	// The second instance succeeds, which does not reset the retries of the first.
+	r.retries.Reset(secondRequest.NamespacedName.String())
+	if attempts := r.retries.Attempts(firstRequest.NamespacedName.String()); attempts != 1 {
+		t.Fatalf("Expected the failed attempt of the first instance to be kept, but got %v", attempts)
+	}
+}
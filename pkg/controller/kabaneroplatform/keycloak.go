@@ -0,0 +1,250 @@
+package kabaneroplatform
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// A client of the Keycloak (RH-SSO) admin REST API.  The client logs in to the
+// master realm as the SSO admin user, and uses the resulting access token for
+// the admin requests.
+type keycloakAdminClient struct {
+	// The base URL of the SSO server, including the /auth context root
+	baseURL    string
+	httpClient *http.Client
+	token      string
+}
+
+// An error response from the Keycloak admin API
+type keycloakError struct {
+	method     string
+	path       string
+	statusCode int
+	body       string
+}
+
+func (e *keycloakError) Error() string {
+	return fmt.Sprintf("The SSO admin request %v %v failed with HTTP status code %v: %v", e.method, e.path, e.statusCode, e.body)
+}
+
+// Determines if an error is a not found response from the Keycloak admin API.
+func isKeycloakNotFound(err error) bool {
+	kerr, ok := err.(*keycloakError)
+	return ok && kerr.statusCode == http.StatusNotFound
+}
+
+type keycloakRealm struct {
+	Realm       string `json:"realm"`
+	Enabled     bool   `json:"enabled"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+type keycloakClient struct {
+	Id                        string   `json:"id,omitempty"`
+	ClientId                  string   `json:"clientId"`
+	Name                      string   `json:"name,omitempty"`
+	Enabled                   bool     `json:"enabled"`
+	Protocol                  string   `json:"protocol,omitempty"`
+	PublicClient              bool     `json:"publicClient"`
+	StandardFlowEnabled       bool     `json:"standardFlowEnabled"`
+	DirectAccessGrantsEnabled bool     `json:"directAccessGrantsEnabled"`
+	RedirectUris              []string `json:"redirectUris"`
+	WebOrigins                []string `json:"webOrigins"`
+}
+
+type keycloakProtocolMapper struct {
+	Id             string            `json:"id,omitempty"`
+	Name           string            `json:"name"`
+	Protocol       string            `json:"protocol"`
+	ProtocolMapper string            `json:"protocolMapper"`
+	Config         map[string]string `json:"config"`
+}
+
+type keycloakCredential struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type keycloakIdentityProvider struct {
+	Alias      string            `json:"alias"`
+	ProviderId string            `json:"providerId"`
+	Enabled    bool              `json:"enabled"`
+	TrustEmail bool              `json:"trustEmail"`
+	Config     map[string]string `json:"config"`
+}
+
+// Logs in to the SSO server as the admin user.
+func newKeycloakAdminClient(ctx context.Context, baseURL string, username string, password string, httpClient *http.Client) (*keycloakAdminClient, error) {
+	form := url.Values{
+		"grant_type": {"password"},
+		"client_id":  {"admin-cli"},
+		"username":   {username},
+		"password":   {password},
+	}
+
+	tokenPath := "/realms/master/protocol/openid-connect/token"
+	req, err := http.NewRequest(http.MethodPost, baseURL+tokenPath, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to log in to the SSO server: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &keycloakError{method: http.MethodPost, path: tokenPath, statusCode: resp.StatusCode, body: string(b)}
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	err = json.Unmarshal(b, &token)
+	if err != nil {
+		return nil, err
+	}
+	if len(token.AccessToken) == 0 {
+		return nil, fmt.Errorf("The SSO server did not return an access token for the admin user")
+	}
+
+	return &keycloakAdminClient{baseURL: baseURL, httpClient: httpClient, token: token.AccessToken}, nil
+}
+
+// Sends an admin request.  The path is relative to the admin realms resource.
+// The body, if not nil, is sent as JSON, and the response is decoded into the
+// result, if not nil.
+func (kc *keycloakAdminClient) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var reqBody *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	} else {
+		reqBody = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, kc.baseURL+"/admin/realms"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+kc.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := kc.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return &keycloakError{method: method, path: path, statusCode: resp.StatusCode, body: string(b)}
+	}
+
+	if result != nil && len(b) > 0 {
+		return json.Unmarshal(b, result)
+	}
+	return nil
+}
+
+// Returns a realm, or nil if it does not exist.
+func (kc *keycloakAdminClient) getRealm(ctx context.Context, realm string) (*keycloakRealm, error) {
+	r := &keycloakRealm{}
+	err := kc.do(ctx, http.MethodGet, "/"+url.PathEscape(realm), nil, r)
+	if isKeycloakNotFound(err) {
+		return nil, nil
+	}
+	return r, err
+}
+
+func (kc *keycloakAdminClient) createRealm(ctx context.Context, r *keycloakRealm) error {
+	return kc.do(ctx, http.MethodPost, "", r, nil)
+}
+
+func (kc *keycloakAdminClient) updateRealm(ctx context.Context, r *keycloakRealm) error {
+	return kc.do(ctx, http.MethodPut, "/"+url.PathEscape(r.Realm), r, nil)
+}
+
+// Returns the client with a client id, or nil if it does not exist.
+func (kc *keycloakAdminClient) getClient(ctx context.Context, realm string, clientId string) (*keycloakClient, error) {
+	clients := []keycloakClient{}
+	err := kc.do(ctx, http.MethodGet, "/"+url.PathEscape(realm)+"/clients?clientId="+url.QueryEscape(clientId), nil, &clients)
+	if err != nil {
+		return nil, err
+	}
+	for _, client := range clients {
+		if client.ClientId == clientId {
+			return &client, nil
+		}
+	}
+	return nil, nil
+}
+
+func (kc *keycloakAdminClient) createClient(ctx context.Context, realm string, client *keycloakClient) error {
+	return kc.do(ctx, http.MethodPost, "/"+url.PathEscape(realm)+"/clients", client, nil)
+}
+
+func (kc *keycloakAdminClient) updateClient(ctx context.Context, realm string, client *keycloakClient) error {
+	return kc.do(ctx, http.MethodPut, "/"+url.PathEscape(realm)+"/clients/"+client.Id, client, nil)
+}
+
+// Returns the generated secret of a confidential client.
+func (kc *keycloakAdminClient) getClientSecret(ctx context.Context, realm string, id string) (string, error) {
+	credential := &keycloakCredential{}
+	err := kc.do(ctx, http.MethodGet, "/"+url.PathEscape(realm)+"/clients/"+id+"/client-secret", nil, credential)
+	return credential.Value, err
+}
+
+func (kc *keycloakAdminClient) getProtocolMappers(ctx context.Context, realm string, id string) ([]keycloakProtocolMapper, error) {
+	mappers := []keycloakProtocolMapper{}
+	err := kc.do(ctx, http.MethodGet, "/"+url.PathEscape(realm)+"/clients/"+id+"/protocol-mappers/models", nil, &mappers)
+	return mappers, err
+}
+
+func (kc *keycloakAdminClient) createProtocolMapper(ctx context.Context, realm string, id string, mapper *keycloakProtocolMapper) error {
+	return kc.do(ctx, http.MethodPost, "/"+url.PathEscape(realm)+"/clients/"+id+"/protocol-mappers/models", mapper, nil)
+}
+
+func (kc *keycloakAdminClient) updateProtocolMapper(ctx context.Context, realm string, id string, mapper *keycloakProtocolMapper) error {
+	return kc.do(ctx, http.MethodPut, "/"+url.PathEscape(realm)+"/clients/"+id+"/protocol-mappers/models/"+mapper.Id, mapper, nil)
+}
+
+// Returns an identity provider, or nil if it does not exist.
+func (kc *keycloakAdminClient) getIdentityProvider(ctx context.Context, realm string, alias string) (*keycloakIdentityProvider, error) {
+	idp := &keycloakIdentityProvider{}
+	err := kc.do(ctx, http.MethodGet, "/"+url.PathEscape(realm)+"/identity-provider/instances/"+url.PathEscape(alias), nil, idp)
+	if isKeycloakNotFound(err) {
+		return nil, nil
+	}
+	return idp, err
+}
+
+func (kc *keycloakAdminClient) createIdentityProvider(ctx context.Context, realm string, idp *keycloakIdentityProvider) error {
+	return kc.do(ctx, http.MethodPost, "/"+url.PathEscape(realm)+"/identity-provider/instances", idp, nil)
+}
+
+func (kc *keycloakAdminClient) updateIdentityProvider(ctx context.Context, realm string, idp *keycloakIdentityProvider) error {
+	return kc.do(ctx, http.MethodPut, "/"+url.PathEscape(realm)+"/identity-provider/instances/"+url.PathEscape(idp.Alias), idp, nil)
+}
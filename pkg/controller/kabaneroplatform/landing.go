@@ -8,12 +8,11 @@ import (
 	"strings"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
-	"github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
 	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"github.com/go-logr/logr"
 	mf "github.com/manifestival/manifestival"
 	mfc "github.com/manifestival/controller-runtime-client"
-	consolev1 "github.com/openshift/api/console/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -28,7 +27,7 @@ import (
 var kllog = rlog.Log.WithName("kabanero-landing")
 
 // Deploys resources and customizes to the Openshift web console.
-func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, logger logr.Logger) error {
+func deployLandingPage(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, logger logr.Logger) error {
 	// if enable is false do not deploy the landing page
 	if k.Spec.Landing.Enable != nil && *(k.Spec.Landing.Enable) == false {
 		err := cleanupLandingPage(k, c)
@@ -46,7 +45,7 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 	if err != nil {
 		return err
 	}
-	templateContext["image"] = image
+	templateContext["image"] = resolveComponentImage(ctx, k, c, "landing", image)
 
 	f, err := rev.OpenOrchestration("kabanero-landing.yaml")
 	if err != nil {
@@ -63,7 +62,8 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 		return err
 	}
 
-	transforms := []mf.Transformer{mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace())}
+	transforms := ingressTransforms(k)
+	transforms = append(transforms, mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace()))
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
 		return err
@@ -102,6 +102,7 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 		mf.InjectNamespace(k.GetNamespace()),
 		kabTransforms.AddEnvVariable("LANDING_URL", landingURL),
 	}
+	transforms = append(transforms, externalSsoTransforms(k)...)
 
 	// See if we should define the OAuth volume and variables
 	secretInstance := &corev1.Secret{}
@@ -121,12 +122,10 @@ func deployLandingPage(_ context.Context, k *kabanerov1alpha2.Kabanero, c client
 			apiUrlString = "https://api.github.com"
 		}
 
-		apiUrl, err := url.Parse(apiUrlString)
+		apiUrl, err := cutils.ParseGithubApiUrl(apiUrlString)
 		if err != nil {
 			kllog.Error(err, "Could not parse Github API url %v, assuming api.github.com", apiUrlString)
 			apiUrl, _ = url.Parse("https://api.github.com")
-		} else if len(apiUrl.Scheme) == 0 {
-			apiUrl.Scheme = "https"
 		}
 		hostname := apiUrl.Hostname()
 		if hostname == "api.github.com" {
@@ -209,7 +208,8 @@ func cleanupLandingPage(k *kabanerov1alpha2.Kabanero, c client.Client) error {
 		return err
 	}
 
-	transforms := []mf.Transformer{mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace())}
+	transforms := ingressTransforms(k)
+	transforms = append(transforms, mf.InjectOwner(k), mf.InjectNamespace(k.GetNamespace()))
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
 		return err
@@ -223,10 +223,17 @@ func cleanupLandingPage(k *kabanerov1alpha2.Kabanero, c client.Client) error {
 	return nil
 }
 
-// Retrieves the landing URL from the landing Route.
+// Retrieves the landing URL from the landing Route, or the landing Ingress if the
+// cluster does not serve Routes.
 func getLandingURL(k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
 	landingURL := ""
 
+	if !routesServed {
+		landingURL, err := getIngressURL(context.TODO(), c, k.ObjectMeta.Namespace, "kabanero-landing", "landing page")
+		kllog.Info(fmt.Sprintf("getLandingURL: URL: %v", landingURL))
+		return landingURL, err
+	}
+
 	// Get the Route instance.
 	landingRoute := &routev1.Route{}
 	landingRouteName := types.NamespacedName{Namespace: k.ObjectMeta.Namespace, Name: "kabanero-landing"}
@@ -259,127 +266,6 @@ func getLandingURL(k *kabanerov1alpha2.Kabanero, c client.Client) (string, error
 	return landingURL, err
 }
 
-// Returns a copy of a ConsoleLink object
-func getConsoleLink(c client.Client, linkName string) (*consolev1.ConsoleLink, error) {
-	consoleLink := &consolev1.ConsoleLink{}
-	err := c.Get(context.TODO(), types.NamespacedName{
-		Namespace: "", Name: linkName}, consoleLink)
-	if err != nil {
-		return nil, err
-	}
-
-	return consoleLink, nil
-}
-
-// Adds customizations to the OpenShift web console.
-func customizeWebConsole(k *kabanerov1alpha2.Kabanero, c client.Client, landingURL string) error {
-
-	// See if we've added the apps link yet.
-	clientOp := utils.Update
-	consoleLink, err := getConsoleLink(c, "kabanero-app-menu-link")
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
-		}
-
-		consoleLink = &consolev1.ConsoleLink{}
-		consoleLink.Name = "kabanero-app-menu-link"
-		consoleLink.Spec.Location = "ApplicationMenu"
-		consoleLink.Spec.Text = "Landing Page"
-		consoleLink.Spec.ApplicationMenu = &consolev1.ApplicationMenuSpec{}
-		consoleLink.Spec.ApplicationMenu.Section = "Kabanero"
-		clientOp = utils.Create
-
-		kllog.Info("Creating ConsoleLink kabanero-app-menu-link")
-	}
-
-	// Stuff that could change (dependent on the landingURL)
-	consoleLink.Spec.Href = landingURL
-	consoleLink.Spec.ApplicationMenu.ImageURL = landingURL + "/img/favicon/favicon-16x16.png"
-	err = clientOp(c, context.TODO(), consoleLink)
-	if err != nil {
-		return err
-	}
-
-	// See if we've added the help links yet.
-	clientOp = utils.Update
-	consoleLink, err = getConsoleLink(c, "kabanero-help-menu-docs")
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
-		}
-
-		consoleLink = &consolev1.ConsoleLink{}
-		consoleLink.Name = "kabanero-help-menu-docs"
-		consoleLink.Spec.Location = "HelpMenu"
-		consoleLink.Spec.Text = "Kabanero Docs"
-		clientOp = utils.Create
-
-		kllog.Info("Creating ConsoleLink kabanero-help-menu-docs")
-	}
-
-	// Stuff that could change (dependent on the landing URL)
-	consoleLink.Spec.Href = landingURL + "/docs"
-	err = clientOp(c, context.TODO(), consoleLink)
-	if err != nil {
-		return err
-	}
-
-	clientOp = utils.Update
-	consoleLink, err = getConsoleLink(c, "kabanero-help-menu-guides")
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return err
-		}
-
-		consoleLink = &consolev1.ConsoleLink{}
-		consoleLink.Name = "kabanero-help-menu-guides"
-		consoleLink.Spec.Location = "HelpMenu"
-		consoleLink.Spec.Text = "Kabanero Guides"
-		clientOp = utils.Create
-	}
-
-	// Stuff that could change (dependent on the landing URL)
-	consoleLink.Spec.Href = landingURL + "/guides"
-	err = clientOp(c, context.TODO(), consoleLink)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-// Removes customizations from the openshift console.
-func removeWebConsoleCustomization(k *kabanerov1alpha2.Kabanero, c client.Client) error {
-	// Since these are cluster level objects, they cannot set a namespace-level owner and must be
-	// removed manually.
-	consoleLink, err := getConsoleLink(c, "kabanero-app-menu-link")
-	if err == nil {
-		err = c.Delete(context.TODO(), consoleLink)
-		if err != nil {
-			kllog.Error(err, "Unable to delete ConsoleLink")
-		}
-	}
-
-	consoleLink, err = getConsoleLink(c, "kabanero-help-menu-docs")
-	if err == nil {
-		err = c.Delete(context.TODO(), consoleLink)
-		if err != nil {
-			kllog.Error(err, "Unable to delete ConsoleLink")
-		}
-	}
-
-	consoleLink, err = getConsoleLink(c, "kabanero-help-menu-guides")
-	if err == nil {
-		err = c.Delete(context.TODO(), consoleLink)
-		if err != nil {
-			kllog.Error(err, "Unable to delete ConsoleLink")
-		}
-	}
-
-	return nil
-}
-
 // Retrieves the current kabanero landing page status.
 func getKabaneroLandingPageStatus(k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error) {
 	// If disabled. Nothing to do. No need to display status if disabled.
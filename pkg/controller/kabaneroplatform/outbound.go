@@ -0,0 +1,115 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	mf "github.com/manifestival/manifestival"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Loads the proxy and trusted certificate authorities used by outbound HTTP clients.
+// The trusted certificate authorities are injected by OpenShift into a ConfigMap
+// created here, which the stack and collection controllers also read.
+func reconcileOutboundConfig(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	err := createTrustedCABundleConfigMap(ctx, k, c, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	err = cutils.LoadTrustedCABundle(ctx, c, k.GetNamespace())
+	if err != nil {
+		return fmt.Errorf("Unable to load the trusted CA bundle: %v", err)
+	}
+
+	err = cutils.LoadClusterProxy(ctx, c)
+	if err != nil {
+		return fmt.Errorf("Unable to load the cluster proxy configuration: %v", err)
+	}
+
+	return nil
+}
+
+// Creates the ConfigMap into which OpenShift injects the trusted certificate authorities.
+// The contents are left to OpenShift, or to the administrator on other clusters.
+func createTrustedCABundleConfigMap(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: cutils.TrustedCABundleConfigMapName, Namespace: k.GetNamespace()}, cm)
+	if err == nil {
+		if cm.GetLabels()[cutils.InjectTrustedCABundleLabel] == "true" {
+			return nil
+		}
+		labels := cm.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[cutils.InjectTrustedCABundleLabel] = "true"
+		cm.SetLabels(labels)
+		return c.Update(ctx, cm)
+	}
+	if !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	ownerRef, err := getOwnerReference(k, c, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	cm = &corev1.ConfigMap{}
+	cm.ObjectMeta.Name = cutils.TrustedCABundleConfigMapName
+	cm.ObjectMeta.Namespace = k.GetNamespace()
+	cm.ObjectMeta.OwnerReferences = append(cm.ObjectMeta.OwnerReferences, ownerRef)
+	cm.ObjectMeta.Labels = map[string]string{cutils.InjectTrustedCABundleLabel: "true"}
+
+	reqLogger.Info(fmt.Sprintf("Attempting to create the trusted CA bundle ConfigMap %v", cm.Name))
+	return c.Create(ctx, cm)
+}
+
+// Returns the transforms which pass the outbound proxy to the controllers deployed
+// by Kabanero.  The controllers cannot read the cluster-wide proxy configuration.
+func outboundProxyTransforms() []mf.Transformer {
+	proxy := cutils.OutboundProxy()
+	transforms := []mf.Transformer{}
+	for _, env := range []struct {
+		name  string
+		value string
+	}{
+		{"HTTP_PROXY", proxy.HTTPProxy},
+		{"HTTPS_PROXY", proxy.HTTPSProxy},
+		{"NO_PROXY", proxy.NoProxy},
+	} {
+		if len(env.value) != 0 {
+			transforms = append(transforms, kabTransforms.AddEnvVariable(env.name, env.value))
+		}
+	}
+	return transforms
+}
+
+// Returns the transforms which pass the Vault settings of the operator to the stack
+// controller and the admission webhook, so that they use and accept the same Vault
+// server and roles, and send file and Vault credentials to the same hosts.
+func vaultSettingsTransforms() []mf.Transformer {
+	transforms := []mf.Transformer{}
+	if len(cutils.CredentialsAllowedHosts) != 0 {
+		transforms = append(transforms,
+			kabTransforms.AddEnvVariable("CREDENTIALS_ALLOWED_HOSTS", strings.Join(cutils.CredentialsAllowedHosts, ",")))
+	}
+	if len(cutils.VaultAddress) == 0 {
+		return transforms
+	}
+
+	transforms = append(transforms,
+		kabTransforms.AddEnvVariable("VAULT_ADDR", cutils.VaultAddress),
+		kabTransforms.AddEnvVariable("VAULT_AUTH_PATH", cutils.VaultAuthPath),
+		kabTransforms.AddEnvVariable("VAULT_ROLES", strings.Join(cutils.VaultRoles, ",")))
+	return transforms
+}
@@ -0,0 +1,53 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Verifies the trusted CA bundle ConfigMap is created for OpenShift to inject into
+func TestReconcileOutboundConfig(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"}}
+	cl := newTestClient(t, k)
+	ctx := context.Background()
+
+	// There is no cluster-wide proxy, so the environment is used.
+	err := reconcileOutboundConfig(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = cl.Get(ctx, types.NamespacedName{Name: cutils.TrustedCABundleConfigMapName, Namespace: "kabanero"}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Labels[cutils.InjectTrustedCABundleLabel] != "true" || len(cm.OwnerReferences) != 1 {
+		t.Fatalf("Expected the ConfigMap to be labelled for injection and owned by the Kabanero instance: %v", cm.ObjectMeta)
+	}
+
+	// A ConfigMap created by the administrator is labelled for injection.
+	cm.Labels = nil
+	if err = cl.Update(ctx, cm); err != nil {
+		t.Fatal(err)
+	}
+	err = reconcileOutboundConfig(ctx, k, cl, logf.NullLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm = &corev1.ConfigMap{}
+	err = cl.Get(ctx, types.NamespacedName{Name: cutils.TrustedCABundleConfigMapName, Namespace: "kabanero"}, cm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cm.Labels[cutils.InjectTrustedCABundleLabel] != "true" {
+		t.Fatalf("Expected the ConfigMap to be labelled for injection: %v", cm.Labels)
+	}
+}
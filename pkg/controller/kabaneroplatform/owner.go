@@ -0,0 +1,28 @@
+package kabaneroplatform
+
+import (
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels identifying the Kabanero instance which manages a resource.  They are used
+// where owner references cannot be, such as for cluster scoped resources or
+// resources in another namespace.
+const (
+	ownerNamespaceLabel = "kabanero.io/owner-namespace"
+	ownerNameLabel      = "kabanero.io/owner-name"
+)
+
+// Returns the labels identifying the Kabanero instance as the manager of a resource.
+func ownerLabels(k *kabanerov1alpha2.Kabanero) map[string]string {
+	return map[string]string{
+		ownerNamespaceLabel: k.GetNamespace(),
+		ownerNameLabel:      k.GetName(),
+	}
+}
+
+// Determines if the resource is labelled as managed by the Kabanero instance.
+func isLabelledOwner(k *kabanerov1alpha2.Kabanero, obj metav1.Object) bool {
+	labels := obj.GetLabels()
+	return labels[ownerNamespaceLabel] == k.GetNamespace() && labels[ownerNameLabel] == k.GetName()
+}
@@ -0,0 +1,197 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kutils "github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	tektoncdv1alpha1 "github.com/tektoncd/operator/pkg/apis/operator/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Retrieves the installed version of a prerequisite operator
+type prerequisiteVersionFunc func(context.Context, *kabanerov1alpha2.Kabanero, client.Client) (string, error)
+
+// The prerequisite operators which may be constrained in versions.yaml
+var prerequisiteVersionFuncs = map[string]prerequisiteVersionFunc{
+	"tekton":               getTektonVersion,
+	"serverless":           getServerlessVersion,
+	"appsody":              getAppsodyVersion,
+	"codeready-workspaces": getCRWVersion,
+}
+
+// The names of the prerequisite operators as shown in status messages
+var prerequisiteDisplayNames = map[string]string{
+	"tekton":               "Tekton",
+	"serverless":           "OpenShift Serverless",
+	"appsody":              "Appsody",
+	"codeready-workspaces": "CodeReady Workspaces",
+}
+
+// Prerequisites which are only needed when the corresponding Kabanero feature
+// is enabled.  Others are always required.
+var prerequisiteEnabledFuncs = map[string]func(*kabanerov1alpha2.Kabanero) bool{
+	"codeready-workspaces": func(k *kabanerov1alpha2.Kabanero) bool {
+		return k.Spec.CodereadyWorkspaces.Enable != nil && *k.Spec.CodereadyWorkspaces.Enable
+	},
+}
+
+// Compatibility status values
+const (
+	prerequisiteCompatible   = "True"
+	prerequisiteIncompatible = "False"
+)
+
+// Returns the version reported by the Tekton operator's config instance.
+func getTektonVersion(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	tekton := &tektoncdv1alpha1.Config{}
+	err := c.Get(ctx, client.ObjectKey{Name: "cluster"}, tekton)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tekton.Status.Conditions) == 0 {
+		return "", fmt.Errorf("The Tekton instance has not reported an installed version")
+	}
+
+	return tekton.Status.Conditions[0].Version, nil
+}
+
+// Returns the version of the installed serverless CSV.
+func getServerlessVersion(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	installedCSVName, err := kutils.GetInstalledCSVName(c, client.ObjectKey{Namespace: serverlessSubscriptionNamespace, Name: serverlessSubscriptionName})
+	if err != nil {
+		return "", err
+	}
+
+	return kutils.GetCSVSpecVersion(c, client.ObjectKey{Namespace: serverlessSubscriptionNamespace, Name: installedCSVName})
+}
+
+// Returns the version of the installed Appsody CSV.
+func getAppsodyVersion(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	return getAppsodyOperatorVersion(k, c)
+}
+
+// Returns the version of the installed CodeReady Workspaces CSV.
+func getCRWVersion(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	return getCRWOperatorVersion(k, c)
+}
+
+// Determines if an installed version satisfies a semantic version range.
+func prerequisiteSatisfied(version string, constraint string) (bool, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false, fmt.Errorf("The installed version `%v` is not a valid semantic version: %v", version, err)
+	}
+
+	r, err := semver.ParseRange(constraint)
+	if err != nil {
+		return false, fmt.Errorf("The version range `%v` is not valid: %v", constraint, err)
+	}
+
+	return r(v), nil
+}
+
+// Checks the installed prerequisite operators against the ranges declared by
+// the Kabanero revision.  The returned error describes every unsatisfied
+// prerequisite.
+func checkPrerequisites(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, rev *versioning.KabaneroRevision) error {
+	var names []string
+	for name := range rev.Prerequisites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, name := range names {
+		if enabledFunc, ok := prerequisiteEnabledFuncs[name]; ok && !enabledFunc(k) {
+			continue
+		}
+
+		constraint := rev.Prerequisites[name]
+		versionFunc, ok := prerequisiteVersionFuncs[name]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%v: unknown prerequisite", name))
+			continue
+		}
+
+		version, err := versionFunc(ctx, k, c)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%v: unable to determine the installed version: %v", name, err))
+			continue
+		}
+
+		satisfied, err := prerequisiteSatisfied(version, constraint)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%v: %v", name, err))
+		} else if !satisfied {
+			failures = append(failures, incompatiblePrerequisiteMessage(name, version, constraint, rev.Version))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("Kabanero version %v prerequisites are not satisfied: %v", rev.Version, strings.Join(failures, " "))
+	}
+
+	return nil
+}
+
+// Builds an actionable message for a prerequisite which is installed at an incompatible version.
+func incompatiblePrerequisiteMessage(name string, version string, constraint string, kabaneroVersion string) string {
+	displayName := prerequisiteDisplayNames[name]
+	if displayName == "" {
+		displayName = name
+	}
+
+	return fmt.Sprintf("%v version %v is installed but incompatible: Kabanero version %v requires %v. Install a version of the %v operator which satisfies this range.",
+		displayName, version, kabaneroVersion, constraint, displayName)
+}
+
+// Checks an installed prerequisite version against the range declared by the
+// requested Kabanero revision.  Returns the compatibility status value, and a
+// message when the installed version is not compatible.  A prerequisite
+// without a declared range is compatible with any version.
+func checkPrerequisiteCompatibility(k *kabanerov1alpha2.Kabanero, name string, version string) (string, string) {
+	doc, kabaneroVersion := resolveKabaneroVersion(k)
+	rev := doc.KabaneroRevision(kabaneroVersion)
+	if rev == nil {
+		return "", ""
+	}
+
+	constraint, ok := rev.Prerequisites[name]
+	if !ok {
+		return prerequisiteCompatible, ""
+	}
+
+	satisfied, err := prerequisiteSatisfied(version, constraint)
+	if err != nil {
+		return prerequisiteIncompatible, fmt.Sprintf("Unable to determine whether %v is compatible with Kabanero version %v: %v", prerequisiteDisplayNames[name], kabaneroVersion, err)
+	}
+
+	if !satisfied {
+		return prerequisiteIncompatible, incompatiblePrerequisiteMessage(name, version, constraint, kabaneroVersion)
+	}
+
+	return prerequisiteCompatible, ""
+}
+
+// Checks the installed version of a component which Kabanero depends on, and
+// records its compatibility in the component status.  An incompatible
+// component is reported as not ready, with a message which explains the
+// versions required.  Returns false when the component is incompatible.
+func checkComponentCompatibility(k *kabanerov1alpha2.Kabanero, name string, version string, compatible *string, ready *string, message *string) bool {
+	var reason string
+	*compatible, reason = checkPrerequisiteCompatibility(k, name, version)
+	if *compatible == prerequisiteIncompatible {
+		*ready = "False"
+		*message = reason
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,27 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Verifies an operator which can no longer be found is not reported as compatible
+func TestPrerequisiteCompatibilityReset(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Version: "0.7.0"},
+	}
+	k.Status.Serverless.Compatible = prerequisiteCompatible
+	k.Status.Appsody.Compatible = prerequisiteCompatible
+	cl := newTestClient(t)
+
+	if ready, _ := getServerlessStatus(k, cl, logf.NullLogger{}); ready || k.Status.Serverless.Compatible != "" {
+		t.Fatalf("Expected the serverless compatibility to be cleared: %v", k.Status.Serverless)
+	}
+	if ready, _ := getAppsodyStatus(k, cl, logf.NullLogger{}); ready || k.Status.Appsody.Compatible != "" {
+		t.Fatalf("Expected the appsody compatibility to be cleared: %v", k.Status.Appsody)
+	}
+}
@@ -0,0 +1,727 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// Records when the operator generated the contents of a secret
+	secretGeneratedAnnotation = "kabanero.io/generated-at"
+
+	// Requests that a secret generated by the operator be rotated now
+	secretRotateNowAnnotation = "kabanero.io/rotate-now"
+
+	// Records when a rotation which spans several reconciles was started
+	secretRotationStartedAnnotation = "kabanero.io/rotation-started-at"
+
+	// Record when the last attempt to rotate a secret failed, why, and how
+	// many attempts in a row have failed
+	secretRotationFailedAnnotation        = "kabanero.io/last-failed-at"
+	secretRotationFailureReasonAnnotation = "kabanero.io/last-failure-reason"
+	secretRotationAttemptsAnnotation      = "kabanero.io/failed-attempts"
+
+	// Set on the pod template of a workload to restart it after a secret it
+	// uses was rotated
+	secretRotatedAnnotation = "kabanero.io/secret-rotated-at"
+
+	// Records that the workloads which use a rotated secret have not all been
+	// restarted yet
+	secretRestartPendingAnnotation = "kabanero.io/restart-pending"
+
+	// How long to wait before checking on a rotation in progress
+	secretRotationPollInterval = 10 * time.Second
+
+	eventsSecretName           = "default-events-secret"
+	cliEncryptionKeySecretName = "kabanero-cli-aes-encryption-key-secret"
+
+	// The Job which changes the password of the SSO database user
+	ssoDbRotationJobName = "kabanero-sso-db-rotation"
+
+	// Holds the new SSO database password while the database user is changed to use it
+	ssoDbPendingPasswordKey = "DB_PASSWORD_PENDING"
+)
+
+// The wait before a failed rotation is attempted again.  It is not jittered,
+// so that the wait can be recomputed from the annotations of the secret.
+var secretRotationBackoff = cutils.Backoff{Initial: 5 * time.Minute, Max: 24 * time.Hour, Factor: 2}
+
+// The characters used in the generated events secret and CLI encryption key
+const generatedSecretChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890!@#$%^&*()-=_+"
+
+// A workload which uses a generated secret, and is restarted when it is rotated
+type secretConsumer struct {
+	kind string
+	name string
+}
+
+// A secret generated by the operator
+type generatedSecret struct {
+	name string
+	// Determines if the secret is used by the Kabanero instance
+	enabled func(k *kabanerov1alpha2.Kabanero) bool
+	// Returns the rotation interval from the Kabanero instance
+	interval func(k *kabanerov1alpha2.Kabanero) string
+	// Replaces the contents of the secret.  Returns false if the rotation is
+	// still in progress, in which case it is called again later.
+	rotate    func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, secret *corev1.Secret, reqLogger logr.Logger) (bool, error)
+	consumers []secretConsumer
+}
+
+var generatedSecrets = []generatedSecret{
+	{
+		name:      sso_db_secret_name,
+		enabled:   func(k *kabanerov1alpha2.Kabanero) bool { return k.Spec.Sso.Enable && k.Spec.Sso.External == nil },
+		interval:  func(k *kabanerov1alpha2.Kabanero) string { return k.Spec.SecretRotation.SsoDatabase },
+		rotate:    rotateSsoDbSecret,
+		consumers: []secretConsumer{{kind: "DeploymentConfig", name: "sso-postgresql"}, {kind: "DeploymentConfig", name: "sso"}},
+	},
+	{
+		name:      eventsSecretName,
+		enabled:   func(k *kabanerov1alpha2.Kabanero) bool { return k.Spec.Events.Enable },
+		interval:  func(k *kabanerov1alpha2.Kabanero) string { return k.Spec.SecretRotation.Events },
+		rotate:    replaceSecretData(generateEventsSecretData),
+		consumers: []secretConsumer{{kind: "Deployment", name: "kabanero-events"}},
+	},
+	{
+		name:      cliEncryptionKeySecretName,
+		enabled:   func(k *kabanerov1alpha2.Kabanero) bool { return true },
+		interval:  func(k *kabanerov1alpha2.Kabanero) string { return k.Spec.SecretRotation.CliEncryptionKey },
+		rotate:    replaceSecretData(generateCliEncryptionKeyData),
+		consumers: []secretConsumer{{kind: "Deployment", name: "kabanero-cli"}},
+	},
+}
+
+// Returns a random integer in [0, n), using a cryptographically secure source.
+func randInt(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// Generates a random string of characters from the given set.
+func randString(length int, chars string) (string, error) {
+	buf := make([]byte, length)
+	for i := range buf {
+		n, err := randInt(len(chars))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = chars[n]
+	}
+	return string(buf), nil
+}
+
+// Generate a random username, password
+// Rules: Minimum Length: 9, 2 Digits, 2 Uppers, 2 Lowers
+// Specials may break sed in sso startup
+func randSecret(length int) (string, error) {
+	if length < 9 {
+		length = 9
+	}
+
+	digits := "0123456789"
+	lowers := "abcdefghijklmnopqrstuvwxyz"
+	uppers := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	required := []string{digits, digits, lowers, lowers, uppers, uppers}
+
+	buf := make([]byte, length)
+	for i := range buf {
+		chars := digits + lowers + uppers
+		if i < len(required) {
+			chars = required[i]
+		}
+		n, err := randInt(len(chars))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = chars[n]
+	}
+
+	// Shuffle, so that the required characters are not always first.
+	for i := len(buf) - 1; i > 0; i-- {
+		j, err := randInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+
+	return string(buf), nil
+}
+
+// Generates the keys of a secret, each with the given length.
+func generateSecretData(lengths map[string]int, generate func(int) (string, error)) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	for key, length := range lengths {
+		value, err := generate(length)
+		if err != nil {
+			return nil, err
+		}
+		data[key] = []byte(value)
+	}
+	return data, nil
+}
+
+func generateSsoDbSecretData() (map[string][]byte, error) {
+	return generateSecretData(map[string]int{"DB_USERNAME": 16, "DB_PASSWORD": 32, "JGROUPS_CLUSTER_PASSWORD": 32}, randSecret)
+}
+
+func generateEventsSecretData() (map[string][]byte, error) {
+	return generateSecretData(map[string]int{"secret": 16}, func(length int) (string, error) {
+		return randString(length, generatedSecretChars)
+	})
+}
+
+func generateCliEncryptionKeyData() (map[string][]byte, error) {
+	return generateSecretData(map[string]int{"AESEncryptionKey": 64}, func(length int) (string, error) {
+		return randString(length, generatedSecretChars)
+	})
+}
+
+// Creates a secret generated by the operator, if it does not already exist.
+func createGeneratedSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, name string, generate func() (map[string][]byte, error), reqLogger logr.Logger) error {
+	secretInstance := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: k.GetNamespace()}, secretInstance)
+	if err == nil || !kerrors.IsNotFound(err) {
+		return err
+	}
+
+	// Not found.  Make a new one.
+	ownerRef, err := getOwnerReference(k, c, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	data, err := generate()
+	if err != nil {
+		return err
+	}
+
+	secretInstance = &corev1.Secret{}
+	secretInstance.ObjectMeta.Name = name
+	secretInstance.ObjectMeta.Namespace = k.GetNamespace()
+	secretInstance.ObjectMeta.OwnerReferences = append(secretInstance.ObjectMeta.OwnerReferences, ownerRef)
+	secretInstance.ObjectMeta.Annotations = map[string]string{secretGeneratedAnnotation: time.Now().UTC().Format(time.RFC3339)}
+	secretInstance.Data = data
+
+	reqLogger.Info(fmt.Sprintf("Attempting to create the generated secret %v", name))
+	return c.Create(ctx, secretInstance)
+}
+
+// Returns a rotation which replaces the contents of a secret in one step.
+func replaceSecretData(generate func() (map[string][]byte, error)) func(context.Context, *kabanerov1alpha2.Kabanero, client.Client, *corev1.Secret, logr.Logger) (bool, error) {
+	return func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, secret *corev1.Secret, reqLogger logr.Logger) (bool, error) {
+		data, err := generate()
+		if err != nil {
+			return false, err
+		}
+		secret.Data = data
+		return true, nil
+	}
+}
+
+// Determines if a generated secret is due to be rotated.  If not, returns how
+// long until it is due, or zero if it is not rotated automatically.
+func secretRotationDue(secret *corev1.Secret, interval time.Duration, now time.Time) (bool, time.Duration) {
+	annotations := secret.GetAnnotations()
+	if _, ok := annotations[secretRotateNowAnnotation]; ok {
+		return true, 0
+	}
+	if _, ok := annotations[secretRotationStartedAnnotation]; ok {
+		return true, 0
+	}
+	if interval <= 0 {
+		return false, 0
+	}
+
+	// A rotation which failed is not attempted again until its backoff has passed.
+	if failedAt, err := time.Parse(time.RFC3339, annotations[secretRotationFailedAnnotation]); err == nil {
+		attempts, _ := strconv.Atoi(annotations[secretRotationAttemptsAnnotation])
+		if wait := failedAt.Add(secretRotationBackoff.Delay(attempts)).Sub(now); wait > 0 {
+			return false, wait
+		}
+	}
+
+	// Secrets created by earlier versions of the operator are not annotated.
+	generatedAt := secret.GetCreationTimestamp().Time
+	if t, err := time.Parse(time.RFC3339, annotations[secretGeneratedAnnotation]); err == nil {
+		generatedAt = t
+	}
+
+	wait := generatedAt.Add(interval).Sub(now)
+	if wait <= 0 {
+		return true, 0
+	}
+	return false, wait
+}
+
+// A rotation failure recorded in the annotations of a generated secret.  It is
+// reported until the secret is rotated successfully.
+type recordedRotationFailure struct {
+	message string
+}
+
+func (f recordedRotationFailure) Error() string {
+	return f.message
+}
+
+// Returns the rotation failure recorded in the annotations of a generated
+// secret, if there is one, and how long until the rotation may be attempted
+// again.
+func getRecordedRotationFailure(secret *corev1.Secret, now time.Time) (time.Duration, error) {
+	annotations := secret.GetAnnotations()
+	failedAt, err := time.Parse(time.RFC3339, annotations[secretRotationFailedAnnotation])
+	if err != nil {
+		return 0, nil
+	}
+
+	attempts, _ := strconv.Atoi(annotations[secretRotationAttemptsAnnotation])
+	retryAt := failedAt.Add(secretRotationBackoff.Delay(attempts))
+	message := fmt.Sprintf("Unable to rotate generated secret %v: %v failed attempts, the last at %v", secret.GetName(), attempts, failedAt.Format(time.RFC3339))
+	if reason := annotations[secretRotationFailureReasonAnnotation]; len(reason) > 0 {
+		message += ": " + reason
+	}
+
+	wait := retryAt.Sub(now)
+	if wait > 0 {
+		message += fmt.Sprintf(".  The rotation is attempted again at %v", retryAt.UTC().Format(time.RFC3339))
+	} else {
+		wait = 0
+	}
+	return wait, recordedRotationFailure{message: message}
+}
+
+// Rotates the secrets generated by the operator which are due, and restarts the
+// workloads which use them.  Secrets which cannot be rotated are reported in
+// the status of the Kabanero instance, and the others are still rotated.
+// Returns how long until the next rotation is due, or zero if none is.
+func reconcileSecretRotation(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) time.Duration {
+	now := time.Now()
+	var requeueAfter time.Duration
+	k.Status.SecretRotationFailures = nil
+	for _, gs := range generatedSecrets {
+		if !gs.enabled(k) {
+			continue
+		}
+
+		wait, err := rotateGeneratedSecret(ctx, k, c, gs, now, reqLogger)
+		if err != nil {
+			k.Status.SecretRotationFailures = append(k.Status.SecretRotationFailures, kabanerov1alpha2.SecretRotationFailureStatus{
+				Name:    gs.name,
+				Message: err.Error(),
+			})
+			// A failure which was not recorded on the secret does not back
+			// off, so check again soon.  A recorded failure is retried when
+			// its backoff has passed.
+			if _, recorded := err.(recordedRotationFailure); !recorded {
+				reqLogger.Error(err, fmt.Sprintf("Error rotating generated secret %v", gs.name))
+				wait = secretRotationPollInterval
+			}
+		}
+		requeueAfter = minRequeueAfter(requeueAfter, wait)
+	}
+
+	return requeueAfter
+}
+
+func rotateGeneratedSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, gs generatedSecret, now time.Time, reqLogger logr.Logger) (time.Duration, error) {
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: gs.name, Namespace: k.GetNamespace()}, secret)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			// Created when the component is reconciled.
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var interval time.Duration
+	if value := gs.interval(k); len(value) > 0 {
+		interval, err = time.ParseDuration(value)
+		if err != nil {
+			reqLogger.Error(err, fmt.Sprintf("Ignoring the invalid rotation interval of secret %v", gs.name))
+			interval = 0
+		}
+	}
+
+	// Finish restarting the workloads which use the secret after an earlier rotation.
+	if _, pending := secret.GetAnnotations()[secretRestartPendingAnnotation]; pending {
+		err = restartSecretConsumers(ctx, k, c, gs, secret, now)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	due, wait := secretRotationDue(secret, interval, now)
+	if !due {
+		// Keep reporting an earlier failure while the rotation backs off.
+		if failureWait, failure := getRecordedRotationFailure(secret, now); failure != nil {
+			return minRequeueAfter(wait, failureWait), failure
+		}
+		return wait, nil
+	}
+
+	reqLogger.Info(fmt.Sprintf("Rotating generated secret %v", gs.name))
+	done, err := gs.rotate(ctx, k, c, secret, reqLogger)
+	if err != nil {
+		// Rotations which record their failure back off before the next attempt.
+		if failureWait, failure := getRecordedRotationFailure(secret, now); failure != nil {
+			reqLogger.Error(err, fmt.Sprintf("Error rotating generated secret %v", gs.name))
+			return failureWait, failure
+		}
+		return 0, fmt.Errorf("Unable to rotate generated secret %v: %v", gs.name, err)
+	}
+	if !done {
+		return secretRotationPollInterval, nil
+	}
+
+	annotations := secret.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[secretGeneratedAnnotation] = now.UTC().Format(time.RFC3339)
+	delete(annotations, secretRotateNowAnnotation)
+	delete(annotations, secretRotationStartedAnnotation)
+	delete(annotations, secretRotationFailedAnnotation)
+	delete(annotations, secretRotationFailureReasonAnnotation)
+	delete(annotations, secretRotationAttemptsAnnotation)
+	if len(gs.consumers) > 0 {
+		annotations[secretRestartPendingAnnotation] = "true"
+	}
+	secret.SetAnnotations(annotations)
+
+	err = c.Update(ctx, secret)
+	if err != nil {
+		return 0, err
+	}
+
+	err = restartSecretConsumers(ctx, k, c, gs, secret, now)
+	if err != nil {
+		return 0, err
+	}
+
+	reqLogger.Info(fmt.Sprintf("Rotated generated secret %v", gs.name))
+	return interval, nil
+}
+
+// Restarts the workloads which use a rotated secret.  The secret records that
+// the restarts are pending until they all succeed, so that the workloads which
+// could not be restarted are restarted on a later reconcile.
+func restartSecretConsumers(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, gs generatedSecret, secret *corev1.Secret, now time.Time) error {
+	for _, consumer := range gs.consumers {
+		err := restartSecretConsumer(ctx, c, k.GetNamespace(), consumer, now)
+		if err != nil {
+			return fmt.Errorf("Unable to restart %v %v after rotating secret %v: %v", consumer.kind, consumer.name, gs.name, err)
+		}
+	}
+
+	annotations := secret.GetAnnotations()
+	if _, pending := annotations[secretRestartPendingAnnotation]; !pending {
+		return nil
+	}
+	delete(annotations, secretRestartPendingAnnotation)
+	secret.SetAnnotations(annotations)
+	return c.Update(ctx, secret)
+}
+
+// Restarts a workload by changing an annotation of its pod template, so that
+// the new pods use the rotated secret.  Workloads which are not deployed are
+// ignored.
+func restartSecretConsumer(ctx context.Context, c client.Client, namespace string, consumer secretConsumer, now time.Time) error {
+	name := types.NamespacedName{Name: consumer.name, Namespace: namespace}
+	restarted := now.UTC().Format(time.RFC3339)
+
+	switch consumer.kind {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		err := c.Get(ctx, name, d)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		setPodTemplateAnnotation(&d.Spec.Template, secretRotatedAnnotation, restarted)
+		return c.Update(ctx, d)
+
+	case "DeploymentConfig":
+		dc := &openshiftappsv1.DeploymentConfig{}
+		err := c.Get(ctx, name, dc)
+		if err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		if dc.Spec.Template == nil {
+			return nil
+		}
+		setPodTemplateAnnotation(dc.Spec.Template, secretRotatedAnnotation, restarted)
+		return c.Update(ctx, dc)
+	}
+
+	return fmt.Errorf("Unsupported workload kind %v", consumer.kind)
+}
+
+func setPodTemplateAnnotation(template *corev1.PodTemplateSpec, name string, value string) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[name] = value
+}
+
+// Rotates the SSO database credentials.  The password of the database user is
+// changed by a Job which connects with the current password, before the secret
+// is updated and the SSO server restarted to use the new one.  The username is
+// not changed.
+func rotateSsoDbSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, secret *corev1.Secret, reqLogger logr.Logger) (bool, error) {
+	job := &batchv1.Job{}
+	err := c.Get(ctx, types.NamespacedName{Name: ssoDbRotationJobName, Namespace: k.GetNamespace()}, job)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return false, err
+	}
+	jobFound := err == nil
+
+	pending := secret.Data[ssoDbPendingPasswordKey]
+	if len(pending) == 0 {
+		// Wait for the Job of a previous rotation to go away.
+		if jobFound {
+			if job.GetDeletionTimestamp() == nil {
+				err = c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			}
+			return false, err
+		}
+
+		password, err := randSecret(32)
+		if err != nil {
+			return false, err
+		}
+		jgroupsPassword, err := randSecret(32)
+		if err != nil {
+			return false, err
+		}
+
+		// If the database has not been deployed, it does not need to be changed.
+		image, err := ssoPostgresImage(ctx, k, c)
+		if err != nil {
+			return false, err
+		}
+		if len(image) == 0 {
+			secret.Data["DB_PASSWORD"] = []byte(password)
+			secret.Data["JGROUPS_CLUSTER_PASSWORD"] = []byte(jgroupsPassword)
+			return true, nil
+		}
+
+		// Record the new password before changing the database, so that the
+		// change can be completed if the operator restarts.
+		secret.Data[ssoDbPendingPasswordKey] = []byte(password)
+		annotations := secret.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string)
+		}
+		annotations[secretRotationStartedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		secret.SetAnnotations(annotations)
+		err = c.Update(ctx, secret)
+		if err != nil {
+			return false, err
+		}
+
+		reqLogger.Info("Changing the password of the SSO database user")
+		return false, createSsoDbRotationJob(ctx, k, c, image, reqLogger)
+	}
+
+	if !jobFound {
+		image, err := ssoPostgresImage(ctx, k, c)
+		if err != nil || len(image) == 0 {
+			return false, err
+		}
+		return false, createSsoDbRotationJob(ctx, k, c, image, reqLogger)
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			// The database uses the new password.  Save it before removing the Job.
+			jgroupsPassword, err := randSecret(32)
+			if err != nil {
+				return false, err
+			}
+			secret.Data["DB_PASSWORD"] = pending
+			secret.Data["JGROUPS_CLUSTER_PASSWORD"] = []byte(jgroupsPassword)
+			delete(secret.Data, ssoDbPendingPasswordKey)
+			err = c.Update(ctx, secret)
+			if err != nil {
+				return false, err
+			}
+
+			err = c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !kerrors.IsNotFound(err) {
+				reqLogger.Error(err, "Unable to delete Job "+ssoDbRotationJobName)
+			}
+			return true, nil
+
+		case batchv1.JobFailed:
+			// The database still uses the current password.  Give up on this
+			// rotation, and record the failure so that the next attempt backs
+			// off instead of creating a new Job on every reconcile.
+			delete(secret.Data, ssoDbPendingPasswordKey)
+			annotations := secret.GetAnnotations()
+			delete(annotations, secretRotationStartedAnnotation)
+			delete(annotations, secretRotateNowAnnotation)
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			reason := fmt.Sprintf("Job %v could not change the password of the SSO database user: %v", ssoDbRotationJobName, condition.Message)
+			attempts, _ := strconv.Atoi(annotations[secretRotationAttemptsAnnotation])
+			annotations[secretRotationFailedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+			annotations[secretRotationFailureReasonAnnotation] = reason
+			annotations[secretRotationAttemptsAnnotation] = strconv.Itoa(attempts + 1)
+			secret.SetAnnotations(annotations)
+			err = c.Update(ctx, secret)
+			if err != nil {
+				return false, err
+			}
+
+			err = c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !kerrors.IsNotFound(err) {
+				return false, err
+			}
+			return false, fmt.Errorf("%v", reason)
+		}
+	}
+
+	// Still running
+	return false, nil
+}
+
+// Returns the image of the SSO database, or an empty string if it is not deployed.
+func ssoPostgresImage(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) (string, error) {
+	dc := &openshiftappsv1.DeploymentConfig{}
+	err := c.Get(ctx, types.NamespacedName{Name: "sso-postgresql", Namespace: k.GetNamespace()}, dc)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	if dc.Spec.Template == nil || len(dc.Spec.Template.Spec.Containers) == 0 {
+		return "", nil
+	}
+	return dc.Spec.Template.Spec.Containers[0].Image, nil
+}
+
+// Creates the Job which changes the password of the SSO database user to the
+// pending password.
+func createSsoDbRotationJob(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, image string, reqLogger logr.Logger) error {
+	ownerRef, err := getOwnerReference(k, c, reqLogger)
+	if err != nil {
+		return err
+	}
+
+	secretEnv := func(name string, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: sso_db_secret_name},
+				Key:                  key,
+			}},
+		}
+	}
+
+	// The generated passwords only contain letters and digits, so they can be
+	// quoted safely.
+	script := `psql -h sso-postgresql -d root -v ON_ERROR_STOP=1 -c "ALTER USER \"$PGUSER\" WITH PASSWORD '$NEW_PASSWORD'"`
+
+	backoffLimit := int32(2)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ssoDbRotationJobName,
+			Namespace:       k.GetNamespace(),
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "rotate-password",
+						Image:   image,
+						Command: []string{"/bin/sh", "-c", script},
+						Env: []corev1.EnvVar{
+							secretEnv("PGUSER", "DB_USERNAME"),
+							secretEnv("PGPASSWORD", "DB_PASSWORD"),
+							secretEnv("NEW_PASSWORD", ssoDbPendingPasswordKey),
+						},
+					}},
+				},
+			},
+		},
+	}
+
+	err = c.Create(ctx, job)
+	if kerrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// Returns the sooner of two requeue delays, ignoring delays which are not set.
+func minRequeueAfter(a time.Duration, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b > 0 && b < a {
+		return b
+	}
+	return a
+}
+
+// Watches the generated secrets, so that adding the rotate now annotation
+// triggers a reconcile of the Kabanero instance which owns them.
+func watchSecretRotation(c controller.Controller) error {
+	rotateNowAdded := func(old map[string]string, new map[string]string) bool {
+		_, wasRequested := old[secretRotateNowAnnotation]
+		_, requested := new[secretRotateNowAnnotation]
+		return requested && !wasRequested
+	}
+
+	pred := predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return false },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return false },
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return rotateNowAdded(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+		},
+	}
+
+	owner := &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &kabanerov1alpha2.Kabanero{},
+	}
+
+	return c.Watch(&source.Kind{Type: &corev1.Secret{}}, owner, pred)
+}
@@ -0,0 +1,417 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	openshiftappsv1 "github.com/openshift/api/apps/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func generatedSecretAt(name string, generatedAt time.Time, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "kabanero",
+			Annotations: map[string]string{secretGeneratedAnnotation: generatedAt.UTC().Format(time.RFC3339)},
+		},
+		Data: data,
+	}
+}
+
+// Verifies the generated passwords follow the rules required by the SSO server
+func TestRandSecret(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		s, err := randSecret(4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(s) != 9 {
+			t.Fatalf("Expected the minimum length of 9, but got %v", s)
+		}
+
+		counts := map[string]int{}
+		for _, c := range s {
+			switch {
+			case strings.ContainsRune("0123456789", c):
+				counts["digits"]++
+			case strings.ContainsRune("abcdefghijklmnopqrstuvwxyz", c):
+				counts["lowers"]++
+			case strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZ", c):
+				counts["uppers"]++
+			default:
+				t.Fatalf("Unexpected character in %v", s)
+			}
+		}
+		for class, count := range counts {
+			if count < 2 {
+				t.Fatalf("Expected at least two %v in %v", class, s)
+			}
+		}
+	}
+}
+
+func TestSecretRotationDue(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		interval    time.Duration
+		due         bool
+		wait        time.Duration
+	}{
+		{"not rotated", map[string]string{secretGeneratedAnnotation: now.Add(-time.Hour).Format(time.RFC3339)}, 0, false, 0},
+		{"not due", map[string]string{secretGeneratedAnnotation: now.Add(-time.Hour).Format(time.RFC3339)}, 3 * time.Hour, false, 2 * time.Hour},
+		{"due", map[string]string{secretGeneratedAnnotation: now.Add(-time.Hour).Format(time.RFC3339)}, 30 * time.Minute, true, 0},
+		{"rotate now", map[string]string{secretRotateNowAnnotation: ""}, 0, true, 0},
+		{"in progress", map[string]string{secretRotationStartedAnnotation: now.Format(time.RFC3339)}, time.Hour, true, 0},
+		{"failed", map[string]string{
+			secretGeneratedAnnotation:        now.Add(-time.Hour).Format(time.RFC3339),
+			secretRotationFailedAnnotation:   now.Add(-time.Minute).Format(time.RFC3339),
+			secretRotationAttemptsAnnotation: "2",
+		}, 30 * time.Minute, false, 9 * time.Minute},
+		{"failed and backed off", map[string]string{
+			secretGeneratedAnnotation:        now.Add(-time.Hour).Format(time.RFC3339),
+			secretRotationFailedAnnotation:   now.Add(-time.Hour).Format(time.RFC3339),
+			secretRotationAttemptsAnnotation: "1",
+		}, 30 * time.Minute, true, 0},
+		{"failed and rotate now", map[string]string{
+			secretRotateNowAnnotation:      "",
+			secretRotationFailedAnnotation: now.Format(time.RFC3339),
+		}, 30 * time.Minute, true, 0},
+	}
+
+	for _, test := range tests {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations, CreationTimestamp: metav1.NewTime(now)}}
+		due, wait := secretRotationDue(secret, test.interval, now)
+		if due != test.due {
+			t.Fatalf("%v: expected due to be %v", test.name, test.due)
+		}
+		if wait.Round(time.Minute) != test.wait {
+			t.Fatalf("%v: expected to wait %v, but was %v", test.name, test.wait, wait)
+		}
+	}
+}
+
+// Verifies a secret which is due is regenerated, and the workload using it is restarted
+func TestRotateGeneratedSecrets(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Events:         kabanerov1alpha2.EventsCustomizationSpec{Enable: true},
+			SecretRotation: kabanerov1alpha2.SecretRotationSpec{Events: "24h", CliEncryptionKey: "720h"},
+		},
+	}
+
+	now := time.Now()
+	events := generatedSecretAt(eventsSecretName, now.Add(-25*time.Hour), map[string][]byte{"secret": []byte("old")})
+	cli := generatedSecretAt(cliEncryptionKeySecretName, now.Add(-time.Hour), map[string][]byte{"AESEncryptionKey": []byte("old")})
+	eventsDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kabanero-events", Namespace: "kabanero"}}
+	cl := newTestClient(t, k, events, cli, eventsDeployment)
+	ctx := context.Background()
+
+	requeueAfter := reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	if len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+
+	// The events secret is due again before the CLI key.
+	if requeueAfter != 24*time.Hour {
+		t.Fatalf("Expected to requeue when the events secret is next due, but was %v", requeueAfter)
+	}
+
+	events = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: eventsSecretName, Namespace: "kabanero"}, events); err != nil {
+		t.Fatal(err)
+	}
+	if string(events.Data["secret"]) == "old" || len(events.Data["secret"]) != 16 {
+		t.Fatalf("Expected the events secret to be regenerated: %v", string(events.Data["secret"]))
+	}
+	if generatedAt, _ := time.Parse(time.RFC3339, events.Annotations[secretGeneratedAnnotation]); now.Sub(generatedAt) > time.Minute {
+		t.Fatalf("Expected the generation time to be updated: %v", events.Annotations)
+	}
+
+	if err := cl.Get(ctx, types.NamespacedName{Name: "kabanero-events", Namespace: "kabanero"}, eventsDeployment); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := eventsDeployment.Spec.Template.Annotations[secretRotatedAnnotation]; !ok {
+		t.Fatal("Expected the events Deployment to be restarted")
+	}
+
+	// Rotate the CLI key on demand.  The CLI is not deployed, which is not an error.
+	if err := cl.Get(ctx, types.NamespacedName{Name: cliEncryptionKeySecretName, Namespace: "kabanero"}, cli); err != nil {
+		t.Fatal(err)
+	}
+	cli.Annotations[secretRotateNowAnnotation] = "true"
+	if err := cl.Update(ctx, cli); err != nil {
+		t.Fatal(err)
+	}
+
+	if reconcileSecretRotation(ctx, k, cl, logf.NullLogger{}); len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+	cli = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: cliEncryptionKeySecretName, Namespace: "kabanero"}, cli); err != nil {
+		t.Fatal(err)
+	}
+	if string(cli.Data["AESEncryptionKey"]) == "old" {
+		t.Fatal("Expected the CLI key to be regenerated")
+	}
+	if _, ok := cli.Annotations[secretRotateNowAnnotation]; ok {
+		t.Fatal("Expected the rotate now annotation to be removed")
+	}
+}
+
+// Verifies the SSO database password is changed by a Job before the secret is updated
+func TestRotateSsoDbSecret(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Sso:            kabanerov1alpha2.SsoCustomizationSpec{Enable: true, AdminSecretName: "sso-admin"},
+			SecretRotation: kabanerov1alpha2.SecretRotationSpec{SsoDatabase: "24h"},
+		},
+	}
+
+	secret := generatedSecretAt(sso_db_secret_name, time.Now().Add(-48*time.Hour), map[string][]byte{
+		"DB_USERNAME":              []byte("user"),
+		"DB_PASSWORD":              []byte("old"),
+		"JGROUPS_CLUSTER_PASSWORD": []byte("old"),
+	})
+	postgres := &openshiftappsv1.DeploymentConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "sso-postgresql", Namespace: "kabanero"},
+		Spec: openshiftappsv1.DeploymentConfigSpec{Template: &corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "postgresql", Image: "postgresql:10"}}},
+		}},
+	}
+	cl := newTestClient(t, k, secret, postgres)
+	ctx := context.Background()
+	secretName := types.NamespacedName{Name: sso_db_secret_name, Namespace: "kabanero"}
+	jobName := types.NamespacedName{Name: ssoDbRotationJobName, Namespace: "kabanero"}
+
+	// The first pass records the new password and starts the Job.
+	requeueAfter := reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	if len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+	if requeueAfter != secretRotationPollInterval {
+		t.Fatalf("Expected to check on the rotation in %v, but was %v", secretRotationPollInterval, requeueAfter)
+	}
+
+	if err := cl.Get(ctx, secretName, secret); err != nil {
+		t.Fatal(err)
+	}
+	pending := secret.Data[ssoDbPendingPasswordKey]
+	if len(pending) == 0 || string(secret.Data["DB_PASSWORD"]) != "old" {
+		t.Fatalf("Expected a pending password, with the current password unchanged: %v", secret.Data)
+	}
+
+	job := &batchv1.Job{}
+	if err := cl.Get(ctx, jobName, job); err != nil {
+		t.Fatal(err)
+	}
+	if job.Spec.Template.Spec.Containers[0].Image != "postgresql:10" {
+		t.Fatalf("Expected the Job to use the database image: %v", job.Spec.Template.Spec.Containers[0].Image)
+	}
+
+	// Nothing changes while the Job runs.
+	if reconcileSecretRotation(ctx, k, cl, logf.NullLogger{}); len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+	if err := cl.Get(ctx, secretName, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["DB_PASSWORD"]) != "old" {
+		t.Fatal("Expected the password to be unchanged while the Job runs")
+	}
+
+	// Once the Job completes, the secret uses the new password.
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if err := cl.Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	requeueAfter = reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	if len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+	if requeueAfter != 24*time.Hour {
+		t.Fatalf("Expected to requeue when the next rotation is due, but was %v", requeueAfter)
+	}
+
+	secret = &corev1.Secret{}
+	if err := cl.Get(ctx, secretName, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["DB_PASSWORD"]) != string(pending) || string(secret.Data["DB_USERNAME"]) != "user" {
+		t.Fatalf("Expected the secret to use the new password: %v", secret.Data)
+	}
+	if _, ok := secret.Data[ssoDbPendingPasswordKey]; ok {
+		t.Fatal("Expected the pending password to be removed")
+	}
+	if _, ok := secret.Annotations[secretRotationStartedAnnotation]; ok {
+		t.Fatal("Expected the rotation to be finished")
+	}
+
+	if err := cl.Get(ctx, jobName, job); err == nil {
+		t.Fatal("Expected the Job to be deleted")
+	}
+	if err := cl.Get(ctx, types.NamespacedName{Name: "sso-postgresql", Namespace: "kabanero"}, postgres); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := postgres.Spec.Template.Annotations[secretRotatedAnnotation]; !ok {
+		t.Fatal("Expected the database to be restarted")
+	}
+}
+
+// Verifies a failed password change leaves the current password in place
+func TestRotateSsoDbSecretFailed(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Sso: kabanerov1alpha2.SsoCustomizationSpec{Enable: true, AdminSecretName: "sso-admin"}},
+	}
+
+	secret := generatedSecretAt(sso_db_secret_name, time.Now(), map[string][]byte{
+		"DB_USERNAME":           []byte("user"),
+		"DB_PASSWORD":           []byte("old"),
+		ssoDbPendingPasswordKey: []byte("new"),
+	})
+	secret.Annotations[secretRotationStartedAnnotation] = time.Now().Format(time.RFC3339)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: ssoDbRotationJobName, Namespace: "kabanero"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+			{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"},
+		}},
+	}
+	cl := newTestClient(t, k, secret, job)
+	ctx := context.Background()
+
+	requeueAfter := reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	failures := k.Status.SecretRotationFailures
+	if len(failures) != 1 || failures[0].Name != sso_db_secret_name || !strings.Contains(failures[0].Message, "BackoffLimitExceeded") {
+		t.Fatalf("Expected the Job failure to be reported in the status, but got %v", failures)
+	}
+	if requeueAfter <= secretRotationPollInterval {
+		t.Fatalf("Expected the next attempt to back off, but got %v", requeueAfter)
+	}
+
+	secret = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: sso_db_secret_name, Namespace: "kabanero"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["DB_PASSWORD"]) != "old" || len(secret.Data[ssoDbPendingPasswordKey]) != 0 {
+		t.Fatalf("Expected the current password to be kept: %v", secret.Data)
+	}
+	if _, ok := secret.Annotations[secretRotationStartedAnnotation]; ok {
+		t.Fatal("Expected the rotation to be abandoned")
+	}
+	if _, ok := secret.Annotations[secretRotationFailedAnnotation]; !ok || secret.Annotations[secretRotationAttemptsAnnotation] != "1" {
+		t.Fatalf("Expected the failed attempt to be recorded: %v", secret.Annotations)
+	}
+
+	// The rotation is not attempted again until the backoff has passed, even
+	// though the secret is due, and the failure is still reported meanwhile.
+	k.Spec.SecretRotation.SsoDatabase = "1s"
+	for i := 0; i < 2; i++ {
+		requeueAfter = reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+		failures = k.Status.SecretRotationFailures
+		if len(failures) != 1 || failures[0].Name != sso_db_secret_name || !strings.Contains(failures[0].Message, "BackoffLimitExceeded") {
+			t.Fatalf("Expected the Job failure to still be reported in the status, but got %v", failures)
+		}
+		if requeueAfter <= secretRotationPollInterval {
+			t.Fatalf("Expected the next attempt to back off, but got %v", requeueAfter)
+		}
+	}
+	err := cl.Get(ctx, types.NamespacedName{Name: ssoDbRotationJobName, Namespace: "kabanero"}, &batchv1.Job{})
+	if err == nil {
+		t.Fatal("Expected no new Job to be created")
+	}
+}
+
+// A client which fails to update Deployments
+type failingDeploymentClient struct {
+	client.Client
+	fail bool
+}
+
+func (c *failingDeploymentClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	if _, ok := obj.(*appsv1.Deployment); ok && c.fail {
+		return fmt.Errorf("Deployment update failed")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+// Verifies a workload which cannot be restarted after a rotation is reported in
+// the status, the other secrets are still rotated, and the restart is retried
+func TestRotateGeneratedSecretsRestartFailed(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Events:         kabanerov1alpha2.EventsCustomizationSpec{Enable: true},
+			SecretRotation: kabanerov1alpha2.SecretRotationSpec{Events: "24h", CliEncryptionKey: "24h"},
+		},
+	}
+
+	now := time.Now()
+	events := generatedSecretAt(eventsSecretName, now.Add(-25*time.Hour), map[string][]byte{"secret": []byte("old")})
+	cli := generatedSecretAt(cliEncryptionKeySecretName, now.Add(-25*time.Hour), map[string][]byte{"AESEncryptionKey": []byte("old")})
+	eventsDeployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "kabanero-events", Namespace: "kabanero"}}
+	cl := &failingDeploymentClient{Client: newTestClient(t, k, events, cli, eventsDeployment), fail: true}
+	ctx := context.Background()
+
+	requeueAfter := reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	failures := k.Status.SecretRotationFailures
+	if len(failures) != 1 || failures[0].Name != eventsSecretName || !strings.Contains(failures[0].Message, "kabanero-events") {
+		t.Fatalf("Expected the events Deployment restart failure to be reported, but got %v", failures)
+	}
+	if requeueAfter != secretRotationPollInterval {
+		t.Fatalf("Expected to retry in %v, but was %v", secretRotationPollInterval, requeueAfter)
+	}
+
+	cli = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: cliEncryptionKeySecretName, Namespace: "kabanero"}, cli); err != nil {
+		t.Fatal(err)
+	}
+	if string(cli.Data["AESEncryptionKey"]) == "old" {
+		t.Fatal("Expected the CLI key to be rotated")
+	}
+
+	events = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: eventsSecretName, Namespace: "kabanero"}, events); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := events.Annotations[secretRestartPendingAnnotation]; !ok {
+		t.Fatalf("Expected the restart to be pending: %v", events.Annotations)
+	}
+	rotated := string(events.Data["secret"])
+
+	// The restart succeeds on the next reconcile, without rotating the secret again.
+	cl.fail = false
+	reconcileSecretRotation(ctx, k, cl, logf.NullLogger{})
+	if len(k.Status.SecretRotationFailures) != 0 {
+		t.Fatalf("Expected no rotation failures: %v", k.Status.SecretRotationFailures)
+	}
+	if err := cl.Get(ctx, types.NamespacedName{Name: "kabanero-events", Namespace: "kabanero"}, eventsDeployment); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := eventsDeployment.Spec.Template.Annotations[secretRotatedAnnotation]; !ok {
+		t.Fatal("Expected the events Deployment to be restarted")
+	}
+	events = &corev1.Secret{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: eventsSecretName, Namespace: "kabanero"}, events); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := events.Annotations[secretRestartPendingAnnotation]; ok || string(events.Data["secret"]) != rotated {
+		t.Fatalf("Expected the restart to be finished without another rotation: %v", events.Annotations)
+	}
+}
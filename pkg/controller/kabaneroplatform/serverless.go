@@ -25,6 +25,8 @@ const (
 // Returns the OpenShift serverless status. The status is based on the availability of the components
 // that make up OpenShift serverless.
 func getServerlessStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+	k.Status.Serverless.Compatible = ""
+
 	// Find the installed CSV name.
 	installedCSVName, err := getInstalledCSVName(k, c, reqLogger)
 	if err != nil {
@@ -46,6 +48,12 @@ func getServerlessStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogge
 	}
 	k.Status.Serverless.Version = csvVersion
 
+	// Installed, but possibly at a version the Kabanero version cannot use.
+	status := &k.Status.Serverless
+	if !checkComponentCompatibility(k, "serverless", csvVersion, &status.Compatible, &status.Ready, &status.Message) {
+		return false, nil
+	}
+
 	// Set the status. The serverless status is based on the status of the components that are part of
 	// the serverless operator.
 	ready, _ := getKnativeServingStatus(k, c, reqLogger)
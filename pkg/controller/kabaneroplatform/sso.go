@@ -4,9 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"strings"
-	"time"
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
@@ -18,7 +16,6 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -32,6 +29,11 @@ func reconcileSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 		return disableSso(ctx, k, c, reqLogger)
 	}
 
+	// An external provider replaces the bundled SSO server.
+	if k.Spec.Sso.External != nil {
+		return reconcileExternalSso(ctx, k, c, reqLogger)
+	}
+
 	// Figure out what version of the orchestration we are going to use.
 	noOverrideVersion := ""
 	rev, err := resolveSoftwareRevision(k, "sso", noOverrideVersion)
@@ -110,8 +112,9 @@ func reconcileSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Cl
 	if err != nil {
 		return err
 	}
-	
-	return nil
+
+	// Configure the realm and clients used by the Kabanero components.
+	return reconcileSsoRealm(ctx, k, c, reqLogger)
 }
 
 // Checks to make sure the secret required by the SSO configuration has
@@ -199,6 +202,13 @@ func disableSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Clie
 }
 
 func getSsoStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+	if isExternalSso(k) {
+		return getExternalSsoStatus(k, c, reqLogger)
+	}
+	k.Status.Sso.Issuer = ""
+	k.Status.Sso.DiscoveryReachable = ""
+	k.Status.Sso.JwksReachable = ""
+
 	// If SSO is not enabled, then there is no status to report.
 	if k.Spec.Sso.Enable == false {
 		k.Status.Sso.Configured = sso_false
@@ -301,73 +311,5 @@ func getSsoStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.
 
 // Creates the secret containing DB_USERNAME, DB_PASSWORD, JGROUPS_CLUSTER_PASSWORD
 func createDbSecret(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
-
-	// Check if the Secret already exists.
-	secretInstance := &corev1.Secret{}
-	err := c.Get(context.Background(), types.NamespacedName{
-		Name:      sso_db_secret_name,
-		Namespace: k.ObjectMeta.Namespace}, secretInstance)
-
-	if err != nil {
-		if kerrors.IsNotFound(err) == false {
-			return err
-		}
-
-		// Not found.  Make a new one.
-		var ownerRef metav1.OwnerReference
-		ownerRef, err = getOwnerReference(k, c, reqLogger)
-		if err != nil {
-			return err
-		}
-
-		secretInstance := &corev1.Secret{}
-		secretInstance.ObjectMeta.Name = sso_db_secret_name
-		secretInstance.ObjectMeta.Namespace = k.ObjectMeta.Namespace
-		secretInstance.ObjectMeta.OwnerReferences = append(secretInstance.ObjectMeta.OwnerReferences, ownerRef)
-
-		secretMap := make(map[string]string)
-		secretMap["DB_USERNAME"] = randSecret(16)
-		secretMap["DB_PASSWORD"] = randSecret(32)
-		secretMap["JGROUPS_CLUSTER_PASSWORD"] = randSecret(32)
-		
-		secretInstance.StringData = secretMap
-
-		reqLogger.Info(fmt.Sprintf("Attempting to create the SSO DB secret"))
-		err = c.Create(context.TODO(), secretInstance)
-	}
-
-	return err
-}
-
-
-// Generate a random username, password
-// Rules: Minimum Length: 9, 2 Digits, 2 Uppers, 2 Lowers
-// Specials may break sed in sso startup
-func randSecret(length int) string {
-
-	if length < 9 {
-		length = 9
-	}
-
-	rand.Seed(time.Now().UnixNano())
-	digits := "0123456789"
-	lowers := "abcdefghijklmnopqrstuvwxyz"
-	uppers := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
-	all := digits + lowers + uppers
-
-	buf := make([]byte, length)
-	buf[0] = digits[rand.Intn(len(digits))]
-	buf[1] = digits[rand.Intn(len(digits))]
-	buf[2] = lowers[rand.Intn(len(lowers))]
-	buf[3] = lowers[rand.Intn(len(lowers))]
-	buf[4] = uppers[rand.Intn(len(uppers))]
-	buf[5] = uppers[rand.Intn(len(uppers))]
-	for i := 6; i < length; i++ {
-			buf[i] = all[rand.Intn(len(all))]
-	}
-	rand.Shuffle(len(buf), func(i, j int) {
-			buf[i], buf[j] = buf[j], buf[i]
-	})
-	
-	return string(buf)
+	return createGeneratedSecret(context.Background(), k, c, sso_db_secret_name, generateSsoDbSecretData, reqLogger)
 }
@@ -0,0 +1,204 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kabTransforms "github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	mf "github.com/manifestival/manifestival"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Where the external provider client credentials are mounted in the components
+const externalSsoMountPath = "/etc/oidc"
+
+// The scopes requested from an external provider when none are specified
+var defaultOidcScopes = []string{"openid", "profile", "email"}
+
+// How long to wait for the external provider to respond
+const oidcTimeout = 30 * time.Second
+
+// Returns the HTTP client used to check the external provider.  Overridden by tests.
+var oidcHTTPClient = func() *http.Client { return cutils.NewHTTPClient(false) }
+
+// The parts of an OpenID Connect discovery document used by Kabanero
+type oidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// Determines if an external OpenID Connect provider is used instead of the bundled SSO server.
+func isExternalSso(k *kabanerov1alpha2.Kabanero) bool {
+	return k.Spec.Sso.Enable && k.Spec.Sso.External != nil
+}
+
+// Returns the scopes requested from the external provider.
+func externalSsoScopes(k *kabanerov1alpha2.Kabanero) []string {
+	if len(k.Spec.Sso.External.Scopes) == 0 {
+		return defaultOidcScopes
+	}
+	return k.Spec.Sso.External.Scopes
+}
+
+// Configures Kabanero to use an external provider.  The bundled SSO server is
+// not needed, so it is removed if it was previously deployed.
+func reconcileExternalSso(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	err := checkExternalSsoSecret(ctx, k, c)
+	if err != nil {
+		return err
+	}
+
+	return disableSso(ctx, k, c, reqLogger)
+}
+
+// Checks that the Secret containing the external provider client credentials
+// exists and contains the required keys.
+func checkExternalSsoSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client) error {
+	secretName := k.Spec.Sso.External.ClientSecretName
+	if len(secretName) == 0 {
+		return fmt.Errorf("The external SSO client secret name must be specified in the Kabanero CR instance")
+	}
+
+	secretInstance := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: secretName, Namespace: k.GetNamespace()}, secretInstance)
+	if err != nil {
+		return fmt.Errorf("Could not retrieve the external SSO client secret %v: %v", secretName, err)
+	}
+
+	for _, key := range []string{"clientId", "clientSecret"} {
+		if len(secretInstance.Data[key]) == 0 {
+			return fmt.Errorf("The external SSO client secret %v does not contain key '%v'", secretName, key)
+		}
+	}
+
+	return nil
+}
+
+// Returns the transforms which pass the external provider settings to a
+// component Deployment, or nothing if the external provider is not used.
+func externalSsoTransforms(k *kabanerov1alpha2.Kabanero) []mf.Transformer {
+	if !isExternalSso(k) {
+		return nil
+	}
+
+	external := k.Spec.Sso.External
+	return []mf.Transformer{
+		kabTransforms.AddEnvVariable("OIDC_ISSUER_URL", external.IssuerUrl),
+		kabTransforms.AddEnvVariable("OIDC_SCOPES", strings.Join(externalSsoScopes(k), " ")),
+		kabTransforms.AddEnvVariable("OIDC_CLIENT_CREDENTIALS", externalSsoMountPath),
+		kabTransforms.MountSecret(external.ClientSecretName, externalSsoMountPath),
+	}
+}
+
+// Reports whether the external provider can be used: the client secret must be
+// valid, and the discovery document and signing keys must be reachable.
+func getExternalSsoStatus(k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), oidcTimeout)
+	defer cancel()
+
+	k.Status.Sso.Configured = sso_true
+	k.Status.Sso.Ready = sso_false
+	k.Status.Sso.Message = ""
+	k.Status.Sso.Issuer = k.Spec.Sso.External.IssuerUrl
+	k.Status.Sso.DiscoveryReachable = sso_false
+	k.Status.Sso.JwksReachable = sso_false
+
+	err := checkExternalSsoSecret(ctx, k, c)
+	if err != nil {
+		k.Status.Sso.Message = err.Error()
+		return false, err
+	}
+
+	httpClient := oidcHTTPClient()
+	metadata, err := discoverOidcProvider(ctx, httpClient, k.Spec.Sso.External.IssuerUrl)
+	if err != nil {
+		k.Status.Sso.Message = err.Error()
+		return false, err
+	}
+	k.Status.Sso.DiscoveryReachable = sso_true
+
+	err = checkOidcJwks(ctx, httpClient, metadata.JwksUri)
+	if err != nil {
+		k.Status.Sso.Message = err.Error()
+		return false, err
+	}
+	k.Status.Sso.JwksReachable = sso_true
+
+	k.Status.Sso.Ready = sso_true
+	return true, nil
+}
+
+// Retrieves the discovery document of an OpenID Connect provider, and checks
+// that it describes the expected issuer.
+func discoverOidcProvider(ctx context.Context, httpClient *http.Client, issuer string) (*oidcProviderMetadata, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	discoveryURL := issuer + "/.well-known/openid-configuration"
+
+	metadata := &oidcProviderMetadata{}
+	err := getOidcDocument(ctx, httpClient, discoveryURL, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the OpenID Connect discovery document %v: %v", discoveryURL, err)
+	}
+
+	if strings.TrimSuffix(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("The OpenID Connect discovery document %v describes issuer %v, not %v", discoveryURL, metadata.Issuer, issuer)
+	}
+	if len(metadata.JwksUri) == 0 {
+		return nil, fmt.Errorf("The OpenID Connect discovery document %v does not contain a jwks_uri", discoveryURL)
+	}
+
+	return metadata, nil
+}
+
+// Checks that the signing keys of an OpenID Connect provider can be retrieved.
+func checkOidcJwks(ctx context.Context, httpClient *http.Client, jwksUri string) error {
+	jwks := struct {
+		Keys []json.RawMessage `json:"keys"`
+	}{}
+	err := getOidcDocument(ctx, httpClient, jwksUri, &jwks)
+	if err != nil {
+		return fmt.Errorf("Unable to retrieve the OpenID Connect signing keys %v: %v", jwksUri, err)
+	}
+	if len(jwks.Keys) == 0 {
+		return fmt.Errorf("The OpenID Connect signing keys %v do not contain any keys", jwksUri)
+	}
+	return nil
+}
+
+func getOidcDocument(ctx context.Context, httpClient *http.Client, url string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status code %v", resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, result)
+}
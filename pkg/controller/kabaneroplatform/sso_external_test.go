@@ -0,0 +1,106 @@
+package kabaneroplatform
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Returns a fake OpenID Connect provider.  The issuer reported in the discovery
+// document, and the signing keys, can be changed by the test.
+func newFakeOidcProvider(issuerSuffix *string, keys *string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/realms/corp/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer": "%v/realms/corp%v", "jwks_uri": "%v/realms/corp/certs"}`, server.URL, *issuerSuffix, server.URL)
+		case "/realms/corp/certs":
+			fmt.Fprintf(w, `{"keys": [%v]}`, *keys)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func newExternalSsoKabanero(issuer string) *kabanerov1alpha2.Kabanero {
+	return &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Sso: kabanerov1alpha2.SsoCustomizationSpec{
+				Enable:   true,
+				External: &kabanerov1alpha2.ExternalSsoSpec{IssuerUrl: issuer, ClientSecretName: "oidc-client"},
+			},
+		},
+	}
+}
+
+// Verifies the external provider status reports discovery and JWKS reachability
+func TestGetExternalSsoStatus(t *testing.T) {
+	issuerSuffix := ""
+	keys := `{"kty": "RSA", "kid": "1"}`
+	server := newFakeOidcProvider(&issuerSuffix, &keys)
+	defer server.Close()
+
+	defaultClient := oidcHTTPClient
+	oidcHTTPClient = server.Client
+	defer func() { oidcHTTPClient = defaultClient }()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "oidc-client", Namespace: "kabanero"},
+		Data:       map[string][]byte{"clientId": []byte("kabanero"), "clientSecret": []byte("secret")},
+	}
+	cl := newTestClient(t, secret)
+
+	k := newExternalSsoKabanero(server.URL + "/realms/corp/")
+	ready, err := getSsoStatus(k, cl, logf.NullLogger{})
+	if !ready || err != nil {
+		t.Fatalf("Expected the external provider to be ready, but got: %v", err)
+	}
+	if k.Status.Sso.DiscoveryReachable != sso_true || k.Status.Sso.JwksReachable != sso_true || k.Status.Sso.Issuer != server.URL+"/realms/corp/" {
+		t.Fatalf("Unexpected SSO status: %#v", k.Status.Sso)
+	}
+
+	// No signing keys
+	keys = ""
+	ready, _ = getSsoStatus(k, cl, logf.NullLogger{})
+	if ready || k.Status.Sso.DiscoveryReachable != sso_true || k.Status.Sso.JwksReachable != sso_false {
+		t.Fatalf("Expected the signing keys to be reported unreachable: %#v", k.Status.Sso)
+	}
+
+	// The discovery document describes a different issuer
+	issuerSuffix = "/other"
+	ready, _ = getSsoStatus(k, cl, logf.NullLogger{})
+	if ready || k.Status.Sso.DiscoveryReachable != sso_false {
+		t.Fatalf("Expected the discovery document to be rejected: %#v", k.Status.Sso)
+	}
+
+	// The client secret does not exist
+	k.Spec.Sso.External.ClientSecretName = "missing"
+	ready, _ = getSsoStatus(k, cl, logf.NullLogger{})
+	if ready || len(k.Status.Sso.Message) == 0 {
+		t.Fatalf("Expected the missing client secret to be reported: %#v", k.Status.Sso)
+	}
+}
+
+// Verifies the external provider settings are only injected when an external provider is used
+func TestExternalSsoTransforms(t *testing.T) {
+	k := newExternalSsoKabanero("https://idp.example.com/realms/corp")
+	if transforms := externalSsoTransforms(k); len(transforms) != 4 {
+		t.Fatalf("Expected four transforms, but got %v", len(transforms))
+	}
+	if scopes := externalSsoScopes(k); len(scopes) != 3 || scopes[0] != "openid" {
+		t.Fatalf("Expected the default scopes, but got %v", scopes)
+	}
+
+	k.Spec.Sso.Enable = false
+	if transforms := externalSsoTransforms(k); len(transforms) != 0 {
+		t.Fatalf("Expected no transforms when SSO is disabled, but got %v", len(transforms))
+	}
+}
@@ -0,0 +1,371 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	appsv1 "github.com/openshift/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The CA which signs OpenShift service serving certificates, such as the one
+// used by the SSO service.  OpenShift mounts it in every pod.
+const serviceCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+
+// The alias of the GitHub identity provider in the Kabanero realm
+const ssoGithubIdentityProvider = "github"
+
+// A Kabanero component which logs users in through the Kabanero realm.  The
+// client redirect URIs are taken from the component Route, and the generated
+// client secret is written to a Secret which the component mounts.
+type ssoComponentClient struct {
+	clientId   string
+	routeName  string
+	secretName string
+}
+
+var ssoComponentClients = []ssoComponentClient{
+	{clientId: "kabanero-landing", routeName: "kabanero-landing", secretName: "kabanero-landing-sso-client"},
+	{clientId: "kabanero-cli", routeName: "kabanero-cli", secretName: "kabanero-cli-sso-client"},
+	{clientId: "kabanero-events", routeName: "kabanero-events", secretName: "kabanero-events-sso-client"},
+}
+
+// The protocol mappers added to each component client.  The audience mapper
+// lets the component verify that a token was issued for it, and the groups
+// mapper lets it authorize users by group.
+func ssoProtocolMappers(clientId string) []keycloakProtocolMapper {
+	return []keycloakProtocolMapper{
+		{
+			Name:           "audience",
+			Protocol:       "openid-connect",
+			ProtocolMapper: "oidc-audience-mapper",
+			Config: map[string]string{
+				"included.client.audience": clientId,
+				"access.token.claim":       "true",
+				"id.token.claim":           "false",
+			},
+		},
+		{
+			Name:           "groups",
+			Protocol:       "openid-connect",
+			ProtocolMapper: "oidc-group-membership-mapper",
+			Config: map[string]string{
+				"claim.name":           "groups",
+				"full.path":            "false",
+				"access.token.claim":   "true",
+				"id.token.claim":       "true",
+				"userinfo.token.claim": "true",
+			},
+		},
+	}
+}
+
+// Builds the client used to reach the SSO admin API.  Overridden by tests.
+var newSsoAdminClient = func(ctx context.Context, k *kabanerov1alpha2.Kabanero, username string, password string) (*keycloakAdminClient, error) {
+	httpClient, err := ssoHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := fmt.Sprintf("https://sso.%v.svc:8443/auth", k.GetNamespace())
+	return newKeycloakAdminClient(ctx, baseURL, username, password, httpClient)
+}
+
+// Returns a HTTP client which trusts the OpenShift service serving certificates.
+func ssoHTTPClient() (*http.Client, error) {
+	transport := cutils.NewTransport(false)
+	if ca, err := ioutil.ReadFile(serviceCAFile); err == nil {
+		transport.TLSClientConfig.RootCAs.AppendCertsFromPEM(ca)
+	}
+
+	return &http.Client{Transport: transport, Timeout: 30 * time.Second}, nil
+}
+
+// Creates, or brings back in sync, the Kabanero realm in the SSO server: the
+// realm itself, a client for each Kabanero component, and optionally the GitHub
+// identity provider.  Nothing is done until the SSO server is available.
+func reconcileSsoRealm(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	available, err := isDeploymentConfigAvailable(ctx, c, "sso", k.GetNamespace())
+	if err != nil || !available {
+		reqLogger.Info("Waiting for the SSO server to be available before configuring the realm")
+		return nil
+	}
+
+	adminSecret := &corev1.Secret{}
+	err = c.Get(ctx, types.NamespacedName{Name: k.Spec.Sso.AdminSecretName, Namespace: k.GetNamespace()}, adminSecret)
+	if err != nil {
+		return err
+	}
+
+	ssoHost, err := getRouteHost(ctx, c, "sso", k.GetNamespace())
+	if err != nil {
+		reqLogger.Info(fmt.Sprintf("Waiting for the SSO Route before configuring the realm: %v", err))
+		return nil
+	}
+
+	admin, err := newSsoAdminClient(ctx, k, string(adminSecret.Data["username"]), string(adminSecret.Data["password"]))
+	if err != nil {
+		return err
+	}
+
+	return provisionSsoRealm(ctx, k, c, admin, string(adminSecret.Data["realm"]), "https://"+ssoHost, reqLogger)
+}
+
+// Provisions the realm using the SSO admin API.  The issuer base is the
+// external URL of the SSO server, which the components redirect users to.
+func provisionSsoRealm(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, admin *keycloakAdminClient, realm string, issuerBase string, reqLogger logr.Logger) error {
+	r, err := admin.getRealm(ctx, realm)
+	if err != nil {
+		return err
+	}
+	if r == nil {
+		reqLogger.Info(fmt.Sprintf("Creating SSO realm %v", realm))
+		err = admin.createRealm(ctx, &keycloakRealm{Realm: realm, Enabled: true, DisplayName: "Kabanero"})
+	} else if !r.Enabled {
+		r.Enabled = true
+		err = admin.updateRealm(ctx, r)
+	}
+	if err != nil {
+		return err
+	}
+
+	issuer := fmt.Sprintf("%v/auth/realms/%v", issuerBase, realm)
+	for _, component := range ssoComponentClients {
+		host, err := getRouteHost(ctx, c, component.routeName, k.GetNamespace())
+		if err != nil {
+			// The component may not be enabled, or its Route not admitted yet.
+			reqLogger.Info(fmt.Sprintf("Skipping SSO client %v: %v", component.clientId, err))
+			continue
+		}
+
+		err = provisionSsoClient(ctx, k, c, admin, realm, issuer, component, host, reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(k.Spec.Sso.GithubSecretName) > 0 {
+		err = provisionSsoGithubIdentityProvider(ctx, k, c, admin, realm, reqLogger)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Creates or updates the client of a component, its protocol mappers, and the
+// Secret holding the client credentials.
+func provisionSsoClient(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, admin *keycloakAdminClient, realm string, issuer string, component ssoComponentClient, host string, reqLogger logr.Logger) error {
+	desired := &keycloakClient{
+		ClientId:            component.clientId,
+		Name:                component.clientId,
+		Enabled:             true,
+		Protocol:            "openid-connect",
+		StandardFlowEnabled: true,
+		RedirectUris:        []string{"https://" + host + "/*"},
+		WebOrigins:          []string{"https://" + host},
+	}
+
+	current, err := admin.getClient(ctx, realm, component.clientId)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		reqLogger.Info(fmt.Sprintf("Creating SSO client %v in realm %v", component.clientId, realm))
+		err = admin.createClient(ctx, realm, desired)
+		if err != nil {
+			return err
+		}
+		current, err = admin.getClient(ctx, realm, component.clientId)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return fmt.Errorf("The SSO client %v was not found after it was created", component.clientId)
+		}
+	} else if !current.Enabled || current.PublicClient || !reflect.DeepEqual(current.RedirectUris, desired.RedirectUris) || !reflect.DeepEqual(current.WebOrigins, desired.WebOrigins) {
+		reqLogger.Info(fmt.Sprintf("Updating SSO client %v in realm %v", component.clientId, realm))
+		desired.Id = current.Id
+		err = admin.updateClient(ctx, realm, desired)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = provisionSsoProtocolMappers(ctx, admin, realm, current.Id, ssoProtocolMappers(component.clientId))
+	if err != nil {
+		return err
+	}
+
+	clientSecret, err := admin.getClientSecret(ctx, realm, current.Id)
+	if err != nil {
+		return err
+	}
+
+	return writeSsoClientSecret(ctx, k, c, component.secretName, map[string]string{
+		"clientId":     component.clientId,
+		"clientSecret": clientSecret,
+		"realm":        realm,
+		"issuer":       issuer,
+	}, reqLogger)
+}
+
+// Adds the protocol mappers which are missing from a client, and corrects those which changed.
+func provisionSsoProtocolMappers(ctx context.Context, admin *keycloakAdminClient, realm string, id string, desired []keycloakProtocolMapper) error {
+	current, err := admin.getProtocolMappers(ctx, realm, id)
+	if err != nil {
+		return err
+	}
+
+	for _, mapper := range desired {
+		found := false
+		for _, existing := range current {
+			if existing.Name != mapper.Name {
+				continue
+			}
+			found = true
+
+			if existing.ProtocolMapper != mapper.ProtocolMapper || !reflect.DeepEqual(existing.Config, mapper.Config) {
+				mapper.Id = existing.Id
+				err = admin.updateProtocolMapper(ctx, realm, id, &mapper)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if !found {
+			err = admin.createProtocolMapper(ctx, realm, id, &mapper)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Creates or updates the GitHub identity provider, using the OAuth application
+// credentials in the GitHub Secret named by the Kabanero instance.
+func provisionSsoGithubIdentityProvider(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, admin *keycloakAdminClient, realm string, reqLogger logr.Logger) error {
+	githubSecret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: k.Spec.Sso.GithubSecretName, Namespace: k.GetNamespace()}, githubSecret)
+	if err != nil {
+		return fmt.Errorf("Could not retrieve the SSO GitHub secret %v: %v", k.Spec.Sso.GithubSecretName, err)
+	}
+
+	clientId := string(githubSecret.Data["clientId"])
+	clientSecret := string(githubSecret.Data["clientSecret"])
+	if len(clientId) == 0 || len(clientSecret) == 0 {
+		return fmt.Errorf("The SSO GitHub secret %v must contain keys 'clientId' and 'clientSecret'", k.Spec.Sso.GithubSecretName)
+	}
+
+	desired := &keycloakIdentityProvider{
+		Alias:      ssoGithubIdentityProvider,
+		ProviderId: "github",
+		Enabled:    true,
+		TrustEmail: true,
+		Config: map[string]string{
+			"clientId":     clientId,
+			"clientSecret": clientSecret,
+			// The SSO server does not return the client secret, so the version of the
+			// Secret it came from is remembered to know when it must be updated.
+			"kabaneroSecretVersion": githubSecret.ResourceVersion,
+		},
+	}
+
+	current, err := admin.getIdentityProvider(ctx, realm, ssoGithubIdentityProvider)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		reqLogger.Info(fmt.Sprintf("Creating the GitHub identity provider in SSO realm %v", realm))
+		return admin.createIdentityProvider(ctx, realm, desired)
+	}
+
+	if !current.Enabled || current.Config["clientId"] != clientId || current.Config["kabaneroSecretVersion"] != githubSecret.ResourceVersion {
+		reqLogger.Info(fmt.Sprintf("Updating the GitHub identity provider in SSO realm %v", realm))
+		return admin.updateIdentityProvider(ctx, realm, desired)
+	}
+
+	return nil
+}
+
+// Creates or updates a Secret holding the credentials of an SSO client.
+func writeSsoClientSecret(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, name string, values map[string]string, reqLogger logr.Logger) error {
+	data := make(map[string][]byte)
+	for key, value := range values {
+		data[key] = []byte(value)
+	}
+
+	secret := &corev1.Secret{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: k.GetNamespace()}, secret)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+
+		ownerRef, err := getOwnerReference(k, c, reqLogger)
+		if err != nil {
+			return err
+		}
+
+		secret = &corev1.Secret{}
+		secret.Name = name
+		secret.Namespace = k.GetNamespace()
+		secret.OwnerReferences = append(secret.OwnerReferences, ownerRef)
+		secret.Data = data
+
+		reqLogger.Info(fmt.Sprintf("Creating SSO client secret %v", name))
+		return c.Create(ctx, secret)
+	}
+
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+
+	secret.Data = data
+	reqLogger.Info(fmt.Sprintf("Updating SSO client secret %v", name))
+	return c.Update(ctx, secret)
+}
+
+// Determines if a DeploymentConfig reports that it is available.
+func isDeploymentConfigAvailable(ctx context.Context, c client.Client, name string, namespace string) (bool, error) {
+	dc := &appsv1.DeploymentConfig{}
+	err := c.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, dc)
+	if err != nil {
+		return false, err
+	}
+
+	for _, condition := range dc.Status.Conditions {
+		if condition.Type == appsv1.DeploymentAvailable {
+			return condition.Status == corev1.ConditionTrue, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns the host of the first admitted ingress of a Route.
+func getRouteHost(ctx context.Context, c client.Client, routeName string, namespace string) (string, error) {
+	locations, err := getRouteLocations(routeName, namespace, c)
+	if err != nil {
+		return "", err
+	}
+
+	// The locations include the Route path, which is not part of the host.
+	return strings.SplitN(locations[0], "/", 2)[0], nil
+}
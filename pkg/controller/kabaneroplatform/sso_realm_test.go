@@ -0,0 +1,248 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// A fake of the parts of the Keycloak admin API used to provision the Kabanero realm.
+type fakeKeycloak struct {
+	mutex     sync.Mutex
+	realms    map[string]*keycloakRealm
+	clients   map[string]*keycloakClient
+	mappers   map[string][]keycloakProtocolMapper
+	idps      map[string]*keycloakIdentityProvider
+	mutations []string
+}
+
+func newFakeKeycloak() *fakeKeycloak {
+	return &fakeKeycloak{
+		realms:  make(map[string]*keycloakRealm),
+		clients: make(map[string]*keycloakClient),
+		mappers: make(map[string][]keycloakProtocolMapper),
+		idps:    make(map[string]*keycloakIdentityProvider),
+	}
+}
+
+func (f *fakeKeycloak) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if req.URL.Path == "/auth/realms/master/protocol/openid-connect/token" {
+		if req.FormValue("username") != "admin" || req.FormValue("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"access_token": "token"}`))
+		return
+	}
+
+	if req.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodGet {
+		f.mutations = append(f.mutations, req.Method+" "+req.URL.Path)
+	}
+
+	// /auth/admin/realms/{realm}/{resource}/{id}/...
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, "/auth/admin/realms"), "/"), "/")
+	decode := func(v interface{}) {
+		json.NewDecoder(req.Body).Decode(v)
+	}
+	respond := func(v interface{}) {
+		if v == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(v)
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "" && req.Method == http.MethodPost:
+		r := &keycloakRealm{}
+		decode(r)
+		f.realms[r.Realm] = r
+	case len(parts) == 1 && req.Method == http.MethodGet:
+		if r := f.realms[parts[0]]; r != nil {
+			respond(r)
+		} else {
+			respond(nil)
+		}
+	case len(parts) == 2 && parts[1] == "clients" && req.Method == http.MethodGet:
+		clients := []keycloakClient{}
+		for _, cl := range f.clients {
+			if cl.ClientId == req.URL.Query().Get("clientId") {
+				clients = append(clients, *cl)
+			}
+		}
+		respond(clients)
+	case len(parts) == 2 && parts[1] == "clients" && req.Method == http.MethodPost:
+		cl := &keycloakClient{}
+		decode(cl)
+		cl.Id = "id-" + cl.ClientId
+		f.clients[cl.Id] = cl
+	case len(parts) == 3 && parts[1] == "clients" && req.Method == http.MethodPut:
+		cl := &keycloakClient{}
+		decode(cl)
+		f.clients[parts[2]] = cl
+	case len(parts) == 4 && parts[3] == "client-secret":
+		respond(&keycloakCredential{Type: "secret", Value: "secret-of-" + parts[2]})
+	case len(parts) == 5 && parts[3] == "protocol-mappers" && req.Method == http.MethodGet:
+		respond(append([]keycloakProtocolMapper{}, f.mappers[parts[2]]...))
+	case len(parts) == 5 && parts[3] == "protocol-mappers" && req.Method == http.MethodPost:
+		mapper := keycloakProtocolMapper{}
+		decode(&mapper)
+		mapper.Id = fmt.Sprintf("mapper-%v", len(f.mappers[parts[2]]))
+		f.mappers[parts[2]] = append(f.mappers[parts[2]], mapper)
+	case len(parts) == 4 && parts[1] == "identity-provider" && req.Method == http.MethodGet:
+		if idp := f.idps[parts[3]]; idp != nil {
+			// The client secret is not returned by the SSO server.
+			masked := *idp
+			masked.Config = map[string]string{"clientId": idp.Config["clientId"], "clientSecret": "**********", "kabaneroSecretVersion": idp.Config["kabaneroSecretVersion"]}
+			respond(&masked)
+		} else {
+			respond(nil)
+		}
+	case len(parts) == 3 && parts[1] == "identity-provider" && req.Method == http.MethodPost:
+		idp := &keycloakIdentityProvider{}
+		decode(idp)
+		f.idps[idp.Alias] = idp
+	case len(parts) == 4 && parts[1] == "identity-provider" && req.Method == http.MethodPut:
+		idp := &keycloakIdentityProvider{}
+		decode(idp)
+		f.idps[parts[3]] = idp
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func admittedRoute(name string, host string) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kabanero"},
+		Status: routev1.RouteStatus{Ingress: []routev1.RouteIngress{{
+			Host:       host,
+			Conditions: []routev1.RouteIngressCondition{{Type: routev1.RouteAdmitted, Status: corev1.ConditionTrue}},
+		}}},
+	}
+}
+
+func newSsoRealmClient(t *testing.T) (*kabanerov1alpha2.Kabanero, client.Client) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", UID: "kabanero-uid"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Sso: kabanerov1alpha2.SsoCustomizationSpec{Enable: true, AdminSecretName: "sso-admin", GithubSecretName: "sso-github"},
+		},
+	}
+	github := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "sso-github", Namespace: "kabanero"},
+		Data:       map[string][]byte{"clientId": []byte("github-client"), "clientSecret": []byte("github-secret")},
+	}
+
+	// The events component is not enabled, so it has no Route.
+	return k, newTestClient(t, k, github,
+		admittedRoute("kabanero-landing", "landing.apps.example.com"),
+		admittedRoute("kabanero-cli", "cli.apps.example.com"))
+}
+
+// Verifies the realm, component clients, mappers, client secrets and GitHub identity provider are created
+func TestProvisionSsoRealm(t *testing.T) {
+	keycloak := newFakeKeycloak()
+	server := httptest.NewServer(keycloak)
+	defer server.Close()
+
+	k, cl := newSsoRealmClient(t)
+	ctx := context.Background()
+	reqLogger := logf.NullLogger{}
+
+	admin, err := newKeycloakAdminClient(ctx, server.URL+"/auth", "admin", "secret", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = provisionSsoRealm(ctx, k, cl, admin, "kabanero", "https://sso.apps.example.com", reqLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := keycloak.realms["kabanero"]; r == nil || !r.Enabled {
+		t.Fatalf("Expected the realm to be created: %v", r)
+	}
+
+	landing := keycloak.clients["id-kabanero-landing"]
+	if landing == nil || len(landing.RedirectUris) != 1 || landing.RedirectUris[0] != "https://landing.apps.example.com/*" {
+		t.Fatalf("Expected the landing client to redirect to the landing Route: %v", landing)
+	}
+	if keycloak.clients["id-kabanero-events"] != nil {
+		t.Fatal("Expected no events client, since the events Route does not exist")
+	}
+	if mappers := keycloak.mappers["id-kabanero-cli"]; len(mappers) != 2 {
+		t.Fatalf("Expected two protocol mappers on the CLI client: %v", mappers)
+	}
+
+	secret := &corev1.Secret{}
+	err = cl.Get(ctx, types.NamespacedName{Name: "kabanero-cli-sso-client", Namespace: "kabanero"}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(secret.Data["clientSecret"]) != "secret-of-id-kabanero-cli" || string(secret.Data["issuer"]) != "https://sso.apps.example.com/auth/realms/kabanero" {
+		t.Fatalf("Unexpected CLI client secret contents: %v", secret.Data)
+	}
+
+	if idp := keycloak.idps["github"]; idp == nil || idp.Config["clientSecret"] != "github-secret" {
+		t.Fatalf("Expected the GitHub identity provider to be created: %v", idp)
+	}
+
+	// A second pass finds everything in sync.
+	keycloak.mutations = nil
+	err = provisionSsoRealm(ctx, k, cl, admin, "kabanero", "https://sso.apps.example.com", reqLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keycloak.mutations) != 0 {
+		t.Fatalf("Expected no changes to the realm, but found: %v", keycloak.mutations)
+	}
+
+	// A changed Route host updates the client redirect URIs.
+	route := &routev1.Route{}
+	if err = cl.Get(ctx, types.NamespacedName{Name: "kabanero-landing", Namespace: "kabanero"}, route); err != nil {
+		t.Fatal(err)
+	}
+	route.Status.Ingress[0].Host = "kabanero.example.com"
+	if err = cl.Update(ctx, route); err != nil {
+		t.Fatal(err)
+	}
+
+	err = provisionSsoRealm(ctx, k, cl, admin, "kabanero", "https://sso.apps.example.com", reqLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if landing := keycloak.clients["id-kabanero-landing"]; landing.RedirectUris[0] != "https://kabanero.example.com/*" {
+		t.Fatalf("Expected the landing client redirect URIs to be updated: %v", landing.RedirectUris)
+	}
+}
+
+// Verifies the admin login is rejected with the wrong credentials
+func TestKeycloakAdminLoginFailure(t *testing.T) {
+	server := httptest.NewServer(newFakeKeycloak())
+	defer server.Close()
+
+	_, err := newKeycloakAdminClient(context.Background(), server.URL+"/auth", "admin", "wrong", server.Client())
+	if err == nil {
+		t.Fatal("Expected the login to fail")
+	}
+}
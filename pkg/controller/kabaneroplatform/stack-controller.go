@@ -41,7 +41,7 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		logger.Error(err, "Kabanero stack controller deployment failed. Unable to process image overrides.")
 		return err
 	}
-	templateCtx["image"] = image
+	templateCtx["image"] = resolveComponentImage(ctx, k, c, "stack-controller", image)
 
 	f, err := rev.OpenOrchestration(scOrchestrationFileName)
 	if err != nil {
@@ -62,6 +62,8 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 		mf.InjectOwner(k),
 		mf.InjectNamespace(k.GetNamespace()),
 	}
+	transforms = append(transforms, outboundProxyTransforms()...)
+	transforms = append(transforms, vaultSettingsTransforms()...)
 
 	m, err := mOrig.Transform(transforms...)
 	if err != nil {
@@ -75,7 +77,8 @@ func reconcileStackController(ctx context.Context, k *kabanerov1alpha2.Kabanero,
 
 	// Create a RoleBinding in the tekton-pipelines namespace that will allow
 	// the stack controller to create triggerbinding and triggertemplate
-	// objects in the tekton-pipelines namespace.
+	// objects in the tekton-pipelines namespace, and a ClusterRoleBinding
+	// that allows it to read the Tekton custom resource definitions.
 	templateCtx["name"] = "kabanero-" + k.GetNamespace() + "-trigger-rolebinding"
 	templateCtx["kabaneroNamespace"] = k.GetNamespace()
 
@@ -0,0 +1,320 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	kutils "github.com/kabanero-io/kabanero-operator/pkg/controller/kabaneroplatform/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	olmapiv1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// A prerequisite operator subscription which the Kabanero instance may manage
+type managedSubscription struct {
+	// The prerequisite name, as used in versions.yaml
+	name string
+
+	// Selects the customization from the Kabanero instance
+	spec func(*kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec
+}
+
+// The subscriptions which may be managed, in the order they are installed
+var managedSubscriptions = []managedSubscription{
+	{name: "tekton", spec: func(k *kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec {
+		return k.Spec.Subscriptions.Tekton
+	}},
+	{name: "serverless", spec: func(k *kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec {
+		return k.Spec.Subscriptions.Serverless
+	}},
+	{name: "appsody", spec: func(k *kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec {
+		return k.Spec.Subscriptions.Appsody
+	}},
+	{name: "codeready-workspaces", spec: func(k *kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec {
+		return k.Spec.Subscriptions.CodereadyWorkspaces
+	}},
+	{name: "kappnav", spec: func(k *kabanerov1alpha2.Kabanero) kabanerov1alpha2.SubscriptionCustomizationSpec {
+		return k.Spec.Subscriptions.Kappnav
+	}},
+}
+
+// Creates, updates or removes the subscriptions for the prerequisite operators
+// which the Kabanero instance manages.  Subscriptions which were not created
+// by the Kabanero instance are never modified.
+func reconcileSubscriptions(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	for _, managed := range managedSubscriptions {
+		customization := managed.spec(k)
+		sub, err := resolveSubscription(k, managed.name, customization)
+		if err != nil {
+			// Reported in the status when enabled.  Otherwise nothing is known
+			// about the subscription, so there is nothing to remove.
+			continue
+		}
+
+		existing, err := kutils.GetSubscription(c, sub.Namespace, sub.Package)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil && !isManagedSubscription(k, existing) {
+			// Installed some other way.  Leave it alone.
+			continue
+		}
+
+		if !customization.Enable {
+			if existing != nil {
+				reqLogger.Info(fmt.Sprintf("Removing the %v subscription", managed.name))
+				err = deleteSubscription(c, existing)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		desired := newSubscription(k, sub)
+		if existing == nil {
+			reqLogger.Info(fmt.Sprintf("Creating the %v subscription in namespace %v", managed.name, sub.Namespace))
+			err = c.Create(ctx, desired)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Only the fields set by the operator are compared, so that defaults
+		// applied by OLM do not cause repeated updates.
+		desiredSpec, _, _ := unstructured.NestedMap(desired.Object, "spec")
+		existingSpec, _, _ := unstructured.NestedMap(existing.Object, "spec")
+		if existingSpec == nil {
+			existingSpec = make(map[string]interface{})
+		}
+		changed := false
+		for key, value := range desiredSpec {
+			if existingSpec[key] != value {
+				existingSpec[key] = value
+				changed = true
+			}
+		}
+		if changed {
+			reqLogger.Info(fmt.Sprintf("Updating the %v subscription in namespace %v", managed.name, sub.Namespace))
+			err = unstructured.SetNestedMap(existing.Object, existingSpec, "spec")
+			if err != nil {
+				return err
+			}
+			err = c.Update(ctx, existing)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Removes the subscriptions managed by the Kabanero instance.
+func cleanupSubscriptions(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	for _, managed := range managedSubscriptions {
+		sub, err := resolveSubscription(k, managed.name, managed.spec(k))
+		if err != nil {
+			continue
+		}
+
+		existing, err := kutils.GetSubscription(c, sub.Namespace, sub.Package)
+		if err != nil {
+			return err
+		}
+
+		if existing != nil && isManagedSubscription(k, existing) {
+			reqLogger.Info(fmt.Sprintf("Removing the %v subscription", managed.name))
+			err = deleteSubscription(c, existing)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Resolves the subscription details from the version catalogue, with the
+// overrides from the Kabanero instance applied.
+func resolveSubscription(k *kabanerov1alpha2.Kabanero, name string, customization kabanerov1alpha2.SubscriptionCustomizationSpec) (versioning.SubscriptionRevision, error) {
+	doc, kabaneroVersion := resolveKabaneroVersion(k)
+	rev := doc.KabaneroRevision(kabaneroVersion)
+	if rev == nil {
+		return versioning.SubscriptionRevision{}, fmt.Errorf("Data related to the Kabanero release identifier `%v` cannot be found", kabaneroVersion)
+	}
+
+	sub, ok := rev.Subscriptions[name]
+	if !ok {
+		return versioning.SubscriptionRevision{}, fmt.Errorf("No subscription for %v is declared for Kabanero version %v", name, kabaneroVersion)
+	}
+
+	if sub.Namespace == "" {
+		sub.Namespace = k.GetNamespace()
+	}
+	if customization.Channel != "" {
+		sub.Channel = customization.Channel
+	}
+	if customization.Source != "" {
+		sub.Source = customization.Source
+	}
+	if customization.SourceNamespace != "" {
+		sub.SourceNamespace = customization.SourceNamespace
+	}
+	if customization.Approval != "" {
+		sub.Approval = customization.Approval
+	}
+	if customization.StartingCSV != "" {
+		sub.StartingCSV = customization.StartingCSV
+	}
+	if sub.Approval == "" {
+		sub.Approval = string(olmapiv1alpha1.ApprovalAutomatic)
+	}
+
+	return sub, nil
+}
+
+// Builds the subscription object.
+func newSubscription(k *kabanerov1alpha2.Kabanero, sub versioning.SubscriptionRevision) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   olmapiv1alpha1.GroupName,
+		Version: olmapiv1alpha1.GroupVersion,
+		Kind:    olmapiv1alpha1.SubscriptionKind,
+	})
+	u.SetName(sub.Package)
+	u.SetNamespace(sub.Namespace)
+	u.SetLabels(ownerLabels(k))
+
+	spec := map[string]interface{}{
+		"name":                sub.Package,
+		"channel":             sub.Channel,
+		"source":              sub.Source,
+		"installPlanApproval": sub.Approval,
+	}
+	if sub.SourceNamespace != "" {
+		spec["sourceNamespace"] = sub.SourceNamespace
+	}
+	if sub.StartingCSV != "" {
+		spec["startingCSV"] = sub.StartingCSV
+	}
+	u.Object["spec"] = spec
+
+	return u
+}
+
+// Determines if the subscription was created by the Kabanero instance.  Owner
+// references cannot be used because the subscriptions are usually in another namespace.
+func isManagedSubscription(k *kabanerov1alpha2.Kabanero, sub *unstructured.Unstructured) bool {
+	return isLabelledOwner(k, sub)
+}
+
+// Deletes the subscription.  The CSV it installed is left in place: it is
+// created by OLM, so nothing shows that this Kabanero instance owns it, and it
+// may provide an operator which other subscriptions or users depend on.
+func deleteSubscription(c client.Client, sub *unstructured.Unstructured) error {
+	err := c.Delete(context.TODO(), sub)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Retrieves the status of the managed subscriptions, including the approval
+// state of any pending InstallPlan.
+func getSubscriptionsStatus(k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error) {
+	ready := true
+	var statuses []kabanerov1alpha2.SubscriptionStatus
+
+	for _, managed := range managedSubscriptions {
+		customization := managed.spec(k)
+		if !customization.Enable {
+			continue
+		}
+
+		status := kabanerov1alpha2.SubscriptionStatus{Name: managed.name, Ready: "False"}
+		statuses = append(statuses, getSubscriptionStatus(k, c, managed.name, customization, status))
+		if statuses[len(statuses)-1].Ready != "True" {
+			ready = false
+		}
+	}
+
+	k.Status.Subscriptions = statuses
+	if !ready {
+		return false, fmt.Errorf("One or more managed subscriptions are not ready")
+	}
+
+	return true, nil
+}
+
+// Retrieves the status of a single managed subscription.
+func getSubscriptionStatus(k *kabanerov1alpha2.Kabanero, c client.Client, name string, customization kabanerov1alpha2.SubscriptionCustomizationSpec, status kabanerov1alpha2.SubscriptionStatus) kabanerov1alpha2.SubscriptionStatus {
+	sub, err := resolveSubscription(k, name, customization)
+	if err != nil {
+		status.Message = err.Error()
+		return status
+	}
+
+	status.Namespace = sub.Namespace
+	status.Package = sub.Package
+	status.Channel = sub.Channel
+	status.InstallPlanApproval = sub.Approval
+
+	existing, err := kutils.GetSubscription(c, sub.Namespace, sub.Package)
+	if err != nil {
+		status.Message = "Unable to retrieve the subscription: " + err.Error()
+		return status
+	}
+	if existing == nil {
+		status.Message = fmt.Sprintf("The subscription for %v has not been created", sub.Package)
+		return status
+	}
+	if !isManagedSubscription(k, existing) {
+		status.Message = fmt.Sprintf("The subscription %v/%v was not created by this Kabanero instance and is not managed", existing.GetNamespace(), existing.GetName())
+		return status
+	}
+
+	status.InstalledCSV, _, _ = unstructured.NestedString(existing.Object, "status", "installedCSV")
+	status.InstallPlan, _, _ = unstructured.NestedString(existing.Object, "status", "installPlanRef", "name")
+
+	if status.InstallPlan != "" {
+		installPlan, err := kutils.GetInstallPlan(c, client.ObjectKey{Namespace: sub.Namespace, Name: status.InstallPlan})
+		if err != nil {
+			status.Message = fmt.Sprintf("Unable to retrieve InstallPlan %v: %v", status.InstallPlan, err)
+			return status
+		}
+
+		status.InstallPlanPhase, _, _ = unstructured.NestedString(installPlan.Object, "status", "phase")
+		approved, _, _ := unstructured.NestedBool(installPlan.Object, "spec", "approved")
+		status.InstallPlanApproved = "False"
+		if approved {
+			status.InstallPlanApproved = "True"
+		}
+
+		switch status.InstallPlanPhase {
+		case string(olmapiv1alpha1.InstallPlanPhaseRequiresApproval):
+			status.Message = fmt.Sprintf("InstallPlan %v requires approval. Approve it by setting spec.approved to true: oc patch installplan %v -n %v --type merge -p '{\"spec\":{\"approved\":true}}'",
+				status.InstallPlan, status.InstallPlan, sub.Namespace)
+			return status
+		case string(olmapiv1alpha1.InstallPlanPhaseFailed):
+			status.Message = fmt.Sprintf("InstallPlan %v failed. Run oc describe installplan %v -n %v for details", status.InstallPlan, status.InstallPlan, sub.Namespace)
+			return status
+		}
+	}
+
+	if status.InstalledCSV == "" {
+		status.Message = fmt.Sprintf("Waiting for the %v operator to be installed", sub.Package)
+		return status
+	}
+
+	status.Ready = "True"
+	return status
+}
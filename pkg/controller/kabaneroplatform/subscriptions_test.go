@@ -0,0 +1,201 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	olmapiv1alpha1 "github.com/operator-framework/operator-lifecycle-manager/pkg/api/apis/operators/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Retrieves an OLM object, or nil if it does not exist.
+func getOLMObject(t *testing.T, c client.Client, kind string, namespace string, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: olmapiv1alpha1.GroupName, Version: olmapiv1alpha1.GroupVersion, Kind: kind})
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, u)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// Simulates OLM resolving a subscription and generating an InstallPlan.
+func resolveTestSubscription(t *testing.T, c client.Client, namespace string, pkg string, csv string, installPlanPhase string) {
+	ctx := context.Background()
+	sub := getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, namespace, pkg)
+	unstructured.SetNestedField(sub.Object, csv, "status", "installedCSV")
+	unstructured.SetNestedField(sub.Object, "install-"+pkg, "status", "installPlanRef", "name")
+	if err := c.Update(ctx, sub); err != nil {
+		t.Fatal(err)
+	}
+
+	ip := &unstructured.Unstructured{}
+	ip.SetGroupVersionKind(sub.GroupVersionKind().GroupVersion().WithKind(olmapiv1alpha1.InstallPlanKind))
+	ip.SetNamespace(namespace)
+	ip.SetName("install-" + pkg)
+	unstructured.SetNestedField(ip.Object, installPlanPhase == string(olmapiv1alpha1.InstallPlanPhaseComplete), "spec", "approved")
+	unstructured.SetNestedField(ip.Object, installPlanPhase, "status", "phase")
+	if err := c.Create(ctx, ip); err != nil {
+		t.Fatal(err)
+	}
+
+	csvObj := &unstructured.Unstructured{}
+	csvObj.SetGroupVersionKind(sub.GroupVersionKind().GroupVersion().WithKind(olmapiv1alpha1.ClusterServiceVersionKind))
+	csvObj.SetNamespace(namespace)
+	csvObj.SetName(csv)
+	if err := c.Create(ctx, csvObj); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newSubscriptionKabanero() *kabanerov1alpha2.Kabanero {
+	return &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Version: "0.7.0",
+			Subscriptions: kabanerov1alpha2.PrerequisiteSubscriptionsSpec{
+				Tekton:  kabanerov1alpha2.SubscriptionCustomizationSpec{Enable: true},
+				Appsody: kabanerov1alpha2.SubscriptionCustomizationSpec{Enable: true, Channel: "beta", Approval: "Manual"},
+			},
+		},
+	}
+}
+
+// Verifies enabled subscriptions are created from the version catalogue
+func TestReconcileSubscriptionsCreate(t *testing.T) {
+	c := newTestClient(t)
+	k := newSubscriptionKabanero()
+
+	err := reconcileSubscriptions(context.Background(), k, c, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tekton := getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "openshift-pipelines-operator")
+	if tekton == nil {
+		t.Fatal("The tekton subscription was not created")
+	}
+	if channel, _, _ := unstructured.NestedString(tekton.Object, "spec", "channel"); channel != "kabanero-0.6" {
+		t.Fatalf("Expected the tekton channel from the version catalogue but was %v", channel)
+	}
+
+	appsody := getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "appsody-operator-certified")
+	if appsody == nil {
+		t.Fatal("The appsody subscription was not created")
+	}
+	if channel, _, _ := unstructured.NestedString(appsody.Object, "spec", "channel"); channel != "beta" {
+		t.Fatalf("Expected the appsody channel override but was %v", channel)
+	}
+
+	subs := &unstructured.UnstructuredList{}
+	subs.SetGroupVersionKind(tekton.GroupVersionKind().GroupVersion().WithKind(olmapiv1alpha1.SubscriptionKind + "List"))
+	if err := c.List(context.Background(), subs); err != nil {
+		t.Fatal(err)
+	}
+	if len(subs.Items) != 2 {
+		t.Fatalf("Expected only the enabled subscriptions to be created: %v", subs.Items)
+	}
+}
+
+// Verifies InstallPlan approval is reported in status
+func TestSubscriptionsStatusRequiresApproval(t *testing.T) {
+	c := newTestClient(t)
+	k := newSubscriptionKabanero()
+
+	err := reconcileSubscriptions(context.Background(), k, c, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolveTestSubscription(t, c, "openshift-operators", "openshift-pipelines-operator", "openshift-pipelines-operator.v0.10.7", string(olmapiv1alpha1.InstallPlanPhaseComplete))
+	resolveTestSubscription(t, c, "openshift-operators", "appsody-operator-certified", "appsody-operator.v0.4.0", string(olmapiv1alpha1.InstallPlanPhaseRequiresApproval))
+
+	ready, _ := getSubscriptionsStatus(k, c)
+	if ready {
+		t.Fatal("Expected the subscriptions to be not ready")
+	}
+
+	if len(k.Status.Subscriptions) != 2 {
+		t.Fatalf("Expected status for two subscriptions: %v", k.Status.Subscriptions)
+	}
+
+	for _, status := range k.Status.Subscriptions {
+		switch status.Name {
+		case "tekton":
+			if status.Ready != "True" || status.InstalledCSV != "openshift-pipelines-operator.v0.10.7" {
+				t.Fatalf("Expected the tekton subscription to be ready: %v", status)
+			}
+		case "appsody":
+			if status.Ready != "False" || status.InstallPlanPhase != "RequiresApproval" || status.InstallPlanApproved != "False" || status.InstallPlanApproval != "Manual" {
+				t.Fatalf("Expected the appsody InstallPlan to require approval: %v", status)
+			}
+		}
+	}
+}
+
+// Verifies disabling a managed subscription removes the subscription but not its CSV,
+// and subscriptions created outside of Kabanero are left alone
+func TestReconcileSubscriptionsDisable(t *testing.T) {
+	c := newTestClient(t)
+	k := newSubscriptionKabanero()
+
+	err := reconcileSubscriptions(context.Background(), k, c, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolveTestSubscription(t, c, "openshift-operators", "openshift-pipelines-operator", "openshift-pipelines-operator.v0.10.7", string(olmapiv1alpha1.InstallPlanPhaseComplete))
+
+	// An appsody subscription created by hand
+	appsody := getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "appsody-operator-certified")
+	appsody.SetLabels(nil)
+	if err := c.Update(context.Background(), appsody); err != nil {
+		t.Fatal(err)
+	}
+
+	k.Spec.Subscriptions.Tekton.Enable = false
+	k.Spec.Subscriptions.Appsody.Enable = false
+	err = reconcileSubscriptions(context.Background(), k, c, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "openshift-pipelines-operator") != nil {
+		t.Fatal("The tekton subscription was not removed")
+	}
+	if getOLMObject(t, c, olmapiv1alpha1.ClusterServiceVersionKind, "openshift-operators", "openshift-pipelines-operator.v0.10.7") == nil {
+		t.Fatal("The tekton CSV was removed")
+	}
+	if getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "appsody-operator-certified") == nil {
+		t.Fatal("The unmanaged appsody subscription was removed")
+	}
+}
+
+// Verifies the kAppNav subscription is created from the version catalogue
+func TestReconcileSubscriptionsKappnav(t *testing.T) {
+	c := newTestClient(t)
+	k := newSubscriptionKabanero()
+	k.Spec.Subscriptions = kabanerov1alpha2.PrerequisiteSubscriptionsSpec{
+		Kappnav: kabanerov1alpha2.SubscriptionCustomizationSpec{Enable: true},
+	}
+
+	err := reconcileSubscriptions(context.Background(), k, c, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kappnav := getOLMObject(t, c, olmapiv1alpha1.SubscriptionKind, "openshift-operators", "kappnav")
+	if kappnav == nil {
+		t.Fatal("The kappnav subscription was not created")
+	}
+	if channel, _, _ := unstructured.NestedString(kappnav.Object, "spec", "channel"); channel != "alpha" {
+		t.Fatalf("Expected the kappnav channel from the version catalogue but was %v", channel)
+	}
+}
@@ -14,6 +14,7 @@ import (
 func getTektonStatus(k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error) {
 	k.Status.Tekton.Message = ""
 	k.Status.Tekton.Ready = "False"
+	k.Status.Tekton.Compatible = ""
 
 	// Get the tekton instance.
 	tektonInstName := "cluster"
@@ -31,6 +32,11 @@ func getTektonStatus(k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error
 
 	// Starting with version 0.5.*, the first condition in the list is the one that matters.
 	// The state of an installation can be: installing, installed, or error.
+	if len(tekton.Status.Conditions) == 0 {
+		k.Status.Tekton.Message = "Tekton instance with the name of " + tektonInstName + " has not reported an installation status."
+		return false, nil
+	}
+
 	ready := false
 	readyCondition := tekton.Status.Conditions[0]
 	k.Status.Tekton.Version = readyCondition.Version
@@ -38,6 +44,12 @@ func getTektonStatus(k *kabanerov1alpha2.Kabanero, c client.Client) (bool, error
 	if code == "error" {
 		k.Status.Tekton.Message = readyCondition.Details
 	} else if code == "installed" {
+		// Installed, but possibly at a version the Kabanero version cannot use.
+		status := &k.Status.Tekton
+		if !checkComponentCompatibility(k, "tekton", readyCondition.Version, &status.Compatible, &status.Ready, &status.Message) {
+			return false, nil
+		}
+
 		ready = true
 		k.Status.Tekton.Ready = "True"
 	}
@@ -0,0 +1,53 @@
+package kabaneroplatform
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	tektoncdv1alpha1 "github.com/tektoncd/operator/pkg/apis/operator/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTektonConfig(conditions ...tektoncdv1alpha1.ConfigCondition) *tektoncdv1alpha1.Config {
+	return &tektoncdv1alpha1.Config{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Status:     tektoncdv1alpha1.ConfigStatus{Conditions: conditions},
+	}
+}
+
+func getTektonStatusWithConfig(t *testing.T, config *tektoncdv1alpha1.Config) (*kabanerov1alpha2.Kabanero, bool) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Version: "0.7.0"},
+	}
+	ready, _ := getTektonStatus(k, newTestClient(t, config))
+	return k, ready
+}
+
+// Verifies a Tekton instance without conditions is not ready
+func TestTektonStatusNoConditions(t *testing.T) {
+	k, ready := getTektonStatusWithConfig(t, newTektonConfig())
+	if ready || k.Status.Tekton.Message == "" {
+		t.Fatalf("Expected Tekton to be not ready with a message: %v", k.Status.Tekton)
+	}
+}
+
+// Verifies an installed but incompatible Tekton version is reported
+func TestTektonStatusIncompatible(t *testing.T) {
+	k, ready := getTektonStatusWithConfig(t, newTektonConfig(tektoncdv1alpha1.ConfigCondition{Code: tektoncdv1alpha1.InstalledStatus, Version: "v0.9.2"}))
+	if ready {
+		t.Fatal("Expected Tekton to be not ready")
+	}
+
+	if k.Status.Tekton.Compatible != prerequisiteIncompatible || k.Status.Tekton.Version != "v0.9.2" {
+		t.Fatalf("Expected Tekton to be installed but incompatible: %v", k.Status.Tekton)
+	}
+}
+
+// Verifies a compatible Tekton version is ready
+func TestTektonStatusCompatible(t *testing.T) {
+	k, ready := getTektonStatusWithConfig(t, newTektonConfig(tektoncdv1alpha1.ConfigCondition{Code: tektoncdv1alpha1.InstalledStatus, Version: "v0.10.1"}))
+	if !ready || k.Status.Tekton.Compatible != prerequisiteCompatible {
+		t.Fatalf("Expected Tekton to be ready: %v", k.Status.Tekton)
+	}
+}
@@ -0,0 +1,320 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Upgrade phases
+const (
+	upgradePhasePending         = "Pending"
+	upgradePhasePreflightFailed = "PreflightFailed"
+	upgradePhaseInProgress      = "InProgress"
+	upgradePhaseCompleted       = "Completed"
+	upgradePhaseRolledBack      = "RolledBack"
+)
+
+// Component upgrade phases
+const (
+	componentPhasePending    = "Pending"
+	componentPhaseUpgrading  = "Upgrading"
+	componentPhaseCompleted  = "Completed"
+	componentPhaseRolledBack = "RolledBack"
+)
+
+// The amount of time a component has to become ready after being upgraded
+// before the upgrade is rolled back.
+const upgradeComponentTimeout = 10 * time.Minute
+
+// The amount of time to wait between checks of an upgrade in progress
+const upgradeRequeueInterval = 15 * time.Second
+
+// Retrieves the readiness of a software component being upgraded
+type componentReadyFunc func(context.Context, *kabanerov1alpha2.Kabanero, client.Client, logr.Logger) (bool, error)
+
+// Readiness checks for the software components named in versions.yaml.
+// Components without a check or workloads are considered ready once upgraded.
+var componentReadyFuncs = map[string]componentReadyFunc{
+	"admission-webhook": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getAdmissionControllerWebhookStatus(k, c, reqLogger)
+	},
+	"collection-controller": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getCollectionControllerStatus(ctx, k, c)
+	},
+	"stack-controller": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getStackControllerStatus(ctx, k, c)
+	},
+	"cli-services": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getCliRouteStatus(k, reqLogger, c)
+	},
+	"landing": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getKabaneroLandingPageStatus(k, c)
+	},
+	"events": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getEventsRouteStatus(k, c, reqLogger)
+	},
+	"sso": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getSsoStatus(k, c, reqLogger)
+	},
+	"codeready-workspaces": func(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) (bool, error) {
+		return getCRWStatus(ctx, k, c)
+	},
+}
+
+// A workload deployed by a software component
+type componentWorkload struct {
+	kind string
+	name string
+}
+
+// The workloads whose rollouts must finish before an upgraded component is
+// considered ready.  The readiness checks above report the Available condition,
+// which the replicas of the previous version keep true during a rolling update.
+var componentWorkloads = map[string][]componentWorkload{
+	"admission-webhook":     {{kind: "Deployment", name: "kabanero-operator-admission-webhook"}},
+	"collection-controller": {{kind: "Deployment", name: ccDeploymentResourceName}},
+	"stack-controller":      {{kind: "Deployment", name: "kabanero-operator-stack-controller"}},
+	"cli-services":          {{kind: "Deployment", name: "kabanero-cli"}},
+	"landing":               {{kind: "Deployment", name: "kabanero-landing"}},
+	"events":                {{kind: "Deployment", name: "kabanero-events"}},
+	"sso":                   {{kind: "DeploymentConfig", name: "sso-postgresql"}, {kind: "DeploymentConfig", name: "sso"}},
+}
+
+// Determines if an upgraded component is ready: the rollouts of its workloads have
+// finished, and its readiness check passes.
+func isUpgradedComponentReady(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, name string, reqLogger logr.Logger) (bool, error) {
+	for _, workload := range componentWorkloads[name] {
+		var complete bool
+		var err error
+		switch workload.kind {
+		case "Deployment":
+			complete, err = isDeploymentRolloutComplete(ctx, c, workload.name, k.GetNamespace())
+		case "DeploymentConfig":
+			complete, err = isDeploymentConfigRolloutComplete(ctx, c, workload.name, k.GetNamespace())
+		}
+		if err != nil || !complete {
+			return false, err
+		}
+	}
+
+	if readyFunc, ok := componentReadyFuncs[name]; ok {
+		return readyFunc(ctx, k, c, reqLogger)
+	}
+	return true, nil
+}
+
+// Drives the Kabanero platform version upgrade state machine.  When the
+// requested version differs from the installed version, the prerequisites of
+// the requested version are checked, and the software components are then
+// upgraded one at a time in the order declared in versions.yaml.  A component
+// which does not become ready in time causes the upgrade to be rolled back.
+func reconcileUpgrade(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	doc, target := resolveKabaneroVersion(k)
+	installed := k.Status.KabaneroInstance.Version
+	upgrade := k.Status.Upgrade
+
+	// A new instance, or one which is already at the requested version.  Forget
+	// about any upgrade which was abandoned by reverting spec.version.
+	if installed == "" || installed == target {
+		if upgrade != nil && upgrade.ToVersion != target {
+			k.Status.Upgrade = nil
+		}
+		k.Status.KabaneroInstance.Version = target
+		return nil
+	}
+
+	fromRevision := doc.KabaneroRevision(installed)
+	toRevision := doc.KabaneroRevision(target)
+	if toRevision == nil {
+		return fmt.Errorf("Data related to the Kabanero release identifier `%v` cannot be found", target)
+	}
+
+	// Without the installed revision, there is nothing to stage or roll back to.
+	if fromRevision == nil {
+		reqLogger.Info(fmt.Sprintf("The installed Kabanero version %v is not in the version catalogue; moving directly to %v", installed, target))
+		k.Status.Upgrade = nil
+		k.Status.KabaneroInstance.Version = target
+		return nil
+	}
+
+	// Start a new upgrade when the requested version changes, or retry a failed
+	// upgrade when the instance spec changes.
+	if upgrade == nil || upgrade.ToVersion != target || upgrade.FromVersion != installed ||
+		(upgrade.Phase == upgradePhaseRolledBack && upgrade.ObservedGeneration != k.GetGeneration()) {
+		upgrade = newUpgradeStatus(doc, fromRevision, toRevision)
+		upgrade.ObservedGeneration = k.GetGeneration()
+		k.Status.Upgrade = upgrade
+		reqLogger.Info(fmt.Sprintf("Starting upgrade of Kabanero from version %v to %v", installed, target))
+	}
+
+	switch upgrade.Phase {
+	case upgradePhaseRolledBack:
+		return nil
+	case upgradePhasePending, upgradePhasePreflightFailed:
+		err := checkPrerequisites(ctx, k, c, toRevision)
+		if err != nil {
+			upgrade.Phase = upgradePhasePreflightFailed
+			upgrade.Message = err.Error()
+			return nil
+		}
+		upgrade.Phase = upgradePhaseInProgress
+		upgrade.Message = ""
+	}
+
+	now := time.Now()
+	for i := range upgrade.Components {
+		component := &upgrade.Components[i]
+		switch component.Phase {
+		case componentPhaseCompleted:
+			continue
+		case componentPhasePending:
+			if component.FromVersion == component.ToVersion {
+				component.Phase = componentPhaseCompleted
+				continue
+			}
+			reqLogger.Info(fmt.Sprintf("Upgrading %v from version %v to %v", component.Name, component.FromVersion, component.ToVersion))
+			component.Phase = componentPhaseUpgrading
+			component.StartTime = &metav1.Time{Time: now}
+			upgrade.Message = fmt.Sprintf("Upgrading %v", component.Name)
+			return nil
+		case componentPhaseUpgrading:
+			ready, _ := isUpgradedComponentReady(ctx, k, c, component.Name, reqLogger)
+			if ready {
+				component.Phase = componentPhaseCompleted
+				continue
+			}
+			if component.StartTime != nil && now.Sub(component.StartTime.Time) > upgradeComponentTimeout {
+				rollbackUpgrade(upgrade, component.Name)
+				reqLogger.Info(upgrade.Message)
+			}
+			return nil
+		}
+	}
+
+	upgrade.Phase = upgradePhaseCompleted
+	upgrade.Message = ""
+	k.Status.KabaneroInstance.Version = target
+	reqLogger.Info(fmt.Sprintf("Completed upgrade of Kabanero from version %v to %v", installed, target))
+
+	return nil
+}
+
+// Creates the status of a new upgrade between two Kabanero revisions.  The
+// components are listed in the order they are upgraded: first those in the
+// declared upgrade order, followed by any others in name order.
+func newUpgradeStatus(doc versioning.VersionDocument, from *versioning.KabaneroRevision, to *versioning.KabaneroRevision) *kabanerov1alpha2.KabaneroUpgradeStatus {
+	upgrade := &kabanerov1alpha2.KabaneroUpgradeStatus{
+		Phase:       upgradePhasePending,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+	}
+
+	ordered := make(map[string]bool)
+	var names []string
+	for _, name := range doc.UpgradeOrder {
+		if _, ok := to.RelatedVersions[name]; ok && !ordered[name] {
+			ordered[name] = true
+			names = append(names, name)
+		}
+	}
+
+	var unordered []string
+	for name := range to.RelatedVersions {
+		if !ordered[name] {
+			unordered = append(unordered, name)
+		}
+	}
+	sort.Strings(unordered)
+	names = append(names, unordered...)
+
+	for _, name := range names {
+		upgrade.Components = append(upgrade.Components, kabanerov1alpha2.ComponentUpgradeStatus{
+			Name:        name,
+			FromVersion: from.RelatedVersions[name],
+			ToVersion:   to.RelatedVersions[name],
+			Phase:       componentPhasePending,
+		})
+	}
+
+	return upgrade
+}
+
+// Marks the upgrade as rolled back.  Every component returns to the version
+// associated with the installed Kabanero revision.
+func rollbackUpgrade(upgrade *kabanerov1alpha2.KabaneroUpgradeStatus, failedComponent string) {
+	for i := range upgrade.Components {
+		component := &upgrade.Components[i]
+		if component.Phase == componentPhaseUpgrading || (component.Phase == componentPhaseCompleted && component.FromVersion != component.ToVersion) {
+			component.Phase = componentPhaseRolledBack
+		}
+	}
+
+	upgrade.Phase = upgradePhaseRolledBack
+	upgrade.Message = fmt.Sprintf("The %v component did not become ready within %v after being upgraded to version %v. The upgrade was rolled back to Kabanero version %v. Update the Kabanero instance to retry the upgrade.",
+		failedComponent, upgradeComponentTimeout, upgrade.ToVersion, upgrade.FromVersion)
+}
+
+// Returns the Kabanero version a software component should be deployed at.
+// While an upgrade is in progress, components which have not yet been
+// upgraded remain at the installed version.
+func componentKabaneroVersion(k *kabanerov1alpha2.Kabanero, softwareComponent string, target string) string {
+	upgrade := k.Status.Upgrade
+	if upgrade == nil || upgrade.ToVersion != target {
+		return target
+	}
+
+	switch upgrade.Phase {
+	case upgradePhaseInProgress:
+		for _, component := range upgrade.Components {
+			if component.Name == softwareComponent {
+				if component.Phase == componentPhaseUpgrading || component.Phase == componentPhaseCompleted {
+					return upgrade.ToVersion
+				}
+				return upgrade.FromVersion
+			}
+		}
+		return upgrade.ToVersion
+	case upgradePhasePending, upgradePhasePreflightFailed, upgradePhaseRolledBack:
+		return upgrade.FromVersion
+	}
+
+	return target
+}
+
+// Retrieves the upgrade status.  An upgrade which is in progress, or which
+// could not be completed, leaves the instance not ready.
+func getUpgradeStatus(k *kabanerov1alpha2.Kabanero) (bool, error) {
+	upgrade := k.Status.Upgrade
+	if upgrade == nil || upgrade.Phase == upgradePhaseCompleted {
+		return true, nil
+	}
+
+	return false, fmt.Errorf("Upgrade from Kabanero version %v to %v is %v: %v", upgrade.FromVersion, upgrade.ToVersion, upgrade.Phase, upgrade.Message)
+}
+
+// Determines if an upgrade is actively progressing and should be checked again soon.
+func upgradeInProgress(k *kabanerov1alpha2.Kabanero) bool {
+	upgrade := k.Status.Upgrade
+	return upgrade != nil && (upgrade.Phase == upgradePhasePending || upgrade.Phase == upgradePhaseInProgress)
+}
+
+// Determines if an upgrade is waiting for its prerequisites.  The prerequisites
+// are not watched, so they are checked again with backoff.
+func upgradePreflightFailed(k *kabanerov1alpha2.Kabanero) bool {
+	upgrade := k.Status.Upgrade
+	return upgrade != nil && upgrade.Phase == upgradePhasePreflightFailed
+}
+
+// The key used to track the retries of the upgrade prerequisite checks.
+func upgradePreflightRetryKey(request reconcile.Request) string {
+	return request.NamespacedName.String() + "/upgrade-preflight"
+}
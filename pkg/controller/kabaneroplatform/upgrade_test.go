@@ -0,0 +1,251 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newUpgradeKabanero(installed string, target string) *kabanerov1alpha2.Kabanero {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero", Generation: 1},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Version: target},
+	}
+	k.Status.KabaneroInstance.Version = installed
+	return k
+}
+
+// Verifies components are listed in the declared upgrade order
+func TestNewUpgradeStatusOrder(t *testing.T) {
+	doc := versioning.Data
+	upgrade := newUpgradeStatus(doc, doc.KabaneroRevision("0.6.0"), doc.KabaneroRevision("0.7.0"))
+
+	if upgrade.Phase != upgradePhasePending {
+		t.Fatalf("Expected phase %v but was %v", upgradePhasePending, upgrade.Phase)
+	}
+
+	if len(upgrade.Components) != len(doc.KabaneroRevision("0.7.0").RelatedVersions) {
+		t.Fatalf("Expected a component entry for every related version: %v", upgrade.Components)
+	}
+
+	for i, name := range doc.UpgradeOrder {
+		if upgrade.Components[i].Name != name {
+			t.Fatalf("Expected component %v at position %v but was %v", name, i, upgrade.Components[i].Name)
+		}
+	}
+
+	if upgrade.Components[0].FromVersion != "0.6.0" || upgrade.Components[0].ToVersion != "0.7.0-alpha.1" {
+		t.Fatalf("Unexpected admission webhook versions: %v", upgrade.Components[0])
+	}
+}
+
+// Verifies a fresh instance is installed at the requested version without an upgrade
+func TestReconcileUpgradeNewInstance(t *testing.T) {
+	k := newUpgradeKabanero("", "0.7.0")
+
+	err := reconcileUpgrade(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Status.Upgrade != nil {
+		t.Fatalf("Expected no upgrade but found %v", k.Status.Upgrade)
+	}
+
+	if k.Status.KabaneroInstance.Version != "0.7.0" {
+		t.Fatalf("Expected the installed version to be 0.7.0 but was %v", k.Status.KabaneroInstance.Version)
+	}
+}
+
+// Verifies missing prerequisites keep every component at the installed version
+func TestReconcileUpgradePreflightFailed(t *testing.T) {
+	k := newUpgradeKabanero("0.6.0", "0.7.0")
+
+	err := reconcileUpgrade(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Status.Upgrade == nil || k.Status.Upgrade.Phase != upgradePhasePreflightFailed {
+		t.Fatalf("Expected the pre-flight checks to fail: %v", k.Status.Upgrade)
+	}
+
+	if !strings.Contains(k.Status.Upgrade.Message, "tekton") {
+		t.Fatalf("Expected the message to identify the tekton prerequisite: %v", k.Status.Upgrade.Message)
+	}
+
+	rev, err := resolveSoftwareRevision(k, "admission-webhook", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Version != "0.6.0" {
+		t.Fatalf("Expected admission-webhook to remain at 0.6.0 but was %v", rev.Version)
+	}
+
+	if k.Status.KabaneroInstance.Version != "0.6.0" {
+		t.Fatalf("Expected the installed version to remain 0.6.0 but was %v", k.Status.KabaneroInstance.Version)
+	}
+
+	// The prerequisites are checked again with backoff, not at the upgrade interval
+	if upgradeInProgress(k) || !upgradePreflightFailed(k) {
+		t.Fatalf("Expected the upgrade to wait for its prerequisites: %v", k.Status.Upgrade)
+	}
+}
+
+// Verifies a component which does not become ready in time rolls the upgrade back
+func TestReconcileUpgradeRollback(t *testing.T) {
+	doc := versioning.Data
+	k := newUpgradeKabanero("0.6.0", "0.7.0")
+	upgrade := newUpgradeStatus(doc, doc.KabaneroRevision("0.6.0"), doc.KabaneroRevision("0.7.0"))
+	upgrade.Phase = upgradePhaseInProgress
+	upgrade.ObservedGeneration = 1
+	upgrade.Components[0].Phase = componentPhaseCompleted
+	upgrade.Components[1].Phase = componentPhaseUpgrading
+	upgrade.Components[1].StartTime = &metav1.Time{Time: time.Now().Add(-2 * upgradeComponentTimeout)}
+	k.Status.Upgrade = upgrade
+
+	// The collection controller deployment does not exist, so it is not ready.
+	err := reconcileUpgrade(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Status.Upgrade.Phase != upgradePhaseRolledBack {
+		t.Fatalf("Expected the upgrade to be rolled back: %v", k.Status.Upgrade)
+	}
+
+	for _, component := range k.Status.Upgrade.Components[:2] {
+		if component.Phase != componentPhaseRolledBack {
+			t.Fatalf("Expected %v to be rolled back but was %v", component.Name, component.Phase)
+		}
+	}
+
+	if version := componentKabaneroVersion(k, "admission-webhook", "0.7.0"); version != "0.6.0" {
+		t.Fatalf("Expected admission-webhook to return to Kabanero version 0.6.0 but was %v", version)
+	}
+
+	if ready, _ := getUpgradeStatus(k); ready {
+		t.Fatal("Expected a rolled back upgrade to leave the instance not ready")
+	}
+}
+
+// Verifies a component whose new pods never become available is rolled back, even
+// though the pods of the previous version keep the Deployment available
+func TestReconcileUpgradeRolloutNotComplete(t *testing.T) {
+	doc := versioning.Data
+	k := newUpgradeKabanero("0.6.0", "0.7.0")
+	upgrade := newUpgradeStatus(doc, doc.KabaneroRevision("0.6.0"), doc.KabaneroRevision("0.7.0"))
+	upgrade.Phase = upgradePhaseInProgress
+	upgrade.ObservedGeneration = 1
+	upgrade.Components[0].Phase = componentPhaseCompleted
+	upgrade.Components[1].Phase = componentPhaseUpgrading
+	upgrade.Components[1].StartTime = &metav1.Time{Time: time.Now()}
+	k.Status.Upgrade = upgrade
+
+	// The old replica is available, and the new one is not.
+	replicas := int32(1)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: ccDeploymentResourceName, Namespace: "kabanero", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           2,
+			UpdatedReplicas:    1,
+			AvailableReplicas:  1,
+			Conditions:         []appsv1.DeploymentCondition{{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue}},
+		},
+	}
+	cl := newTestClient(t, deployment)
+
+	err := reconcileUpgrade(context.Background(), k, cl, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phase := k.Status.Upgrade.Components[1].Phase; phase != componentPhaseUpgrading {
+		t.Fatalf("Expected the collection controller to still be upgrading but was %v", phase)
+	}
+
+	// The new replica never becomes available.
+	k.Status.Upgrade.Components[1].StartTime = &metav1.Time{Time: time.Now().Add(-2 * upgradeComponentTimeout)}
+	err = reconcileUpgrade(context.Background(), k, cl, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.Upgrade.Phase != upgradePhaseRolledBack {
+		t.Fatalf("Expected the upgrade to be rolled back: %v", k.Status.Upgrade)
+	}
+
+	// Once the rollout finishes, the component is upgraded.
+	upgrade = newUpgradeStatus(doc, doc.KabaneroRevision("0.6.0"), doc.KabaneroRevision("0.7.0"))
+	upgrade.Phase = upgradePhaseInProgress
+	upgrade.ObservedGeneration = 1
+	upgrade.Components[0].Phase = componentPhaseCompleted
+	upgrade.Components[1].Phase = componentPhaseUpgrading
+	upgrade.Components[1].StartTime = &metav1.Time{Time: time.Now()}
+	k.Status.Upgrade = upgrade
+	deployment.Status.Replicas = 1
+	err = cl.Status().Update(context.Background(), deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = reconcileUpgrade(context.Background(), k, cl, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phase := k.Status.Upgrade.Components[1].Phase; phase != componentPhaseCompleted {
+		t.Fatalf("Expected the collection controller upgrade to be completed but was %v", phase)
+	}
+}
+
+// Verifies only upgraded components move to the new version while an upgrade is in progress
+func TestComponentKabaneroVersion(t *testing.T) {
+	doc := versioning.Data
+	k := newUpgradeKabanero("0.6.0", "0.7.0")
+	upgrade := newUpgradeStatus(doc, doc.KabaneroRevision("0.6.0"), doc.KabaneroRevision("0.7.0"))
+	upgrade.Phase = upgradePhaseInProgress
+	upgrade.Components[0].Phase = componentPhaseUpgrading
+	k.Status.Upgrade = upgrade
+
+	if version := componentKabaneroVersion(k, upgrade.Components[0].Name, "0.7.0"); version != "0.7.0" {
+		t.Fatalf("Expected the upgrading component to use 0.7.0 but was %v", version)
+	}
+
+	if version := componentKabaneroVersion(k, upgrade.Components[1].Name, "0.7.0"); version != "0.6.0" {
+		t.Fatalf("Expected the pending component to use 0.6.0 but was %v", version)
+	}
+}
+
+func TestPrerequisiteSatisfied(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		satisfied  bool
+	}{
+		{"v0.10.1", ">=0.10.0", true},
+		{"0.9.2", ">=0.10.0", false},
+		{"1.4.1", ">=1.4.0 <2.0.0", true},
+		{"2.0.0", ">=1.4.0 <2.0.0", false},
+	}
+
+	for _, test := range tests {
+		satisfied, err := prerequisiteSatisfied(test.version, test.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if satisfied != test.satisfied {
+			t.Fatalf("Expected %v satisfies %v to be %v", test.version, test.constraint, test.satisfied)
+		}
+	}
+
+	if _, err := prerequisiteSatisfied("latest", ">=1.0.0"); err == nil {
+		t.Fatal("Expected an error for a version which is not a semantic version")
+	}
+}
@@ -12,41 +12,74 @@ import (
 
 // GetInstalledCSVName retrieves the name of the installed CSV from the subscription.
 func GetInstalledCSVName(c client.Client, cok client.ObjectKey) (string, error) {
+	sub, err := GetSubscription(c, cok.Namespace, cok.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if sub == nil {
+		return "", fmt.Errorf("The subscription %v could not be found", cok.Name)
+	}
+
+	installedCSVName, found, err := unstructured.NestedString(sub.Object, "status", "installedCSV")
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		err = fmt.Errorf("The value of the installedCSV entry in the subscription entry was not found")
+		return "", err
+	}
+
+	return installedCSVName, nil
+}
+
+// GetSubscription retrieves the subscription for the named package in the namespace.
+// Nil is returned if there is no subscription for the package.
+func GetSubscription(c client.Client, namespace string, packageName string) (*unstructured.Unstructured, error) {
 	sList := &unstructured.UnstructuredList{}
 	sList.SetGroupVersionKind(schema.GroupVersionKind{
 		Group:   olmapiv1alpha1.GroupName,
 		Version: olmapiv1alpha1.GroupVersion,
-		Kind:    olmapiv1alpha1.SubscriptionKind,
+		Kind:    olmapiv1alpha1.SubscriptionKind + "List",
 	})
 
-  err := c.List(context.TODO(), sList, client.InNamespace(cok.Namespace))
+	err := c.List(context.TODO(), sList, client.InNamespace(namespace))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	for _, curSub := range sList.Items {
 		subscriptionPackageName, found, err := unstructured.NestedString(curSub.Object, "spec", "name")
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if !found {
 			continue
 		}
-		if subscriptionPackageName == cok.Name {
-			installedCSVName, found, err := unstructured.NestedString(curSub.Object, "status", "installedCSV")
-			if err != nil {
-				return "", err
-			}
-			if !found {
-				err = fmt.Errorf("The value of the installedCSV entry in the subscription entry was not found")
-				return "", err
-			}
-
-			return installedCSVName, nil
+		if subscriptionPackageName == packageName {
+			sub := curSub
+			return &sub, nil
 		}
 	}
 
-	return "", fmt.Errorf("The subscription %v could not be found", cok.Name)
+	return nil, nil
+}
+
+// GetInstallPlan retrieves the InstallPlan with the input name.
+func GetInstallPlan(c client.Client, cok client.ObjectKey) (*unstructured.Unstructured, error) {
+	installPlan := &unstructured.Unstructured{}
+	installPlan.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   olmapiv1alpha1.GroupName,
+		Version: olmapiv1alpha1.GroupVersion,
+		Kind:    olmapiv1alpha1.InstallPlanKind,
+	})
+
+	err := c.Get(context.TODO(), cok, installPlan)
+	if err != nil {
+		return nil, err
+	}
+
+	return installPlan, nil
 }
 
 // GetCSVSpecVersion retrieves the version of the serverless CSV associated with the input CSV name.
@@ -0,0 +1,218 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// The source name reported in status when the embedded catalogue is active
+const embeddedVersionCatalogSource = "embedded"
+
+// The amount of time to wait between polls of a version catalogue URL
+const versionCatalogPollInterval = 5 * time.Minute
+
+// The active version catalogue for each Kabanero instance.  Entries are only
+// present when an overlay was successfully applied.
+var versionCatalogs = struct {
+	sync.Mutex
+	docs map[types.NamespacedName]activeCatalog
+}{docs: make(map[types.NamespacedName]activeCatalog)}
+
+// An overlaid version catalogue, and where its overlay was loaded from
+type activeCatalog struct {
+	doc    versioning.VersionDocument
+	source string
+}
+
+// Returns the active version catalogue for the Kabanero instance.
+func activeVersionCatalog(k *kabanerov1alpha2.Kabanero) versioning.VersionDocument {
+	versionCatalogs.Lock()
+	defer versionCatalogs.Unlock()
+
+	if active, ok := versionCatalogs.docs[types.NamespacedName{Namespace: k.GetNamespace(), Name: k.GetName()}]; ok {
+		return active.doc
+	}
+
+	return versioning.Data
+}
+
+// Returns the overlaid version catalogue for the Kabanero instance, if there is one.
+func getActiveVersionCatalogOverlay(k *kabanerov1alpha2.Kabanero) (activeCatalog, bool) {
+	versionCatalogs.Lock()
+	defer versionCatalogs.Unlock()
+
+	active, ok := versionCatalogs.docs[types.NamespacedName{Namespace: k.GetNamespace(), Name: k.GetName()}]
+	return active, ok
+}
+
+// Stores (or removes, if nil) the overlaid version catalogue for the Kabanero instance.
+func setActiveVersionCatalog(k *kabanerov1alpha2.Kabanero, doc *versioning.VersionDocument, source string) {
+	versionCatalogs.Lock()
+	defer versionCatalogs.Unlock()
+
+	key := types.NamespacedName{Namespace: k.GetNamespace(), Name: k.GetName()}
+	if doc == nil {
+		delete(versionCatalogs.docs, key)
+	} else {
+		versionCatalogs.docs[key] = activeCatalog{doc: *doc, source: source}
+	}
+}
+
+// Loads the version catalogue overlay, if one is configured, and merges it
+// over the embedded catalogue.  Failures are reported in the instance status.
+// The last overlay which was applied successfully remains active, so that a
+// transient failure to retrieve the overlay does not remove the versions it
+// declares; when there is none, the embedded catalogue remains active.
+func reconcileVersionCatalog(ctx context.Context, k *kabanerov1alpha2.Kabanero, c client.Client, reqLogger logr.Logger) error {
+	spec := k.Spec.VersionCatalog
+	status := &k.Status.VersionCatalog
+
+	status.Ready = "False"
+	status.Message = ""
+
+	setEmbedded := func(message string) {
+		setActiveVersionCatalog(k, nil, "")
+		status.Source = embeddedVersionCatalogSource
+		status.DefaultVersion = versioning.Data.DefaultKabaneroRevision
+		status.Versions = versioning.Data.Versions()
+		status.Message = message
+	}
+
+	setFailed := func(message string) {
+		active, ok := getActiveVersionCatalogOverlay(k)
+		if !ok {
+			setEmbedded(message)
+			return
+		}
+		status.Source = active.source
+		status.DefaultVersion = active.doc.DefaultKabaneroRevision
+		status.Versions = active.doc.Versions()
+		status.Message = message
+	}
+
+	if versioning.LoadError != nil {
+		setEmbedded(fmt.Sprintf("The embedded version catalogue could not be loaded: %v", versioning.LoadError))
+		return nil
+	}
+
+	var source string
+	var data []byte
+	var err error
+	switch {
+	case spec.ConfigMapName != "" && spec.Url != "":
+		setFailed("Only one of configMapName or url may be specified for the version catalogue")
+		return nil
+	case spec.ConfigMapName != "":
+		source = fmt.Sprintf("configmap:%v/%v", k.GetNamespace(), spec.ConfigMapName)
+		data, err = versioning.ReadConfigMapOverlay(ctx, c, k.GetNamespace(), spec.ConfigMapName, spec.ConfigMapKey)
+	case spec.Url != "":
+		source = spec.Url
+		data, err = getVersionCatalogFromUrl(ctx, spec.Url, spec.SkipCertVerification)
+	default:
+		setEmbedded("")
+		status.Ready = "True"
+		return nil
+	}
+
+	if err != nil {
+		reqLogger.Error(err, "Unable to load the version catalogue overlay", "source", source)
+		setFailed(fmt.Sprintf("Unable to load the version catalogue from %v: %v", source, err))
+		return nil
+	}
+
+	merged, err := versioning.ApplyOverlay(data)
+	if err != nil {
+		setFailed(fmt.Sprintf("The version catalogue from %v is not valid: %v", source, err))
+		return nil
+	}
+
+	setActiveVersionCatalog(k, &merged, source)
+	status.Ready = "True"
+	status.Source = source
+	status.DefaultVersion = merged.DefaultKabaneroRevision
+	status.Versions = merged.Versions()
+
+	return nil
+}
+
+// Reads the version catalogue overlay from a URL.
+func getVersionCatalogFromUrl(ctx context.Context, url string, skipCertVerification bool) ([]byte, error) {
+	httpClient := cutils.NewHTTPClient(skipCertVerification)
+	httpClient.Timeout = 30 * time.Second
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Could not retrieve the version catalogue. Http status code: %v", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Retrieves the version catalogue status.  A catalogue which fails to load
+// leaves the last catalogue which loaded active, but is reported as not ready.
+func getVersionCatalogStatus(k *kabanerov1alpha2.Kabanero) (bool, error) {
+	if k.Status.VersionCatalog.Ready != "True" {
+		return false, fmt.Errorf("%v", k.Status.VersionCatalog.Message)
+	}
+
+	return true, nil
+}
+
+// Determines how long to wait before polling the version catalogue again.
+// Only catalogues retrieved from a URL are polled; ConfigMaps are watched.
+func versionCatalogRequeueAfter(k *kabanerov1alpha2.Kabanero) time.Duration {
+	if k.Spec.VersionCatalog.Url != "" {
+		return versionCatalogPollInterval
+	}
+	return 0
+}
+
+// Watches ConfigMaps so that changes to a version catalogue overlay trigger
+// a reconcile of the Kabanero instances which refer to it.
+func watchVersionCatalog(c controller.Controller, cl client.Client) error {
+	mapper := handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+		kabaneroList := &kabanerov1alpha2.KabaneroList{}
+		err := cl.List(context.TODO(), kabaneroList, client.InNamespace(a.Meta.GetNamespace()))
+		if err != nil {
+			log.Error(err, "Unable to list Kabanero instances for version catalogue ConfigMap", "configmap", a.Meta.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, k := range kabaneroList.Items {
+			if k.Spec.VersionCatalog.ConfigMapName == a.Meta.GetName() {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: k.GetNamespace(), Name: k.GetName()}})
+			}
+		}
+		return requests
+	})
+
+	return c.Watch(&source.Kind{Type: &corev1.ConfigMap{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: mapper})
+}
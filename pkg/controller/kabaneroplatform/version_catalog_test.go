@@ -0,0 +1,127 @@
+package kabaneroplatform
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const catalogOverlay = `
+default: "9.9.9"
+kabanero:
+- version: "9.9.9"
+  related-versions:
+    landing: "0.6.0"
+`
+
+func newCatalogKabanero(spec kabanerov1alpha2.VersionCatalogSpec) *kabanerov1alpha2.Kabanero {
+	return &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{VersionCatalog: spec},
+	}
+}
+
+// Verifies that an overlay in a ConfigMap becomes the active catalogue
+func TestVersionCatalogFromConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero-versions", Namespace: "kabanero"},
+		Data:       map[string]string{"versions.yaml": catalogOverlay},
+	}
+	cl := newTestClient(t, cm)
+
+	k := newCatalogKabanero(kabanerov1alpha2.VersionCatalogSpec{ConfigMapName: "kabanero-versions"})
+	defer setActiveVersionCatalog(k, nil, "")
+
+	err := reconcileVersionCatalog(context.Background(), k, cl, logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Status.VersionCatalog.Ready != "True" {
+		t.Fatalf("Expected the version catalogue to be ready: %v", k.Status.VersionCatalog.Message)
+	}
+
+	_, version := resolveKabaneroVersion(k)
+	if version != "9.9.9" {
+		t.Fatalf("Expected the default version to be 9.9.9 but was %v", version)
+	}
+
+	if _, err := resolveSoftwareRevision(k, "landing", ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Verifies that an invalid overlay is reported in status and the embedded catalogue remains active
+func TestVersionCatalogInvalidUrl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("kabanero:\n- version: \"9.9.9\"\n  related-versions:\n    landing: \"1.2.3\"\n"))
+	}))
+	defer server.Close()
+
+	k := newCatalogKabanero(kabanerov1alpha2.VersionCatalogSpec{Url: server.URL})
+	defer setActiveVersionCatalog(k, nil, "")
+
+	err := reconcileVersionCatalog(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if k.Status.VersionCatalog.Ready != "False" || k.Status.VersionCatalog.Message == "" {
+		t.Fatalf("Expected the version catalogue to report an error: %v", k.Status.VersionCatalog)
+	}
+
+	if k.Status.VersionCatalog.Source != embeddedVersionCatalogSource {
+		t.Fatalf("Expected the embedded catalogue to be active but was %v", k.Status.VersionCatalog.Source)
+	}
+
+	if k.Status.VersionCatalog.DefaultVersion != "0.6.0" {
+		t.Fatalf("Expected the embedded default version but was %v", k.Status.VersionCatalog.DefaultVersion)
+	}
+}
+
+// Verifies that an overlay which can no longer be retrieved remains active, and the failure is reported in status
+func TestVersionCatalogUrlUnavailable(t *testing.T) {
+	available := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !available {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(catalogOverlay))
+	}))
+	defer server.Close()
+
+	k := newCatalogKabanero(kabanerov1alpha2.VersionCatalogSpec{Url: server.URL})
+	defer setActiveVersionCatalog(k, nil, "")
+
+	err := reconcileVersionCatalog(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.VersionCatalog.Ready != "True" {
+		t.Fatalf("Expected the version catalogue to be ready: %v", k.Status.VersionCatalog.Message)
+	}
+
+	available = false
+	err = reconcileVersionCatalog(context.Background(), k, newTestClient(t), logf.Log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Status.VersionCatalog.Ready != "False" || k.Status.VersionCatalog.Message == "" {
+		t.Fatalf("Expected the version catalogue to report an error: %v", k.Status.VersionCatalog)
+	}
+	if k.Status.VersionCatalog.Source != server.URL || k.Status.VersionCatalog.DefaultVersion != "9.9.9" {
+		t.Fatalf("Expected the last overlay to remain active: %v", k.Status.VersionCatalog)
+	}
+
+	_, version := resolveKabaneroVersion(k)
+	if version != "9.9.9" {
+		t.Fatalf("Expected the default version to be 9.9.9 but was %v", version)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
 	yml "gopkg.in/yaml.v2"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/util/yaml"
@@ -40,19 +42,43 @@ type StackAsset struct {
 	Yaml    unstructured.Unstructured
 }
 
-func DownloadToByte(c client.Client, namespace string, url string, gitRelease kabanerov1alpha2.GitReleaseSpec) ([]byte, error) {
+// DownloadToByte retrieves a pipeline archive, or stack index, from a GitHub
+// release or a URL.  Concurrent requests for the same file share one download.
+func DownloadToByte(ctx context.Context, c client.Client, namespace string, https kabanerov1alpha2.HttpsProtocolFile, gitRelease kabanerov1alpha2.GitReleaseSpec) ([]byte, error) {
+	return inflightDownloads.do(ctx, downloadKey(namespace, https, gitRelease), func(ctx context.Context) ([]byte, error) {
+		return downloadToByte(ctx, c, namespace, https, gitRelease)
+	})
+}
+
+// Returns the key which identifies a download, so that it is only shared by requests
+// which would make the same download.  Every field of the source is part of the key,
+// since each affects the request or its transport, such as the credentials or whether
+// the certificate is verified.  Credentials are found in the namespace, so downloads
+// are only shared within a namespace.
+func downloadKey(namespace string, https kabanerov1alpha2.HttpsProtocolFile, gitRelease kabanerov1alpha2.GitReleaseSpec) string {
+	if sutils.IsGitReleaseUsable(gitRelease) {
+		return fmt.Sprintf("%v:%+v", namespace, gitRelease)
+	}
+	return fmt.Sprintf("%v:%+v", namespace, https)
+}
+
+func downloadToByte(ctx context.Context, c client.Client, namespace string, https kabanerov1alpha2.HttpsProtocolFile, gitRelease kabanerov1alpha2.GitReleaseSpec) ([]byte, error) {
 	var archiveBytes []byte
 	switch {
 	// GIT:
-	case isGitReleaseUsable(gitRelease):
-		bytes, err := getStackIndexUsingGit(c, gitRelease, namespace)
+	case sutils.IsGitReleaseUsable(gitRelease):
+		bytes, err := getStackIndexUsingGit(ctx, c, gitRelease, namespace)
 		if err != nil {
 			return nil, err
 		}
 		archiveBytes = bytes
 	// HTTPS:
-	case len(url) != 0:
-		bytes, err := getFromCache(url, false)
+	case len(https.Url) != 0:
+		creds, err := getHttpsCredentials(ctx, c, https, namespace)
+		if err != nil {
+			return nil, err
+		}
+		bytes, err := downloadFromCache(ctx, https.Url, creds)
 		if err != nil {
 			return nil, err
 		}
@@ -280,7 +306,7 @@ var yamlType fileType = ".yaml"
 
 func getPipelineFileType(pipelineStatus kabanerov1alpha2.PipelineStatus) fileType {
 	fileName := pipelineStatus.Url
-	if isGitReleaseUsable(pipelineStatus.GitRelease) {
+	if sutils.IsGitReleaseUsable(pipelineStatus.GitRelease) {
 		fileName = pipelineStatus.GitRelease.AssetName
 	}
 	switch {
@@ -293,8 +319,11 @@ func getPipelineFileType(pipelineStatus kabanerov1alpha2.PipelineStatus) fileTyp
 	}
 }
 
-func GetManifests(c client.Client, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, renderingContext map[string]interface{}, reqLogger logr.Logger) ([]StackAsset, error) {
-	b, err := DownloadToByte(c, namespace, pipelineStatus.Url, pipelineStatus.GitRelease)
+// GetManifests retrieves and decodes the assets of a pipeline.  The pipeline is retrieved
+// from its URL using the credentials and trust in https.
+func GetManifests(ctx context.Context, c client.Client, namespace string, pipelineStatus kabanerov1alpha2.PipelineStatus, https kabanerov1alpha2.HttpsProtocolFile, renderingContext map[string]interface{}, reqLogger logr.Logger) ([]StackAsset, error) {
+	https.Url = pipelineStatus.Url
+	b, err := DownloadToByte(ctx, c, namespace, https, pipelineStatus.GitRelease)
 	if err != nil {
 		return nil, err
 	}
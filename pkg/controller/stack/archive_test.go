@@ -1,6 +1,7 @@
 package stack
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -15,7 +16,7 @@ func TestGetManifests(t *testing.T) {
 		Digest:     "8eacd2a6870c2b7c729ae1441cc58d6f1356bde08a022875f9f50bca8fc66543",
 		GitRelease: kabanerov1alpha2.GitReleaseSpec{}}
 
-	manifests, err := GetManifests(nil, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, reqLogger)
+	manifests, err := GetManifests(context.Background(), nil, "kabanero", pipelineStatus, kabanerov1alpha2.HttpsProtocolFile{}, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, reqLogger)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -32,7 +33,7 @@ func TestGetManifestsYaml(t *testing.T) {
 		Digest: "3b34de594df82cac3cb67c556a416443f6fafc0bc79101613eaa7ae0d59dd462",
 		GitRelease: kabanerov1alpha2.GitReleaseSpec{}}
 	
-	manifests, err := GetManifests(nil, "kabanero", pipelineStatus, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, reqLogger)
+	manifests, err := GetManifests(context.Background(), nil, "kabanero", pipelineStatus, kabanerov1alpha2.HttpsProtocolFile{}, map[string]interface{}{"StackName": "Eclipse Microprofile", "StackId": "java-microprofile"}, reqLogger)
 	if err != nil {
 		t.Fatal(err)
 	}
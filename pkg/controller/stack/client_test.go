@@ -0,0 +1,43 @@
+package stack
+
+import (
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Builds a scheme with the types the stack controller works with.  The Tekton
+// assets are only handled as unstructured objects, which the fake client can
+// only list when their kinds are registered as unstructured.
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		kabanerov1alpha2.SchemeBuilder.AddToScheme,
+		corev1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, version := range stackAssetVersions {
+		for _, kind := range stackAssetKinds {
+			gvk := schema.GroupVersionKind{Group: "tekton.dev", Version: version, Kind: kind}
+			scheme.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+			scheme.AddKnownTypeWithName(gvk.GroupVersion().WithKind(kind+"List"), &unstructured.UnstructuredList{})
+		}
+	}
+
+	return scheme
+}
+
+// Creates a fake client holding the input objects.
+func newTestClient(t *testing.T, objs ...runtime.Object) client.Client {
+	return fake.NewFakeClientWithScheme(newTestScheme(t), objs...)
+}
@@ -0,0 +1,73 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Downloads of stack indexes and pipeline archives which are in progress.
+// Stacks which share a pipeline archive are often reconciled together, for
+// example when a stack repository is first added, so concurrent requests for
+// the same archive wait for a single download.
+var inflightDownloads = &downloadGroup{}
+
+// A group of downloads, keyed by what is being downloaded.
+type downloadGroup struct {
+	mutex     sync.Mutex
+	downloads map[string]*download
+}
+
+type download struct {
+	done chan struct{}
+	// The number of other callers waiting for the download
+	waiters int
+	body    []byte
+	err     error
+}
+
+// Runs the download function, unless a download with the same key is already in
+// progress, in which case its result is returned instead.  The download runs on
+// a context of its own, bounded by the download timeout of the caller which
+// started it, so that it completes for the other callers even if that caller
+// goes away.  Each caller stops waiting if its own context is cancelled.
+func (g *downloadGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	g.mutex.Lock()
+	if g.downloads == nil {
+		g.downloads = make(map[string]*download)
+	}
+	d, found := g.downloads[key]
+	if found {
+		d.waiters++
+	} else {
+		d = &download{done: make(chan struct{})}
+		g.downloads[key] = d
+		go g.run(ctx, key, d, fn)
+	}
+	g.mutex.Unlock()
+
+	select {
+	case <-d.done:
+		return d.body, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Runs a download and releases the callers waiting for it.
+func (g *downloadGroup) run(ctx context.Context, key string, d *download, fn func(ctx context.Context) ([]byte, error)) {
+	dctx, cancel := detachedDownloadContext(ctx)
+	defer cancel()
+
+	d.body, d.err = fn(dctx)
+
+	g.mutex.Lock()
+	delete(g.downloads, key)
+	waiters := d.waiters
+	g.mutex.Unlock()
+	close(d.done)
+
+	if waiters > 0 {
+		log.Info(fmt.Sprintf("Shared the download of %v with %v other requests", key, waiters))
+	}
+}
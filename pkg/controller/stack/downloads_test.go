@@ -0,0 +1,171 @@
+package stack
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Returns the number of callers waiting for a download in progress.
+func downloadWaiters(g *downloadGroup, key string) int {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if d, found := g.downloads[key]; found {
+		return d.waiters
+	}
+	return -1
+}
+
+// Verifies concurrent requests for the same pipeline archive share one download
+func TestDownloadGroupCoalesced(t *testing.T) {
+	g := &downloadGroup{}
+	release := make(chan struct{})
+	calls := 0
+	download := func(ctx context.Context) ([]byte, error) {
+		calls++
+		<-release
+		return []byte("archive"), nil
+	}
+
+	const stacks = 5
+	var wg sync.WaitGroup
+	results := make([][]byte, stacks)
+	for i := 0; i < stacks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = g.do(context.Background(), "kabanero:https://example.com/default.tar.gz", download)
+		}(i)
+
+		// Make sure the first request starts the download before the others join it.
+		for downloadWaiters(g, "kabanero:https://example.com/default.tar.gz") != i {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("Expected one download, but there were %v", calls)
+	}
+	for _, result := range results {
+		if string(result) != "archive" {
+			t.Fatalf("Expected every request to get the archive, but got %v", string(result))
+		}
+	}
+
+	// Later requests start a new download.
+	g.do(context.Background(), "kabanero:https://example.com/default.tar.gz", download)
+	if calls != 2 {
+		t.Fatalf("Expected a second download, but there were %v", calls)
+	}
+}
+
+// Verifies a request stops waiting for a shared download when its context is cancelled
+func TestDownloadGroupCancelled(t *testing.T) {
+	g := &downloadGroup{}
+	release := make(chan struct{})
+	defer close(release)
+
+	go g.do(context.Background(), "key", func(ctx context.Context) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	for downloadWaiters(g, "key") != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.do(ctx, "key", nil); err != context.Canceled {
+		t.Fatalf("Expected the request to be cancelled, but got %v", err)
+	}
+}
+
+// Verifies a shared download completes for the other requests when the request
+// which started it is cancelled, and is bounded by the download timeout
+func TestDownloadGroupFirstRequestCancelled(t *testing.T) {
+	g := &downloadGroup{}
+	release := make(chan struct{})
+	download := func(ctx context.Context) ([]byte, error) {
+		select {
+		case <-release:
+			return []byte("archive"), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(WithDownloadTimeout(context.Background(), time.Minute))
+	first := make(chan error)
+	go func() {
+		_, err := g.do(ctx, "key", download)
+		first <- err
+	}()
+	for downloadWaiters(g, "key") != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan []byte)
+	go func() {
+		body, _ := g.do(context.Background(), "key", download)
+		second <- body
+	}()
+	for downloadWaiters(g, "key") != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("Expected the first request to be cancelled, but got %v", err)
+	}
+
+	close(release)
+	if body := <-second; string(body) != "archive" {
+		t.Fatalf("Expected the second request to get the archive, but got %v", string(body))
+	}
+
+	// The download stops when the download timeout passes.
+	_, err := g.do(WithDownloadTimeout(context.Background(), time.Millisecond), "slow", func(ctx context.Context) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the download to time out, but got %v", err)
+	}
+}
+
+// Verifies downloads are only shared by requests with the same source settings
+func TestDownloadKey(t *testing.T) {
+	https := kabanerov1alpha2.HttpsProtocolFile{Url: "https://example.com/index.yaml"}
+	insecure := https
+	insecure.SkipCertVerification = true
+	authenticated := https
+	authenticated.Auth = kabanerov1alpha2.HttpsAuthSpec{Type: kabanerov1alpha2.HttpsAuthTypeBearer, SecretName: "token"}
+
+	key := downloadKey("kabanero", https, kabanerov1alpha2.GitReleaseSpec{})
+	if key != downloadKey("kabanero", https, kabanerov1alpha2.GitReleaseSpec{}) {
+		t.Fatal("Expected the same download to have the same key")
+	}
+	for _, other := range []string{
+		downloadKey("other", https, kabanerov1alpha2.GitReleaseSpec{}),
+		downloadKey("kabanero", insecure, kabanerov1alpha2.GitReleaseSpec{}),
+		downloadKey("kabanero", authenticated, kabanerov1alpha2.GitReleaseSpec{}),
+	} {
+		if other == key {
+			t.Fatalf("Expected a different key than %v", key)
+		}
+	}
+
+	gitRelease := kabanerov1alpha2.GitReleaseSpec{Hostname: "github.com", Organization: "kabanero-io", Project: "stacks", Release: "0.1.0", AssetName: "index.yaml"}
+	insecureGitRelease := gitRelease
+	insecureGitRelease.SkipCertVerification = true
+	if downloadKey("kabanero", https, gitRelease) == downloadKey("kabanero", https, insecureGitRelease) {
+		t.Fatal("Expected Git releases which skip certificate verification to have a different key")
+	}
+}
@@ -1,12 +1,14 @@
 package stack
 
 import (
-	"crypto/tls"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"sync"
 	"time"
+
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	rlog "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -23,7 +25,8 @@ type cacheValue struct {
 
 // The cache is stored as a map.  We are storing the value as a struct
 // instead of a pointer because multiple threads will be using the values
-// concurrently.
+// concurrently.  The map is only accessed while holding cacheLock, since
+// stacks are reconciled concurrently.
 var httpCache = make(map[string]cacheValue)
 
 // Initialization mutex
@@ -40,19 +43,24 @@ var cacheLock sync.Mutex
 
 // Returns the requested resource, either from the cache, or from the
 // remote server.  The cache is not meant to be a "high performance" or
-// "heavily concurrent" cache.
-func getFromCache(url string, skipCertVerify bool) ([]byte, error) {
+// "heavily concurrent" cache.  The request is abandoned if the context is
+// cancelled, or its deadline passes.  The response is only served from the
+// cache to requests using the same credentials.
+func getFromCache(ctx context.Context, url string, creds httpsCredentials) ([]byte, error) {
 
 	// Build the request.
 	req, err := http.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
+	req = req.WithContext(ctx)
+	creds.authorize(req)
+	key := cacheKey(url, creds)
 
 	// See if the object is in the cache.  Drop the lock after adding the
 	// header so we're not holding the lock around the HTTP request.
 	cacheLock.Lock()
-	cacheData, ok := httpCache[url]
+	cacheData, ok := httpCache[key]
 	cacheLock.Unlock()
 	if ok {
 		req.Header.Add("If-None-Match", cacheData.etag)
@@ -60,11 +68,13 @@ func getFromCache(url string, skipCertVerify bool) ([]byte, error) {
 	}
 
 	// Drive the request. Certificate validation is not disabled by default.
-	transport := &http.Transport{DisableCompression: true}
-	if skipCertVerify {
-		config := &tls.Config{InsecureSkipVerify: skipCertVerify}
-		transport.TLSClientConfig = config
+	config, err := creds.tlsConfig()
+	if err != nil {
+		return nil, err
 	}
+	transport := cutils.NewTransport(creds.skipCertVerify)
+	transport.DisableCompression = true
+	transport.TLSClientConfig = config
 
 	client := &http.Client{Transport: transport}
 	resp, err := client.Do(req)
@@ -79,10 +89,14 @@ func getFromCache(url string, skipCertVerify bool) ([]byte, error) {
 	if resp.StatusCode == http.StatusNotModified {
 		cachelog.Info(fmt.Sprintf("Retrieved from cache: %v", url))
 
-		// Update the last used time so the entry does not get purged.
-		cacheData.lastUsed = time.Now()
+		// Update the last used time so the entry does not get purged.  Another
+		// request for the same resource may have replaced or purged the entry
+		// in the meantime, in which case it is left alone.
 		cacheLock.Lock()
-		httpCache[url] = cacheData
+		if current, found := httpCache[key]; found && current.etag == cacheData.etag {
+			current.lastUsed = time.Now()
+			httpCache[key] = current
+		}
 		cacheLock.Unlock()
 		
 		return cacheData.body, nil
@@ -106,16 +120,27 @@ func getFromCache(url string, skipCertVerify bool) ([]byte, error) {
 	if (len(etag) > 0) && (len(date) > 0) {
 		// Before adding an entry to the cache, make sure the purge task is running.
 		startPurgeTicker.Do(startCachePurgeTask)
-		httpCache[url] = cacheValue{etag: etag, date: date, body: b, lastUsed: time.Now()}
+		httpCache[key] = cacheValue{etag: etag, date: date, body: b, lastUsed: time.Now()}
 		cachelog.Info(fmt.Sprintf("Stored to cache: %v", url))
 	} else {
 		// Take the entry out of the map if it's already there.
-		delete(httpCache, url)
+		delete(httpCache, key)
 	}
 
 	return b, nil
 }
 
+// Returns the cache map key of a resource.  The key of a resource retrieved
+// with credentials includes a hash of them, so that the resource is not
+// served to requests without them.
+func cacheKey(url string, creds httpsCredentials) string {
+	identity := creds.identity()
+	if len(identity) == 0 {
+		return url
+	}
+	return url + " " + identity
+}
+
 // Starts the periodic purge task
 func startCachePurgeTask() {
 	// Start a ticker that will receive periodic requests to purge the cache.
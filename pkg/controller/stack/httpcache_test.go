@@ -1,6 +1,7 @@
 package stack
 
 import (
+	"context"
 	"testing"
 
 	"bytes"
@@ -40,7 +41,7 @@ func TestCachePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... the first time should not cache, the second should cache.
-	data, err := getFromCache(server.URL, false)
+	data, err := getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,7 +49,7 @@ func TestCachePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = getFromCache(server.URL, false)
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -96,7 +97,7 @@ func TestCacheChangePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page thrice... the first time and second time should not cache, the third should cache.
-	data, err := getFromCache(server.URL, false)
+	data, err := getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -104,7 +105,7 @@ func TestCacheChangePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = getFromCache(server.URL, false)
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -112,7 +113,7 @@ func TestCacheChangePage(t *testing.T) {
 		t.Fatal("Response 2 not correct")
 	}
 
-	data, err = getFromCache(server.URL, false)
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -140,7 +141,7 @@ func TestNoCachePage(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... 
-	data, err := getFromCache(server.URL, false)
+	data, err := getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -148,7 +149,7 @@ func TestNoCachePage(t *testing.T) {
 		t.Fatal("Response 1 not correct")
 	}
 
-	data, err = getFromCache(server.URL, false)
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -165,7 +166,7 @@ func TestCachePurge(t *testing.T) {
 	defer server.Close()
 
 	// Get the page twice... the first time should not cache.
-	data, err := getFromCache(server.URL, false)
+	data, err := getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -177,7 +178,7 @@ func TestCachePurge(t *testing.T) {
 	purgeCache(0)
 
 	// Get the page the second time... it should not be cached.
-	data, err = getFromCache(server.URL, false)
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -190,3 +191,50 @@ func TestCachePurge(t *testing.T) {
 		t.Fatalf("Wrong number of cache hits: %v", cacheHits)
 	}
 }
+
+// HTTP handler that only serves the resource to requests with the expected token.
+type TokenCacheHandler struct {
+	CacheHandler
+	token string
+}
+
+func (ch TokenCacheHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer "+ch.token {
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	ch.CacheHandler.ServeHTTP(rw, req)
+}
+
+// Show that a resource retrieved with a token is only served from the cache to requests with the same token.
+func TestCachePageWithToken(t *testing.T) {
+	var cacheHits int32 = 0
+	handler := TokenCacheHandler{CacheHandler: CacheHandler{etag: "TOKEN", cacheHits: &cacheHits}, token: "secret"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, err := getFromCache(context.Background(), server.URL, httpsCredentials{token: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response 1 not correct")
+	}
+
+	// A request without the token is not served from the cache.
+	_, err = getFromCache(context.Background(), server.URL, httpsCredentials{})
+	if err == nil {
+		t.Fatal("Expected the request without the token to be rejected")
+	}
+
+	data, err = getFromCache(context.Background(), server.URL, httpsCredentials{token: []byte("secret")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Compare([]byte(theResponse), data) != 0 {
+		t.Fatal("Response 2 not correct")
+	}
+	if cacheHits != 1 {
+		t.Fatalf("Wrong number of cache hits: %v", cacheHits)
+	}
+}
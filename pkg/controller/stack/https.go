@@ -0,0 +1,146 @@
+package stack
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The ConfigMap key holding the certificate authorities, when no key is specified.
+const defaultCaBundleKey = "ca-bundle.crt"
+
+// The credentials and trust used to retrieve a file over HTTPS.
+type httpsCredentials struct {
+	skipCertVerify bool
+	// Sent as a bearer token
+	token []byte
+	// Sent using basic authentication
+	username []byte
+	password []byte
+	// The PEM encoded client certificate and key
+	clientCert []byte
+	clientKey  []byte
+	// PEM encoded certificate authorities trusted in addition to the system ones
+	caBundle []byte
+}
+
+// Reads the credentials and trust configured for a file retrieved over HTTPS.
+func getHttpsCredentials(ctx context.Context, c client.Client, https kabanerov1alpha2.HttpsProtocolFile, namespace string) (httpsCredentials, error) {
+	creds := httpsCredentials{skipCertVerify: https.SkipCertVerification}
+
+	// Credentials are only sent when a source is specified.
+	if https.Credentials != (kabanerov1alpha2.CredentialsSource{}) {
+		token, err := getCredentialProvider(c, https.Credentials, namespace).GetToken(ctx, https.Url)
+		if err != nil {
+			return creds, err
+		}
+		creds.token = token
+	}
+
+	if len(https.Auth.SecretName) != 0 {
+		secret := &corev1.Secret{}
+		err := c.Get(ctx, types.NamespacedName{Name: https.Auth.SecretName, Namespace: namespace}, secret)
+		if err != nil {
+			return creds, fmt.Errorf("Unable to retrieve the authentication Secret %v of %v: %v", https.Auth.SecretName, https.Url, err)
+		}
+
+		var keys []string
+		switch https.Auth.Type {
+		case kabanerov1alpha2.HttpsAuthTypeBasic:
+			keys = []string{"username", "password"}
+			creds.username, creds.password = secret.Data["username"], secret.Data["password"]
+		case kabanerov1alpha2.HttpsAuthTypeBearer:
+			keys = []string{"token"}
+			creds.token = secret.Data["token"]
+		case kabanerov1alpha2.HttpsAuthTypeClientCert:
+			keys = []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey}
+			creds.clientCert, creds.clientKey = secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey]
+		default:
+			return creds, fmt.Errorf("The authentication type %v of %v is not supported. Specify %v, %v or %v.", https.Auth.Type, https.Url,
+				kabanerov1alpha2.HttpsAuthTypeBasic, kabanerov1alpha2.HttpsAuthTypeBearer, kabanerov1alpha2.HttpsAuthTypeClientCert)
+		}
+
+		for _, key := range keys {
+			if len(secret.Data[key]) == 0 {
+				return creds, fmt.Errorf("The authentication Secret %v of %v does not contain key '%v'", https.Auth.SecretName, https.Url, key)
+			}
+		}
+	}
+
+	if len(https.CaBundle.Name) != 0 {
+		key := https.CaBundle.Key
+		if len(key) == 0 {
+			key = defaultCaBundleKey
+		}
+
+		configMap := &corev1.ConfigMap{}
+		err := c.Get(ctx, types.NamespacedName{Name: https.CaBundle.Name, Namespace: namespace}, configMap)
+		if err != nil {
+			return creds, fmt.Errorf("Unable to retrieve the CA bundle ConfigMap %v of %v: %v", https.CaBundle.Name, https.Url, err)
+		}
+		bundle, ok := configMap.Data[key]
+		if !ok {
+			return creds, fmt.Errorf("The CA bundle ConfigMap %v of %v does not contain key '%v'", https.CaBundle.Name, https.Url, key)
+		}
+		creds.caBundle = []byte(bundle)
+	}
+
+	return creds, nil
+}
+
+// Adds the credentials to a request.
+func (creds httpsCredentials) authorize(req *http.Request) {
+	switch {
+	case len(creds.token) != 0:
+		req.Header.Set("Authorization", "Bearer "+string(creds.token))
+	case len(creds.username) != 0:
+		req.SetBasicAuth(string(creds.username), string(creds.password))
+	}
+}
+
+// Returns the TLS configuration presenting the client certificate, if specified, and
+// trusting the CA bundle in addition to the operator trusted certificate authorities.
+// Certificate validation is not disabled by default.
+func (creds httpsCredentials) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: creds.skipCertVerify, RootCAs: cutils.TrustedCertPool()}
+
+	if len(creds.clientCert) != 0 {
+		cert, err := tls.X509KeyPair(creds.clientCert, creds.clientKey)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to load the client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(creds.caBundle) != 0 && !config.RootCAs.AppendCertsFromPEM(creds.caBundle) {
+		return nil, fmt.Errorf("The CA bundle does not contain any PEM encoded certificates")
+	}
+
+	return config, nil
+}
+
+// Returns a hash identifying the credentials, or an empty string if there are none.
+// Resources retrieved with different credentials are cached separately, so that a
+// resource is only served to requests with the credentials used to retrieve it.
+func (creds httpsCredentials) identity() string {
+	if len(creds.token) == 0 && len(creds.username) == 0 && len(creds.clientCert) == 0 {
+		return ""
+	}
+
+	h := sha256.New()
+	for _, b := range [][]byte{creds.token, creds.username, creds.password, creds.clientCert} {
+		// Length prefix each field, so that fields cannot run into each other.
+		fmt.Fprintf(h, "%d:", len(b))
+		h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,119 @@
+package stack
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// Returns a PEM encoded self signed client certificate and key.
+func newTestClientCert(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kabanero"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// Verifies files are retrieved using basic authentication, a client certificate and a CA bundle
+func TestGetFromCacheWithHttpsAuth(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		if len(req.TLS.PeerCertificates) == 0 && (!ok || user != "reader" || pass != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(theResponse))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	clientCert, clientKey := newTestClientCert(t)
+	cl := fake.NewFakeClient(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "basic", Namespace: "kabanero"},
+			Data:       map[string][]byte{"username": []byte("reader"), "password": []byte("secret")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "client-cert", Namespace: "kabanero"},
+			Data:       map[string][]byte{corev1.TLSCertKey: clientCert, corev1.TLSPrivateKeyKey: clientKey},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trusted-ca", Namespace: "kabanero"},
+			Data:       map[string]string{defaultCaBundleKey: string(caBundle)},
+		})
+	ctx := context.Background()
+
+	https := kabanerov1alpha2.HttpsProtocolFile{
+		Url:      server.URL + "/index.yaml",
+		Auth:     kabanerov1alpha2.HttpsAuthSpec{Type: kabanerov1alpha2.HttpsAuthTypeBasic, SecretName: "basic"},
+		CaBundle: kabanerov1alpha2.ConfigMapKeyRef{Name: "trusted-ca"},
+	}
+	creds, err := getHttpsCredentials(ctx, cl, https, "kabanero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := getFromCache(ctx, https.Url, creds); err != nil || string(data) != theResponse {
+		t.Fatalf("Expected the file to be retrieved using basic authentication, but got %v: %v", string(data), err)
+	}
+
+	https.Auth = kabanerov1alpha2.HttpsAuthSpec{Type: kabanerov1alpha2.HttpsAuthTypeClientCert, SecretName: "client-cert"}
+	certCreds, err := getHttpsCredentials(ctx, cl, https, "kabanero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := getFromCache(ctx, https.Url, certCreds); err != nil || string(data) != theResponse {
+		t.Fatalf("Expected the file to be retrieved using a client certificate, but got %v: %v", string(data), err)
+	}
+	if cacheKey(https.Url, creds) == cacheKey(https.Url, certCreds) || cacheKey(https.Url, creds) == https.Url {
+		t.Fatal("Expected the cache keys to depend on the credentials")
+	}
+
+	// The server certificate is not trusted without the CA bundle.
+	https.CaBundle = kabanerov1alpha2.ConfigMapKeyRef{}
+	noCaCreds, err := getHttpsCredentials(ctx, cl, https, "kabanero")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = getFromCache(ctx, https.Url, noCaCreds); err == nil {
+		t.Fatal("Expected the server certificate to be rejected")
+	}
+
+	// The Secret must contain the keys of the authentication type.
+	https.Auth = kabanerov1alpha2.HttpsAuthSpec{Type: kabanerov1alpha2.HttpsAuthTypeBearer, SecretName: "basic"}
+	if _, err = getHttpsCredentials(ctx, cl, https, "kabanero"); err == nil {
+		t.Fatal("Expected the missing token key to be reported")
+	}
+}
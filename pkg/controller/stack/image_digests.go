@@ -0,0 +1,89 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The amount of time a resolved stack image digest is remembered before the
+// registry is asked again.  This bounds how long a moved tag goes unnoticed.
+const stackDigestCacheTTL = 10 * time.Minute
+
+// Resolved stack image digests
+var stackDigestCache = cutils.NewDigestCache(stackDigestCacheTTL)
+
+// The digest pinning configuration which applies to the stacks in a namespace.
+type stackDigestPinning struct {
+	creds    cutils.RegistryCredentials
+	credsErr error
+}
+
+// Retrieves the digest pinning configuration of the Kabanero instance in the
+// stack's namespace.  Nil is returned when digest pinning is not enabled.
+func getStackDigestPinning(ctx context.Context, namespace string, c client.Client) *stackDigestPinning {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneroList, client.InNamespace(namespace))
+	if err != nil || len(kabaneroList.Items) == 0 {
+		return nil
+	}
+
+	spec := kabaneroList.Items[0].Spec.DigestPinning
+	if !spec.Enable {
+		return nil
+	}
+
+	pinning := &stackDigestPinning{}
+	pinning.creds, pinning.credsErr = cutils.GetRegistryCredentials(c, namespace, spec.PullSecrets)
+	return pinning
+}
+
+// Builds the image status of a stack version.  When digest pinning is enabled,
+// each image is resolved at the tag matching the stack version, and compared
+// with the previously reported digest to detect a tag which has moved.
+func resolveStackImages(ctx context.Context, version string, images []kabanerov1alpha2.Image, previous []kabanerov1alpha2.ImageStatus, pinning *stackDigestPinning) []kabanerov1alpha2.ImageStatus {
+	var statuses []kabanerov1alpha2.ImageStatus
+	for _, image := range images {
+		status := kabanerov1alpha2.ImageStatus{Id: image.Id, Image: image.Image}
+		if pinning == nil {
+			statuses = append(statuses, status)
+			continue
+		}
+
+		prev := kabanerov1alpha2.ImageStatus{}
+		for _, p := range previous {
+			if p.Id == image.Id && p.Image == image.Image {
+				prev = p
+				break
+			}
+		}
+
+		taggedImage := image.Image + ":" + version
+		digest, err := "", pinning.credsErr
+		if err == nil {
+			digest, err = stackDigestCache.Resolve(ctx, taggedImage, pinning.creds, cutils.RegistryTransport)
+		}
+
+		if err != nil {
+			// Keep reporting the last known digest.
+			status.Digest = prev.Digest
+			status.PreviousDigest = prev.PreviousDigest
+			status.MovedTime = prev.MovedTime
+			status.Message = fmt.Sprintf("Unable to resolve the digest of image %v: %v", taggedImage, err)
+		} else {
+			status.Digest = digest
+			status.PreviousDigest, status.MovedTime = cutils.TrackTagMove(prev.Digest, prev.PreviousDigest, prev.MovedTime, digest, time.Now())
+			if status.PreviousDigest != "" {
+				status.Message = cutils.TagMovedMessage(taggedImage, status.PreviousDigest, status.Digest)
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
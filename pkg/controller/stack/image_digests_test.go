@@ -0,0 +1,76 @@
+package stack
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Verifies stack image digests are resolved at the stack version tag, and a moved tag is reported
+func TestResolveStackImages(t *testing.T) {
+	digests := map[string]string{"/v2/kabanero/nodejs/manifests/0.3.3": "sha256:1111"}
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		digest, ok := digests[req.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	defer registry.Close()
+
+	cutils.RegistryTransport = registry.Client().Transport
+	defer func() { cutils.RegistryTransport = nil }()
+
+	host := strings.TrimPrefix(registry.URL, "https://")
+	images := []kabanerov1alpha2.Image{{Id: "nodejs", Image: host + "/kabanero/nodejs"}}
+	pinning := &stackDigestPinning{}
+
+	statuses := resolveStackImages(context.Background(), "0.3.3", images, nil, pinning)
+	if len(statuses) != 1 || statuses[0].Digest != "sha256:1111" || statuses[0].Message != "" {
+		t.Fatalf("Unexpected image status: %v", statuses)
+	}
+
+	// The registry now reports a different digest for the same tag
+	digests["/v2/kabanero/nodejs/manifests/0.3.3"] = "sha256:2222"
+	stackDigestCache = cutils.NewDigestCache(stackDigestCacheTTL)
+	statuses = resolveStackImages(context.Background(), "0.3.3", images, statuses, pinning)
+	if statuses[0].Digest != "sha256:2222" || statuses[0].PreviousDigest != "sha256:1111" || !strings.Contains(statuses[0].Message, "moved") {
+		t.Fatalf("Expected the moved tag to be reported: %v", statuses)
+	}
+
+	// An image which cannot be resolved keeps the last known digest
+	statuses = resolveStackImages(context.Background(), "0.4.0", images, statuses, pinning)
+	if statuses[0].Digest != "sha256:2222" || !strings.Contains(statuses[0].Message, "Unable to resolve") {
+		t.Fatalf("Expected the resolution failure to be reported: %v", statuses)
+	}
+
+	// Without digest pinning only the repositories are reported
+	statuses = resolveStackImages(context.Background(), "0.3.3", images, statuses, nil)
+	if statuses[0].Digest != "" || statuses[0].Image != images[0].Image {
+		t.Fatalf("Expected no digest when digest pinning is disabled: %v", statuses)
+	}
+}
+
+// Verifies digest pinning follows the Kabanero instance in the stack's namespace
+func TestGetStackDigestPinning(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{DigestPinning: kabanerov1alpha2.DigestPinningSpec{Enable: true}},
+	}
+	cl := newTestClient(t, k)
+
+	if pinning := getStackDigestPinning(context.Background(), "kabanero", cl); pinning == nil || pinning.credsErr != nil {
+		t.Fatalf("Expected digest pinning to be enabled: %v", pinning)
+	}
+
+	if pinning := getStackDigestPinning(context.Background(), "other", cl); pinning != nil {
+		t.Fatal("Expected digest pinning to be disabled in a namespace without a Kabanero instance")
+	}
+}
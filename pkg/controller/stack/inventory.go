@@ -0,0 +1,229 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Labels and annotations applied to every asset created for a stack.  The
+// labels can be used to select assets.  The annotations hold values which do
+// not fit in a label, or which can have more than one value.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "kabanero"
+
+	StackIdLabel    = "kabanero.io/stack-id"
+	PipelineIdLabel = "kabanero.io/pipeline-id"
+	// Only set when a single stack version uses the asset.  The versions
+	// sharing an asset are listed in the stack versions annotation.
+	StackVersionLabel = "kabanero.io/stack-version"
+
+	StackVersionsAnnotation  = "kabanero.io/stack-versions"
+	PipelineDigestAnnotation = "kabanero.io/pipeline-digest"
+	PipelineSourceAnnotation = "kabanero.io/pipeline-source"
+)
+
+// The labels which are only set when their values are known and valid, and
+// which are removed when they no longer apply.
+var optionalStackAssetLabels = []string{StackIdLabel, PipelineIdLabel, StackVersionLabel}
+
+// StackAssetOrigin identifies the stack, stack versions and pipeline archive
+// which an asset was created from.
+type StackAssetOrigin struct {
+	// The stack id, from the stack spec
+	StackId string
+	// The namespace of the stack, when it is known
+	StackNamespace string
+	// The stack versions which use the asset
+	StackVersions []string
+	PipelineId    string
+	// The sha256 digest of the pipeline archive
+	PipelineDigest string
+	// The location the pipeline archive was retrieved from
+	PipelineSource string
+}
+
+// GetStackAssetOrigin returns the origin of an object created by the stack
+// controller, or nil if the object was not created by the stack controller.
+func GetStackAssetOrigin(obj metav1.Object) *StackAssetOrigin {
+	objLabels := obj.GetLabels()
+	if objLabels[ManagedByLabel] != ManagedByValue || len(objLabels[StackIdLabel]) == 0 {
+		return nil
+	}
+
+	annotations := obj.GetAnnotations()
+	origin := &StackAssetOrigin{
+		StackId:        objLabels[StackIdLabel],
+		StackNamespace: annotations[transforms.OwnerNamespaceAnnotation],
+		PipelineId:     objLabels[PipelineIdLabel],
+		PipelineDigest: annotations[PipelineDigestAnnotation],
+		PipelineSource: annotations[PipelineSourceAnnotation],
+	}
+	if len(origin.StackNamespace) == 0 {
+		origin.StackNamespace = obj.GetNamespace()
+	}
+	if versions := annotations[StackVersionsAnnotation]; len(versions) > 0 {
+		origin.StackVersions = strings.Split(versions, ",")
+	}
+
+	return origin
+}
+
+// StackAssetSelector returns the label selector which matches the assets
+// created for a stack.
+func StackAssetSelector(stackId string) labels.Set {
+	return labels.Set{ManagedByLabel: ManagedByValue, StackIdLabel: stackId}
+}
+
+// ListStackAssets lists the assets created for a stack in a namespace,
+// including those created in other namespaces, such as trigger bindings.
+func ListStackAssets(ctx context.Context, reader client.Reader, namespace string, stackId string) ([]unstructured.Unstructured, error) {
+	var assets []unstructured.Unstructured
+	for _, kind := range stackAssetKinds {
+		u := &unstructured.Unstructured{}
+		u.SetKind(kind)
+		assetNamespace := getNamespaceForObject(u, namespace)
+
+		items, err := listStackAssetKind(ctx, reader, kind, assetNamespace, client.MatchingLabels(StackAssetSelector(stackId)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			// Stacks in other namespaces may have the same id.
+			if assetNamespace != namespace && item.GetAnnotations()[transforms.OwnerNamespaceAnnotation] != namespace {
+				continue
+			}
+			assets = append(assets, item)
+		}
+	}
+
+	return assets, nil
+}
+
+// Returns the labels and annotations for the assets of a pipeline archive.
+// Values which are not valid label values are left out of the labels.
+func stackAssetMetadata(stackId string, value *pipelineUseMapValue) (map[string]string, map[string]string) {
+	assetLabels := map[string]string{ManagedByLabel: ManagedByValue}
+	if len(validation.IsValidLabelValue(stackId)) == 0 {
+		assetLabels[StackIdLabel] = stackId
+	}
+	if len(value.pipelineIds) > 0 && len(validation.IsValidLabelValue(value.pipelineIds[0])) == 0 {
+		assetLabels[PipelineIdLabel] = value.pipelineIds[0]
+	}
+
+	versions := append([]string(nil), value.stackVersions...)
+	sort.Strings(versions)
+	if len(versions) == 1 && len(validation.IsValidLabelValue(versions[0])) == 0 {
+		assetLabels[StackVersionLabel] = versions[0]
+	}
+	annotations := map[string]string{
+		StackVersionsAnnotation:  strings.Join(versions, ","),
+		PipelineDigestAnnotation: value.Digest,
+		PipelineSourceAnnotation: pipelineSource(value.PipelineStatus),
+	}
+
+	return assetLabels, annotations
+}
+
+// Returns the location a pipeline archive is retrieved from.
+func pipelineSource(pipeline kabanerov1alpha2.PipelineStatus) string {
+	if len(pipeline.Url) > 0 {
+		return pipeline.Url
+	}
+
+	release := pipeline.GitRelease
+	if len(release.Hostname) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("https://%v/%v/%v/releases/download/%v/%v", release.Hostname, release.Organization, release.Project, release.Release, release.AssetName)
+}
+
+// Adds labels and annotations to an object, and removes the optional labels
+// which no longer apply.  Returns true if the object changed.
+func mergeStackAssetMetadata(u *unstructured.Unstructured, assetLabels map[string]string, annotations map[string]string) bool {
+	changed := false
+
+	objLabels := u.GetLabels()
+	if objLabels == nil {
+		objLabels = make(map[string]string)
+	}
+	for _, key := range optionalStackAssetLabels {
+		if _, found := assetLabels[key]; !found {
+			if _, found := objLabels[key]; found {
+				delete(objLabels, key)
+				changed = true
+			}
+		}
+	}
+	for key, value := range assetLabels {
+		if objLabels[key] != value {
+			objLabels[key] = value
+			changed = true
+		}
+	}
+
+	objAnnotations := u.GetAnnotations()
+	if objAnnotations == nil {
+		objAnnotations = make(map[string]string)
+	}
+	for key, value := range annotations {
+		if objAnnotations[key] != value {
+			objAnnotations[key] = value
+			changed = true
+		}
+	}
+
+	if changed {
+		u.SetLabels(objLabels)
+		u.SetAnnotations(objAnnotations)
+	}
+
+	return changed
+}
+
+// Returns a transformer which adds labels and annotations to the assets.
+func injectStackAssetMetadata(assetLabels map[string]string, annotations map[string]string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		mergeStackAssetMetadata(u, assetLabels, annotations)
+		return nil
+	}
+}
+
+// Summarizes the assets of the active stack versions.
+func summarizeStackAssets(stackId string, status kabanerov1alpha2.StackStatus) *kabanerov1alpha2.StackAssetSummary {
+	summary := &kabanerov1alpha2.StackAssetSummary{Selector: StackAssetSelector(stackId).String()}
+
+	// Versions may share a pipeline archive, so only count each asset once.
+	counted := make(map[string]bool)
+	for _, version := range status.Versions {
+		for _, pipeline := range version.Pipelines {
+			for _, asset := range pipeline.ActiveAssets {
+				key := strings.Join([]string{asset.Group, asset.Kind, asset.Namespace, asset.Name}, "/")
+				if counted[key] {
+					continue
+				}
+				counted[key] = true
+
+				switch asset.Status {
+				case assetStatusActive:
+					summary.Active++
+				case assetStatusFailed:
+					summary.Failed++
+				}
+			}
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,126 @@
+package stack
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Verifies the origin of an asset can be read back from its labels and annotations
+func TestGetStackAssetOrigin(t *testing.T) {
+	value := &pipelineUseMapValue{
+		PipelineStatus: kabanerov1alpha2.PipelineStatus{
+			Digest:     "0123456789abcdef",
+			GitRelease: kabanerov1alpha2.GitReleaseSpec{Hostname: "github.com", Organization: "kabanero-io", Project: "stacks", Release: "0.6.0", AssetName: "default.tar.gz"},
+		},
+		stackVersions: []string{"0.2.6", "0.2.5"},
+		pipelineIds:   []string{"default", "default"},
+	}
+
+	assetLabels, annotations := stackAssetMetadata("java-microprofile", value)
+	u := tektonObject("Task", "java-microprofile-build-task", "kabanero", nil, nil)
+	if err := injectStackAssetMetadata(assetLabels, annotations)(u); err != nil {
+		t.Fatal(err)
+	}
+
+	origin := GetStackAssetOrigin(u)
+	if origin == nil {
+		t.Fatal("Expected the asset to have an origin")
+	}
+	if origin.StackId != "java-microprofile" || origin.StackNamespace != "kabanero" || origin.PipelineId != "default" {
+		t.Fatalf("Unexpected asset origin: %#v", origin)
+	}
+	if len(origin.StackVersions) != 2 || origin.StackVersions[0] != "0.2.5" || origin.StackVersions[1] != "0.2.6" {
+		t.Fatalf("Expected the sorted stack versions: %v", origin.StackVersions)
+	}
+	if origin.PipelineDigest != "0123456789abcdef" || origin.PipelineSource != "https://github.com/kabanero-io/stacks/releases/download/0.6.0/default.tar.gz" {
+		t.Fatalf("Expected the pipeline archive: %#v", origin)
+	}
+
+	if _, found := u.GetLabels()[StackVersionLabel]; found {
+		t.Fatalf("Expected no stack version label on an asset shared by two versions: %v", u.GetLabels())
+	}
+
+	// Applying the same metadata again changes nothing
+	if mergeStackAssetMetadata(u, assetLabels, annotations) {
+		t.Fatal("Expected the metadata to be unchanged")
+	}
+
+	// Only one version uses the asset now
+	value.stackVersions = []string{"0.2.6"}
+	assetLabels, annotations = stackAssetMetadata("java-microprofile", value)
+	if !mergeStackAssetMetadata(u, assetLabels, annotations) || u.GetLabels()[StackVersionLabel] != "0.2.6" {
+		t.Fatalf("Expected the stack version label: %v", u.GetLabels())
+	}
+
+	// Labels which no longer apply are removed
+	value.stackVersions = []string{"0.2.6", "0.2.7"}
+	value.pipelineIds = nil
+	assetLabels, annotations = stackAssetMetadata("java-microprofile", value)
+	if !mergeStackAssetMetadata(u, assetLabels, annotations) {
+		t.Fatal("Expected the metadata to change")
+	}
+	if _, found := u.GetLabels()[StackVersionLabel]; found {
+		t.Fatalf("Expected the stack version label to be removed: %v", u.GetLabels())
+	}
+	if _, found := u.GetLabels()[PipelineIdLabel]; found {
+		t.Fatalf("Expected the pipeline id label to be removed: %v", u.GetLabels())
+	}
+
+	// Objects which were not created by the stack controller have no origin
+	if GetStackAssetOrigin(tektonObject("Task", "other", "kabanero", nil, nil)) != nil {
+		t.Fatal("Expected no origin for an unlabeled object")
+	}
+}
+
+// Verifies the assets of a stack are listed from the stack namespace and the
+// trigger namespace, in both Tekton API versions
+func TestListStackAssets(t *testing.T) {
+	cl := newOrphanedAssetClient(t, "")
+	labels := StackAssetSelector("java-microprofile")
+	objects := []*unstructured.Unstructured{
+		tektonObject("Task", "java-microprofile-build-task", "kabanero", nil, nil),
+		tektonObject("Task", "java-microprofile-deploy-task", "kabanero", nil, nil),
+		tektonObject("TriggerBinding", "java-microprofile-binding", "tekton-pipelines", nil, map[string]string{transforms.OwnerNamespaceAnnotation: "kabanero"}),
+		tektonObject("TriggerBinding", "other-namespace-binding", "tekton-pipelines", nil, map[string]string{transforms.OwnerNamespaceAnnotation: "other"}),
+	}
+	objects[1].SetAPIVersion("tekton.dev/v1beta1")
+	for _, u := range objects {
+		u.SetLabels(labels)
+		if err := cl.Create(context.Background(), u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assets, err := ListStackAssets(context.Background(), cl, "kabanero", "java-microprofile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(assets) != 3 || assets[0].GetName() != "java-microprofile-build-task" || assets[1].GetName() != "java-microprofile-deploy-task" || assets[2].GetName() != "java-microprofile-binding" {
+		t.Fatalf("Unexpected stack assets: %v", assets)
+	}
+}
+
+// Verifies assets shared between versions are counted once
+func TestSummarizeStackAssets(t *testing.T) {
+	assets := []kabanerov1alpha2.RepositoryAssetStatus{
+		{Name: "build-task", Namespace: "kabanero", Group: "tekton.dev", Kind: "Task", Status: assetStatusActive},
+		{Name: "build-pipeline", Namespace: "kabanero", Group: "tekton.dev", Kind: "Pipeline", Status: assetStatusFailed},
+	}
+	status := kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{
+		{Version: "0.2.5", Pipelines: []kabanerov1alpha2.PipelineStatus{{ActiveAssets: assets}}},
+		{Version: "0.2.6", Pipelines: []kabanerov1alpha2.PipelineStatus{{ActiveAssets: assets}}},
+	}}
+
+	summary := summarizeStackAssets("java-microprofile", status)
+	if summary.Active != 1 || summary.Failed != 1 {
+		t.Fatalf("Unexpected asset counts: %#v", summary)
+	}
+	if summary.Selector != "app.kubernetes.io/managed-by=kabanero,kabanero.io/stack-id=java-microprofile" {
+		t.Fatalf("Unexpected asset selector: %v", summary.Selector)
+	}
+}
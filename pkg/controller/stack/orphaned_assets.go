@@ -0,0 +1,323 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// How often the stack controller looks for orphaned stack assets, after the
+// first look when the controller starts.
+const orphanedAssetSweepInterval = 1 * time.Hour
+
+// The Tekton kinds which the stack controller creates from pipeline archives
+var stackAssetKinds = []string{"Pipeline", "Task", "Condition", "TriggerBinding", "TriggerTemplate"}
+
+// The Tekton API versions which the stack controller creates assets in
+var stackAssetVersions = []string{tektonV1alpha1, tektonV1beta1}
+
+// Looks for stack assets whose owning stack no longer exists.  This happens
+// when a stack is force deleted, or its status is lost, so that its finalizer
+// does not remove its assets.  Kubernetes garbage collection takes care of
+// most of these, but not assets created in a different namespace, or assets
+// which are still owned by something else.
+type orphanedAssetSweeper struct {
+	namespace string
+	client    client.Client
+	// Reads directly from the API server, since the assets in other namespaces
+	// are not in the cache.
+	reader client.Reader
+}
+
+// A stack asset whose owning stacks no longer exist.
+type orphanedAsset struct {
+	object *unstructured.Unstructured
+	// The UIDs of the stacks which no longer exist
+	missingOwners map[types.UID]bool
+	// A stack in the namespace which still shares the asset, and which the
+	// owner annotations are pointed at in place of the missing stack
+	replacementOwner *metav1.OwnerReference
+	// Why the asset may still be in use, if it is only reported
+	reportOnly string
+}
+
+// Start implements manager.Runnable.  The sweep runs once at startup, and
+// then periodically until the controller is stopped.
+func (s *orphanedAssetSweeper) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(orphanedAssetSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sweep(context.Background())
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Finds the orphaned stack assets, and reports or deletes them according to
+// the policy of the Kabanero instance.
+func (s *orphanedAssetSweeper) sweep(ctx context.Context) {
+	orphans, err := findOrphanedAssets(ctx, s.namespace, s.reader)
+	if err != nil {
+		log.Error(err, "Unable to look for orphaned stack assets")
+		return
+	}
+
+	if len(orphans) == 0 {
+		return
+	}
+
+	policy := getOrphanedAssetPolicy(ctx, s.namespace, s.reader)
+	for _, orphan := range orphans {
+		description := fmt.Sprintf("%v %v in namespace %v", orphan.object.GetKind(), orphan.object.GetName(), orphan.object.GetNamespace())
+		if len(orphan.reportOnly) != 0 {
+			log.Info(fmt.Sprintf("Found stack asset %v which may be orphaned. %v It is not deleted.", description, orphan.reportOnly))
+			continue
+		}
+
+		if policy != kabanerov1alpha2.OrphanedAssetPolicyDelete {
+			if orphan.replacementOwner != nil {
+				log.Info(fmt.Sprintf("Found stack asset %v whose recorded owning stack no longer exists. It is still used by stack %v.", description, orphan.replacementOwner.Name))
+			} else {
+				log.Info(fmt.Sprintf("Found orphaned stack asset %v. Its owning stack no longer exists.", description))
+			}
+			continue
+		}
+
+		deleted, err := deleteOrphanedAsset(ctx, s.client, orphan)
+		switch {
+		case err != nil:
+			log.Error(err, fmt.Sprintf("Unable to delete orphaned stack asset %v", description))
+		case deleted:
+			log.Info(fmt.Sprintf("Deleted orphaned stack asset %v", description))
+		default:
+			log.Info(fmt.Sprintf("Removed the missing stacks from stack asset %v, which is still owned by something else", description))
+		}
+	}
+}
+
+// Returns the orphaned asset policy of the Kabanero instance in the namespace.
+func getOrphanedAssetPolicy(ctx context.Context, namespace string, reader client.Reader) string {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := reader.List(ctx, kabaneroList, client.InNamespace(namespace))
+	if err != nil || len(kabaneroList.Items) == 0 || kabaneroList.Items[0].Spec.Stacks.OrphanedAssetPolicy == "" {
+		return kabanerov1alpha2.OrphanedAssetPolicyReport
+	}
+
+	return kabaneroList.Items[0].Spec.Stacks.OrphanedAssetPolicy
+}
+
+// Lists the stack assets belonging to stacks in a namespace, and returns those
+// whose owning stacks no longer exist.  Assets in the stack namespace are
+// owned through owner references.  Assets in other namespaces are owned
+// through annotations, since owner references cannot cross namespaces.
+func findOrphanedAssets(ctx context.Context, namespace string, reader client.Reader) ([]orphanedAsset, error) {
+	var assets []unstructured.Unstructured
+	for _, kind := range stackAssetKinds {
+		u := &unstructured.Unstructured{}
+		u.SetKind(kind)
+		items, err := listStackAssetKind(ctx, reader, kind, getNamespaceForObject(u, namespace))
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, items...)
+	}
+
+	// The stacks are listed after the assets, so that the assets of a stack
+	// created during the sweep do not look like they have been orphaned.
+	stackList := &kabanerov1alpha2.StackList{}
+	err := reader.List(ctx, stackList, client.InNamespace(namespace))
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := make(map[types.UID]bool)
+	for _, stack := range stackList.Items {
+		stacks[stack.UID] = true
+	}
+
+	var orphans []orphanedAsset
+	for i := range assets {
+		asset := &assets[i]
+		if asset.GetNamespace() != namespace {
+			if orphan, found := findOrphanedCrossNamespaceAsset(asset, namespace, stacks); found {
+				orphans = append(orphans, orphan)
+			}
+			continue
+		}
+
+		var owners []types.UID
+		for _, ownerRef := range asset.GetOwnerReferences() {
+			if isStackOwnerReference(ownerRef) {
+				owners = append(owners, ownerRef.UID)
+			}
+		}
+		if len(owners) == 0 {
+			continue
+		}
+
+		missing := make(map[types.UID]bool)
+		for _, owner := range owners {
+			if !stacks[owner] {
+				missing[owner] = true
+			}
+		}
+
+		if len(missing) == len(owners) {
+			orphans = append(orphans, orphanedAsset{object: asset, missingOwners: missing})
+		}
+	}
+
+	return orphans, nil
+}
+
+// Lists the assets of a Tekton kind in a namespace, in each Tekton API version
+// which the cluster serves.  The API server returns the same object in every
+// version, so each asset is only listed once.
+func listStackAssetKind(ctx context.Context, reader client.Reader, kind string, namespace string, opts ...client.ListOption) ([]unstructured.Unstructured, error) {
+	var assets []unstructured.Unstructured
+	listed := make(map[string]bool)
+	for _, version := range stackAssetVersions {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{Group: tektonGroup, Version: version, Kind: kind + "List"})
+		err := reader.List(ctx, list, append([]client.ListOption{client.InNamespace(namespace)}, opts...)...)
+		if err != nil {
+			// Tekton triggers may not be installed, and older Tekton releases
+			// do not serve v1beta1.
+			if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			if !listed[item.GetName()] {
+				listed[item.GetName()] = true
+				assets = append(assets, item)
+			}
+		}
+	}
+
+	return assets, nil
+}
+
+// Determines if an asset in a different namespace from the stacks, which
+// belongs to a stack in the swept namespace, is orphaned.  Owner references
+// cannot cross namespaces, so the stack which created the asset is recorded
+// in the owner annotations.  Stacks which share the asset add owner
+// references to it, but these do not identify the namespace of the stack: an
+// owner reference which does not resolve to a stack in the swept namespace
+// may refer to a stack in another namespace.  Assets which may still be in
+// use by such a stack are only reported.
+func findOrphanedCrossNamespaceAsset(asset *unstructured.Unstructured, namespace string, stacks map[types.UID]bool) (orphanedAsset, bool) {
+	var liveOwner *metav1.OwnerReference
+	var unresolvedOwners []types.UID
+	for _, ownerRef := range asset.GetOwnerReferences() {
+		if !isStackOwnerReference(ownerRef) {
+			continue
+		}
+		if !stacks[ownerRef.UID] {
+			unresolvedOwners = append(unresolvedOwners, ownerRef.UID)
+		} else if liveOwner == nil {
+			ref := ownerRef
+			liveOwner = &ref
+		}
+	}
+
+	if isLegacyCrossNamespaceAsset(asset) {
+		if liveOwner != nil || len(unresolvedOwners) == 0 {
+			return orphanedAsset{}, false
+		}
+		return orphanedAsset{object: asset, reportOnly: fmt.Sprintf("It was created before stack assets recorded their owner, and none of its owning stacks are in namespace %v, but they may be in another namespace.", namespace)}, true
+	}
+
+	annotations := asset.GetAnnotations()
+	owner := types.UID(annotations[transforms.OwnerUidAnnotation])
+	if annotations[transforms.OwnerNamespaceAnnotation] != namespace || len(owner) == 0 || stacks[owner] {
+		return orphanedAsset{}, false
+	}
+
+	orphan := orphanedAsset{object: asset, missingOwners: map[types.UID]bool{owner: true}, replacementOwner: liveOwner}
+	if liveOwner == nil {
+		for _, uid := range unresolvedOwners {
+			if uid != owner {
+				orphan.reportOnly = fmt.Sprintf("It is still referenced by stacks which are not in namespace %v, and may be in use by a stack in another namespace.", namespace)
+				break
+			}
+		}
+	}
+	return orphan, true
+}
+
+// Determines if an asset in another namespace was created by the stack
+// controller before the owner annotations were added.
+func isLegacyCrossNamespaceAsset(asset *unstructured.Unstructured) bool {
+	if _, found := asset.GetAnnotations()[transforms.OwnerNamespaceAnnotation]; found {
+		return false
+	}
+	assetLabels := asset.GetLabels()
+	return assetLabels[ManagedByLabel] == ManagedByValue && len(assetLabels[StackIdLabel]) > 0
+}
+
+// Determines if an owner reference refers to a stack.
+func isStackOwnerReference(ownerRef metav1.OwnerReference) bool {
+	return ownerRef.Kind == "Stack" && strings.HasPrefix(ownerRef.APIVersion, kabanerov1alpha2.SchemeGroupVersion.Group+"/")
+}
+
+// Deletes an orphaned asset, and returns true if it was deleted.  If the asset
+// is still owned by something other than the missing stacks, only the
+// references to the missing stacks are removed, and the owner annotations are
+// pointed at the stack in the namespace which still shares the asset, if any.
+// The annotations are otherwise kept, so that the asset is not mistaken for
+// one created before they were added.
+func deleteOrphanedAsset(ctx context.Context, c client.Client, orphan orphanedAsset) (bool, error) {
+	u := orphan.object
+
+	var ownerRefs []metav1.OwnerReference
+	for _, ownerRef := range u.GetOwnerReferences() {
+		if !orphan.missingOwners[ownerRef.UID] {
+			ownerRefs = append(ownerRefs, ownerRef)
+		}
+	}
+
+	if len(ownerRefs) == 0 && orphan.replacementOwner == nil {
+		err := c.Delete(ctx, u)
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return err == nil, err
+	}
+
+	changed := len(ownerRefs) != len(u.GetOwnerReferences())
+	if orphan.replacementOwner != nil {
+		annotations := u.GetAnnotations()
+		annotations[transforms.OwnerUidAnnotation] = string(orphan.replacementOwner.UID)
+		u.SetAnnotations(annotations)
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+
+	u.SetOwnerReferences(ownerRefs)
+	err := c.Update(ctx, u)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,172 @@
+package stack
+
+import (
+	"context"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func stackOwnerReference(uid string) metav1.OwnerReference {
+	return metav1.OwnerReference{APIVersion: "kabanero.io/v1alpha2", Kind: "Stack", Name: "nodejs", UID: types.UID(uid)}
+}
+
+func tektonObject(kind string, name string, namespace string, ownerRefs []metav1.OwnerReference, annotations map[string]string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("tekton.dev/v1alpha1")
+	u.SetKind(kind)
+	u.SetName(name)
+	u.SetNamespace(namespace)
+	u.SetOwnerReferences(ownerRefs)
+	u.SetAnnotations(annotations)
+	return u
+}
+
+func newOrphanedAssetClient(t *testing.T, policy string) client.Client {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Stacks: kabanerov1alpha2.InstanceStackConfig{OrphanedAssetPolicy: policy},
+		},
+	}
+	stack := &kabanerov1alpha2.Stack{ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero", UID: "live"}}
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "configmap"}
+
+	v1beta1Task := tektonObject("Task", "orphaned-v1beta1-task", "kabanero", []metav1.OwnerReference{stackOwnerReference("gone")}, nil)
+	v1beta1Task.SetAPIVersion("tekton.dev/v1beta1")
+	legacyLabels := map[string]string{ManagedByLabel: ManagedByValue, StackIdLabel: "java-microprofile"}
+	legacyBinding := tektonObject("TriggerBinding", "orphaned-legacy-binding", "tekton-pipelines", []metav1.OwnerReference{stackOwnerReference("gone")}, nil)
+	legacyBinding.SetLabels(legacyLabels)
+	liveLegacyBinding := tektonObject("TriggerBinding", "live-legacy-binding", "tekton-pipelines", []metav1.OwnerReference{stackOwnerReference("live")}, nil)
+	liveLegacyBinding.SetLabels(legacyLabels)
+	missingOwner := map[string]string{transforms.OwnerUidAnnotation: "gone", transforms.OwnerNamespaceAnnotation: "kabanero"}
+	return newTestClient(t, k, stack, v1beta1Task, legacyBinding, liveLegacyBinding,
+		tektonObject("Task", "live-task", "kabanero", []metav1.OwnerReference{stackOwnerReference("live")}, nil),
+		tektonObject("Task", "orphaned-task", "kabanero", []metav1.OwnerReference{stackOwnerReference("gone")}, nil),
+		tektonObject("Pipeline", "shared-pipeline", "kabanero", []metav1.OwnerReference{stackOwnerReference("gone"), other}, nil),
+		tektonObject("Pipeline", "unowned-pipeline", "kabanero", nil, nil),
+		tektonObject("TriggerBinding", "orphaned-binding", "tekton-pipelines", nil, missingOwner),
+		// Shared with a stack in another namespace, whose owner reference does not say which
+		tektonObject("TriggerBinding", "other-namespace-shared-binding", "tekton-pipelines", []metav1.OwnerReference{stackOwnerReference("elsewhere")}, missingOwner),
+		// Shared with a stack in the namespace
+		tektonObject("TriggerTemplate", "shared-template", "tekton-pipelines", []metav1.OwnerReference{stackOwnerReference("gone"), stackOwnerReference("live")}, missingOwner),
+		tektonObject("TriggerBinding", "live-binding", "tekton-pipelines", nil, map[string]string{
+			transforms.OwnerUidAnnotation: "live", transforms.OwnerNamespaceAnnotation: "kabanero"}),
+		tektonObject("TriggerTemplate", "other-namespace-template", "tekton-pipelines", nil, map[string]string{
+			transforms.OwnerUidAnnotation: "gone", transforms.OwnerNamespaceAnnotation: "other"}),
+	)
+}
+
+func tektonObjectExists(t *testing.T, cl client.Client, kind string, name string, namespace string) *unstructured.Unstructured {
+	u := tektonObject(kind, "", "", nil, nil)
+	err := cl.Get(context.Background(), client.ObjectKey{Name: name, Namespace: namespace}, u)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// Verifies only assets whose owning stacks are all gone are found
+func TestFindOrphanedAssets(t *testing.T) {
+	cl := newOrphanedAssetClient(t, "")
+	orphans, err := findOrphanedAssets(context.Background(), "kabanero", cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := make(map[string]orphanedAsset)
+	for _, orphan := range orphans {
+		found[orphan.object.GetName()] = orphan
+	}
+	for _, name := range []string{"orphaned-task", "shared-pipeline", "orphaned-binding", "orphaned-v1beta1-task", "orphaned-legacy-binding", "other-namespace-shared-binding", "shared-template"} {
+		if _, ok := found[name]; !ok {
+			t.Fatalf("Expected %v to be found: %v", name, found)
+		}
+	}
+	if len(found) != 7 {
+		t.Fatalf("Unexpected orphaned assets: %v", found)
+	}
+
+	// Assets which may be in use by stacks in other namespaces are only reported
+	for name, orphan := range found {
+		reportOnly := name == "orphaned-legacy-binding" || name == "other-namespace-shared-binding"
+		if (len(orphan.reportOnly) != 0) != reportOnly {
+			t.Fatalf("Unexpected report only reason for %v: %v", name, orphan.reportOnly)
+		}
+	}
+	if owner := found["shared-template"].replacementOwner; owner == nil || owner.UID != "live" {
+		t.Fatalf("Expected the shared template to be given the live stack as its owner: %v", owner)
+	}
+}
+
+// Verifies orphaned assets are only reported by default
+func TestSweepOrphanedAssetsReport(t *testing.T) {
+	cl := newOrphanedAssetClient(t, "")
+	sweeper := &orphanedAssetSweeper{namespace: "kabanero", client: cl, reader: cl}
+	sweeper.sweep(context.Background())
+
+	if tektonObjectExists(t, cl, "Task", "orphaned-task", "kabanero") == nil {
+		t.Fatal("Expected the orphaned task to be reported, not deleted")
+	}
+}
+
+// Verifies orphaned assets are deleted, and shared assets lose their stack owner, when requested
+func TestSweepOrphanedAssetsDelete(t *testing.T) {
+	cl := newOrphanedAssetClient(t, kabanerov1alpha2.OrphanedAssetPolicyDelete)
+	sweeper := &orphanedAssetSweeper{namespace: "kabanero", client: cl, reader: cl}
+	sweeper.sweep(context.Background())
+
+	if tektonObjectExists(t, cl, "Task", "orphaned-task", "kabanero") != nil {
+		t.Fatal("Expected the orphaned task to be deleted")
+	}
+	if tektonObjectExists(t, cl, "TriggerBinding", "orphaned-binding", "tekton-pipelines") != nil {
+		t.Fatal("Expected the orphaned trigger binding to be deleted")
+	}
+	v1beta1Task := tektonObject("Task", "", "", nil, nil)
+	v1beta1Task.SetAPIVersion("tekton.dev/v1beta1")
+	if err := cl.Get(context.Background(), client.ObjectKey{Name: "orphaned-v1beta1-task", Namespace: "kabanero"}, v1beta1Task); !errors.IsNotFound(err) {
+		t.Fatalf("Expected the orphaned v1beta1 task to be deleted: %v", err)
+	}
+
+	shared := tektonObjectExists(t, cl, "Pipeline", "shared-pipeline", "kabanero")
+	if shared == nil || len(shared.GetOwnerReferences()) != 1 || shared.GetOwnerReferences()[0].Kind != "ConfigMap" {
+		t.Fatalf("Expected the shared pipeline to lose only its stack owner: %v", shared)
+	}
+
+	// The owner annotations of a shared asset move to the stack which still uses it
+	shared = tektonObjectExists(t, cl, "TriggerTemplate", "shared-template", "tekton-pipelines")
+	if shared == nil || len(shared.GetOwnerReferences()) != 1 || shared.GetOwnerReferences()[0].UID != "live" {
+		t.Fatalf("Expected the shared template to lose only its missing stack owner: %v", shared)
+	}
+	if annotations := shared.GetAnnotations(); annotations[transforms.OwnerUidAnnotation] != "live" || annotations[transforms.OwnerNamespaceAnnotation] != "kabanero" {
+		t.Fatalf("Expected the shared template to be annotated with the live stack: %v", annotations)
+	}
+
+	// Assets which may be used by stacks in other namespaces are left alone
+	shared = tektonObjectExists(t, cl, "TriggerBinding", "other-namespace-shared-binding", "tekton-pipelines")
+	if shared == nil || len(shared.GetOwnerReferences()) != 1 || shared.GetAnnotations()[transforms.OwnerUidAnnotation] != "gone" {
+		t.Fatalf("Expected the binding shared with another namespace to be unchanged: %v", shared)
+	}
+
+	for _, asset := range []struct{ kind, name, namespace string }{
+		{"Task", "live-task", "kabanero"},
+		{"Pipeline", "unowned-pipeline", "kabanero"},
+		{"TriggerBinding", "live-binding", "tekton-pipelines"},
+		{"TriggerBinding", "live-legacy-binding", "tekton-pipelines"},
+		{"TriggerBinding", "orphaned-legacy-binding", "tekton-pipelines"},
+		{"TriggerTemplate", "other-namespace-template", "tekton-pipelines"},
+	} {
+		if tektonObjectExists(t, cl, asset.kind, asset.name, asset.namespace) == nil {
+			t.Fatalf("Expected %v %v to be kept", asset.kind, asset.name)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The parts of the platform which stack version requirements are checked against.
+type stackPlatform struct {
+	kabaneroVersion    string
+	kabaneroVersionErr error
+	tektonApiVersions  []string
+	tektonErr          error
+	// The active versions of each stack in the namespace, by stack name
+	activeStacks    map[string][]string
+	activeStacksErr error
+}
+
+// Determines if a stack version declares any requirements.
+func hasRequirements(requires kabanerov1alpha2.StackRequirements) bool {
+	return len(requires.Kabanero) > 0 || len(requires.TektonApiVersions) > 0 || len(requires.Stacks) > 0
+}
+
+// Retrieves the Kabanero version, the Tekton API versions served by the
+// cluster, and the active stack versions in the stack's namespace.
+func getStackPlatform(ctx context.Context, namespace string, c client.Client) *stackPlatform {
+	platform := &stackPlatform{activeStacks: make(map[string][]string)}
+
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneroList, client.InNamespace(namespace))
+	if err != nil {
+		platform.kabaneroVersionErr = err
+	} else if len(kabaneroList.Items) == 0 {
+		platform.kabaneroVersionErr = fmt.Errorf("No Kabanero instance was found in namespace %v", namespace)
+	} else {
+		k := kabaneroList.Items[0]
+		platform.kabaneroVersion = k.Status.KabaneroInstance.Version
+		if platform.kabaneroVersion == "" {
+			platform.kabaneroVersion = k.Spec.Version
+		}
+		if platform.kabaneroVersion == "" {
+			platform.kabaneroVersionErr = fmt.Errorf("The version of Kabanero instance %v is not known yet", k.Name)
+		}
+	}
+
+	platform.tektonApiVersions, platform.tektonErr = getTektonApiVersions(ctx, c)
+
+	stackList := &kabanerov1alpha2.StackList{}
+	err = c.List(ctx, stackList, client.InNamespace(namespace))
+	if err != nil {
+		platform.activeStacksErr = err
+	} else {
+		for _, stack := range stackList.Items {
+			name := stack.Spec.Name
+			if name == "" {
+				name = stack.Name
+			}
+			for _, version := range stack.Status.Versions {
+				if version.Status == kabanerov1alpha2.StackDesiredStateActive {
+					platform.activeStacks[name] = append(platform.activeStacks[name], version.Version)
+				}
+			}
+		}
+	}
+
+	return platform
+}
+
+// Checks the requirements of a stack version against the platform.  A message
+// is returned for each requirement which is not satisfied.
+func checkStackRequirements(requires kabanerov1alpha2.StackRequirements, platform *stackPlatform) []string {
+	var problems []string
+
+	if len(requires.Kabanero) > 0 {
+		if platform.kabaneroVersionErr != nil {
+			problems = append(problems, fmt.Sprintf("Unable to determine the Kabanero version required to be %v: %v.", requires.Kabanero, platform.kabaneroVersionErr))
+		} else {
+			satisfied, err := versionSatisfies(platform.kabaneroVersion, requires.Kabanero)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Unable to check the Kabanero version: %v.", err))
+			} else if !satisfied {
+				problems = append(problems, fmt.Sprintf("Kabanero version %v is required, but the Kabanero version is %v.", requires.Kabanero, platform.kabaneroVersion))
+			}
+		}
+	}
+
+	for _, apiVersion := range requires.TektonApiVersions {
+		if platform.tektonErr != nil {
+			problems = append(problems, fmt.Sprintf("Unable to determine the Tekton API versions served by the cluster: %v.", platform.tektonErr))
+			break
+		}
+		if !containsString(platform.tektonApiVersions, apiVersion) {
+			problems = append(problems, fmt.Sprintf("Tekton API version %v is required, but the cluster serves %v.", apiVersion, strings.Join(platform.tektonApiVersions, ", ")))
+		}
+	}
+
+dependencies:
+	for _, dependency := range requires.Stacks {
+		if platform.activeStacksErr != nil {
+			problems = append(problems, fmt.Sprintf("Unable to determine the active stacks: %v.", platform.activeStacksErr))
+			break
+		}
+
+		satisfied := false
+		for _, version := range platform.activeStacks[dependency.Name] {
+			if dependency.Version == "" {
+				satisfied = true
+				break
+			}
+			ok, err := versionSatisfies(version, dependency.Version)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("Unable to check the version of stack %v: %v.", dependency.Name, err))
+				continue dependencies
+			}
+			if ok {
+				satisfied = true
+				break
+			}
+		}
+		if !satisfied {
+			if dependency.Version == "" {
+				problems = append(problems, fmt.Sprintf("An active version of stack %v is required.", dependency.Name))
+			} else {
+				problems = append(problems, fmt.Sprintf("An active version %v of stack %v is required.", dependency.Version, dependency.Name))
+			}
+		}
+	}
+
+	return problems
+}
+
+// Determines if a version satisfies a semantic version range.
+func versionSatisfies(version string, constraint string) (bool, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return false, fmt.Errorf("Version %v is not a valid semantic version: %v", version, err)
+	}
+
+	r, err := semver.ParseRange(constraint)
+	if err != nil {
+		return false, fmt.Errorf("The version range %v is not valid: %v", constraint, err)
+	}
+
+	return r(v), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the requests for the stacks in a namespace which have versions left
+// inactive because their requirements are not satisfied.
+func unsatisfiedStackRequests(ctx context.Context, c client.Client, namespace string) []reconcile.Request {
+	stackList := &kabanerov1alpha2.StackList{}
+	err := c.List(ctx, stackList, client.InNamespace(namespace))
+	if err != nil {
+		log.Error(err, "Unable to list the stacks with unsatisfied requirements", "namespace", namespace)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for i := range stackList.Items {
+		if unsatisfiedVersions(&stackList.Items[i]) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: stackList.Items[i].Name}})
+		}
+	}
+	return requests
+}
+
+// Determines if a Kabanero instance update changed its version, which stack versions may require.
+func kabaneroVersionChanged(e event.UpdateEvent) bool {
+	oldK, ok := e.ObjectOld.(*kabanerov1alpha2.Kabanero)
+	if !ok {
+		return true
+	}
+	newK, ok := e.ObjectNew.(*kabanerov1alpha2.Kabanero)
+	if !ok {
+		return true
+	}
+	return oldK.Spec.Version != newK.Spec.Version || oldK.Status.KabaneroInstance.Version != newK.Status.KabaneroInstance.Version
+}
+
+// Determines if a Stack update changed its active versions, which other stack versions may require.
+func activeStackVersionsChanged(e event.UpdateEvent) bool {
+	oldStack, ok := e.ObjectOld.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return true
+	}
+	newStack, ok := e.ObjectNew.(*kabanerov1alpha2.Stack)
+	if !ok {
+		return true
+	}
+	return activeStackVersions(oldStack) != activeStackVersions(newStack)
+}
+
+// Returns the names and active versions of a stack, as a string which can be compared.
+func activeStackVersions(stack *kabanerov1alpha2.Stack) string {
+	active := []string{stack.Spec.Name}
+	for _, version := range stack.Status.Versions {
+		if version.Status == kabanerov1alpha2.StackDesiredStateActive {
+			active = append(active, version.Version)
+		}
+	}
+	return strings.Join(active, ",")
+}
@@ -0,0 +1,182 @@
+package stack
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// Verifies each kind of requirement is checked against the platform
+func TestCheckStackRequirements(t *testing.T) {
+	platform := &stackPlatform{
+		kabaneroVersion:   "0.7.0",
+		tektonApiVersions: []string{"v1alpha1", "v1beta1"},
+		activeStacks:      map[string][]string{"java-microprofile": {"0.2.5"}},
+	}
+
+	tests := []struct {
+		name      string
+		requires  kabanerov1alpha2.StackRequirements
+		satisfied bool
+	}{
+		{"kabanero", kabanerov1alpha2.StackRequirements{Kabanero: ">=0.7.0"}, true},
+		{"kabanero too old", kabanerov1alpha2.StackRequirements{Kabanero: ">=0.8.0"}, false},
+		{"kabanero bad range", kabanerov1alpha2.StackRequirements{Kabanero: "latest"}, false},
+		{"tekton", kabanerov1alpha2.StackRequirements{TektonApiVersions: []string{"v1beta1"}}, true},
+		{"tekton missing", kabanerov1alpha2.StackRequirements{TektonApiVersions: []string{"v1"}}, false},
+		{"stack", kabanerov1alpha2.StackRequirements{Stacks: []kabanerov1alpha2.StackDependency{{Name: "java-microprofile"}}}, true},
+		{"stack version", kabanerov1alpha2.StackRequirements{Stacks: []kabanerov1alpha2.StackDependency{{Name: "java-microprofile", Version: ">=0.2.0 <0.3.0"}}}, true},
+		{"stack version too old", kabanerov1alpha2.StackRequirements{Stacks: []kabanerov1alpha2.StackDependency{{Name: "java-microprofile", Version: ">=0.3.0"}}}, false},
+		{"stack inactive", kabanerov1alpha2.StackRequirements{Stacks: []kabanerov1alpha2.StackDependency{{Name: "nodejs"}}}, false},
+	}
+
+	for _, test := range tests {
+		problems := checkStackRequirements(test.requires, platform)
+		if (len(problems) == 0) != test.satisfied {
+			t.Fatalf("%v: expected satisfied to be %v, but got problems: %v", test.name, test.satisfied, problems)
+		}
+	}
+}
+
+// Verifies the platform is read from the Kabanero instance, the Tekton CRD and the stacks in the namespace
+func TestGetStackPlatform(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Version: "0.8.0"},
+		Status:     kabanerov1alpha2.KabaneroStatus{KabaneroInstance: kabanerov1alpha2.KabaneroInstanceStatus{Version: "0.7.0"}},
+	}
+	stack := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "java-microprofile"},
+		Status: kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{
+			{Version: "0.2.5", Status: kabanerov1alpha2.StackDesiredStateActive},
+			{Version: "0.2.4", Status: kabanerov1alpha2.StackDesiredStateInactive},
+		}},
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1beta1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName(tektonPipelineCRDName)
+	unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"name": "v1alpha1", "served": true},
+		map[string]interface{}{"name": "v1beta1", "served": true},
+		map[string]interface{}{"name": "v1", "served": false},
+	}, "spec", "versions")
+
+	cl := newTestClient(t, k, stack, crd)
+
+	platform := getStackPlatform(context.Background(), "kabanero", cl)
+	if platform.kabaneroVersion != "0.7.0" || platform.kabaneroVersionErr != nil {
+		t.Fatalf("Expected the running Kabanero version: %v %v", platform.kabaneroVersion, platform.kabaneroVersionErr)
+	}
+	if strings.Join(platform.tektonApiVersions, ",") != "v1alpha1,v1beta1" || platform.tektonErr != nil {
+		t.Fatalf("Expected the served Tekton API versions: %v %v", platform.tektonApiVersions, platform.tektonErr)
+	}
+	if versions := platform.activeStacks["java-microprofile"]; len(versions) != 1 || versions[0] != "0.2.5" {
+		t.Fatalf("Expected the active stack version: %v", platform.activeStacks)
+	}
+
+	platform = getStackPlatform(context.Background(), "other", cl)
+	if platform.kabaneroVersionErr == nil {
+		t.Fatal("Expected an error for a namespace without a Kabanero instance")
+	}
+}
+
+// Verifies a stack version with unsatisfied requirements is left inactive, and its assets are not applied
+func TestReconcileActiveVersionsUnsatisfiedRequirements(t *testing.T) {
+	server := httptest.NewServer(stackHandler{})
+	defer server.Close()
+
+	stackResource := kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{UID: myuid, Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.StackSpec{
+			Name: "java-microprofile",
+			Versions: []kabanerov1alpha2.StackVersion{{
+				Version:      "0.2.5",
+				DesiredState: "active",
+				Pipelines: []kabanerov1alpha2.PipelineSpec{{
+					Id:     "default",
+					Sha256: basicPipeline.sha256,
+					Https:  kabanerov1alpha2.HttpsProtocolFile{Url: server.URL + basicPipeline.name},
+				}},
+				Images: []kabanerov1alpha2.Image{{
+					Id:    "default",
+					Image: "kabanero/kabanero-image",
+				}},
+				Requires: kabanerov1alpha2.StackRequirements{Kabanero: ">=0.7.0"},
+			}},
+		},
+	}
+
+	// The unit test client does not know of any Kabanero instance
+	client := unitTestClient{map[client.ObjectKey][]metav1.OwnerReference{}}
+
+	err := reconcileActiveVersions(context.Background(), &stackResource, client)
+	if err != nil {
+		t.Fatal("Returned error: " + err.Error())
+	}
+
+	status := stackResource.Status.Versions[0]
+	if status.Status != kabanerov1alpha2.StackDesiredStateInactive || !strings.Contains(status.StatusMessage, "requirements are not satisfied") {
+		t.Fatalf("Expected the stack version to be inactive with an explanation: %#v", status)
+	}
+	if len(status.Pipelines) != 0 || len(client.objs) != 0 {
+		t.Fatalf("Expected no assets to be applied: %v %v", status.Pipelines, client.objs)
+	}
+	if !unsatisfiedVersions(&stackResource) {
+		t.Fatal("Expected the stack to be requeued until the requirements are satisfied")
+	}
+}
+
+// Verifies the stacks with unsatisfied requirements are reconciled when the platform changes
+func TestUnsatisfiedStackRequests(t *testing.T) {
+	unsatisfied := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "needs-kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.StackSpec{Versions: []kabanerov1alpha2.StackVersion{{
+			Version: "1.0.0", DesiredState: kabanerov1alpha2.StackDesiredStateActive, Requires: kabanerov1alpha2.StackRequirements{Kabanero: ">=0.9.0"},
+		}}},
+		Status: kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{Version: "1.0.0", Status: kabanerov1alpha2.StackDesiredStateInactive}}},
+	}
+	active := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "java-microprofile", Namespace: "kabanero"},
+		Spec:       kabanerov1alpha2.StackSpec{Name: "java-microprofile", Versions: []kabanerov1alpha2.StackVersion{{Version: "0.2.5"}}},
+		Status:     kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{Version: "0.2.5", Status: kabanerov1alpha2.StackDesiredStateActive}}},
+	}
+
+	cl := newTestClient(t, unsatisfied, active)
+
+	requests := unsatisfiedStackRequests(context.Background(), cl, "kabanero")
+	if len(requests) != 1 || requests[0].Name != "needs-kabanero" || requests[0].Namespace != "kabanero" {
+		t.Fatalf("Expected a request for the stack with unsatisfied requirements, but got %v", requests)
+	}
+
+	// Only changes to the active stack versions are of interest.
+	updated := active.DeepCopy()
+	updated.Status.StatusMessage = "reconciled"
+	if activeStackVersionsChanged(event.UpdateEvent{ObjectOld: active, ObjectNew: updated}) {
+		t.Fatal("Expected a status message change to be ignored")
+	}
+	updated.Status.Versions[0].Status = kabanerov1alpha2.StackDesiredStateInactive
+	if !activeStackVersionsChanged(event.UpdateEvent{ObjectOld: active, ObjectNew: updated}) {
+		t.Fatal("Expected a change of the active versions to be noticed")
+	}
+
+	// Likewise for the Kabanero version.
+	k := &kabanerov1alpha2.Kabanero{Spec: kabanerov1alpha2.KabaneroSpec{Version: "0.8.0"}}
+	updatedK := k.DeepCopy()
+	updatedK.Status.KabaneroInstance.Message = "ready"
+	if kabaneroVersionChanged(event.UpdateEvent{ObjectOld: k, ObjectNew: updatedK}) {
+		t.Fatal("Expected a status message change to be ignored")
+	}
+	updatedK.Status.KabaneroInstance.Version = "0.9.0"
+	if !kabaneroVersionChanged(event.UpdateEvent{ObjectOld: k, ObjectNew: updatedK}) {
+		t.Fatal("Expected a change of the Kabanero version to be noticed")
+	}
+}
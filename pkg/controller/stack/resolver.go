@@ -2,7 +2,6 @@ package stack
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/google/go-github/v29/github"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
 	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
@@ -19,20 +19,21 @@ import (
 )
 
 // ResolveIndex returns a structure representation of the yaml file represented by the index.
-func ResolveIndex(c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string) (*Index, error) {
+// The index is retrieved using the context, so the retrieval can be cancelled or limited in time.
+func ResolveIndex(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string, pipelines []Pipelines, triggers []Trigger, imagePrefix string) (*Index, error) {
 	var indexBytes []byte
 
 	switch {
 	// GIT:
-	case isGitReleaseUsable(repoConf.GitRelease):
-		bytes, err := getStackIndexUsingGit(c, repoConf.GitRelease, namespace)
+	case sutils.IsGitReleaseUsable(repoConf.GitRelease):
+		bytes, err := getStackIndexUsingGit(ctx, c, repoConf.GitRelease, namespace)
 		if err != nil {
 			return nil, err
 		}
 		indexBytes = bytes
 	// HTTPS:
 	case len(repoConf.Https.Url) != 0:
-		bytes, err := getStackIndexUsingHttp(repoConf)
+		bytes, err := getStackIndexUsingHttp(ctx, c, repoConf, namespace)
 		if err != nil {
 			return nil, err
 		}
@@ -123,14 +124,8 @@ func SearchStack(stackName string, index *Index) ([]Stack, error) {
 	return stackRefs, nil
 }
 
-// Returns true if the user specified all values in Kabanero.Spec.Stacks.Repositories.GitRelease.
-func isGitReleaseUsable(gitRelease kabanerov1alpha2.GitReleaseSpec) bool {
-	return len(gitRelease.Hostname) != 0 && len(gitRelease.Organization) != 0 && len(gitRelease.Project) != 0 &&
-		len(gitRelease.Release) != 0 && len(gitRelease.AssetName) != 0
-}
-
 // Retrieves a stack index file content using HTTP.
-func getStackIndexUsingHttp(repoConf kabanerov1alpha2.RepositoryConfig) ([]byte, error) {
+func getStackIndexUsingHttp(ctx context.Context, c client.Client, repoConf kabanerov1alpha2.RepositoryConfig, namespace string) ([]byte, error) {
 	url := repoConf.Https.Url
 
 	// user may specify url to yaml file or directory
@@ -142,21 +137,51 @@ func getStackIndexUsingHttp(repoConf kabanerov1alpha2.RepositoryConfig) ([]byte,
 		url = url + "/index.yaml"
 	}
 
-	return getFromCache(url, repoConf.Https.SkipCertVerification)
+	creds, err := getHttpsCredentials(ctx, c, repoConf.Https, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return downloadFromCache(ctx, url, creds)
+}
+
+// Retrieves a resource using the cache, within the download timeout.
+func downloadFromCache(ctx context.Context, url string, creds httpsCredentials) ([]byte, error) {
+	dctx, cancel, timeout := downloadContext(ctx)
+	defer cancel()
+
+	b, err := getFromCache(dctx, url, creds)
+	if ctx.Err() == nil {
+		err = checkTimeout(dctx, err, "retrieving "+url, timeout)
+	}
+	return b, err
+}
+
+// Retrieves a stack index file content using GitHub APIs.  The GitHub requests
+// together are limited to the download timeout.
+func getStackIndexUsingGit(ctx context.Context, c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, namespace string) ([]byte, error) {
+	dctx, cancel, timeout := downloadContext(ctx)
+	defer cancel()
+
+	indexBytes, err := getReleaseAssetUsingGit(dctx, c, gitRelease, namespace)
+	if ctx.Err() == nil {
+		err = checkTimeout(dctx, err, fmt.Sprintf("retrieving release asset %v of %v/%v/%v", gitRelease.AssetName, gitRelease.Hostname, gitRelease.Organization, gitRelease.Project), timeout)
+	}
+	return indexBytes, err
 }
 
-// Retrieves a stack index file content using GitHub APIs
-func getStackIndexUsingGit(c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, namespace string) ([]byte, error) {
+// Retrieves a GitHub release asset.
+func getReleaseAssetUsingGit(ctx context.Context, c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, namespace string) ([]byte, error) {
 	var indexBytes []byte
 
 	// Get a Github client.
-	gclient, err := getGitClient(c, gitRelease, namespace)
+	gclient, err := getGitClient(ctx, c, gitRelease, namespace)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get the release tagged in Github as repoConf.GitRelease.Release.
-	release, response, err := gclient.Repositories.GetReleaseByTag(context.Background(), gitRelease.Organization, gitRelease.Project, gitRelease.Release)
+	release, response, err := gclient.Repositories.GetReleaseByTag(ctx, gitRelease.Organization, gitRelease.Project, gitRelease.Release)
 	if err != nil || response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Unable to retrieve object representing Github repository release %v. Configured GitRelease data: %v. Error: %v", gitRelease.Release, gitRelease, err)
 	}
@@ -166,7 +191,7 @@ func getStackIndexUsingGit(c client.Client, gitRelease kabanerov1alpha2.GitRelea
 	for _, asset := range assets {
 		if asset.GetName() == gitRelease.AssetName {
 			id := asset.GetID()
-			reader, _, err := gclient.Repositories.DownloadReleaseAsset(context.Background(), gitRelease.Organization, gitRelease.Project, id, http.DefaultClient)
+			reader, _, err := gclient.Repositories.DownloadReleaseAsset(ctx, gitRelease.Organization, gitRelease.Project, id, cutils.NewHTTPClient(gitRelease.SkipCertVerification))
 			if err != nil {
 				return nil, fmt.Errorf("Unable to download release asset %v. Configured GitRelease data: %v. Error: %v", gitRelease.AssetName, gitRelease, err)
 			}
@@ -185,22 +210,16 @@ func getStackIndexUsingGit(c client.Client, gitRelease kabanerov1alpha2.GitRelea
 }
 
 // Retrieves a Git client.
-func getGitClient(c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, namespace string) (*github.Client, error) {
+func getGitClient(ctx context.Context, c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, namespace string) (*github.Client, error) {
 	var client *github.Client
 
-	transport := &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: gitRelease.SkipCertVerification}}
+	transport := cutils.NewTransport(gitRelease.SkipCertVerification)
 
-	// Search all secrets under the given namespace for the one containing the required hostname.
-	secret, err := cutils.GetMatchingSecret(c, namespace, secretFilter, gitRelease.Hostname)
+	pat, err := getCredentialProvider(c, gitRelease.Credentials, namespace).GetToken(ctx, gitRelease.Hostname)
 	if err != nil {
 		return nil, err
 	}
 
-	var pat []byte
-	if secret != nil {
-		pat, _ = secret.Data["password"]
-	}
-
 	httpClient, err := cutils.GetHTTPClient(pat, transport)
 	if err != nil {
 		return nil, err
@@ -225,6 +244,23 @@ func getGitClient(c client.Client, gitRelease kabanerov1alpha2.GitReleaseSpec, n
 	return client, nil
 }
 
+// Returns the provider of the token for a file.  When no credentials source is
+// specified, the Secrets in the namespace are searched for one annotated with the
+// URL of the host.
+func getCredentialProvider(c client.Client, credentials kabanerov1alpha2.CredentialsSource, namespace string) cutils.CredentialProvider {
+	switch {
+	case len(credentials.SecretRef.Name) != 0:
+		return cutils.SecretRefCredentialProvider{Client: c, Namespace: namespace, Name: credentials.SecretRef.Name, Key: credentials.SecretRef.Key}
+	case len(credentials.File) != 0:
+		return cutils.FileCredentialProvider{Path: credentials.File}
+	case credentials.Vault != (kabanerov1alpha2.VaultSecretSource{}):
+		vault := credentials.Vault
+		return cutils.VaultCredentialProvider{Address: vault.Address, Path: vault.Path, Key: vault.Key, Role: vault.Role}
+	default:
+		return cutils.AnnotatedSecretCredentialProvider{Client: c, Namespace: namespace, Filter: secretFilter}
+	}
+}
+
 // Custom filter method that allows the retrieval of a secret containing an annotation with a value
 // that has the input filter strings (hostname). If there are multiple matching secrets, the annotation
 // with the lexically lowest value key (kabanero.io/git-*) is used. If no secret matches annotation key
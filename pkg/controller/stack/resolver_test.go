@@ -1,9 +1,12 @@
 package stack
 
 import (
+	"context"
+	"reflect"
 	"testing"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -32,7 +35,7 @@ func TestResolveIndex(t *testing.T) {
 		},
 	}
 
-	index, err := ResolveIndex(nil, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "")
+	index, err := ResolveIndex(context.Background(), nil, repoConfig, "kabanero", []Pipelines{}, []Trigger{}, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -54,7 +57,7 @@ func TestResolveIndexForStacks(t *testing.T) {
 
 	pipelines := []Pipelines{{Id: "testPipeline", Sha256: "1234567890", Url: "https://github.com/kabanero-io/collections/releases/download/0.5.0-rc.2/incubator.common.pipeline.default.tar.gz"}}
 	triggers := []Trigger{{Id: "testTrigger", Sha256: "0987654321", Url: "https://github.com/kabanero-io/collections/releases/download/0.5.0-rc.2/incubator.trigger.tar.gz"}}
-	index, err := ResolveIndex(nil, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta")
+	index, err := ResolveIndex(context.Background(), nil, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta")
 
 	if err != nil {
 		t.Fatal(err)
@@ -118,7 +121,7 @@ func TestResolveIndexForStacksInPublicGitFailure1(t *testing.T) {
 
 	pipelines := []Pipelines{{Id: "testPipeline", Sha256: "1234567890", Url: "https://github.com/kabanero-io/collections/releases/download/0.5.0-rc.2/incubator.common.pipeline.default.tar.gz"}}
 	triggers := []Trigger{{Id: "testTrigger", Sha256: "0987654321", Url: "https://github.com/kabanero-io/collections/releases/download/0.5.0-rc.2/incubator.trigger.tar.gz"}}
-	index, err := ResolveIndex(nil, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta")
+	index, err := ResolveIndex(context.Background(), nil, repoConfig, "kabanero", pipelines, triggers, "kabanerobeta")
 
 	if err == nil {
 		t.Fatal("No Git release or Http url were specified. An error was expected. Index: ", index)
@@ -360,3 +363,23 @@ func TestSecretFilterHostFoundInMultipleSecrets(t *testing.T) {
 		t.Fatal("Secret:", secret.Name, " does not contain an annotation with a value that includes hostname:", hostname, ". Expected secrets: ", secret1.Name, "  or ", secret3.Name)
 	}
 }
+
+// Verifies the credentials source selects the provider, and the annotated Secrets are searched by default
+func TestGetCredentialProvider(t *testing.T) {
+	tests := []struct {
+		credentials kabanerov1alpha2.CredentialsSource
+		expected    cutils.CredentialProvider
+	}{
+		{kabanerov1alpha2.CredentialsSource{}, cutils.AnnotatedSecretCredentialProvider{}},
+		{kabanerov1alpha2.CredentialsSource{SecretRef: kabanerov1alpha2.SecretKeyRef{Name: "token"}}, cutils.SecretRefCredentialProvider{}},
+		{kabanerov1alpha2.CredentialsSource{File: "/etc/kabanero/credentials/token"}, cutils.FileCredentialProvider{}},
+		{kabanerov1alpha2.CredentialsSource{Vault: kabanerov1alpha2.VaultSecretSource{Path: "secret/data/github"}}, cutils.VaultCredentialProvider{}},
+	}
+
+	for _, test := range tests {
+		provider := getCredentialProvider(nil, test.credentials, "kabanero")
+		if reflect.TypeOf(provider) != reflect.TypeOf(test.expected) {
+			t.Fatalf("Expected a %T for %#v, but got a %T", test.expected, test.credentials, provider)
+		}
+	}
+}
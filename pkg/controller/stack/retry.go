@@ -0,0 +1,99 @@
+package stack
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The backoff between attempts to apply a failed asset
+var assetBackoff = cutils.DefaultBackoff
+
+// Records the outcome of an attempt to apply an asset.  A failed asset waits
+// longer before each retry.  An active asset forgets its failed attempts.
+func recordAssetAttempt(asset *kabanerov1alpha2.RepositoryAssetStatus, now time.Time) {
+	switch asset.Status {
+	case assetStatusFailed:
+		asset.Attempts++
+		asset.LastError = asset.StatusMessage
+		nextRetryTime := metav1.NewTime(now.Add(assetBackoff.Delay(asset.Attempts)))
+		asset.NextRetryTime = &nextRetryTime
+	case assetStatusActive:
+		asset.Attempts = 0
+		asset.LastError = ""
+		asset.NextRetryTime = nil
+	}
+}
+
+// Determines if a failed asset should wait longer before it is retried.
+func assetRetryPending(asset kabanerov1alpha2.RepositoryAssetStatus, now time.Time) bool {
+	return asset.Status == assetStatusFailed && asset.NextRetryTime != nil && now.Before(asset.NextRetryTime.Time)
+}
+
+// Returns the wait until the next failed asset should be retried.
+func failedAssetsRequeueAfter(status kabanerov1alpha2.StackStatus, now time.Time) time.Duration {
+	var requeueAfter time.Duration
+	for _, version := range status.Versions {
+		for _, pipeline := range version.Pipelines {
+			for _, asset := range pipeline.ActiveAssets {
+				if asset.Status != assetStatusFailed {
+					continue
+				}
+
+				// Assets which failed before retry times were recorded wait the initial backoff.
+				wait := assetBackoff.Initial
+				if asset.NextRetryTime != nil {
+					wait = asset.NextRetryTime.Sub(now)
+				}
+				if wait < time.Second {
+					wait = time.Second
+				}
+
+				if requeueAfter == 0 || wait < requeueAfter {
+					requeueAfter = wait
+				}
+			}
+		}
+	}
+
+	if requeueAfter == 0 {
+		return assetBackoff.Initial
+	}
+	return requeueAfter
+}
+
+// Handles a request to retry the failed assets of a stack now.  The annotation
+// is removed, so that it can be set again later, and the failed assets are made
+// due for a retry.
+func processRetryNow(ctx context.Context, stack *kabanerov1alpha2.Stack, c client.Client, reqLogger logr.Logger) error {
+	if !cutils.RetryNowRequested(stack) {
+		return nil
+	}
+
+	reqLogger.Info("Retrying failed assets now, as requested by the " + cutils.RetryNowAnnotation + " annotation")
+
+	annotations := stack.GetAnnotations()
+	delete(annotations, cutils.RetryNowAnnotation)
+	stack.SetAnnotations(annotations)
+	err := c.Update(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	// The update returns the stored status, so the retry times are cleared after it.
+	for i := range stack.Status.Versions {
+		for j := range stack.Status.Versions[i].Pipelines {
+			assets := stack.Status.Versions[i].Pipelines[j].ActiveAssets
+			for k := range assets {
+				assets[k].NextRetryTime = nil
+			}
+		}
+	}
+
+	return nil
+}
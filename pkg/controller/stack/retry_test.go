@@ -0,0 +1,95 @@
+package stack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Verifies failed attempts are counted and back off, and are forgotten once the asset is active
+func TestRecordAssetAttempt(t *testing.T) {
+	now := time.Now()
+	asset := kabanerov1alpha2.RepositoryAssetStatus{Name: "build-task", Status: assetStatusFailed, StatusMessage: "connection refused"}
+
+	recordAssetAttempt(&asset, now)
+	if asset.Attempts != 1 || asset.LastError != "connection refused" || asset.NextRetryTime == nil {
+		t.Fatalf("Expected the failed attempt to be recorded: %#v", asset)
+	}
+	firstRetry := asset.NextRetryTime.Time
+	if !assetRetryPending(asset, now) {
+		t.Fatal("Expected the retry to wait for the backoff")
+	}
+
+	recordAssetAttempt(&asset, now)
+	if asset.Attempts != 2 || !asset.NextRetryTime.After(firstRetry) {
+		t.Fatalf("Expected the second failed attempt to wait longer: %#v", asset)
+	}
+	if assetRetryPending(asset, asset.NextRetryTime.Add(time.Second)) {
+		t.Fatal("Expected the retry to be due after the backoff")
+	}
+
+	asset.Status = assetStatusActive
+	asset.StatusMessage = ""
+	recordAssetAttempt(&asset, now)
+	if asset.Attempts != 0 || asset.LastError != "" || asset.NextRetryTime != nil {
+		t.Fatalf("Expected the failed attempts to be forgotten: %#v", asset)
+	}
+}
+
+// Verifies the stack is requeued when its next failed asset is due
+func TestFailedAssetsRequeueAfter(t *testing.T) {
+	now := time.Now()
+	soon := metav1.NewTime(now.Add(2 * time.Minute))
+	later := metav1.NewTime(now.Add(8 * time.Minute))
+	assets := []kabanerov1alpha2.RepositoryAssetStatus{
+		{Name: "build-task", Status: assetStatusFailed, NextRetryTime: &later},
+		{Name: "deploy-task", Status: assetStatusFailed, NextRetryTime: &soon},
+		{Name: "build-pipeline", Status: assetStatusActive},
+	}
+	status := kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{Pipelines: []kabanerov1alpha2.PipelineStatus{{ActiveAssets: assets}}}}}
+
+	if requeueAfter := failedAssetsRequeueAfter(status, now); requeueAfter != 2*time.Minute {
+		t.Fatalf("Expected a requeue in 2m, but was %v", requeueAfter)
+	}
+
+	// Assets which failed before their retry times were recorded wait the initial backoff.
+	assets[1].NextRetryTime = nil
+	if requeueAfter := failedAssetsRequeueAfter(status, now); requeueAfter != assetBackoff.Initial {
+		t.Fatalf("Expected a requeue after the initial backoff, but was %v", requeueAfter)
+	}
+}
+
+// Verifies the retry now annotation is removed, and the failed assets are made due for a retry
+func TestProcessRetryNow(t *testing.T) {
+	later := metav1.NewTime(time.Now().Add(time.Hour))
+	stack := &kabanerov1alpha2.Stack{
+		ObjectMeta: metav1.ObjectMeta{Name: "nodejs", Namespace: "kabanero", Annotations: map[string]string{cutils.RetryNowAnnotation: "true"}},
+		Status: kabanerov1alpha2.StackStatus{Versions: []kabanerov1alpha2.StackVersionStatus{{Pipelines: []kabanerov1alpha2.PipelineStatus{{
+			ActiveAssets: []kabanerov1alpha2.RepositoryAssetStatus{{Name: "build-task", Status: assetStatusFailed, Attempts: 3, NextRetryTime: &later}},
+		}}}}},
+	}
+
+	cl := newTestClient(t, stack.DeepCopy())
+
+	if err := processRetryNow(context.Background(), stack, cl, log); err != nil {
+		t.Fatal(err)
+	}
+
+	asset := stack.Status.Versions[0].Pipelines[0].ActiveAssets[0]
+	if assetRetryPending(asset, time.Now()) || asset.Attempts != 3 {
+		t.Fatalf("Expected the failed asset to be due for a retry: %#v", asset)
+	}
+
+	stored := &kabanerov1alpha2.Stack{}
+	if err := cl.Get(context.Background(), client.ObjectKey{Name: "nodejs", Namespace: "kabanero"}, stored); err != nil {
+		t.Fatal(err)
+	}
+	if cutils.RetryNowRequested(stored) {
+		t.Fatal("Expected the retry now annotation to be removed")
+	}
+}
@@ -16,10 +16,24 @@ type Stack struct {
 	Maintainers      []Maintainers `yaml:"maintainers,omitempty"`
 	Name             string        `yaml:"name,omitempty"`
 	Pipelines        []Pipelines   `yaml:"pipelines,omitempty"`
+	Requires         Requires      `yaml:"requires,omitempty"`
 	Templates        []Templates   `yaml:"templates,omitempty"`
 	Version          string        `yaml:"version,omitempty"`
 }
 
+// Requires holds what a stack version needs from the platform.
+type Requires struct {
+	Kabanero          string          `yaml:"kabanero,omitempty"`
+	TektonApiVersions []string        `yaml:"tekton-api-versions,omitempty"`
+	Stacks            []RequiredStack `yaml:"stacks,omitempty"`
+}
+
+// RequiredStack holds a stack which must have an active version.
+type RequiredStack struct {
+	Id      string `yaml:"id,omitempty"`
+	Version string `yaml:"version,omitempty"`
+}
+
 // Images holds a stack image data.
 type Images struct {
 	Id    string `yaml:"id,omitempty"`
@@ -40,6 +54,9 @@ type Pipelines struct {
 	Url                  string                          `yaml:"url,omitempty"`
 	GitRelease           kabanerov1alpha2.GitReleaseSpec `yaml:"gitRelease,omitempty"`
 	SkipCertVerification bool                            `yaml:"skipCertVerification,omitempty"`
+	// The credentials and trust used to retrieve the pipeline over HTTPS.  These are not
+	// read from the index; they come from the pipelines of the Kabanero instance.
+	Https kabanerov1alpha2.HttpsProtocolFile `yaml:"-"`
 }
 
 // Templates holds the stack's associated template data.
@@ -3,6 +3,7 @@ package stack
 import (
 	"context"
 	"fmt"
+	"os"
 	"regexp"
 	"strings"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/go-logr/logr"
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
 	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
 	"github.com/kabanero-io/kabanero-operator/pkg/controller/transforms"
 	mf "github.com/manifestival/manifestival"
 	mfc "github.com/manifestival/controller-runtime-client"
@@ -35,6 +37,10 @@ import (
 var log = logf.Log.WithName("controller_stack")
 var cIDRegex = regexp.MustCompile("^[a-z]([a-z0-9-]*[a-z0-9])?$")
 
+// MaxConcurrentReconciles is the number of Stacks which are reconciled at the
+// same time.  It must be set before the controller is added to the manager.
+var MaxConcurrentReconciles = 1
+
 const (
 	// Asset status.
 	assetStatusActive  = "active"
@@ -50,13 +56,14 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileStack{client: mgr.GetClient(), scheme: mgr.GetScheme(), indexResolver: ResolveIndex}
+	return &ReconcileStack{client: mgr.GetClient(), scheme: mgr.GetScheme(), indexResolver: ResolveIndex,
+		unsatisfiedRetries: cutils.NewRetryTracker(cutils.DefaultBackoff)}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	// Create a new controller
-	c, err := controller.New("stack-controller", mgr, controller.Options{Reconciler: r})
+	c, err := controller.New("stack-controller", mgr, controller.Options{Reconciler: r, MaxConcurrentReconciles: MaxConcurrentReconciles})
 	if err != nil {
 		return err
 	}
@@ -69,8 +76,10 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			// Returning true only when the metadata generation has changed,
 			// allows us to ignore events where only the object status has changed,
-			// since the generation is not incremented when only the status changes
-			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration()
+			// since the generation is not incremented when only the status changes.
+			// A request to retry failed assets now does not change the generation either.
+			return e.MetaOld.GetGeneration() != e.MetaNew.GetGeneration() ||
+				(!cutils.RetryNowRequested(e.MetaOld) && cutils.RetryNowRequested(e.MetaNew))
 		},
 	}
 
@@ -122,6 +131,46 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Newer pipeline archives contain v1beta1 Tasks and Pipelines.  Watch them too,
+	// when the cluster serves that version.  There are no v1beta1 Conditions.
+	if tektonVersionServed(mgr.GetRESTMapper(), tektonV1beta1) {
+		for _, kind := range []string{"Pipeline", "Task"} {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(schema.GroupVersionKind{Group: tektonGroup, Version: tektonV1beta1, Kind: kind})
+			err = c.Watch(&source.Kind{Type: u}, tH, tPred)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Stack versions left inactive because their requirements are not satisfied are
+	// reconciled again when the Kabanero version, or the active versions of the
+	// stacks in the namespace, change.
+	platformHandler := &handler.EnqueueRequestsFromMapFunc{ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+		return unsatisfiedStackRequests(context.TODO(), mgr.GetClient(), a.Meta.GetNamespace())
+	})}
+
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Kabanero{}}, platformHandler, predicate.Funcs{UpdateFunc: kabaneroVersionChanged})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &kabanerov1alpha2.Stack{}}, platformHandler, predicate.Funcs{UpdateFunc: activeStackVersionsChanged})
+	if err != nil {
+		return err
+	}
+
+	// Look for orphaned stack assets when the controller starts, and periodically after.
+	if namespace, found := os.LookupEnv("KABANERO_NAMESPACE"); found {
+		err = mgr.Add(&orphanedAssetSweeper{namespace: namespace, client: mgr.GetClient(), reader: mgr.GetAPIReader()})
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Info("KABANERO_NAMESPACE is not set. Orphaned stack assets will not be found.")
+	}
+
 	return nil
 }
 
@@ -136,7 +185,10 @@ type ReconcileStack struct {
 	scheme *runtime.Scheme
 
 	//The indexResolver which will be used during reconciliation
-	indexResolver func(client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string) (*Index, error)
+	indexResolver func(context.Context, client.Client, kabanerov1alpha2.RepositoryConfig, string, []Pipelines, []Trigger, string) (*Index, error)
+
+	// Backs off the periodic checks of stack versions with unsatisfied requirements
+	unsatisfiedRetries *cutils.RetryTracker
 }
 
 // Reconcile reads that state of the cluster for a Stack object and makes changes based on the state read
@@ -176,17 +228,56 @@ func (r *ReconcileStack) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, nil
 	}
 
-	rr, err := r.ReconcileStack(instance)
+	// Trust the certificate authorities injected into the trusted CA bundle ConfigMap.
+	err = cutils.LoadTrustedCABundle(ctx, r.client, request.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "Unable to load the trusted CA bundle")
+	}
+
+	// If asked to retry failed assets now, do not wait for their backoff to pass.
+	err = processRetryNow(ctx, instance, r.client, reqLogger)
+	if err != nil {
+		reqLogger.Error(err, "Unable to remove the "+cutils.RetryNowAnnotation+" annotation")
+		return reconcile.Result{}, err
+	}
+
+	// Limit the time spent on the reconcile, and on each download, so that a
+	// server which does not respond cannot hold up the controller.
+	timeouts := getNamespaceTimeouts(ctx, request.Namespace, r.client)
+	rctx, cancel := context.WithTimeout(WithDownloadTimeout(ctx, timeouts.Download), timeouts.Reconcile)
+	defer cancel()
+
+	rr, err := r.ReconcileStack(rctx, instance)
+
+	if rctx.Err() == context.DeadlineExceeded {
+		timeoutErr := &TimeoutError{Operation: "reconciling the stack", Timeout: timeouts.Reconcile, Err: rctx.Err()}
+		reqLogger.Info(timeoutErr.Error())
+		instance.Status.StatusMessage = timeoutErr.Error()
+	}
 
 	r.client.Status().Update(ctx, instance)
 
 	// Force a requeue if there are failed assets.  These should be retried, and since
 	// they are hosted outside of Kubernetes, the controller will not see when they
-	// are updated.
+	// are updated.  The retries back off according to the failed attempts of each asset.
 	if failedAssets(instance.Status) && (rr.Requeue == false) {
-		reqLogger.Info("Forcing requeue due to failed assets in the Stack")
 		rr.Requeue = true
-		rr.RequeueAfter = 60 * time.Second
+		rr.RequeueAfter = failedAssetsRequeueAfter(instance.Status, time.Now())
+		reqLogger.Info(fmt.Sprintf("Forcing requeue in %v due to failed assets in the Stack", rr.RequeueAfter.Round(time.Second)))
+	}
+
+	// Changes to the Kabanero version and to the active stacks are watched, but the
+	// Tekton API versions served by the cluster are not.  Check the requirements of
+	// stack versions which were left inactive again, backing off while they remain
+	// unsatisfied.
+	if unsatisfiedVersions(instance) {
+		if rr.Requeue == false {
+			rr.Requeue = true
+			rr.RequeueAfter = r.unsatisfiedRetries.Failed(request.NamespacedName.String())
+			reqLogger.Info(fmt.Sprintf("Forcing requeue in %v due to stack versions with unsatisfied requirements", rr.RequeueAfter.Round(time.Second)))
+		}
+	} else {
+		r.unsatisfiedRetries.Reset(request.NamespacedName.String())
 	}
 
 	return rr, err
@@ -206,6 +297,21 @@ func failedAssets(status kabanerov1alpha2.StackStatus) bool {
 	return false
 }
 
+// Check to see if any stack version which should be active was left inactive
+func unsatisfiedVersions(stack *kabanerov1alpha2.Stack) bool {
+	for _, curSpec := range stack.Spec.Versions {
+		if strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+			continue
+		}
+		for _, curStatus := range stack.Status.Versions {
+			if curStatus.Version == curSpec.Version && curStatus.Status == kabanerov1alpha2.StackDesiredStateInactive {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Used internally by ReconcileStack to store matching stacks
 // Could be less cumbersome to just use kabanerov1alpha2.Stack
 type resolvedStack struct {
@@ -214,7 +320,7 @@ type resolvedStack struct {
 }
 
 // ReconcileStack activates or deactivates the input stack.
-func (r *ReconcileStack) ReconcileStack(c *kabanerov1alpha2.Stack) (reconcile.Result, error) {
+func (r *ReconcileStack) ReconcileStack(ctx context.Context, c *kabanerov1alpha2.Stack) (reconcile.Result, error) {
 	r_log := log.WithValues("Request.Namespace", c.GetNamespace()).WithValues("Request.Name", c.GetName())
 
 	// Clear the status message, we'll generate a new one if necessary
@@ -232,7 +338,7 @@ func (r *ReconcileStack) ReconcileStack(c *kabanerov1alpha2.Stack) (reconcile.Re
 	r_log = r_log.WithValues("Stack.Name", stackName)
 
 	// Process the versions array and activate (or deactivate) the desired versions.
-	err := reconcileActiveVersions(c, r.client)
+	err := reconcileActiveVersions(ctx, c, r.client)
 	if err != nil {
 		// TODO - what is useful to print?
 		log.Error(err, fmt.Sprintf("Error during reconcileActiveVersions"))
@@ -251,9 +357,15 @@ type pipelineUseMapKey struct {
 // The value in the pipeline use count map
 type pipelineUseMapValue struct {
 	kabanerov1alpha2.PipelineStatus
-	useCount      int64
-	manifests     []StackAsset
-	manifestError error
+	useCount        int64
+	manifests       []StackAsset
+	manifestError   error
+	conversionError error
+	// The active stack versions using the pipeline, and the ids they know it by
+	stackVersions []string
+	pipelineIds   []string
+	// The credentials and trust used to retrieve the pipeline over HTTPS
+	https kabanerov1alpha2.HttpsProtocolFile
 }
 
 // A specific version of a pipeline zip in a specific version of a stack
@@ -275,7 +387,7 @@ func getNamespaceForObject(u *unstructured.Unstructured, defaultNamespace string
 	return defaultNamespace
 }
 
-func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Client) error {
+func reconcileActiveVersions(ctx context.Context, stackResource *kabanerov1alpha2.Stack, c client.Client) error {
 
 	// Gather the known stack asset (*-tasks, *-pipeline) substitution data.
 	renderingContext := make(map[string]interface{})
@@ -305,6 +417,21 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 		Controller: &ownerIsController,
 	}
 
+	// Stack versions whose requirements are not satisfied by the platform are left inactive.
+	var platform *stackPlatform
+	unsatisfied := make(map[string]string)
+	for _, curSpec := range stackResource.Spec.Versions {
+		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) && hasRequirements(curSpec.Requires) {
+			if platform == nil {
+				platform = getStackPlatform(ctx, stackResource.GetNamespace(), c)
+			}
+			problems := checkStackRequirements(curSpec.Requires, platform)
+			if len(problems) > 0 {
+				unsatisfied[curSpec.Version] = "The stack version was not activated because its requirements are not satisfied. " + strings.Join(problems, " ")
+			}
+		}
+	}
+
 	// Multiple versions of the same stack, could be using the same pipeline zip.  Count how many
 	// times each pipeline has been used.
 	assetUseMap := make(map[pipelineUseMapKey]*pipelineUseMapValue)
@@ -333,6 +460,9 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 	}
 
 	for _, curSpec := range stackResource.Spec.Versions {
+		if _, found := unsatisfied[curSpec.Version]; found {
+			continue
+		}
 		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
 			for _, pipeline := range curSpec.Pipelines {
 				cur := pipelineVersion{pipelineUseMapKey: pipelineUseMapKey{url: pipeline.Https.Url, gitRelease: pipeline.GitRelease, digest: pipeline.Sha256}, version: curSpec.Version}
@@ -366,6 +496,29 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 		value.useCount++
 	}
 
+	// Record which stack versions use each pipeline, for the asset labels and annotations.
+	for _, curSpec := range stackResource.Spec.Versions {
+		if _, found := unsatisfied[curSpec.Version]; found {
+			continue
+		}
+		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+			for _, pipeline := range curSpec.Pipelines {
+				value := assetUseMap[pipelineUseMapKey{url: pipeline.Https.Url, gitRelease: pipeline.GitRelease, digest: pipeline.Sha256}]
+				if value != nil {
+					value.stackVersions = append(value.stackVersions, curSpec.Version)
+					value.pipelineIds = append(value.pipelineIds, pipeline.Id)
+					value.https = pipeline.Https
+				}
+			}
+		}
+	}
+
+	// Pipeline assets are converted to the Tekton API version requested by the Kabanero instance.
+	converter := &tektonAssetConverter{ctx: ctx, namespace: stackResource.GetNamespace(), client: c}
+
+	// Failed assets are only retried once their backoff has passed.
+	now := time.Now()
+
 	// Now iterate thru the asset use map and delete any assets with a use count of 0,
 	// and create any assets with a positive use count.
 	for _, value := range assetUseMap {
@@ -386,6 +539,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 	for _, value := range assetUseMap {
 		if value.useCount > 0 {
 			log.Info(fmt.Sprintf("Creating assets with use count %v: %v", value.useCount, value))
+			assetLabels, assetAnnotations := stackAssetMetadata(cID, value)
 
 			// Check to see if there is already an asset list.  If not, read the manifests and
 			// create one.
@@ -396,13 +550,17 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 				renderingContext["Digest"] = value.Digest[0:8]
 
 				// Retrieve manifests as unstructured.  If we could not get them, skip.
-				manifests, err := GetManifests(c, stackResource.GetNamespace(), value.PipelineStatus, renderingContext, log)
+				manifests, err := GetManifests(ctx, c, stackResource.GetNamespace(), value.PipelineStatus, value.https, renderingContext, log)
 				if err != nil {
 					log.Error(err, fmt.Sprintf("Error retrieving archive manifests: %v", value))
 					value.manifestError = err
 					continue
 				}
 
+				// Convert the Tekton assets to the requested API version.  If they cannot be
+				// converted, they are applied as they are.
+				value.conversionError = converter.convert(manifests)
+
 				// Save the manifests for later.
 				value.manifests = manifests
 
@@ -430,6 +588,8 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 					value.ActiveAssets[index].Namespace = asset.Namespace
 				}
 
+				attempted := true
+
 				u := &unstructured.Unstructured{}
 				u.SetGroupVersionKind(schema.GroupVersionKind{
 					Group:   asset.Group,
@@ -437,7 +597,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 					Kind:    asset.Kind,
 				})
 
-				err := c.Get(context.Background(), client.ObjectKey{
+				err := c.Get(ctx, client.ObjectKey{
 					Namespace: asset.Namespace,
 					Name:      asset.Name,
 				}, u)
@@ -447,6 +607,9 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 						log.Error(err, fmt.Sprintf("Unable to check asset name %v", asset.Name))
 						value.ActiveAssets[index].Status = assetStatusUnknown
 						value.ActiveAssets[index].StatusMessage = "Unable to check asset: " + err.Error()
+					} else if assetRetryPending(asset, now) {
+						log.Info(fmt.Sprintf("Waiting until %v to retry failed asset %v", asset.NextRetryTime, asset.Name))
+						attempted = false
 					} else {
 						// Make sure the manifests are loaded.
 						if len(value.manifests) == 0 {
@@ -454,12 +617,14 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 							renderingContext["Digest"] = value.Digest[0:8]
 
 							// Retrieve manifests as unstructured
-							manifests, err := GetManifests(c, stackResource.GetNamespace(), value.PipelineStatus, renderingContext, log)
+							manifests, err := GetManifests(ctx, c, stackResource.GetNamespace(), value.PipelineStatus, value.https, renderingContext, log)
 							if err != nil {
 								log.Error(err, fmt.Sprintf("Object %v not found and manifests not available: %v", asset.Name, value))
 								value.ActiveAssets[index].Status = assetStatusFailed
 								value.ActiveAssets[index].StatusMessage = "Manifests are no longer available at specified URL"
 							} else {
+								value.conversionError = converter.convert(manifests)
+
 								// Save the manifests for later.
 								value.manifests = manifests
 							}
@@ -475,6 +640,8 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 
 								transforms := []mf.Transformer{
 									transforms.InjectOwnerReference(assetOwner),
+									transforms.InjectOwnerAnnotations(assetOwner, stackResource.GetNamespace()),
+									injectStackAssetMetadata(assetLabels, assetAnnotations),
 									mf.InjectNamespace(asset.Namespace),
 								}
 
@@ -482,7 +649,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 								if err != nil {
 									log.Error(err, fmt.Sprintf("Error transforming manifests for %v", asset.Name))
 									value.ActiveAssets[index].Status = assetStatusFailed
-									value.ActiveAssets[index].Status = err.Error()
+									value.ActiveAssets[index].StatusMessage = err.Error()
 								} else {
 									log.Info(fmt.Sprintf("Applying resources: %v", m.Resources()))
 									err = m.Apply()
@@ -515,25 +682,62 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 						// be controller references.  It's not clear what Kubernetes does with this field.
 						ownerRefs = append(ownerRefs, assetOwner)
 						u.SetOwnerReferences(ownerRefs)
+					}
 
-						err = c.Update(context.TODO(), u)
+					// The labels and annotations change as stack versions start or stop using the asset.
+					metadataChanged := mergeStackAssetMetadata(u, assetLabels, assetAnnotations)
+
+					// Assets in other namespaces created before the owner annotations were added
+					// are annotated now, so that they can be found if the stack goes away.
+					if _, found := u.GetAnnotations()[transforms.OwnerNamespaceAnnotation]; !found {
+						transforms.InjectOwnerAnnotations(assetOwner, stackResource.GetNamespace())(u)
+						if _, found := u.GetAnnotations()[transforms.OwnerNamespaceAnnotation]; found {
+							metadataChanged = true
+						}
+					}
+
+					if foundOurselves == false || metadataChanged {
+						err = c.Update(ctx, u)
 						if err != nil {
-							log.Error(err, fmt.Sprintf("Unable to add owner reference to %v", asset.Name))
+							log.Error(err, fmt.Sprintf("Unable to update the owner reference and labels of %v", asset.Name))
 						}
 					}
 
 					value.ActiveAssets[index].Status = assetStatusActive
 					value.ActiveAssets[index].StatusMessage = ""
 				}
+
+				if attempted {
+					recordAssetAttempt(&value.ActiveAssets[index], now)
+				}
 			}
 		}
 	}
 
+	// Resolve image digests if the Kabanero instance asks for it, and there are images to resolve.
+	var digestPinning *stackDigestPinning
+	for _, curSpec := range stackResource.Spec.Versions {
+		if _, found := unsatisfied[curSpec.Version]; found {
+			continue
+		}
+		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) && len(curSpec.Images) > 0 {
+			digestPinning = getStackDigestPinning(ctx, stackResource.GetNamespace(), c)
+			break
+		}
+	}
+	previousImages := make(map[string][]kabanerov1alpha2.ImageStatus)
+	for _, curStatus := range stackResource.Status.Versions {
+		previousImages[curStatus.Version] = curStatus.Images
+	}
+
 	// Now update the StackStatus to reflect the current state of things.
 	newStackStatus := kabanerov1alpha2.StackStatus{}
 	for i, curSpec := range stackResource.Spec.Versions {
 		newStackVersionStatus := kabanerov1alpha2.StackVersionStatus{Version: curSpec.Version}
-		if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+		if message, found := unsatisfied[curSpec.Version]; found {
+			newStackVersionStatus.Status = kabanerov1alpha2.StackDesiredStateInactive
+			newStackVersionStatus.StatusMessage = message
+		} else if !strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
 			if (len(curSpec.DesiredState) > 0) && (!strings.EqualFold(curSpec.DesiredState, kabanerov1alpha2.StackDesiredStateActive)) {
 				newStackVersionStatus.StatusMessage = "An invalid desiredState value of " + curSpec.DesiredState + " was specified. The stack is activated by default."
 			}
@@ -552,6 +756,8 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 					// If we had a problem loading the pipeline manifests, say so.
 					if value.manifestError != nil {
 						newStackVersionStatus.StatusMessage = value.manifestError.Error()
+					} else if value.conversionError != nil {
+						newStackVersionStatus.StatusMessage = "The pipeline assets were applied without conversion: " + value.conversionError.Error()
 					}
 				}
 			}
@@ -567,7 +773,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 			stackResource.Spec.Versions[i] = curSpec
 
 			// Update the status of the Stack object to reflect the images used
-			newStackVersionStatus.Images = curSpec.Images
+			newStackVersionStatus.Images = resolveStackImages(ctx, curSpec.Version, curSpec.Images, previousImages[curSpec.Version], digestPinning)
 		} else {
 			newStackVersionStatus.Status = kabanerov1alpha2.StackDesiredStateInactive
 			newStackVersionStatus.StatusMessage = "The stack has been deactivated."
@@ -577,6 +783,7 @@ func reconcileActiveVersions(stackResource *kabanerov1alpha2.Stack, c client.Cli
 		newStackStatus.Versions = append(newStackStatus.Versions, newStackVersionStatus)
 	}
 
+	newStackStatus.Assets = summarizeStackAssets(cID, newStackStatus)
 	stackResource.Status = newStackStatus
 
 	return nil
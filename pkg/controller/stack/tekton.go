@@ -0,0 +1,222 @@
+package stack
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The name of the Tekton pipeline custom resource definition
+const tektonPipelineCRDName = "pipelines.tekton.dev"
+
+// The Tekton API group and the API versions the stack controller understands
+const (
+	tektonGroup    = "tekton.dev"
+	tektonV1alpha1 = "v1alpha1"
+	tektonV1beta1  = "v1beta1"
+)
+
+// Retrieves the Tekton API versions served by the cluster, from the Tekton
+// pipeline custom resource definition.
+func getTektonApiVersions(ctx context.Context, c client.Client) ([]string, error) {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1beta1", Kind: "CustomResourceDefinition"})
+	err := c.Get(ctx, client.ObjectKey{Name: tektonPipelineCRDName}, crd)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiVersions []string
+	versions, _, _ := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(version, "name")
+		served, _, _ := unstructured.NestedBool(version, "served")
+		if served {
+			apiVersions = append(apiVersions, name)
+		}
+	}
+	if len(versions) == 0 {
+		version, _, _ := unstructured.NestedString(crd.Object, "spec", "version")
+		apiVersions = append(apiVersions, version)
+	}
+
+	return apiVersions, nil
+}
+
+// Determines if the cluster serves a version of the Tekton API, using the
+// REST mapper of the manager.  This is used before the cache is started, to
+// decide which Tekton objects can be watched.
+func tektonVersionServed(mapper meta.RESTMapper, version string) bool {
+	_, err := mapper.RESTMapping(schema.GroupKind{Group: tektonGroup, Kind: "Pipeline"}, version)
+	return err == nil
+}
+
+// Determines the Tekton API version which pipeline assets should be converted
+// to before they are applied.  An empty string is returned when the assets
+// should be applied as they are.  An error is returned when the Kabanero
+// instance requests a version which the cluster does not serve.
+func getTektonConversionVersion(ctx context.Context, namespace string, c client.Client) (string, error) {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneroList, client.InNamespace(namespace))
+	if err != nil {
+		return "", fmt.Errorf("Unable to list Kabanero instances in namespace %v: %v", namespace, err)
+	}
+
+	if len(kabaneroList.Items) == 0 {
+		return "", nil
+	}
+
+	version := kabaneroList.Items[0].Spec.Stacks.TektonApiVersion
+	if version == "" || version == tektonV1alpha1 {
+		return "", nil
+	}
+
+	if version != tektonV1beta1 {
+		return "", fmt.Errorf("Tekton API version %v is not supported for pipeline assets", version)
+	}
+
+	served, err := getTektonApiVersions(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("Unable to determine the Tekton API versions served by the cluster: %v", err)
+	}
+
+	if !containsString(served, version) {
+		return "", fmt.Errorf("Tekton API version %v was requested for pipeline assets, but the cluster serves %v", version, strings.Join(served, ", "))
+	}
+
+	return version, nil
+}
+
+// Converts pipeline assets to the Tekton API version requested by the Kabanero
+// instance.  The requested version is looked up the first time manifests are
+// converted, and remembered for the rest of the reconcile.
+type tektonAssetConverter struct {
+	ctx       context.Context
+	namespace string
+	client    client.Client
+	resolved  bool
+	version   string
+	err       error
+}
+
+// Converts a set of pipeline assets in place.  When the requested version
+// cannot be used, the assets are left as they are and the reason is returned.
+func (t *tektonAssetConverter) convert(assets []StackAsset) error {
+	if !t.resolved {
+		t.version, t.err = getTektonConversionVersion(t.ctx, t.namespace, t.client)
+		t.resolved = true
+	}
+
+	if t.err != nil {
+		return t.err
+	}
+
+	if t.version == tektonV1beta1 {
+		return convertTektonAssets(assets)
+	}
+
+	return nil
+}
+
+// Converts the v1alpha1 Tekton Tasks and Pipelines in a set of pipeline assets
+// to v1beta1.  Other assets, including Conditions which have no v1beta1
+// version, are left as they are.  The asset version is updated so the stack
+// status reports the version which is applied.
+func convertTektonAssets(assets []StackAsset) error {
+	for i := range assets {
+		asset := &assets[i]
+		if asset.Group != tektonGroup || asset.Version != tektonV1alpha1 {
+			continue
+		}
+
+		switch asset.Kind {
+		case "Task", "ClusterTask":
+			err := convertTaskToV1beta1(&asset.Yaml)
+			if err != nil {
+				return fmt.Errorf("Unable to convert %v %v to %v: %v", asset.Kind, asset.Name, tektonV1beta1, err)
+			}
+		case "Pipeline":
+			// Only the API version of a pipeline changes.  The task inputs,
+			// outputs and variable references which move in v1beta1 are
+			// rewritten when the tasks themselves are converted.
+		default:
+			continue
+		}
+
+		asset.Yaml.SetAPIVersion(tektonGroup + "/" + tektonV1beta1)
+		asset.Version = tektonV1beta1
+	}
+
+	return nil
+}
+
+// Moves the v1alpha1 task inputs and outputs to their v1beta1 locations.
+// Input parameters become task parameters, and input and output resources
+// move under spec.resources.  References to input parameters and to input
+// and output resources are rewritten.
+func convertTaskToV1beta1(u *unstructured.Unstructured) error {
+	spec, found, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil || !found {
+		return err
+	}
+
+	resources := make(map[string]interface{})
+	if inputs, ok := spec["inputs"].(map[string]interface{}); ok {
+		if params, ok := inputs["params"]; ok {
+			spec["params"] = params
+		}
+		if inputResources, ok := inputs["resources"]; ok {
+			resources["inputs"] = inputResources
+		}
+		delete(spec, "inputs")
+	}
+	if outputs, ok := spec["outputs"].(map[string]interface{}); ok {
+		if outputResources, ok := outputs["resources"]; ok {
+			resources["outputs"] = outputResources
+		}
+		delete(spec, "outputs")
+	}
+	if len(resources) > 0 {
+		spec["resources"] = resources
+	}
+
+	u.Object["spec"] = replaceStrings(spec, v1beta1TaskReferences)
+	return nil
+}
+
+// The v1alpha1 task variable references, and their v1beta1 replacements
+var v1beta1TaskReferences = strings.NewReplacer(
+	"$(inputs.params.", "$(params.",
+	"$(inputs.resources.", "$(resources.inputs.",
+	"$(outputs.resources.", "$(resources.outputs.",
+)
+
+// Replaces substrings in every string value of an unstructured object.
+func replaceStrings(value interface{}, replacer *strings.Replacer) interface{} {
+	switch v := value.(type) {
+	case string:
+		return replacer.Replace(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = replaceStrings(item, replacer)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceStrings(item, replacer)
+		}
+		return v
+	default:
+		return v
+	}
+}
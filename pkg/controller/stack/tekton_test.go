@@ -0,0 +1,139 @@
+package stack
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const v1alpha1Task = `
+apiVersion: tekton.dev/v1alpha1
+kind: Task
+metadata:
+  name: build-task
+spec:
+  inputs:
+    params:
+    - name: docker-imagename
+      default: kabanero
+    resources:
+    - name: git-source
+      type: git
+  outputs:
+    resources:
+    - name: docker-image
+      type: image
+  steps:
+  - name: build
+    image: kabanero/kabanero-utils:0.8.0
+    args:
+    - $(inputs.params.docker-imagename)
+    - $(inputs.resources.git-source.path)
+    - $(outputs.resources.docker-image.url)
+`
+
+func tektonAsset(t *testing.T, document string) StackAsset {
+	u := unstructured.Unstructured{}
+	if err := yaml.NewYAMLOrJSONDecoder(strings.NewReader(document), 4096).Decode(&u.Object); err != nil {
+		t.Fatal(err)
+	}
+	gvk := u.GroupVersionKind()
+	return StackAsset{Name: u.GetName(), Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind, Yaml: u}
+}
+
+// Verifies v1alpha1 tasks and pipelines are converted to v1beta1, and other assets are left alone
+func TestConvertTektonAssets(t *testing.T) {
+	assets := []StackAsset{
+		tektonAsset(t, v1alpha1Task),
+		tektonAsset(t, "apiVersion: tekton.dev/v1alpha1\nkind: Pipeline\nmetadata:\n  name: build-pipeline\nspec:\n  tasks:\n  - name: build\n    taskRef:\n      name: build-task\n"),
+		tektonAsset(t, "apiVersion: tekton.dev/v1alpha1\nkind: Condition\nmetadata:\n  name: deploy-condition\n"),
+		tektonAsset(t, "apiVersion: tekton.dev/v1alpha1\nkind: TriggerBinding\nmetadata:\n  name: build-binding\n"),
+	}
+
+	if err := convertTektonAssets(assets); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"v1beta1", "v1beta1", "v1alpha1", "v1alpha1"}
+	for i, asset := range assets {
+		if asset.Version != expected[i] || asset.Yaml.GetAPIVersion() != "tekton.dev/"+expected[i] {
+			t.Fatalf("Expected %v %v to be %v, but was %v (%v)", asset.Kind, asset.Name, expected[i], asset.Version, asset.Yaml.GetAPIVersion())
+		}
+	}
+
+	task := assets[0].Yaml.Object
+	if _, found, _ := unstructured.NestedMap(task, "spec", "inputs"); found {
+		t.Fatalf("Expected the task inputs to be removed: %v", task)
+	}
+	params, _, _ := unstructured.NestedSlice(task, "spec", "params")
+	if len(params) != 1 {
+		t.Fatalf("Expected the input parameters to become task parameters: %v", task)
+	}
+	inputs, _, _ := unstructured.NestedSlice(task, "spec", "resources", "inputs")
+	outputs, _, _ := unstructured.NestedSlice(task, "spec", "resources", "outputs")
+	if len(inputs) != 1 || len(outputs) != 1 {
+		t.Fatalf("Expected the task resources to be moved: %v", task)
+	}
+	steps, _, _ := unstructured.NestedSlice(task, "spec", "steps")
+	args, _, _ := unstructured.NestedStringSlice(steps[0].(map[string]interface{}), "args")
+	if args[0] != "$(params.docker-imagename)" || args[1] != "$(resources.inputs.git-source.path)" || args[2] != "$(resources.outputs.docker-image.url)" {
+		t.Fatalf("Expected the parameter and resource references to be rewritten: %v", args)
+	}
+}
+
+// Verifies conversion is only requested when the cluster serves the requested version
+func TestGetTektonConversionVersion(t *testing.T) {
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+		Spec: kabanerov1alpha2.KabaneroSpec{
+			Stacks: kabanerov1alpha2.InstanceStackConfig{TektonApiVersion: "v1beta1"},
+		},
+	}
+	crd := &unstructured.Unstructured{}
+	crd.SetAPIVersion("apiextensions.k8s.io/v1beta1")
+	crd.SetKind("CustomResourceDefinition")
+	crd.SetName(tektonPipelineCRDName)
+	unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"name": "v1alpha1", "served": true},
+	}, "spec", "versions")
+
+	cl := newTestClient(t, k, crd)
+
+	// v1beta1 is not served
+	version, err := getTektonConversionVersion(context.Background(), "kabanero", cl)
+	if err == nil || version != "" {
+		t.Fatalf("Expected an error when v1beta1 is not served: %v %v", version, err)
+	}
+
+	unstructured.SetNestedSlice(crd.Object, []interface{}{
+		map[string]interface{}{"name": "v1alpha1", "served": true},
+		map[string]interface{}{"name": "v1beta1", "served": true},
+	}, "spec", "versions")
+	if err := cl.Update(context.Background(), crd); err != nil {
+		t.Fatal(err)
+	}
+
+	version, err = getTektonConversionVersion(context.Background(), "kabanero", cl)
+	if err != nil || version != "v1beta1" {
+		t.Fatalf("Expected conversion to v1beta1: %v %v", version, err)
+	}
+
+	// No Kabanero instance, no conversion
+	version, err = getTektonConversionVersion(context.Background(), "other", cl)
+	if err != nil || version != "" {
+		t.Fatalf("Expected no conversion: %v %v", version, err)
+	}
+
+	// Kabanero instances cannot be listed
+	version, err = getTektonConversionVersion(context.Background(), "kabanero", fake.NewFakeClientWithScheme(runtime.NewScheme()))
+	if err == nil || version != "" {
+		t.Fatalf("Expected an error when Kabanero instances cannot be listed: %v %v", version, err)
+	}
+}
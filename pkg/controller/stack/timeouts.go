@@ -0,0 +1,121 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The timeouts used when the Kabanero instance does not set them.
+const (
+	defaultDownloadTimeout        = 1 * time.Minute
+	defaultIndexResolutionTimeout = 3 * time.Minute
+	defaultReconcileTimeout       = 5 * time.Minute
+)
+
+// Timeouts limit the time spent retrieving stack indexes and pipeline archives.
+type Timeouts struct {
+	// Each download of a stack index or pipeline archive
+	Download time.Duration
+	// The retrieval of the index of a stack repository
+	IndexResolution time.Duration
+	// A single reconcile of a Stack
+	Reconcile time.Duration
+}
+
+// GetTimeouts returns the timeouts set in a Kabanero instance.  Timeouts which
+// are not set, or are not valid durations, take their default values.
+func GetTimeouts(spec kabanerov1alpha2.StackTimeoutsSpec) Timeouts {
+	return Timeouts{
+		Download:        parseTimeout(spec.Download, defaultDownloadTimeout),
+		IndexResolution: parseTimeout(spec.IndexResolution, defaultIndexResolutionTimeout),
+		Reconcile:       parseTimeout(spec.Reconcile, defaultReconcileTimeout),
+	}
+}
+
+func parseTimeout(value string, defaultTimeout time.Duration) time.Duration {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return defaultTimeout
+	}
+	return timeout
+}
+
+// Returns the timeouts set in the Kabanero instance in a namespace.
+func getNamespaceTimeouts(ctx context.Context, namespace string, c client.Client) Timeouts {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := c.List(ctx, kabaneroList, client.InNamespace(namespace))
+	if err != nil || len(kabaneroList.Items) == 0 {
+		return GetTimeouts(kabanerov1alpha2.StackTimeoutsSpec{})
+	}
+
+	return GetTimeouts(kabaneroList.Items[0].Spec.Stacks.Timeouts)
+}
+
+type downloadTimeoutKey struct{}
+
+// WithDownloadTimeout returns a context which limits each download made using
+// it, in addition to any deadline of the parent context.
+func WithDownloadTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, downloadTimeoutKey{}, timeout)
+}
+
+// Returns the time allowed for each download made using the context.
+func getDownloadTimeout(ctx context.Context) time.Duration {
+	timeout, ok := ctx.Value(downloadTimeoutKey{}).(time.Duration)
+	if !ok || timeout <= 0 {
+		return defaultDownloadTimeout
+	}
+	return timeout
+}
+
+// Returns the context for a single download, and the time allowed for it.
+func downloadContext(ctx context.Context) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := getDownloadTimeout(ctx)
+	dctx, cancel := context.WithTimeout(ctx, timeout)
+	return dctx, cancel, timeout
+}
+
+// Returns a context for a download which is shared between callers, so that
+// it is not cancelled with the caller which started it.  It keeps the download
+// timeout of the caller, and is bounded by it.
+func detachedDownloadContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := getDownloadTimeout(ctx)
+	return context.WithTimeout(WithDownloadTimeout(context.Background(), timeout), timeout)
+}
+
+// TimeoutError reports an operation which did not complete in the time allowed.
+type TimeoutError struct {
+	// What was being done, for example "retrieving https://example.com/index.yaml"
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Timed out after %v %v", e.Timeout, e.Operation)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Returns a TimeoutError if an operation failed because the deadline of its
+// context passed.  Errors which are already TimeoutErrors, from an operation
+// with a shorter timeout, are returned as they are.
+func checkTimeout(ctx context.Context, err error, operation string, timeout time.Duration) error {
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return err
+	}
+
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return err
+	}
+
+	return &TimeoutError{Operation: operation, Timeout: timeout, Err: err}
+}
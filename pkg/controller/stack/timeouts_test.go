@@ -0,0 +1,48 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+)
+
+// Verifies timeouts which are not set, or not valid, take their default values
+func TestGetTimeouts(t *testing.T) {
+	timeouts := GetTimeouts(kabanerov1alpha2.StackTimeoutsSpec{Download: "30s", IndexResolution: "ten minutes", Reconcile: "-1m"})
+
+	if timeouts.Download != 30*time.Second {
+		t.Fatalf("Expected a 30s download timeout, but was %v", timeouts.Download)
+	}
+	if timeouts.IndexResolution != defaultIndexResolutionTimeout {
+		t.Fatalf("Expected the default index resolution timeout, but was %v", timeouts.IndexResolution)
+	}
+	if timeouts.Reconcile != defaultReconcileTimeout {
+		t.Fatalf("Expected the default reconcile timeout, but was %v", timeouts.Reconcile)
+	}
+}
+
+// Verifies a download which does not complete within the download timeout reports a TimeoutError
+func TestDownloadTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ctx := WithDownloadTimeout(context.Background(), 100*time.Millisecond)
+	_, err := downloadFromCache(ctx, server.URL+"/slow.yaml", httpsCredentials{})
+
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected a TimeoutError, but got: %v", err)
+	}
+	if timeoutErr.Timeout != 100*time.Millisecond {
+		t.Fatalf("Expected the download timeout to be reported, but was %v", timeoutErr.Timeout)
+	}
+}
@@ -1,9 +1,17 @@
 package utils
 
 import (
+	"context"
+	"crypto"
 	"fmt"
-	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	"net/http"
 	"strings"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // Removes the tag portion of all images associated with the input stack version.
@@ -19,6 +27,12 @@ func RemoveTagFromStackImages(stack *kabanerov1alpha2.StackVersion, stackName st
 	return nil
 }
 
+// Returns true if the user specified all values in Kabanero.Spec.Stacks.Repositories.GitRelease.
+func IsGitReleaseUsable(gitRelease kabanerov1alpha2.GitReleaseSpec) bool {
+	return len(gitRelease.Hostname) != 0 && len(gitRelease.Organization) != 0 && len(gitRelease.Project) != 0 &&
+		len(gitRelease.Release) != 0 && len(gitRelease.AssetName) != 0
+}
+
 // Retrieves the repository part of input image. An error is returned if the image input is empty.
 // If the image does not contain a tag, the  input value is returned.
 func GetImageRepository(image string) (string, error) {
@@ -29,3 +43,79 @@ func GetImageRepository(image string) (string, error) {
 	imageParts := strings.Split(image, ":")
 	return imageParts[0], nil
 }
+
+// The ConfigMap key holding the signature keys of the stack image policy, when none is specified.
+const defaultSignatureKeysKey = "cosign.pub"
+
+// Retrieves the public keys trusted to sign stack images from the ConfigMap named by
+// the stack image policy.
+func GetSignatureKeys(ctx context.Context, c client.Client, namespace string, policy kabanerov1alpha2.StackImagePolicySpec) ([]crypto.PublicKey, error) {
+	if len(policy.SignatureKeys.Name) == 0 {
+		return nil, fmt.Errorf("The stack image policy requires signatures, but does not specify the signatureKeys ConfigMap")
+	}
+	key := policy.SignatureKeys.Key
+	if len(key) == 0 {
+		key = defaultSignatureKeysKey
+	}
+
+	configMap := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: policy.SignatureKeys.Name, Namespace: namespace}, configMap)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve the signature keys ConfigMap %v: %v", policy.SignatureKeys.Name, err)
+	}
+	data, ok := configMap.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("The signature keys ConfigMap %v does not contain key '%v'", policy.SignatureKeys.Name, key)
+	}
+
+	keys, err := cutils.ParseSignatureKeys([]byte(data))
+	if err != nil {
+		return nil, fmt.Errorf("The signature keys ConfigMap %v is not valid: %v", policy.SignatureKeys.Name, err)
+	}
+	return keys, nil
+}
+
+// Checks the images of a stack version against the stack image policy.  The images must not
+// contain a tag; signatures are looked up at the tag matching the stack version, and must
+// verify with one of the input keys.  Only the images accepted by the input filter are
+// checked, when a filter is specified.  When a signature cache is specified, signatures
+// already verified for the digest of an image are not verified again.  A description of
+// each violation is returned.
+func CheckStackImagePolicy(ctx context.Context, policy kabanerov1alpha2.StackImagePolicySpec, stackName string, version kabanerov1alpha2.StackVersion, filter func(kabanerov1alpha2.Image) bool, keys []crypto.PublicKey, creds cutils.RegistryCredentials, transport http.RoundTripper, signatures *cutils.SignatureCache) []string {
+	var violations []string
+	for _, image := range version.Images {
+		if filter != nil && !filter(image) {
+			continue
+		}
+
+		if len(policy.AllowedImages) > 0 {
+			allowed, err := cutils.ImageAllowed(image.Image, policy.AllowedImages)
+			if err != nil {
+				violations = append(violations, fmt.Sprintf("Image %v associated with stack %v %v is not valid: %v", image.Image, stackName, version.Version, err))
+				continue
+			}
+			if !allowed {
+				violations = append(violations, fmt.Sprintf("Image %v associated with stack %v %v is not from an allowed registry or repository. Allowed images: %v", image.Image, stackName, version.Version, strings.Join(policy.AllowedImages, ", ")))
+				continue
+			}
+		}
+
+		if policy.RequireSignatures {
+			taggedImage := image.Image + ":" + version.Version
+			var signed bool
+			var err error
+			if signatures != nil {
+				signed, err = signatures.Verify(ctx, taggedImage, keys, creds, transport)
+			} else {
+				signed, err = cutils.VerifyImageSignature(ctx, taggedImage, keys, creds, transport)
+			}
+			if err != nil {
+				violations = append(violations, fmt.Sprintf("Unable to verify that image %v associated with stack %v %v is signed: %v", taggedImage, stackName, version.Version, err))
+			} else if !signed {
+				violations = append(violations, fmt.Sprintf("Image %v associated with stack %v %v is not signed by a trusted key, and the stack image policy requires signatures", taggedImage, stackName, version.Version))
+			}
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,10 @@
+package transforms
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Mount a config map by name
+func MountConfigMap(configMapName string, mountPoint string) func(u *unstructured.Unstructured) error {
+	return mountVolume(configMapName, mountPoint, map[string]interface{}{"configMap": map[string]interface{}{"name": configMapName}})
+}
@@ -0,0 +1,76 @@
+package transforms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMountConfigMap(t *testing.T) {
+	inputYaml := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-events
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      name: kabanero-events
+  template:
+    metadata:
+      labels:
+        name: kabanero-events
+    spec:
+      serviceAccountName: kabanero-events
+      containers:
+        - name: kabanero-events
+          image: image
+          imagePullPolicy: Always`
+
+	expectedOutput := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: kabanero-events
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      name: kabanero-events
+  template:
+    metadata:
+      labels:
+        name: kabanero-events
+    spec:
+      containers:
+      - image: image
+        imagePullPolicy: Always
+        name: kabanero-events
+        volumeMounts:
+        - mountPath: /etc/kabanero-events
+          name: kabanero-events-config
+          readOnly: true
+      serviceAccountName: kabanero-events
+      volumes:
+      - configMap:
+          name: kabanero-events-config
+        name: kabanero-events-config`
+
+	u, err := unmarshal([]byte(inputYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deployment := &u[0]
+	err = MountConfigMap("kabanero-events-config", "/etc/kabanero-events")(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := marshal(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(expectedOutput) != strings.TrimSpace(string(b)) {
+		t.Log("Expected: ", expectedOutput)
+		t.Log("Found: ", string(b))
+
+		t.Fatal("Expected output did not match")
+	}
+}
@@ -6,14 +6,42 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// Annotations which identify the owner of an object that cannot hold an owner
+// reference, because it is created in a different namespace from its owner.
+const (
+	OwnerUidAnnotation       = "kabanero.io/owner-uid"
+	OwnerNamespaceAnnotation = "kabanero.io/owner-namespace"
+)
+
 func InjectOwnerReference(ownerReference metav1.OwnerReference) func(u *unstructured.Unstructured) error {
 	return func(u *unstructured.Unstructured) error {
 		kind := u.GetKind()
 		// Presently, TriggerBinding and TriggerTemplate objects are created
 		// in a different namespace, and cannot be owned by Kabanero.
-		if (kind != "TriggerBinding") && (kind != "TriggerTemplate") {
+		if !isCrossNamespaceKind(kind) {
 			u.SetOwnerReferences([]metav1.OwnerReference{ownerReference})
 		}
 		return nil
 	}
 }
+
+// InjectOwnerAnnotations records the owner of objects which cannot be given an
+// owner reference, so that they can be found if the owner goes away.
+func InjectOwnerAnnotations(ownerReference metav1.OwnerReference, ownerNamespace string) func(u *unstructured.Unstructured) error {
+	return func(u *unstructured.Unstructured) error {
+		if isCrossNamespaceKind(u.GetKind()) {
+			annotations := u.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[OwnerUidAnnotation] = string(ownerReference.UID)
+			annotations[OwnerNamespaceAnnotation] = ownerNamespace
+			u.SetAnnotations(annotations)
+		}
+		return nil
+	}
+}
+
+func isCrossNamespaceKind(kind string) bool {
+	return (kind == "TriggerBinding") || (kind == "TriggerTemplate")
+}
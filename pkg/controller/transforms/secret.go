@@ -7,6 +7,11 @@ import (
 
 // Mount a secret by name
 func MountSecret(secretName string, mountPoint string) func(u *unstructured.Unstructured) error {
+	return mountVolume(secretName, mountPoint, map[string]interface{}{"secret": map[string]interface{}{"secretName": secretName}})
+}
+
+// Mount a volume with the given name and source into every container of a deployment
+func mountVolume(volumeName string, mountPoint string, source map[string]interface{}) func(u *unstructured.Unstructured) error {
 	return func(u *unstructured.Unstructured) error {
 		// Only apply this to deployments
 		if u.GetKind() != "Deployment" && u.GetAPIVersion() != "apps/v1" {
@@ -47,7 +52,7 @@ func MountSecret(secretName string, mountPoint string) func(u *unstructured.Unst
 					}
 
 					// Copy all the volume mounts to the new list, skipping the desired name if it exists.
-					if volumeMount["name"] != secretName {
+					if volumeMount["name"] != volumeName {
 						newVolumeMounts = append(newVolumeMounts, volumeMount)
 					}
 				}
@@ -55,7 +60,7 @@ func MountSecret(secretName string, mountPoint string) func(u *unstructured.Unst
 
 			// Now add the one we wanted
 			newVolumeMount := make(map[string]interface{})
-			newVolumeMount["name"] = secretName
+			newVolumeMount["name"] = volumeName
 			newVolumeMount["mountPath"] = mountPoint
 			newVolumeMount["readOnly"] = true
 			newVolumeMounts = append(newVolumeMounts, newVolumeMount)
@@ -86,7 +91,7 @@ func MountSecret(secretName string, mountPoint string) func(u *unstructured.Unst
 				}
 
 				// Copy all the volumes to the new list, skipping the desired name if it exists.
-				if volume["name"] != secretName {
+				if volume["name"] != volumeName {
 					newVolumes = append(newVolumes, volume)
 				}
 			}
@@ -94,8 +99,10 @@ func MountSecret(secretName string, mountPoint string) func(u *unstructured.Unst
 
 		// Now add the one we wanted
 		newVolume := make(map[string]interface{})
-		newVolume["name"] = secretName
-		newVolume["secret"] = map[string]interface{}{"secretName": secretName}
+		newVolume["name"] = volumeName
+		for key, value := range source {
+			newVolume[key] = value
+		}
 		newVolumes = append(newVolumes, newVolume)
 
 		err = unstructured.SetNestedSlice(u.Object, newVolumes, "spec", "template", "spec", "volumes")
@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RetryNowAnnotation can be set, to any value, on a Kabanero or Stack instance to
+// retry failed work immediately instead of waiting for the backoff to pass.  The
+// controller removes the annotation once it has seen it.
+const RetryNowAnnotation = "kabanero.io/retry-now"
+
+// Backoff computes the wait before retrying work which failed.  The wait grows
+// exponentially with the number of failed attempts, up to a maximum, and is
+// jittered so that retries of work which failed together are spread out.
+type Backoff struct {
+	// The wait after the first failed attempt
+	Initial time.Duration
+	// The longest wait
+	Max time.Duration
+	// The growth of the wait after each failed attempt
+	Factor float64
+	// The largest fraction of the wait which is randomly added to it
+	Jitter float64
+}
+
+// DefaultBackoff is the backoff used by the Kabanero and Stack controllers.
+var DefaultBackoff = Backoff{Initial: 30 * time.Second, Max: 15 * time.Minute, Factor: 2, Jitter: 0.2}
+
+// Delay returns the wait after the given number of failed attempts.
+func (b Backoff) Delay(attempts int) time.Duration {
+	delay := float64(b.Initial)
+	for i := 1; i < attempts && delay < float64(b.Max); i++ {
+		delay *= b.Factor
+	}
+
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * rand.Float64()
+	}
+
+	if delay > float64(b.Max) {
+		return b.Max
+	}
+	return time.Duration(delay)
+}
+
+// RetryNowRequested determines if the retry now annotation is set on an object.
+func RetryNowRequested(obj metav1.Object) bool {
+	_, found := obj.GetAnnotations()[RetryNowAnnotation]
+	return found
+}
+
+// RetryTracker keeps the number of failed attempts of keyed work, and when the
+// work should next be retried.  It is safe for concurrent use.
+type RetryTracker struct {
+	backoff Backoff
+	mutex   sync.Mutex
+	entries map[string]retryEntry
+}
+
+type retryEntry struct {
+	attempts  int
+	nextRetry time.Time
+}
+
+// NewRetryTracker returns a RetryTracker which waits according to the backoff.
+func NewRetryTracker(backoff Backoff) *RetryTracker {
+	return &RetryTracker{backoff: backoff, entries: make(map[string]retryEntry)}
+}
+
+// Failed records a failed attempt, and returns the wait before the next attempt.
+// A failure reported before the previous wait has passed, for example from work
+// triggered by a watch, is not counted and does not extend the wait.
+func (t *RetryTracker) Failed(key string) time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	entry := t.entries[key]
+	if entry.attempts > 0 && now.Before(entry.nextRetry) {
+		return entry.nextRetry.Sub(now)
+	}
+
+	entry.attempts++
+	delay := t.backoff.Delay(entry.attempts)
+	entry.nextRetry = now.Add(delay)
+	t.entries[key] = entry
+
+	return delay
+}
+
+// Attempts returns the number of failed attempts recorded for the work.
+func (t *RetryTracker) Attempts(key string) int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.entries[key].attempts
+}
+
+// Reset forgets the failed attempts, after the work succeeds or a retry is requested.
+func (t *RetryTracker) Reset(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.entries, key)
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Verifies the delay doubles after each failed attempt, up to the maximum
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Second, Max: time.Minute, Factor: 2}
+
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, delay := range expected {
+		if actual := b.Delay(i + 1); actual != delay {
+			t.Fatalf("Expected a delay of %v after %v attempts, but was %v", delay, i+1, actual)
+		}
+	}
+}
+
+// Verifies the jitter only lengthens the delay, and never past the maximum
+func TestBackoffDelayJitter(t *testing.T) {
+	b := Backoff{Initial: 10 * time.Second, Max: 15 * time.Second, Factor: 2, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		if delay := b.Delay(1); delay < 10*time.Second || delay > 15*time.Second {
+			t.Fatalf("Expected a delay between 10s and 15s, but was %v", delay)
+		}
+		if delay := b.Delay(2); delay != 15*time.Second {
+			t.Fatalf("Expected the maximum delay, but was %v", delay)
+		}
+	}
+}
+
+// Verifies failures reported before the wait passes are not counted, and that a reset starts over
+func TestRetryTracker(t *testing.T) {
+	tracker := NewRetryTracker(Backoff{Initial: time.Minute, Max: time.Hour, Factor: 2})
+
+	if delay := tracker.Failed("kabanero"); delay != time.Minute {
+		t.Fatalf("Expected the initial delay, but was %v", delay)
+	}
+	if delay := tracker.Failed("kabanero"); delay > time.Minute || tracker.Attempts("kabanero") != 1 {
+		t.Fatalf("Expected the failure within the wait not to be counted: %v, %v attempts", delay, tracker.Attempts("kabanero"))
+	}
+	if tracker.Attempts("other") != 0 {
+		t.Fatal("Expected no failed attempts in another namespace")
+	}
+
+	tracker.Reset("kabanero")
+	if tracker.Attempts("kabanero") != 0 {
+		t.Fatal("Expected the failed attempts to be forgotten")
+	}
+}
+
+// Verifies the retry now annotation is detected
+func TestRetryNowRequested(t *testing.T) {
+	obj := &metav1.ObjectMeta{}
+	if RetryNowRequested(obj) {
+		t.Fatal("Expected no retry to be requested")
+	}
+
+	obj.SetAnnotations(map[string]string{RetryNowAnnotation: ""})
+	if !RetryNowRequested(obj) {
+		t.Fatal("Expected a retry to be requested")
+	}
+}
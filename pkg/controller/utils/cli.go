@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+)
+
+// The format of the CLI session expiration.  A session must not expire immediately.
+var sessionExpirationRegex = regexp.MustCompile(`^[1-9]\d*[smh]$`)
+
+// ValidateSessionExpiration checks the format of the CLI session expiration.
+func ValidateSessionExpiration(value string) error {
+	if !sessionExpirationRegex.MatchString(value) {
+		return errors.New("must be a positive integer followed by a unit of time, which can be hours (h), minutes (m), or seconds (s)")
+	}
+	return nil
+}
@@ -0,0 +1,462 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The key holding the token in a Secret, when no key is specified.
+const DefaultCredentialsKey = "password"
+
+// The default directory under which credential files must be mounted.
+const defaultCredentialsFileRoot = "/etc/kabanero/credentials"
+
+// The service account token used to log in to Vault.
+const serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// CredentialsSecretSelector limits the Secrets searched by AnnotatedSecretCredentialProvider.
+// All Secrets in the namespace are searched by default.
+var CredentialsSecretSelector = labels.Everything()
+
+// CredentialsFileRoot is the directory under which FileCredentialProvider files must be found,
+// so that the credentials of the operator itself cannot be read.
+var CredentialsFileRoot = defaultCredentialsFileRoot
+
+// VaultAddress is the Vault server VaultCredentialProvider logs in to, VaultAuthPath is
+// where its Kubernetes auth method is mounted, and VaultRoles are the roles which may be
+// used to log in.  They are configured for the operator rather than by instances, so that
+// an instance cannot send the service account token of the operator to another server.
+var (
+	VaultAddress  string
+	VaultAuthPath = "kubernetes"
+	VaultRoles    []string
+)
+
+// CredentialsAllowedHosts are the hosts which tokens read by FileCredentialProvider and
+// VaultCredentialProvider may be sent to.  The files and the Vault identity belong to the
+// operator, so an instance may only have their tokens sent to the hosts the operator allows.
+var CredentialsAllowedHosts []string
+
+// CredentialProvider retrieves the token used to access a host.
+type CredentialProvider interface {
+	// Returns the token, or nil if the provider has no token for the host.
+	GetToken(ctx context.Context, hostname string) ([]byte, error)
+}
+
+// LoadCredentialSettings reads the credential provider settings from the environment.
+// GIT_CREDENTIALS_SECRET_SELECTOR is a label selector which limits the Secrets searched
+// for annotated credentials, and CREDENTIALS_FILE_ROOT is the directory under which
+// credential files are mounted.  VAULT_ADDR is the URL of the Vault server, VAULT_AUTH_PATH
+// is where its Kubernetes auth method is mounted, and VAULT_ROLES is a comma separated
+// list of the roles which may be used.  CREDENTIALS_ALLOWED_HOSTS is a comma separated list
+// of the hosts which tokens read from credential files or Vault may be sent to.
+func LoadCredentialSettings() error {
+	if value, found := os.LookupEnv("GIT_CREDENTIALS_SECRET_SELECTOR"); found && len(value) != 0 {
+		selector, err := labels.Parse(value)
+		if err != nil {
+			return fmt.Errorf("GIT_CREDENTIALS_SECRET_SELECTOR must be a label selector, but was %v: %v", value, err)
+		}
+		CredentialsSecretSelector = selector
+	}
+
+	if value, found := os.LookupEnv("CREDENTIALS_FILE_ROOT"); found && len(value) != 0 {
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("CREDENTIALS_FILE_ROOT must be an absolute path, but was %v", value)
+		}
+		CredentialsFileRoot = value
+	}
+
+	if value, found := os.LookupEnv("VAULT_ADDR"); found && len(value) != 0 {
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("VAULT_ADDR must be an absolute http or https URL, but was %v", value)
+		}
+		VaultAddress = strings.TrimSuffix(value, "/")
+	}
+
+	if value, found := os.LookupEnv("VAULT_AUTH_PATH"); found && len(value) != 0 {
+		VaultAuthPath = strings.Trim(value, "/")
+	}
+
+	if value, found := os.LookupEnv("VAULT_ROLES"); found {
+		VaultRoles = nil
+		for _, role := range strings.Split(value, ",") {
+			if role = strings.TrimSpace(role); len(role) != 0 {
+				VaultRoles = append(VaultRoles, role)
+			}
+		}
+	}
+
+	if value, found := os.LookupEnv("CREDENTIALS_ALLOWED_HOSTS"); found {
+		CredentialsAllowedHosts = nil
+		for _, host := range strings.Split(value, ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); len(host) != 0 {
+				CredentialsAllowedHosts = append(CredentialsAllowedHosts, host)
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckCredentialsHost returns an error unless tokens read from credential files or Vault
+// may be sent to a host.  The host is a hostname, or a URL.
+func CheckCredentialsHost(host string) error {
+	hostname := credentialsHostname(host)
+	if len(hostname) == 0 {
+		return fmt.Errorf("Credentials from files or Vault are only sent to known hosts, but the host of %v could not be determined", host)
+	}
+	if len(CredentialsAllowedHosts) == 0 {
+		return fmt.Errorf("No hosts may receive credentials from files or Vault. Set CREDENTIALS_ALLOWED_HOSTS to the hosts which may be used")
+	}
+	for _, allowed := range CredentialsAllowedHosts {
+		if strings.EqualFold(hostname, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("The host %v is not one of the hosts which may receive credentials from files or Vault: %v", hostname, strings.Join(CredentialsAllowedHosts, ", "))
+}
+
+// Returns the hostname of a hostname, host and port, or URL.
+func credentialsHostname(host string) string {
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return ""
+		}
+		return u.Hostname()
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return hostname
+	}
+	return host
+}
+
+// ResolveVaultSettings checks the Vault address and role requested by an instance against
+// the operator configuration, and returns the address and role to use.  An empty address
+// refers to the configured server, and an empty role to the first configured role.
+func ResolveVaultSettings(address string, role string) (string, string, error) {
+	if len(VaultAddress) == 0 {
+		return "", "", fmt.Errorf("Vault is not configured for the operator. Set VAULT_ADDR to the URL of the Vault server")
+	}
+	if len(address) != 0 && strings.TrimSuffix(address, "/") != VaultAddress {
+		return "", "", fmt.Errorf("The Vault address %v is not the Vault server configured for the operator, %v", address, VaultAddress)
+	}
+
+	if len(VaultRoles) == 0 {
+		return "", "", fmt.Errorf("No Vault roles are configured for the operator. Set VAULT_ROLES to the roles which may be used")
+	}
+	if len(role) == 0 {
+		return VaultAddress, VaultRoles[0], nil
+	}
+	for _, allowed := range VaultRoles {
+		if role == allowed {
+			return VaultAddress, role, nil
+		}
+	}
+	return "", "", fmt.Errorf("The Vault role %v is not one of the roles configured for the operator: %v", role, strings.Join(VaultRoles, ", "))
+}
+
+// AnnotatedSecretCredentialProvider searches the Secrets in a namespace for one selected
+// by the filter, and returns its password.  The Secrets searched are limited by
+// CredentialsSecretSelector.
+type AnnotatedSecretCredentialProvider struct {
+	Client    client.Client
+	Namespace string
+	Filter    filter
+}
+
+func (p AnnotatedSecretCredentialProvider) GetToken(ctx context.Context, hostname string) ([]byte, error) {
+	secretList := &corev1.SecretList{}
+	err := p.Client.List(ctx, secretList, client.InNamespace(p.Namespace), client.MatchingLabelsSelector{Selector: CredentialsSecretSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := p.Filter(secretList, hostname)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+
+	return secret.Data[DefaultCredentialsKey], nil
+}
+
+// SecretRefCredentialProvider returns a key of a named Secret.
+type SecretRefCredentialProvider struct {
+	Client    client.Client
+	Namespace string
+	Name      string
+	Key       string
+}
+
+func (p SecretRefCredentialProvider) GetToken(ctx context.Context, hostname string) ([]byte, error) {
+	key := p.Key
+	if len(key) == 0 {
+		key = DefaultCredentialsKey
+	}
+
+	secret := &corev1.Secret{}
+	err := p.Client.Get(ctx, types.NamespacedName{Name: p.Name, Namespace: p.Namespace}, secret)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve credentials Secret %v: %v", p.Name, err)
+	}
+
+	token, ok := secret.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("The credentials Secret %v does not contain key '%v'", p.Name, key)
+	}
+	return token, nil
+}
+
+// FileCredentialProvider returns the contents of a file under CredentialsFileRoot,
+// such as one mounted by a CSI secrets store driver.
+type FileCredentialProvider struct {
+	Path string
+}
+
+func (p FileCredentialProvider) GetToken(ctx context.Context, hostname string) ([]byte, error) {
+	if err := CheckCredentialsHost(hostname); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Clean(p.Path)
+	root := filepath.Clean(CredentialsFileRoot)
+	if !filepath.IsAbs(path) || !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return nil, fmt.Errorf("The credentials file %v must be located under %v", p.Path, root)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read credentials file %v: %v", p.Path, err)
+	}
+	return bytes.TrimSpace(b), nil
+}
+
+// VaultCredentialProvider returns a key of a secret in a Vault KV secrets engine.  Both
+// version 1 and version 2 of the engine are supported; for version 2 the path includes
+// the "data" segment, such as "secret/data/kabanero/github".  The provider logs in to
+// VaultAddress using the Kubernetes auth method at VaultAuthPath, and the service account
+// token of the operator.  The address and role are checked by ResolveVaultSettings.  The
+// client token returned by the login is cached, and shared by the providers which use
+// the same address and role.
+type VaultCredentialProvider struct {
+	Address string
+	Path    string
+	Key     string
+	Role    string
+	// Defaults to a client from NewHTTPClient
+	HTTPClient *http.Client
+	// Defaults to the service account token of the pod
+	TokenFile string
+}
+
+func (p VaultCredentialProvider) GetToken(ctx context.Context, hostname string) ([]byte, error) {
+	if err := CheckCredentialsHost(hostname); err != nil {
+		return nil, err
+	}
+
+	address, role, err := ResolveVaultSettings(p.Address, p.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	response := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	secretUrl := address + "/v1/" + strings.TrimPrefix(p.Path, "/")
+	for attempt := 0; ; attempt++ {
+		clientToken, err := p.clientToken(ctx, address, role)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to log in to Vault at %v: %v", address, err)
+		}
+
+		err = p.do(ctx, http.MethodGet, secretUrl, clientToken, nil, &response)
+		if err == nil {
+			break
+		}
+
+		// The cached client token may have been revoked.  Log in again, once.
+		if statusErr, ok := err.(vaultStatusError); ok && int(statusErr) == http.StatusForbidden && attempt == 0 {
+			vaultTokens.remove(address, role, clientToken)
+			continue
+		}
+		return nil, fmt.Errorf("Unable to read Vault secret %v: %v", p.Path, err)
+	}
+
+	key := p.Key
+	if len(key) == 0 {
+		key = DefaultCredentialsKey
+	}
+
+	// Version 2 of the KV engine nests the secret in a second data field.
+	data := response.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+
+	value, ok := data[key].(string)
+	if !ok {
+		return nil, fmt.Errorf("The Vault secret %v does not contain key '%v'", p.Path, key)
+	}
+	return []byte(value), nil
+}
+
+// Vault client tokens, by the address and role used to log in
+var vaultTokens = &vaultTokenCache{tokens: make(map[string]vaultToken)}
+
+type vaultTokenCache struct {
+	mutex  sync.Mutex
+	tokens map[string]vaultToken
+}
+
+type vaultToken struct {
+	token string
+	// When the token is no longer used.  Zero if it does not expire.
+	expires time.Time
+}
+
+func vaultTokenKey(address string, role string) string {
+	return address + "|" + VaultAuthPath + "|" + role
+}
+
+func (c *vaultTokenCache) get(address string, role string, now time.Time) (string, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, found := c.tokens[vaultTokenKey(address, role)]
+	if !found || (!cached.expires.IsZero() && !now.Before(cached.expires)) {
+		return "", false
+	}
+	return cached.token, true
+}
+
+func (c *vaultTokenCache) put(address string, role string, token vaultToken) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tokens[vaultTokenKey(address, role)] = token
+}
+
+// Removes a token, unless it was already replaced by another.
+func (c *vaultTokenCache) remove(address string, role string, token string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	key := vaultTokenKey(address, role)
+	if c.tokens[key].token == token {
+		delete(c.tokens, key)
+	}
+}
+
+// Returns the cached client token for the address and role, or logs in to get one.
+func (p VaultCredentialProvider) clientToken(ctx context.Context, address string, role string) (string, error) {
+	now := time.Now()
+	if token, found := vaultTokens.get(address, role, now); found {
+		return token, nil
+	}
+
+	token, err := p.login(ctx, address, role, now)
+	if err != nil {
+		return "", err
+	}
+	vaultTokens.put(address, role, token)
+	return token.token, nil
+}
+
+// Logs in to Vault using the Kubernetes auth method, and returns the client token.  The
+// token is used until three quarters of its lease have passed.
+func (p VaultCredentialProvider) login(ctx context.Context, address string, role string, now time.Time) (vaultToken, error) {
+	tokenFile := p.TokenFile
+	if len(tokenFile) == 0 {
+		tokenFile = serviceAccountTokenFile
+	}
+	jwt, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return vaultToken{}, err
+	}
+
+	request := map[string]string{"role": role, "jwt": strings.TrimSpace(string(jwt))}
+	response := struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int64  `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+	err = p.do(ctx, http.MethodPost, address+"/v1/auth/"+strings.Trim(VaultAuthPath, "/")+"/login", "", request, &response)
+	if err != nil {
+		return vaultToken{}, err
+	}
+	if len(response.Auth.ClientToken) == 0 {
+		return vaultToken{}, fmt.Errorf("no client token was returned")
+	}
+
+	token := vaultToken{token: response.Auth.ClientToken}
+	if response.Auth.LeaseDuration > 0 {
+		token.expires = now.Add(time.Duration(response.Auth.LeaseDuration) * time.Second * 3 / 4)
+	}
+	return token, nil
+}
+
+// An unexpected HTTP status code returned by Vault
+type vaultStatusError int
+
+func (e vaultStatusError) Error() string {
+	return fmt.Sprintf("HTTP status code %v", int(e))
+}
+
+func (p VaultCredentialProvider) do(ctx context.Context, method string, requestUrl string, clientToken string, body interface{}, result interface{}) error {
+	var reqBody []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = b
+	}
+
+	req, err := http.NewRequest(method, requestUrl, bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if len(clientToken) != 0 {
+		req.Header.Set("X-Vault-Token", clientToken)
+	}
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = NewHTTPClient(false)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return vaultStatusError(resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, result)
+}
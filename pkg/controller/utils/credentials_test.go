@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// A Vault server which accepts Kubernetes logins for one role, and serves a
+// version 1 and a version 2 KV secret.  Each login returns a new client token,
+// and the number of logins is counted.
+func newFakeVault(logins *int) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/kubernetes/login", func(w http.ResponseWriter, req *http.Request) {
+		login := map[string]string{}
+		json.NewDecoder(req.Body).Decode(&login)
+		if req.Method != http.MethodPost || login["role"] != "kabanero" || login["jwt"] != "sa-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		*logins++
+		fmt.Fprintf(w, `{"auth": {"client_token": "vault-token-%v", "lease_duration": 3600}}`, *logins)
+	})
+	mux.HandleFunc("/v1/kv/github", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != fmt.Sprintf("vault-token-%v", *logins) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"password": "v1-pat"}}`)
+	})
+	mux.HandleFunc("/v1/secret/data/github", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Vault-Token") != fmt.Sprintf("vault-token-%v", *logins) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"data": {"data": {"token": "v2-pat"}, "metadata": {"version": 3}}}`)
+	})
+	return httptest.NewServer(mux)
+}
+
+// Verifies tokens are read from version 1 and version 2 KV secrets after logging in to Vault
+func TestVaultCredentialProvider(t *testing.T) {
+	logins := 0
+	server := newFakeVault(&logins)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "vault")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err = ioutil.WriteFile(tokenFile, []byte("sa-token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(address string, roles []string) { VaultAddress, VaultRoles = address, roles }(VaultAddress, VaultRoles)
+	VaultAddress = server.URL
+	VaultRoles = []string{"kabanero", "other"}
+
+	defer func(hosts []string) { CredentialsAllowedHosts = hosts }(CredentialsAllowedHosts)
+	CredentialsAllowedHosts = []string{"github.com"}
+
+	// The role defaults to the first configured role.
+	p := VaultCredentialProvider{Path: "kv/github", HTTPClient: server.Client(), TokenFile: tokenFile}
+	token, err := p.GetToken(context.Background(), "github.com")
+	if err != nil || string(token) != "v1-pat" {
+		t.Fatalf("Expected the version 1 secret to be read, but got %v: %v", string(token), err)
+	}
+
+	p.Path = "secret/data/github"
+	p.Key = "token"
+	token, err = p.GetToken(context.Background(), "github.com")
+	if err != nil || string(token) != "v2-pat" {
+		t.Fatalf("Expected the version 2 secret to be read, but got %v: %v", string(token), err)
+	}
+	if logins != 1 {
+		t.Fatalf("Expected the client token to be reused, but logged in %v times", logins)
+	}
+
+	// A revoked client token is replaced by logging in again.
+	logins++
+	token, err = p.GetToken(context.Background(), "https://github.com/kabanero-io")
+	if err != nil || string(token) != "v2-pat" || logins != 3 {
+		t.Fatalf("Expected to log in again and read the secret, but got %v after %v logins: %v", string(token), logins, err)
+	}
+
+	// Tokens are only sent to allowed hosts.
+	if _, err = p.GetToken(context.Background(), "https://example.com/index.yaml"); err == nil || !strings.Contains(err.Error(), "not one of the hosts") {
+		t.Fatalf("Expected a host which is not allowed to be rejected, but got %v", err)
+	}
+
+	p.Key = "missing"
+	if _, err = p.GetToken(context.Background(), "github.com"); err == nil {
+		t.Fatal("Expected a missing key to be reported")
+	}
+
+	p.Role = "other"
+	if _, err = p.GetToken(context.Background(), "github.com"); err == nil {
+		t.Fatal("Expected the login to fail")
+	}
+
+	// Only the configured server and roles may be used.
+	p = VaultCredentialProvider{Address: "https://vault.example.com", Path: "kv/github", HTTPClient: server.Client(), TokenFile: tokenFile}
+	if _, err = p.GetToken(context.Background(), "github.com"); err == nil || !strings.Contains(err.Error(), "not the Vault server configured") {
+		t.Fatalf("Expected another Vault server to be rejected, but got %v", err)
+	}
+	p = VaultCredentialProvider{Address: server.URL + "/", Path: "kv/github", Role: "admin", HTTPClient: server.Client(), TokenFile: tokenFile}
+	if _, err = p.GetToken(context.Background(), "github.com"); err == nil || !strings.Contains(err.Error(), "not one of the roles") {
+		t.Fatalf("Expected a role which is not configured to be rejected, but got %v", err)
+	}
+}
+
+// Verifies the Vault settings are read from the environment
+func TestLoadVaultSettings(t *testing.T) {
+	defer func(address string, authPath string, roles []string, hosts []string) {
+		VaultAddress, VaultAuthPath, VaultRoles, CredentialsAllowedHosts = address, authPath, roles, hosts
+	}(VaultAddress, VaultAuthPath, VaultRoles, CredentialsAllowedHosts)
+
+	for name, value := range map[string]string{"VAULT_ADDR": "https://vault.example.com:8200/", "VAULT_AUTH_PATH": "/k8s/", "VAULT_ROLES": "kabanero, stacks,", "CREDENTIALS_ALLOWED_HOSTS": "GitHub.com, api.github.com,"} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	if err := LoadCredentialSettings(); err != nil {
+		t.Fatal(err)
+	}
+	if VaultAddress != "https://vault.example.com:8200" || VaultAuthPath != "k8s" || strings.Join(VaultRoles, ",") != "kabanero,stacks" {
+		t.Fatalf("Unexpected Vault settings: %v %v %v", VaultAddress, VaultAuthPath, VaultRoles)
+	}
+	if strings.Join(CredentialsAllowedHosts, ",") != "github.com,api.github.com" {
+		t.Fatalf("Unexpected allowed hosts: %v", CredentialsAllowedHosts)
+	}
+
+	os.Setenv("VAULT_ADDR", "vault.example.com")
+	if err := LoadCredentialSettings(); err == nil {
+		t.Fatal("Expected an address which is not a URL to be rejected")
+	}
+}
+
+// Verifies credential files are only read from under the credentials root
+func TestFileCredentialProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "credentials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultRoot, defaultHosts := CredentialsFileRoot, CredentialsAllowedHosts
+	CredentialsFileRoot = filepath.Join(dir, "root")
+	defer func() { CredentialsFileRoot, CredentialsAllowedHosts = defaultRoot, defaultHosts }()
+
+	if err = os.Mkdir(CredentialsFileRoot, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(CredentialsFileRoot, "github"), []byte("file-pat\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "outside"), []byte("outside"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := FileCredentialProvider{Path: filepath.Join(CredentialsFileRoot, "github")}
+	CredentialsAllowedHosts = nil
+	if _, err = p.GetToken(context.Background(), "github.com"); err == nil {
+		t.Fatal("Expected the file not to be read when no hosts are allowed")
+	}
+
+	CredentialsAllowedHosts = []string{"github.com"}
+	token, err := p.GetToken(context.Background(), "github.com:443")
+	if err != nil || string(token) != "file-pat" {
+		t.Fatalf("Expected the file to be read, but got %v: %v", string(token), err)
+	}
+	if _, err = p.GetToken(context.Background(), "github.example.com"); err == nil {
+		t.Fatal("Expected a host which is not allowed to be rejected")
+	}
+
+	for _, path := range []string{filepath.Join(dir, "outside"), filepath.Join(CredentialsFileRoot, "..", "outside"), "github"} {
+		if _, err = (FileCredentialProvider{Path: path}).GetToken(context.Background(), "github.com"); err == nil {
+			t.Fatalf("Expected the file %v to be rejected", path)
+		}
+	}
+}
+
+// Verifies tokens are read from named and annotated Secrets
+func TestSecretCredentialProviders(t *testing.T) {
+	named := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "named", Namespace: "kabanero"},
+		Data:       map[string][]byte{"token": []byte("named-pat")},
+	}
+	annotated := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "kabanero", Labels: map[string]string{"kabanero.io/credentials": "git"}},
+		Data:       map[string][]byte{"password": []byte("annotated-pat")},
+	}
+	cl := fake.NewFakeClient(named, annotated)
+	ctx := context.Background()
+
+	token, err := SecretRefCredentialProvider{Client: cl, Namespace: "kabanero", Name: "named", Key: "token"}.GetToken(ctx, "github.com")
+	if err != nil || string(token) != "named-pat" {
+		t.Fatalf("Expected the named Secret to be read, but got %v: %v", string(token), err)
+	}
+	if _, err = (SecretRefCredentialProvider{Client: cl, Namespace: "kabanero", Name: "named"}).GetToken(ctx, "github.com"); err == nil {
+		t.Fatal("Expected a missing password key to be reported")
+	}
+
+	// Select the last Secret searched, so the test shows which Secrets were searched.
+	last := func(secretList *corev1.SecretList, filterStrings ...string) (*corev1.Secret, error) {
+		if len(secretList.Items) == 0 {
+			return nil, nil
+		}
+		return &secretList.Items[len(secretList.Items)-1], nil
+	}
+
+	defaultSelector := CredentialsSecretSelector
+	defer func() { CredentialsSecretSelector = defaultSelector }()
+
+	CredentialsSecretSelector = labels.SelectorFromSet(labels.Set{"kabanero.io/credentials": "git"})
+	token, err = AnnotatedSecretCredentialProvider{Client: cl, Namespace: "kabanero", Filter: last}.GetToken(ctx, "github.com")
+	if err != nil || string(token) != "annotated-pat" {
+		t.Fatalf("Expected the selected Secret to be read, but got %v: %v", string(token), err)
+	}
+
+	CredentialsSecretSelector = labels.SelectorFromSet(labels.Set{"kabanero.io/credentials": "none"})
+	token, err = AnnotatedSecretCredentialProvider{Client: cl, Namespace: "kabanero", Filter: last}.GetToken(ctx, "github.com")
+	if err != nil || token != nil {
+		t.Fatalf("Expected no token, but got %v: %v", string(token), err)
+	}
+}
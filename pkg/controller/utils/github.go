@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ParseGithubApiUrl parses the GitHub API URL of a Kabanero instance.  A value
+// without a scheme, such as api.github.com, names a host which is contacted
+// using https.  The URL must use http or https, and must name a host.
+func ParseGithubApiUrl(value string) (*url.URL, error) {
+	absolute := value
+	if !strings.Contains(value, "://") {
+		absolute = "https://" + value
+	}
+
+	apiUrl, err := url.Parse(absolute)
+	if err != nil {
+		return nil, err
+	}
+
+	if (apiUrl.Scheme != "http" && apiUrl.Scheme != "https") || len(apiUrl.Host) == 0 {
+		return nil, fmt.Errorf("The GitHub API URL %v must be an http or https URL, or a host name", value)
+	}
+
+	return apiUrl, nil
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+)
+
+// Verifies GitHub API URLs with and without a scheme are accepted, and values without a host are not
+func TestParseGithubApiUrl(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"https://api.github.com", "https://api.github.com"},
+		{"api.github.com", "https://api.github.com"},
+		{"github.example.com/api/v3", "https://github.example.com/api/v3"},
+		{"http://github.example.com:8080", "http://github.example.com:8080"},
+		{"https://api.github.com:port", ""},
+		{"ftp://github.example.com", ""},
+		{"https:///api/v3", ""},
+	}
+
+	for _, test := range tests {
+		apiUrl, err := ParseGithubApiUrl(test.value)
+		if test.expected == "" {
+			if err == nil {
+				t.Fatalf("Expected %v to be rejected, but got %v", test.value, apiUrl)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %v: %v", test.value, err)
+		}
+		if apiUrl.String() != test.expected {
+			t.Fatalf("Expected %v to be parsed as %v, but got %v", test.value, test.expected, apiUrl)
+		}
+	}
+}
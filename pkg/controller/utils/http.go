@@ -9,9 +9,12 @@ import (
 )
 
 // Retrieves a HTTP client. If the input access token is specified, an oauth2 generated http client is returned.
-// If the access token is not specified a default http client is returned. The default http client will contain
-// the input transport if specified.
+// The client uses the input transport if specified, or otherwise a transport from NewTransport.
 func GetHTTPClient(accessToken []byte, transport *http.Transport) (*http.Client, error) {
+	if transport == nil {
+		transport = NewTransport(false)
+	}
+
 	if accessToken != nil {
 		encodedToken := base64.StdEncoding.EncodeToString([]byte(accessToken))
 		decodedTokenBytes, err := base64.StdEncoding.DecodeString(encodedToken)
@@ -22,13 +25,9 @@ func GetHTTPClient(accessToken []byte, transport *http.Transport) (*http.Client,
 		ts := oauth2.StaticTokenSource(
 			&oauth2.Token{AccessToken: string(decodedTokenBytes)},
 		)
-		ctx := context.Background()
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
 		return oauth2.NewClient(ctx, ts), nil
 	}
 
-	if transport != nil {
-		return &http.Client{Transport: transport}, nil
-	}
-
-	return http.DefaultClient, nil
+	return &http.Client{Transport: transport}, nil
 }
@@ -0,0 +1,352 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Determines if an image is covered by one of the allowed registry or
+// repository prefixes.  A prefix is a registry host, optionally followed by a
+// repository path, for example quay.io or docker.io/kabanero.  A prefix without
+// a registry host refers to Docker Hub.  Repository paths match whole path
+// segments: docker.io/kabanero matches docker.io/kabanero/nodejs but not
+// docker.io/kabanero-io/nodejs.
+func ImageAllowed(image string, allowed []string) (bool, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return false, err
+	}
+
+	for _, prefix := range allowed {
+		prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/")
+		if prefix == "" {
+			continue
+		}
+
+		host, path := dockerHubDomain, prefix
+		parts := strings.SplitN(prefix, "/", 2)
+		if strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost" {
+			host = parts[0]
+			path = ""
+			if len(parts) == 2 {
+				path = parts[1]
+			}
+		}
+
+		if normalizeRegistryHost(host) != ref.Registry {
+			continue
+		}
+
+		if path == "" || ref.Repository == path || strings.HasPrefix(ref.Repository, path+"/") {
+			return true, nil
+		}
+
+		// Official Docker Hub images live under library/, but are rarely written that way.
+		if ref.Registry == dockerHubRegistry && strings.HasPrefix(ref.Repository, "library/") {
+			if ref.Repository == "library/"+path {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// The media type of the layers of a cosign signature manifest, and the layer
+// annotation holding the base64 encoded signature of the layer's payload.
+const (
+	cosignPayloadMediaType    = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+)
+
+// Signature manifests are single platform image manifests.
+const signatureManifestMediaTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+
+// Signature manifests and payloads are small.  Anything larger is not read.
+const maxSignatureContentSize = 1024 * 1024
+
+// The parts of an OCI image manifest which describe the signature layers.
+type signatureManifest struct {
+	Layers []struct {
+		MediaType   string            `json:"mediaType"`
+		Digest      string            `json:"digest"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"layers"`
+}
+
+// The parts of a simple signing payload which identify the signed image.
+type signaturePayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// Parses the PEM encoded public keys which are trusted to sign images.  ECDSA,
+// RSA and Ed25519 keys are supported.
+func ParseSignatureKeys(data []byte) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse public key: %v", err)
+		}
+		switch key.(type) {
+		case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		default:
+			return nil, fmt.Errorf("Public keys of type %T are not supported", key)
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("No PEM encoded public keys were found")
+	}
+	return keys, nil
+}
+
+// Determines if an image has been signed by one of the trusted public keys.
+// Signatures are located using the cosign convention: a manifest tagged with the
+// image digest and a .sig suffix, in the image's repository.  Each layer of the
+// manifest is a simple signing payload, signed by the layer's signature
+// annotation.  A signature is accepted when its payload names the digest of the
+// image, and it verifies with one of the keys.
+func VerifyImageSignature(ctx context.Context, image string, keys []crypto.PublicKey, creds RegistryCredentials, transport http.RoundTripper) (bool, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return false, err
+	}
+
+	digest, err := ResolveImageDigest(ctx, image, creds, transport)
+	if err != nil {
+		return false, err
+	}
+
+	return verifyImageDigestSignature(ctx, image, ref, digest, keys, creds, transport)
+}
+
+// Determines if the image with the input digest has been signed by one of the
+// trusted public keys.
+func verifyImageDigestSignature(ctx context.Context, image string, ref ImageReference, digest string, keys []crypto.PublicKey, creds RegistryCredentials, transport http.RoundTripper) (bool, error) {
+	if transport == nil {
+		transport = NewTransport(false)
+	}
+	httpClient := &http.Client{Transport: transport}
+	auth, hasAuth := creds[ref.Registry]
+	authorization := ""
+	repositoryUrl := fmt.Sprintf("https://%v/v2/%v", ref.Registry, ref.Repository)
+
+	signatureTag := strings.Replace(digest, ":", "-", 1) + ".sig"
+	b, err := getRegistryContent(ctx, httpClient, repositoryUrl+"/manifests/"+signatureTag, signatureManifestMediaTypes, auth, hasAuth, &authorization)
+	if errors.Is(err, ErrImageNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to retrieve the signature of image %v: %v", image, err)
+	}
+
+	manifest := signatureManifest{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return false, fmt.Errorf("Unable to parse the signature manifest of image %v: %v", image, err)
+	}
+
+	for _, layer := range manifest.Layers {
+		signature, ok := layer.Annotations[cosignSignatureAnnotation]
+		if layer.MediaType != cosignPayloadMediaType || !ok {
+			continue
+		}
+
+		payload, err := getRegistryContent(ctx, httpClient, repositoryUrl+"/blobs/"+layer.Digest, "*/*", auth, hasAuth, &authorization)
+		if err != nil {
+			return false, fmt.Errorf("Unable to retrieve the signature payload %v of image %v: %v", layer.Digest, image, err)
+		}
+		if fmt.Sprintf("sha256:%x", sha256.Sum256(payload)) != layer.Digest {
+			continue
+		}
+
+		signed := signaturePayload{}
+		if err := json.Unmarshal(payload, &signed); err != nil || signed.Critical.Image.DockerManifestDigest != digest {
+			continue
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			continue
+		}
+		for _, key := range keys {
+			if verifySignature(key, payload, sig) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// SignatureCache remembers whether images are signed, so that the registry is
+// not queried on every reconcile.  Image tags are resolved to digests through
+// a DigestCache, and the outcome is cached by the digest, the trusted keys and
+// the credentials, like cached digests are.  An image is therefore verified
+// again when its tag moves to another digest, or the keys change.  Failures to
+// verify a signature are not cached.
+type SignatureCache struct {
+	digests *DigestCache
+	mutex   sync.Mutex
+	entries map[string]bool
+}
+
+// Creates a signature cache whose resolved digests expire after the input duration.
+func NewSignatureCache(digestTTL time.Duration) *SignatureCache {
+	return &SignatureCache{digests: NewDigestCache(digestTTL), entries: make(map[string]bool)}
+}
+
+// Determines if an image has been signed by one of the trusted public keys,
+// using the cached outcome for its digest if there is one.
+func (cache *SignatureCache) Verify(ctx context.Context, image string, keys []crypto.PublicKey, creds RegistryCredentials, transport http.RoundTripper) (bool, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return false, err
+	}
+
+	digest, err := cache.digests.Resolve(ctx, image, creds, transport)
+	if err != nil {
+		return false, err
+	}
+
+	keysIdentity, err := signatureKeysIdentity(keys)
+	if err != nil {
+		return false, err
+	}
+	key := ref.DigestReference(digest) + "|" + keysIdentity + "|" + creds.identity(ref.Registry)
+
+	cache.mutex.Lock()
+	signed, ok := cache.entries[key]
+	cache.mutex.Unlock()
+	if ok {
+		return signed, nil
+	}
+
+	signed, err = verifyImageDigestSignature(ctx, image, ref, digest, keys, creds, transport)
+	if err != nil {
+		return false, err
+	}
+
+	cache.mutex.Lock()
+	cache.entries[key] = signed
+	cache.mutex.Unlock()
+
+	return signed, nil
+}
+
+// Returns a hash identifying a set of public keys, regardless of their order.
+func signatureKeysIdentity(keys []crypto.PublicKey) (string, error) {
+	var encoded []string
+	for _, key := range keys {
+		der, err := x509.MarshalPKIXPublicKey(key)
+		if err != nil {
+			return "", fmt.Errorf("Unable to encode public key: %v", err)
+		}
+		encoded = append(encoded, fmt.Sprintf("%x", sha256.Sum256(der)))
+	}
+	sort.Strings(encoded)
+
+	h := sha256.Sum256([]byte(strings.Join(encoded, ",")))
+	return fmt.Sprintf("%x", h), nil
+}
+
+// Verifies the signature of a payload.  ECDSA and RSA signatures are of the
+// SHA-256 digest of the payload, as cosign creates them.
+func verifySignature(key crypto.PublicKey, payload []byte, sig []byte) bool {
+	hash := sha256.Sum256(payload)
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		ecdsaSig := struct {
+			R, S *big.Int
+		}{}
+		if rest, err := asn1.Unmarshal(sig, &ecdsaSig); err != nil || len(rest) != 0 {
+			return false
+		}
+		return ecdsa.Verify(key, hash[:], ecdsaSig.R, ecdsaSig.S)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, payload, sig)
+	}
+	return false
+}
+
+// Retrieves the content at a registry v2 API URL.  The registry's authentication
+// challenge is answered using the input credentials, and the resulting
+// authorization is kept so that later requests to the repository reuse it.
+func getRegistryContent(ctx context.Context, httpClient *http.Client, contentUrl string, accept string, auth RegistryAuth, hasAuth bool, authorization *string) ([]byte, error) {
+	get := func() (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, contentUrl, nil)
+		if err != nil {
+			return nil, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Accept", accept)
+		if *authorization != "" {
+			req.Header.Set("Authorization", *authorization)
+		}
+		return httpClient.Do(req)
+	}
+
+	resp, err := get()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && *authorization == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		*authorization, err = answerChallenge(ctx, httpClient, challenge, auth, hasAuth)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = get()
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrImageNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, fmt.Errorf("access was denied: %v", resp.Status)
+	default:
+		return nil, fmt.Errorf("the registry returned %v", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxSignatureContentSize))
+}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImageAllowed(t *testing.T) {
+	tests := []struct {
+		image   string
+		allowed []string
+		result  bool
+	}{
+		{"kabanero/nodejs", []string{"docker.io/kabanero"}, true},
+		{"docker.io/kabanero/nodejs", []string{"kabanero"}, true},
+		{"kabanero-io/nodejs", []string{"docker.io/kabanero"}, false},
+		{"quay.io/kabanero/nodejs", []string{"docker.io/kabanero"}, false},
+		{"quay.io/kabanero/nodejs", []string{"quay.io"}, true},
+		{"quay.io/kabanero/nodejs", []string{"quay.io/kabanero/"}, true},
+		{"nginx", []string{"docker.io/nginx"}, true},
+		{"localhost:5000/kabanero/nodejs", []string{"localhost:5000/kabanero"}, true},
+		{"kabanero/nodejs", nil, false},
+	}
+
+	for _, test := range tests {
+		allowed, err := ImageAllowed(test.image, test.allowed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if allowed != test.result {
+			t.Fatalf("Expected %v allowed by %v to be %v", test.image, test.allowed, test.result)
+		}
+	}
+}
+
+// Publishes a cosign signature of an image digest, made with the input key.
+func signTestImage(t *testing.T, registry *fakeRegistry, repository string, digest string, signedDigest string, key *ecdsa.PrivateKey) {
+	payload := fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%v"},"image":{"docker-manifest-digest":"%v"},"type":"cosign container image signature"},"optional":null}`, repository, signedDigest)
+	hash := sha256.Sum256([]byte(payload))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloadDigest := manifestTestDigest(payload)
+	registry.blobs[payloadDigest] = payload
+	registry.manifests[repository+":"+strings.Replace(digest, ":", "-", 1)+".sig"] = fmt.Sprintf(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.manifest.v1+json",
+  "layers": [{
+    "mediaType": "application/vnd.dev.cosign.simplesigning.v1+json",
+    "digest": "%v",
+    "size": %v,
+    "annotations": {"dev.cosignproject.cosign/signature": "%v"}
+  }]
+}`, payloadDigest, len(payload), base64.StdEncoding.EncodeToString(sig))
+}
+
+// Returns the PEM encoding of a public key.
+func encodeTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+// Verifies signatures are located by the digest of the image, and verified with the trusted keys
+func TestVerifyImageSignature(t *testing.T) {
+	trusted, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	untrusted, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseSignatureKeys(encodeTestKey(t, trusted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseSignatureKeys([]byte("not a key")); err == nil {
+		t.Fatal("Expected an error for data without public keys")
+	}
+
+	registry := newFakeRegistry()
+	defer registry.Close()
+	registry.manifests["kabanero/signed:1.0"] = "signed"
+	signTestImage(t, registry, "kabanero/signed", manifestTestDigest("signed"), manifestTestDigest("signed"), trusted)
+	registry.manifests["kabanero/untrusted:1.0"] = "untrusted"
+	signTestImage(t, registry, "kabanero/untrusted", manifestTestDigest("untrusted"), manifestTestDigest("untrusted"), untrusted)
+	registry.manifests["kabanero/copied:1.0"] = "copied"
+	signTestImage(t, registry, "kabanero/copied", manifestTestDigest("copied"), manifestTestDigest("signed"), trusted)
+	registry.manifests["kabanero/unsigned:1.0"] = "unsigned"
+
+	tests := []struct {
+		image  string
+		signed bool
+	}{
+		{"kabanero/signed:1.0", true},
+		// Signed with a key which is not trusted
+		{"kabanero/untrusted:1.0", false},
+		// The payload names the digest of another image
+		{"kabanero/copied:1.0", false},
+		{"kabanero/unsigned:1.0", false},
+	}
+	for _, test := range tests {
+		signed, err := VerifyImageSignature(context.Background(), registry.host()+"/"+test.image, keys, registry.credentials(), registry.Client().Transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if signed != test.signed {
+			t.Fatalf("Expected the signature of %v to be verified as %v", test.image, test.signed)
+		}
+	}
+
+	// The image itself does not exist
+	_, err = VerifyImageSignature(context.Background(), registry.host()+"/kabanero/missing:1.0", keys, registry.credentials(), registry.Client().Transport)
+	if err == nil {
+		t.Fatal("Expected an error for an image which does not exist")
+	}
+}
+
+// Verifies signatures are only verified again when the digest of the image or the trusted keys change
+func TestSignatureCache(t *testing.T) {
+	trusted, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseSignatureKeys(encodeTestKey(t, trusted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKeys, err := ParseSignatureKeys(encodeTestKey(t, other))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := newFakeRegistry()
+	defer registry.Close()
+	registry.manifests["kabanero/signed:1.0"] = "signed"
+	signTestImage(t, registry, "kabanero/signed", manifestTestDigest("signed"), manifestTestDigest("signed"), trusted)
+	image := registry.host() + "/kabanero/signed:1.0"
+
+	cache := NewSignatureCache(time.Hour)
+	verify := func(keys []crypto.PublicKey) bool {
+		signed, err := cache.Verify(context.Background(), image, keys, registry.credentials(), registry.Client().Transport)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	if !verify(keys) {
+		t.Fatal("Expected the image to be signed")
+	}
+
+	// The registry is not asked again for the same digest and keys
+	signatureTag := "kabanero/signed:" + strings.Replace(manifestTestDigest("signed"), ":", "-", 1) + ".sig"
+	signature := registry.manifests[signatureTag]
+	delete(registry.manifests, signatureTag)
+	if !verify(keys) {
+		t.Fatal("Expected the cached signature to be used")
+	}
+
+	// Other keys are verified again
+	registry.manifests[signatureTag] = signature
+	if verify(otherKeys) {
+		t.Fatal("Expected the image not to be signed by the other key")
+	}
+
+	// A moved tag is verified again once its cached digest expires
+	registry.manifests["kabanero/signed:1.0"] = "unsigned"
+	cache.digests.ttl = 0
+	cache.digests.entries = make(map[string]digestCacheEntry)
+	if verify(keys) {
+		t.Fatal("Expected the moved tag not to be signed")
+	}
+}
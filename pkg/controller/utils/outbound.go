@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The ConfigMap into which OpenShift injects the cluster trusted certificate authorities.
+const TrustedCABundleConfigMapName = "kabanero-trusted-ca-bundle"
+
+// The key of the trusted certificate authorities in the ConfigMap.
+const TrustedCABundleKey = "ca-bundle.crt"
+
+// The label requesting OpenShift to inject the trusted certificate authorities into a ConfigMap.
+const InjectTrustedCABundleLabel = "config.openshift.io/inject-trusted-cabundle"
+
+// The settings shared by all outbound HTTP clients.  The proxy settings come from the
+// environment, unless the cluster-wide proxy has been loaded.
+var outbound = struct {
+	sync.RWMutex
+	proxy    *httpproxy.Config
+	proxyFn  func(*url.URL) (*url.URL, error)
+	caBundle []byte
+}{}
+
+func init() {
+	setOutboundProxy(httpproxy.FromEnvironment())
+}
+
+func setOutboundProxy(config *httpproxy.Config) {
+	outbound.Lock()
+	defer outbound.Unlock()
+	outbound.proxy = config
+	outbound.proxyFn = config.ProxyFunc()
+}
+
+// OutboundProxy returns the proxy settings used by outbound HTTP clients.
+func OutboundProxy() httpproxy.Config {
+	outbound.RLock()
+	defer outbound.RUnlock()
+	return *outbound.proxy
+}
+
+// Returns the proxy for a request, using the current proxy settings.
+func outboundProxyForRequest(req *http.Request) (*url.URL, error) {
+	outbound.RLock()
+	proxyFn := outbound.proxyFn
+	outbound.RUnlock()
+	return proxyFn(req.URL)
+}
+
+// SetTrustedCABundle sets the PEM encoded certificate authorities trusted by outbound
+// HTTP clients, in addition to the system ones.
+func SetTrustedCABundle(bundle []byte) error {
+	if len(bundle) != 0 && !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("The trusted CA bundle does not contain any PEM encoded certificates")
+	}
+
+	outbound.Lock()
+	defer outbound.Unlock()
+	outbound.caBundle = bundle
+	return nil
+}
+
+// TrustedCertPool returns the certificate authorities trusted by outbound HTTP clients:
+// the system ones, and the trusted CA bundle.  The pool can be added to by the caller.
+func TrustedCertPool() *x509.CertPool {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+
+	outbound.RLock()
+	defer outbound.RUnlock()
+	if len(outbound.caBundle) != 0 {
+		pool.AppendCertsFromPEM(outbound.caBundle)
+	}
+	return pool
+}
+
+// NewTransport returns a transport using the outbound proxy, and trusting the outbound
+// certificate authorities.  Certificate validation can be disabled, but should only be
+// when the server certificate cannot be trusted by adding its CA to the trusted bundle.
+func NewTransport(skipCertVerify bool) *http.Transport {
+	return &http.Transport{
+		Proxy: outboundProxyForRequest,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       &tls.Config{RootCAs: TrustedCertPool(), InsecureSkipVerify: skipCertVerify},
+	}
+}
+
+// NewHTTPClient returns a HTTP client using a transport from NewTransport.
+func NewHTTPClient(skipCertVerify bool) *http.Client {
+	return &http.Client{Transport: NewTransport(skipCertVerify)}
+}
+
+// LoadTrustedCABundle reads the trusted certificate authorities from the ConfigMap into
+// which OpenShift injects them.  If the ConfigMap does not exist, only the system
+// certificate authorities are trusted.
+func LoadTrustedCABundle(ctx context.Context, c client.Reader, namespace string) error {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Name: TrustedCABundleConfigMapName, Namespace: namespace}, cm)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return SetTrustedCABundle(nil)
+		}
+		return err
+	}
+
+	bundle := []byte(cm.Data[TrustedCABundleKey])
+	outbound.RLock()
+	unchanged := bytes.Equal(bundle, outbound.caBundle)
+	outbound.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return SetTrustedCABundle(bundle)
+}
+
+// LoadClusterProxy reads the OpenShift cluster-wide proxy settings.  When the cluster has
+// no proxy configuration, such as when it is not OpenShift, the proxy settings from the
+// environment are used.
+func LoadClusterProxy(ctx context.Context, c client.Reader) error {
+	proxy := &unstructured.Unstructured{}
+	proxy.SetGroupVersionKind(schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Proxy"})
+	err := c.Get(ctx, types.NamespacedName{Name: "cluster"}, proxy)
+	if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return err
+	}
+
+	config := httpproxy.FromEnvironment()
+	if err == nil {
+		httpProxy, _, _ := unstructured.NestedString(proxy.Object, "status", "httpProxy")
+		httpsProxy, _, _ := unstructured.NestedString(proxy.Object, "status", "httpsProxy")
+		noProxy, _, _ := unstructured.NestedString(proxy.Object, "status", "noProxy")
+		if len(httpProxy) != 0 || len(httpsProxy) != 0 {
+			config = &httpproxy.Config{HTTPProxy: httpProxy, HTTPSProxy: httpsProxy, NoProxy: noProxy}
+		}
+	}
+
+	if *config != OutboundProxy() {
+		setOutboundProxy(config)
+	}
+	return nil
+}
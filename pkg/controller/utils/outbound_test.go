@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// A reader which returns the cluster-wide proxy configuration.
+type proxyReader struct {
+	client.Reader
+	status map[string]interface{}
+}
+
+func (r proxyReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	obj.(*unstructured.Unstructured).Object["status"] = r.status
+	return nil
+}
+
+// Verifies the cluster-wide proxy configuration is used by outbound transports
+func TestLoadClusterProxy(t *testing.T) {
+	defaultProxy := OutboundProxy()
+	defer setOutboundProxy(&defaultProxy)
+
+	reader := proxyReader{status: map[string]interface{}{
+		"httpProxy":  "http://proxy.example.com:3128",
+		"httpsProxy": "http://proxy.example.com:3129",
+		"noProxy":    ".svc,internal.example.com",
+	}}
+	if err := LoadClusterProxy(context.Background(), reader); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := NewTransport(false)
+	tests := []struct {
+		url   string
+		proxy string
+	}{
+		{"https://github.com/kabanero-io", "http://proxy.example.com:3129"},
+		{"http://example.com/index.yaml", "http://proxy.example.com:3128"},
+		{"https://sso.kabanero.svc:8443/auth", ""},
+		{"https://internal.example.com/index.yaml", ""},
+	}
+	for _, test := range tests {
+		u, _ := url.Parse(test.url)
+		proxy, err := transport.Proxy(&http.Request{URL: u})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (proxy == nil && test.proxy != "") || (proxy != nil && proxy.String() != test.proxy) {
+			t.Fatalf("Expected %v to use proxy '%v', but got %v", test.url, test.proxy, proxy)
+		}
+	}
+
+	// Without a cluster-wide proxy, the environment is used.
+	if err := LoadClusterProxy(context.Background(), proxyReader{status: map[string]interface{}{}}); err != nil {
+		t.Fatal(err)
+	}
+	if OutboundProxy() != *httpproxy.FromEnvironment() {
+		t.Fatalf("Expected the proxy settings from the environment, but got %v", OutboundProxy())
+	}
+}
+
+// Verifies outbound clients trust the certificate authorities in the trusted CA bundle ConfigMap
+func TestLoadTrustedCABundle(t *testing.T) {
+	defer SetTrustedCABundle(nil)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	if _, err := NewHTTPClient(false).Get(server.URL); err == nil {
+		t.Fatal("Expected the server certificate not to be trusted")
+	}
+
+	bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	cl := fake.NewFakeClient(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: TrustedCABundleConfigMapName, Namespace: "kabanero"},
+		Data:       map[string]string{TrustedCABundleKey: string(bundle)},
+	})
+	if err := LoadTrustedCABundle(context.Background(), cl, "kabanero"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := NewHTTPClient(false).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the server certificate to be trusted: %v", err)
+	}
+	resp.Body.Close()
+
+	// The ConfigMap does not exist in another namespace, so only the system certificate authorities are trusted.
+	if err := LoadTrustedCABundle(context.Background(), cl, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHTTPClient(false).Get(server.URL); err == nil {
+		t.Fatal("Expected the server certificate not to be trusted")
+	}
+
+	if err := SetTrustedCABundle([]byte("not a certificate")); err == nil {
+		t.Fatal("Expected a bundle without certificates to be rejected")
+	}
+}
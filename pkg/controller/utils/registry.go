@@ -0,0 +1,535 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	rlog "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var registrylog = rlog.Log.WithName("registry")
+
+const (
+	dockerHubDomain   = "docker.io"
+	dockerHubRegistry = "registry-1.docker.io"
+	defaultImageTag   = "latest"
+)
+
+// The service account whose image pull secrets are used when no pull secrets
+// are specified
+const defaultPullServiceAccount = "default"
+
+// The transport used to contact image registries.  Nil uses the default
+// transport.  Tests replace it to reach a local registry.
+var RegistryTransport http.RoundTripper
+
+// How long a tag which moved to a new digest is reported
+const TagMovedReportPeriod = 24 * time.Hour
+
+// Returned, possibly wrapped, when the registry does not have the requested image.
+var ErrImageNotFound = errors.New("the image was not found")
+
+// The manifest media types accepted when resolving a digest.  Manifest lists
+// and OCI indexes are preferred so that the digest covers every platform.
+var manifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+}
+
+// ImageReference is a parsed container image reference.
+type ImageReference struct {
+	// The image name without a tag or digest, as it was written.
+	Name string
+	// The registry host serving the image.
+	Registry string
+	// The repository path within the registry.
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parses an image reference of the form [registry/]repository[:tag][@digest].
+// Images without a registry are served by Docker Hub, and images without a
+// tag or digest use the latest tag.
+func ParseImageReference(image string) (ImageReference, error) {
+	if len(image) == 0 {
+		return ImageReference{}, fmt.Errorf("The input image is empty.")
+	}
+
+	ref := ImageReference{}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Digest = name[i+1:]
+		name = name[:i]
+		if !strings.HasPrefix(ref.Digest, "sha256:") {
+			return ImageReference{}, fmt.Errorf("The image %v contains an unsupported digest", image)
+		}
+	}
+
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		ref.Tag = name[i+1:]
+		name = name[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultImageTag
+	}
+
+	if len(name) == 0 {
+		return ImageReference{}, fmt.Errorf("The image %v does not contain a repository", image)
+	}
+	ref.Name = name
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry = parts[0]
+		ref.Repository = parts[1]
+	} else {
+		ref.Registry = dockerHubDomain
+		ref.Repository = name
+	}
+
+	if ref.Registry == dockerHubDomain || ref.Registry == "index."+dockerHubDomain {
+		ref.Registry = dockerHubRegistry
+		if !strings.Contains(ref.Repository, "/") {
+			ref.Repository = "library/" + ref.Repository
+		}
+	}
+
+	return ref, nil
+}
+
+// Returns the image reference pinned to the input digest.
+func (ref ImageReference) DigestReference(digest string) string {
+	return ref.Name + "@" + digest
+}
+
+// RegistryAuth holds the credentials used to access a single registry.
+type RegistryAuth struct {
+	Username string
+	Password string
+}
+
+// RegistryCredentials maps registry hosts to their credentials.
+type RegistryCredentials map[string]RegistryAuth
+
+// The contents of a kubernetes.io/dockercfg secret, which is also the "auths"
+// section of a kubernetes.io/dockerconfigjson secret.
+type dockerConfigEntries map[string]struct {
+	Auth     string `json:"auth,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Normalizes a docker config key, which may be a URL, to a registry host.
+func normalizeRegistryHost(key string) string {
+	host := key
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host = strings.SplitN(host, "/", 2)[0]
+
+	if host == dockerHubDomain || host == "index."+dockerHubDomain {
+		return dockerHubRegistry
+	}
+	return host
+}
+
+// Adds the credentials held by a docker config pull secret.  Secrets of other
+// types are ignored.
+func (creds RegistryCredentials) addSecret(secret *corev1.Secret) error {
+	var entries dockerConfigEntries
+	parsed := RegistryCredentials{}
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := struct {
+			Auths dockerConfigEntries `json:"auths"`
+		}{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return fmt.Errorf("Unable to parse pull secret %v: %v", secret.Name, err)
+		}
+		entries = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &entries); err != nil {
+			return fmt.Errorf("Unable to parse pull secret %v: %v", secret.Name, err)
+		}
+	default:
+		return nil
+	}
+
+	for key, entry := range entries {
+		auth := RegistryAuth{Username: entry.Username, Password: entry.Password}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return fmt.Errorf("Unable to decode the credentials for %v in pull secret %v: %v", key, secret.Name, err)
+			}
+			userPass := strings.SplitN(string(decoded), ":", 2)
+			if len(userPass) != 2 {
+				return fmt.Errorf("The credentials for %v in pull secret %v are not of the form username:password", key, secret.Name)
+			}
+			auth = RegistryAuth{Username: userPass[0], Password: userPass[1]}
+		}
+		parsed[normalizeRegistryHost(key)] = auth
+	}
+
+	// Nothing is added from a secret which could not be parsed completely.
+	for host, auth := range parsed {
+		creds[host] = auth
+	}
+	return nil
+}
+
+// Returns a value identifying the credentials used for a registry, without
+// revealing them.  Anonymous access is identified by an empty string.
+func (creds RegistryCredentials) identity(registry string) string {
+	auth, ok := creds[registry]
+	if !ok {
+		return ""
+	}
+	digest := sha256.Sum256([]byte(auth.Username + ":" + auth.Password))
+	return hex.EncodeToString(digest[:])
+}
+
+// Retrieves registry credentials from pull secrets in the specified namespace.
+// When secret names are specified, only those secrets are used, and each must
+// exist.  Otherwise the image pull secrets of the namespace's default service
+// account are used.  Pull secrets which cannot be parsed are logged and skipped.
+func GetRegistryCredentials(c client.Client, namespace string, secretNames []string) (RegistryCredentials, error) {
+	creds := RegistryCredentials{}
+
+	if len(secretNames) > 0 {
+		for _, name := range secretNames {
+			secret := &corev1.Secret{}
+			err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: name}, secret)
+			if err != nil {
+				return nil, fmt.Errorf("Unable to retrieve pull secret %v: %v", name, err)
+			}
+			if secret.Type != corev1.SecretTypeDockerConfigJson && secret.Type != corev1.SecretTypeDockercfg {
+				return nil, fmt.Errorf("The pull secret %v is of type %v. It must be of type %v or %v", name, secret.Type, corev1.SecretTypeDockerConfigJson, corev1.SecretTypeDockercfg)
+			}
+			creds.addPullSecret(secret)
+		}
+		return creds, nil
+	}
+
+	serviceAccount := &corev1.ServiceAccount{}
+	err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: defaultPullServiceAccount}, serviceAccount)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return creds, nil
+		}
+		return nil, fmt.Errorf("Unable to retrieve service account %v: %v", defaultPullServiceAccount, err)
+	}
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		secret := &corev1.Secret{}
+		err := c.Get(context.Background(), client.ObjectKey{Namespace: namespace, Name: ref.Name}, secret)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				registrylog.Info(fmt.Sprintf("Skipping pull secret %v of service account %v in namespace %v, which was not found", ref.Name, defaultPullServiceAccount, namespace))
+				continue
+			}
+			return nil, fmt.Errorf("Unable to retrieve pull secret %v: %v", ref.Name, err)
+		}
+		creds.addPullSecret(secret)
+	}
+
+	return creds, nil
+}
+
+// Adds the credentials held by a pull secret.  A pull secret which cannot be
+// parsed is logged and skipped, so that it does not prevent the use of others.
+func (creds RegistryCredentials) addPullSecret(secret *corev1.Secret) {
+	if err := creds.addSecret(secret); err != nil {
+		registrylog.Error(err, fmt.Sprintf("Skipping pull secret %v in namespace %v", secret.Name, secret.Namespace))
+	}
+}
+
+// Resolves an image reference to the sha256 digest of its manifest, using the
+// registry's v2 API.  An image which already contains a digest resolves to that
+// digest.  Bearer token and basic authentication challenges are answered using
+// the credentials for the image's registry, if any.
+func ResolveImageDigest(ctx context.Context, image string, creds RegistryCredentials, transport http.RoundTripper) (string, error) {
+	ref, err := ParseImageReference(image)
+	if err != nil {
+		return "", err
+	}
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	if transport == nil {
+		transport = NewTransport(false)
+	}
+	httpClient := &http.Client{Transport: transport}
+	auth, hasAuth := creds[ref.Registry]
+	manifestUrl := fmt.Sprintf("https://%v/v2/%v/manifests/%v", ref.Registry, ref.Repository, ref.Tag)
+
+	// The first attempt is anonymous.  The registry's challenge describes how to authenticate.
+	authorization := ""
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, err := doManifestRequest(ctx, httpClient, method, manifestUrl, authorization)
+		if err != nil {
+			return "", err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && authorization == "" {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			authorization, err = answerChallenge(ctx, httpClient, challenge, auth, hasAuth)
+			if err != nil {
+				return "", fmt.Errorf("Unable to authenticate with registry %v for image %v: %v", ref.Registry, image, err)
+			}
+			resp, err = doManifestRequest(ctx, httpClient, method, manifestUrl, authorization)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		digest, err := manifestDigest(resp, method)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("Unable to resolve the digest of image %v: %w", image, err)
+		}
+
+		// Some registries do not report the digest of a HEAD request.  Fall back to
+		// computing it from the manifest itself.
+		if digest != "" {
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("Unable to resolve the digest of image %v: the registry did not return a manifest", image)
+}
+
+// Issues a manifest request with the accepted manifest media types.
+func doManifestRequest(ctx context.Context, httpClient *http.Client, method string, manifestUrl string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequest(method, manifestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return httpClient.Do(req)
+}
+
+// Retrieves the digest from a manifest response.  The Docker-Content-Digest
+// header is used when present.  Otherwise the digest of a GET response body is
+// computed.  An empty digest is returned for a HEAD response without the header.
+func manifestDigest(resp *http.Response, method string) (string, error) {
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrImageNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", fmt.Errorf("access was denied: %v", resp.Status)
+	default:
+		return "", fmt.Errorf("the registry returned %v", resp.Status)
+	}
+
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	if method == http.MethodHead {
+		return "", nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, resp.Body); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// Answers a WWW-Authenticate challenge, returning the Authorization header value to use.
+func answerChallenge(ctx context.Context, httpClient *http.Client, challenge string, auth RegistryAuth, hasAuth bool) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasAuth {
+			return "", fmt.Errorf("the registry requires credentials, and no pull secret provides them")
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth.Username+":"+auth.Password)), nil
+	case "bearer":
+		realm := params["realm"]
+		if realm == "" {
+			return "", fmt.Errorf("the bearer challenge does not contain a realm")
+		}
+		tokenUrl, err := url.Parse(realm)
+		if err != nil {
+			return "", err
+		}
+		query := tokenUrl.Query()
+		if service := params["service"]; service != "" {
+			query.Set("service", service)
+		}
+		if scope := params["scope"]; scope != "" {
+			query.Set("scope", scope)
+		}
+		tokenUrl.RawQuery = query.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, tokenUrl.String(), nil)
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(ctx)
+		if hasAuth {
+			req.SetBasicAuth(auth.Username, auth.Password)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("the token service returned %v", resp.Status)
+		}
+
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		token := struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}{}
+		if err := json.Unmarshal(b, &token); err != nil {
+			return "", fmt.Errorf("unable to parse the token service response: %v", err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		if token.Token == "" {
+			return "", fmt.Errorf("the token service did not return a token")
+		}
+		return "Bearer " + token.Token, nil
+	}
+
+	return "", fmt.Errorf("the registry requested an unsupported authentication scheme: %v", challenge)
+}
+
+// Splits a WWW-Authenticate challenge into its scheme and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := make(map[string]string)
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+
+	rest := parts[1]
+	for len(rest) > 0 {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+
+	return parts[0], params
+}
+
+// DigestCache remembers resolved image digests for a period of time, so that
+// the registry is not queried on every reconcile.  A tag which moves is
+// noticed once the cached digest expires.  Digests are cached separately for
+// each set of credentials, since the credentials determine what may be seen.
+type DigestCache struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]digestCacheEntry
+}
+
+type digestCacheEntry struct {
+	digest  string
+	expires time.Time
+}
+
+// Creates a digest cache whose entries expire after the input duration.
+func NewDigestCache(ttl time.Duration) *DigestCache {
+	return &DigestCache{ttl: ttl, entries: make(map[string]digestCacheEntry)}
+}
+
+// Resolves an image digest, using a cached digest if one has not expired.
+func (cache *DigestCache) Resolve(ctx context.Context, image string, creds RegistryCredentials, transport http.RoundTripper) (string, error) {
+	now := time.Now()
+	key := image
+	if ref, err := ParseImageReference(image); err == nil {
+		key = image + "|" + creds.identity(ref.Registry)
+	}
+
+	cache.mutex.Lock()
+	entry, ok := cache.entries[key]
+	cache.mutex.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.digest, nil
+	}
+
+	digest, err := ResolveImageDigest(ctx, image, creds, transport)
+	if err != nil {
+		return "", err
+	}
+
+	cache.mutex.Lock()
+	cache.entries[key] = digestCacheEntry{digest: digest, expires: now.Add(cache.ttl)}
+	cache.mutex.Unlock()
+
+	return digest, nil
+}
+
+// Determines the previous digest reported for an image whose tag resolved to the
+// input digest, and when the tag moved.  A move is reported for
+// TagMovedReportPeriod after it was noticed, after which it is cleared.
+func TrackTagMove(prevDigest string, prevPreviousDigest string, prevMovedTime *metav1.Time, digest string, now time.Time) (string, *metav1.Time) {
+	if prevDigest != "" && prevDigest != digest {
+		moved := metav1.NewTime(now)
+		return prevDigest, &moved
+	}
+	if prevPreviousDigest != "" && prevMovedTime != nil && now.Sub(prevMovedTime.Time) < TagMovedReportPeriod {
+		return prevPreviousDigest, prevMovedTime
+	}
+	return "", nil
+}
+
+// Builds the status message reported when an image tag has moved to a new digest.
+func TagMovedMessage(image string, previousDigest string, digest string) string {
+	return fmt.Sprintf("The tag of image %v moved from digest %v to %v", image, previousDigest, digest)
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// A registry which serves manifests for the tags in its map, and blobs by
+// digest, and requires a bearer token obtained with the expected credentials.
+type fakeRegistry struct {
+	*httptest.Server
+	manifests map[string]string
+	blobs     map[string]string
+	username  string
+	password  string
+	// Omits the Docker-Content-Digest header from HEAD responses
+	noHeadDigest bool
+}
+
+func newFakeRegistry() *fakeRegistry {
+	r := &fakeRegistry{manifests: make(map[string]string), blobs: make(map[string]string), username: "user", password: "secret"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		user, pass, ok := req.BasicAuth()
+		if !ok || user != r.username || pass != r.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token":"valid-token"}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer valid-token" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%v/token",service="fake",scope="repository:kabanero/test:pull"`, r.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if parts := strings.Split(req.URL.Path, "/blobs/"); len(parts) == 2 {
+			blob, ok := r.blobs[parts[1]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, blob)
+			return
+		}
+		parts := strings.Split(req.URL.Path, "/manifests/")
+		manifest, ok := r.manifests[strings.TrimPrefix(parts[0], "/v2/")+":"+parts[len(parts)-1]]
+		if len(parts) != 2 || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if req.Method == http.MethodGet || !r.noHeadDigest {
+			w.Header().Set("Docker-Content-Digest", manifestTestDigest(manifest))
+		}
+		if req.Method == http.MethodGet {
+			fmt.Fprint(w, manifest)
+		}
+	})
+	r.Server = httptest.NewTLSServer(mux)
+	return r
+}
+
+func manifestTestDigest(manifest string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(manifest)))
+}
+
+func (r *fakeRegistry) host() string {
+	return strings.TrimPrefix(r.URL, "https://")
+}
+
+func (r *fakeRegistry) credentials() RegistryCredentials {
+	return RegistryCredentials{r.host(): {Username: r.username, Password: r.password}}
+}
+
+func TestParseImageReference(t *testing.T) {
+	tests := []struct {
+		image      string
+		registry   string
+		repository string
+		tag        string
+		digest     string
+	}{
+		{"nginx", "registry-1.docker.io", "library/nginx", "latest", ""},
+		{"kabanero/kabanero-operator:0.7.0", "registry-1.docker.io", "kabanero/kabanero-operator", "0.7.0", ""},
+		{"docker.io/kabanero/landing:1.0", "registry-1.docker.io", "kabanero/landing", "1.0", ""},
+		{"localhost:5000/kabanero/cli:1.0", "localhost:5000", "kabanero/cli", "1.0", ""},
+		{"quay.io/kabanero/events@sha256:abcd", "quay.io", "kabanero/events", "", "sha256:abcd"},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseImageReference(test.image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ref.Registry != test.registry || ref.Repository != test.repository || ref.Tag != test.tag || ref.Digest != test.digest {
+			t.Fatalf("Unexpected reference for %v: %#v", test.image, ref)
+		}
+	}
+
+	ref, _ := ParseImageReference("kabanero/cli:1.0")
+	if pinned := ref.DigestReference("sha256:1234"); pinned != "kabanero/cli@sha256:1234" {
+		t.Fatalf("Unexpected digest reference %v", pinned)
+	}
+}
+
+// Verifies a tag is resolved through the bearer token flow
+func TestResolveImageDigest(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	registry.manifests["kabanero/test:1.0"] = `{"schemaVersion":2}`
+
+	image := registry.host() + "/kabanero/test:1.0"
+	digest, err := ResolveImageDigest(context.Background(), image, registry.credentials(), registry.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != manifestTestDigest(`{"schemaVersion":2}`) {
+		t.Fatalf("Unexpected digest %v", digest)
+	}
+
+	// Without credentials the token service refuses access
+	_, err = ResolveImageDigest(context.Background(), image, nil, registry.Client().Transport)
+	if err == nil {
+		t.Fatal("Expected an error resolving the image without credentials")
+	}
+
+	// An unknown tag is reported
+	_, err = ResolveImageDigest(context.Background(), registry.host()+"/kabanero/test:2.0", registry.credentials(), registry.Client().Transport)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("Expected a not found error but was %v", err)
+	}
+}
+
+// Verifies the digest is computed from the manifest when HEAD does not report it
+func TestResolveImageDigestWithoutHeader(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	registry.noHeadDigest = true
+	registry.manifests["kabanero/test:1.0"] = `{"schemaVersion":2,"layers":[]}`
+
+	digest, err := ResolveImageDigest(context.Background(), registry.host()+"/kabanero/test:1.0", registry.credentials(), registry.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest != manifestTestDigest(`{"schemaVersion":2,"layers":[]}`) {
+		t.Fatalf("Unexpected digest %v", digest)
+	}
+}
+
+// Verifies cached digests are used until they expire, after which a moved tag is noticed
+func TestDigestCache(t *testing.T) {
+	registry := newFakeRegistry()
+	defer registry.Close()
+	registry.manifests["kabanero/test:1.0"] = "first"
+	image := registry.host() + "/kabanero/test:1.0"
+
+	cache := NewDigestCache(time.Hour)
+	digest, err := cache.Resolve(context.Background(), image, registry.credentials(), registry.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry.manifests["kabanero/test:1.0"] = "second"
+	cached, _ := cache.Resolve(context.Background(), image, registry.credentials(), registry.Client().Transport)
+	if cached != digest {
+		t.Fatalf("Expected the cached digest %v but was %v", digest, cached)
+	}
+
+	// Other credentials may see a different image.
+	anonymous, err := cache.Resolve(context.Background(), image, RegistryCredentials{}, registry.Client().Transport)
+	if err == nil && anonymous == digest {
+		t.Fatalf("Expected the digest cached for other credentials not to be used")
+	}
+
+	cache.ttl = 0
+	cache.entries = make(map[string]digestCacheEntry)
+	moved, _ := cache.Resolve(context.Background(), image, registry.credentials(), registry.Client().Transport)
+	if moved != manifestTestDigest("second") {
+		t.Fatalf("Expected the moved digest but was %v", moved)
+	}
+}
+
+func TestGetRegistryCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("user:secret"))
+	configJson := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "quay", Namespace: "kabanero"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://quay.io/v1/":{"auth":"` + auth + `"}}}`)},
+	}
+	dockercfg := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub", Namespace: "kabanero"},
+		Type:       corev1.SecretTypeDockercfg,
+		Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"docker.io":{"username":"hubuser","password":"hubpass"}}`)},
+	}
+	malformed := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "malformed", Namespace: "kabanero"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":`)},
+	}
+	unlisted := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "unlisted", Namespace: "kabanero"},
+		Type:       corev1.SecretTypeDockercfg,
+		Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"registry.example.com":{"username":"u","password":"p"}}`)},
+	}
+	opaque := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "kabanero"},
+		Data:       map[string][]byte{"password": []byte("x")},
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "kabanero"},
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "quay"}, {Name: "malformed"}, {Name: "hub"}, {Name: "missing"}},
+	}
+	cl := fake.NewFakeClient(configJson, dockercfg, malformed, unlisted, opaque, serviceAccount)
+
+	// The pull secrets of the default service account are used, skipping those
+	// which are missing or cannot be parsed.
+	creds, err := GetRegistryCredentials(cl, "kabanero", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds["quay.io"].Username != "user" || creds["quay.io"].Password != "secret" {
+		t.Fatalf("Unexpected quay.io credentials: %v", creds)
+	}
+	if creds["registry-1.docker.io"].Username != "hubuser" {
+		t.Fatalf("Unexpected Docker Hub credentials: %v", creds)
+	}
+	if len(creds) != 2 {
+		t.Fatalf("Expected only the pull secrets of the service account to be used: %v", creds)
+	}
+
+	creds, err = GetRegistryCredentials(cl, "kabanero", []string{"quay", "malformed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != 1 {
+		t.Fatalf("Expected only the named pull secret to be used: %v", creds)
+	}
+
+	if _, err = GetRegistryCredentials(cl, "kabanero", []string{"other"}); err == nil {
+		t.Fatal("Expected an error naming a secret which is not a pull secret")
+	}
+
+	// Without a default service account there are no credentials.
+	creds, err = GetRegistryCredentials(fake.NewFakeClient(configJson), "kabanero", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(creds) != 0 {
+		t.Fatalf("Expected no credentials: %v", creds)
+	}
+}
+
+// Verifies a moved tag is reported for a limited time
+func TestTrackTagMove(t *testing.T) {
+	now := time.Now()
+	previous, moved := TrackTagMove("sha256:1111", "", nil, "sha256:2222", now)
+	if previous != "sha256:1111" || moved == nil || !moved.Time.Equal(metav1.NewTime(now).Time) {
+		t.Fatalf("Expected the move to be reported: %v %v", previous, moved)
+	}
+
+	previous, moved = TrackTagMove("sha256:2222", previous, moved, "sha256:2222", now.Add(time.Hour))
+	if previous != "sha256:1111" || moved == nil {
+		t.Fatalf("Expected the move to still be reported: %v %v", previous, moved)
+	}
+
+	previous, moved = TrackTagMove("sha256:2222", previous, moved, "sha256:2222", now.Add(TagMovedReportPeriod))
+	if previous != "" || moved != nil {
+		t.Fatalf("Expected the move to be cleared: %v %v", previous, moved)
+	}
+
+	previous, moved = TrackTagMove("", "", nil, "sha256:2222", now)
+	if previous != "" || moved != nil {
+		t.Fatalf("Expected no move for a new image: %v %v", previous, moved)
+	}
+}
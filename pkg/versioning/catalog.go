@@ -0,0 +1,53 @@
+package versioning
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultCatalogKey is the key within a version catalogue ConfigMap which is read
+// when no other key is configured.
+const DefaultCatalogKey = "versions.yaml"
+
+// ReadConfigMapOverlay reads a version catalogue overlay from a ConfigMap.  If the
+// key is empty, DefaultCatalogKey is read.
+func ReadConfigMapOverlay(ctx context.Context, c client.Reader, namespace string, name string, key string) ([]byte, error) {
+	cm := &corev1.ConfigMap{}
+	err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, cm)
+	if err != nil {
+		return nil, err
+	}
+
+	if key == "" {
+		key = DefaultCatalogKey
+	}
+
+	value, ok := cm.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("The ConfigMap does not contain the key %v", key)
+	}
+
+	return []byte(value), nil
+}
+
+// ApplyOverlay decodes a version catalogue overlay and merges it over the embedded
+// version document.  An error is returned if the merged document is not valid.
+func ApplyOverlay(data []byte) (VersionDocument, error) {
+	overlay, err := Decode(bytes.NewReader(data))
+	if err != nil {
+		return VersionDocument{}, err
+	}
+
+	merged := Merge(Data, overlay)
+	err = merged.Validate()
+	if err != nil {
+		return VersionDocument{}, err
+	}
+
+	return merged, nil
+}
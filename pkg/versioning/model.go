@@ -1,32 +1,50 @@
 package versioning
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/blang/semver"
 	"github.com/kabanero-io/kabanero-operator/pkg/assets/config"
 	"gopkg.in/yaml.v2"
-	"net/http"
 )
 
-var Data = func() VersionDocument {
+// Data is the version document embedded in the operator image.  If the
+// embedded document could not be loaded, Data is empty and LoadError
+// describes the problem.
+var Data, LoadError = func() (VersionDocument, error) {
 	f, err := config.Open("versions.yaml")
 	if err != nil {
-		panic(err)
+		return VersionDocument{}, err
 	}
+	defer f.Close()
+
+	return Decode(f)
+}()
 
-	dec := yaml.NewDecoder(f)
+// Decode reads a version document from the reader and links each Kabanero
+// revision back to the document.
+func Decode(r io.Reader) (VersionDocument, error) {
+	dec := yaml.NewDecoder(r)
 	var versionData VersionDocument
-	err = dec.Decode(&versionData)
+	err := dec.Decode(&versionData)
 	if err != nil {
-		panic(err)
+		return VersionDocument{}, err
 	}
 
-	//Update the pointer between Kabanero's and the document
-	for i, k := range versionData.KabaneroRevisions {
-		k.Document = &versionData
-		versionData.KabaneroRevisions[i] = k
-	}
+	versionData.link()
 
-	return versionData
-}()
+	return versionData, nil
+}
+
+// Updates the pointer between Kabanero's and the document
+func (doc *VersionDocument) link() {
+	for i, k := range doc.KabaneroRevisions {
+		k.Document = doc
+		doc.KabaneroRevisions[i] = k
+	}
+}
 
 // The top level resource within the versioning model
 type VersionDocument struct {
@@ -38,6 +56,9 @@ type VersionDocument struct {
 
 	// The versions of related software known to this operator
 	RelatedSoftwareRevisions map[string][]SoftwareRevision `yaml:"related-software,omitempty"`
+
+	// The order in which software components are upgraded when the Kabanero version changes
+	UpgradeOrder []string `yaml:"upgrade-order,omitempty"`
 }
 
 func (doc VersionDocument) KabaneroRevision(KabaneroRevision string) *KabaneroRevision {
@@ -58,6 +79,12 @@ type KabaneroRevision struct {
 	// The versions associated with this Kabanero Version
 	RelatedVersions map[string]string `yaml:"related-versions,omitempty"`
 
+	// Semantic version ranges which the prerequisite software must satisfy
+	Prerequisites map[string]string `yaml:"prerequisites,omitempty"`
+
+	// The OLM subscriptions used to install the prerequisite software
+	Subscriptions map[string]SubscriptionRevision `yaml:"subscriptions,omitempty"`
+
 	Document *VersionDocument `yaml:"-"`
 }
 
@@ -75,6 +102,23 @@ func (KabaneroRevision KabaneroRevision) SoftwareComponent(softwareComponent str
 	return nil
 }
 
+// Describes the OLM subscription used to install a prerequisite operator
+type SubscriptionRevision struct {
+	// The name of the package in the catalog
+	Package string `yaml:"package,omitempty"`
+
+	// The namespace of the subscription.  If empty, the Kabanero namespace is used.
+	Namespace string `yaml:"namespace,omitempty"`
+
+	Channel         string `yaml:"channel,omitempty"`
+	Source          string `yaml:"source,omitempty"`
+	SourceNamespace string `yaml:"source-namespace,omitempty"`
+
+	// The InstallPlan approval strategy, Automatic or Manual
+	Approval    string `yaml:"approval,omitempty"`
+	StartingCSV string `yaml:"starting-csv,omitempty"`
+}
+
 // Contains version specific data for software which is orchestrated as part of Kabanero
 type SoftwareRevision struct {
 	// The version of this piece of software that the orchestrations and identifiers apply to
@@ -92,3 +136,141 @@ func (rev SoftwareRevision) OpenOrchestration(path string) (http.File, error) {
 	f, err := config.Open(rev.OrchestrationPath + "/" + path)
 	return f, err
 }
+
+// Validate checks the integrity of the document: a default version must be
+// present and resolvable, and every related version referenced by a
+// Kabanero revision must exist in the related software table.
+func (doc VersionDocument) Validate() error {
+	if doc.DefaultKabaneroRevision == "" {
+		return fmt.Errorf("No default version")
+	}
+
+	if len(doc.KabaneroRevisions) < 1 {
+		return fmt.Errorf("No Kabanero versions are present")
+	}
+
+	if doc.KabaneroRevision(doc.DefaultKabaneroRevision) == nil {
+		return fmt.Errorf("The default Kabanero version `%v` cannot be resolved", doc.DefaultKabaneroRevision)
+	}
+
+	if len(doc.RelatedSoftwareRevisions) < 1 {
+		return fmt.Errorf("No related software versions are present")
+	}
+
+	for softwarePackage, versions := range doc.RelatedSoftwareRevisions {
+		if len(versions) < 1 {
+			return fmt.Errorf("Expected '%v' to have at least one software version", softwarePackage)
+		}
+	}
+
+	for _, sw := range doc.UpgradeOrder {
+		if _, ok := doc.RelatedSoftwareRevisions[sw]; !ok {
+			return fmt.Errorf("The upgrade order refers to the software %v, which has no versions", sw)
+		}
+	}
+
+	for _, k := range doc.KabaneroRevisions {
+		if k.Version == "" {
+			return fmt.Errorf("Kabanero version has an empty version identifier")
+		}
+
+		for prereq, sub := range k.Subscriptions {
+			if sub.Package == "" || sub.Channel == "" || sub.Source == "" {
+				return fmt.Errorf("The Kabanero version `%v` declares a subscription for %v without a package, channel and source", k.Version, prereq)
+			}
+			if sub.Approval != "" && sub.Approval != "Automatic" && sub.Approval != "Manual" {
+				return fmt.Errorf("The Kabanero version `%v` declares an invalid approval `%v` for the %v subscription", k.Version, sub.Approval, prereq)
+			}
+		}
+
+		for prereq, constraint := range k.Prerequisites {
+			if _, err := semver.ParseRange(constraint); err != nil {
+				return fmt.Errorf("The Kabanero version `%v` declares an invalid version range `%v` for %v: %v", k.Version, constraint, prereq, err)
+			}
+		}
+
+		for sw, v := range k.RelatedVersions {
+			var found bool
+			for _, rev := range doc.RelatedSoftwareRevisions[sw] {
+				if rev.Version == v {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("The Kabanero version `%v` points to the software %v version `%v`, but that reference cannot be resolved", k.Version, sw, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Merge returns a new document containing the base document with the overlay
+// applied on top of it.  Kabanero revisions and software revisions in the
+// overlay replace entries with the same version in the base, and are
+// appended otherwise.  The default version is replaced when the overlay sets one.
+func Merge(base VersionDocument, overlay VersionDocument) VersionDocument {
+	merged := VersionDocument{
+		DefaultKabaneroRevision:  base.DefaultKabaneroRevision,
+		RelatedSoftwareRevisions: make(map[string][]SoftwareRevision),
+	}
+
+	if overlay.DefaultKabaneroRevision != "" {
+		merged.DefaultKabaneroRevision = overlay.DefaultKabaneroRevision
+	}
+
+	merged.UpgradeOrder = base.UpgradeOrder
+	if len(overlay.UpgradeOrder) > 0 {
+		merged.UpgradeOrder = overlay.UpgradeOrder
+	}
+
+	merged.KabaneroRevisions = append(merged.KabaneroRevisions, base.KabaneroRevisions...)
+	for _, k := range overlay.KabaneroRevisions {
+		replaced := false
+		for i, existing := range merged.KabaneroRevisions {
+			if existing.Version == k.Version {
+				merged.KabaneroRevisions[i] = k
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.KabaneroRevisions = append(merged.KabaneroRevisions, k)
+		}
+	}
+
+	for sw, revisions := range base.RelatedSoftwareRevisions {
+		merged.RelatedSoftwareRevisions[sw] = append([]SoftwareRevision(nil), revisions...)
+	}
+	for sw, revisions := range overlay.RelatedSoftwareRevisions {
+		for _, rev := range revisions {
+			replaced := false
+			existing := merged.RelatedSoftwareRevisions[sw]
+			for i := range existing {
+				if existing[i].Version == rev.Version {
+					existing[i] = rev
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				existing = append(existing, rev)
+			}
+			merged.RelatedSoftwareRevisions[sw] = existing
+		}
+	}
+
+	merged.link()
+
+	return merged
+}
+
+// Versions returns the Kabanero versions known to this document
+func (doc VersionDocument) Versions() []string {
+	var versions []string
+	for _, k := range doc.KabaneroRevisions {
+		versions = append(versions, k.Version)
+	}
+	return versions
+}
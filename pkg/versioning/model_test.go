@@ -1,56 +1,92 @@
 package versioning
 
 import (
+	"strings"
 	"testing"
 )
 
 // Verifies validitity of the internal static model
 func TestStaticVersionData(t *testing.T) {
-	v := Data
+	if LoadError != nil {
+		t.Fatal(LoadError)
+	}
 
-	if v.DefaultKabaneroRevision == "" {
-		t.Fatal("No default version")
+	if err := Data.Validate(); err != nil {
+		t.Fatal(err)
 	}
+}
 
-	if len(v.KabaneroRevisions) < 1 {
-		t.Fatal("No Kabanero versions are present")
+func TestKabaneroRevisions(t *testing.T) {
+	rev := Data.KabaneroRevision("0.6.0")
+	if rev == nil {
+		t.Fatal("Revision was nil")
 	}
+}
 
-	for _, k := range v.KabaneroRevisions {
-		if k.Version == "" {
-			t.Fatal("Kabanero version has an empty version identifier")
-		}
+const overlayDocument = `
+default: "9.9.9"
+kabanero:
+- version: "9.9.9"
+  related-versions:
+    landing: "9.9.9"
+    cli-services: "0.6.0"
+related-software:
+  landing:
+  - version: "9.9.9"
+    orchestrations: "orchestrations/landing/0.1"
+    identifiers:
+      repository: "kabanero/landing"
+      tag: "9.9.9"
+`
 
-		//Verify the integrity of relationships from the kabanero revision table
-		//into the version data for those components
-		for sw, v := range k.RelatedVersions {
-			var found bool
-			for _, rev := range k.Document.RelatedSoftwareRevisions[sw] {
-				if rev.Version == v {
-					found = true
-					break
-				}
-			}
-			if !found {
-				t.Fatalf("The Kabanero version `%v` points to the software %v version `%v`, but that reference cannot be resolved", k.Version, sw, v)
-			}
-		}
+// Verifies an overlay adds new revisions without removing the embedded ones
+func TestMergeOverlay(t *testing.T) {
+	overlay, err := Decode(strings.NewReader(overlayDocument))
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if len(v.RelatedSoftwareRevisions) < 1 {
-		t.Fatal("No Kabanero versions are present")
+	merged := Merge(Data, overlay)
+	if err := merged.Validate(); err != nil {
+		t.Fatal(err)
 	}
 
-	for software_package, versions := range v.RelatedSoftwareRevisions {
-		if len(versions) < 1 {
-			t.Fatalf("Expected '%v' to have at least one software version", software_package)
-		}
+	if merged.DefaultKabaneroRevision != "9.9.9" {
+		t.Fatalf("Expected default version 9.9.9 but was %v", merged.DefaultKabaneroRevision)
 	}
-}
 
-func TestKabaneroRevisions(t *testing.T) {
-	rev := Data.KabaneroRevision("0.6.0")
+	rev := merged.KabaneroRevision("9.9.9")
 	if rev == nil {
-		t.Fatal("Revision was nil")
+		t.Fatal("Overlay revision was not merged")
+	}
+
+	landing := rev.SoftwareComponent("landing")
+	if landing == nil || landing.Identifiers["tag"] != "9.9.9" {
+		t.Fatalf("Overlay landing revision was not resolved: %v", landing)
+	}
+
+	if merged.KabaneroRevision("0.6.0") == nil {
+		t.Fatal("Embedded revision 0.6.0 was lost during the merge")
+	}
+
+	if len(Data.RelatedSoftwareRevisions["landing"]) == len(merged.RelatedSoftwareRevisions["landing"]) {
+		t.Fatal("Overlay landing revision was not appended")
+	}
+}
+
+// Verifies an overlay with a dangling reference fails validation
+func TestValidateDanglingReference(t *testing.T) {
+	overlay, err := Decode(strings.NewReader(`
+kabanero:
+- version: "9.9.9"
+  related-versions:
+    landing: "1.2.3"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Merge(Data, overlay).Validate(); err == nil {
+		t.Fatal("Expected a validation error for an unresolvable landing version")
 	}
 }
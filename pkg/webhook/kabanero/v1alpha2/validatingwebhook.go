@@ -7,9 +7,19 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"github.com/kabanero-io/kabanero-operator/pkg/versioning"
 
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -43,6 +53,25 @@ func (v *kabaneroValidator) Handle(ctx context.Context, req admission.Request) a
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
 
+	if allowed {
+		// Only validate the spec when it changes, so that an instance created before a
+		// validation rule existed can still be updated, for example to remove a finalizer.
+		current := &kabanerov1alpha2.Kabanero{}
+		if len(req.OldObject.Raw) > 0 {
+			err = v.decoder.DecodeRaw(req.OldObject, current)
+			if err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+
+		if kabanero.GetDeletionTimestamp() == nil && (len(req.OldObject.Raw) == 0 || !reflect.DeepEqual(current.Spec, kabanero.Spec)) {
+			errs := v.validateSpecFn(ctx, kabanero)
+			if len(errs) > 0 {
+				return admission.ValidationResponse(false, fmt.Sprintf("Kabanero %s in namespace %s is not valid: %v", kabanero.Name, kabanero.Namespace, errs.ToAggregate()))
+			}
+		}
+	}
+
 	return admission.ValidationResponse(allowed, reason)
 }
 
@@ -76,6 +105,524 @@ func (v *kabaneroValidator) validatekabaneroFn(ctx context.Context, pod *kabaner
 	}
 }
 
+// validateSpecFn validates the contents of the Kabanero spec, so that problems are
+// reported at admission rather than later during reconcile.
+func (v *kabaneroValidator) validateSpecFn(ctx context.Context, k *kabanerov1alpha2.Kabanero) field.ErrorList {
+	errs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	if len(k.Spec.Github.ApiUrl) > 0 {
+		apiUrlPath := specPath.Child("github", "apiUrl")
+		_, err := cutils.ParseGithubApiUrl(k.Spec.Github.ApiUrl)
+		if err != nil {
+			errs = append(errs, field.Invalid(apiUrlPath, k.Spec.Github.ApiUrl, err.Error()))
+		}
+	}
+
+	if len(k.Spec.CliServices.SessionExpirationSeconds) > 0 {
+		if err := cutils.ValidateSessionExpiration(k.Spec.CliServices.SessionExpirationSeconds); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("cliServices", "sessionExpirationSeconds"), k.Spec.CliServices.SessionExpirationSeconds, err.Error()))
+		}
+	}
+
+	if k.Spec.Sso.External != nil {
+		errs = append(errs, validateExternalSso(specPath.Child("sso", "external"), k.Spec.Sso.External)...)
+	} else if k.Spec.Sso.Enable && len(k.Spec.Sso.AdminSecretName) == 0 {
+		errs = append(errs, field.Required(specPath.Child("sso", "adminSecretName"), "must be specified when SSO is enabled"))
+	}
+
+	if len(k.Spec.VersionCatalog.ConfigMapName) > 0 && len(k.Spec.VersionCatalog.Url) > 0 {
+		errs = append(errs, field.Forbidden(specPath.Child("versionCatalog", "url"), "may not be specified together with configMapName"))
+	}
+
+	for i, repository := range k.Spec.Stacks.Repositories {
+		repositoryPath := specPath.Child("stacks", "repositories").Index(i)
+		errs = append(errs, validateRepository(repositoryPath, repository)...)
+		for j, pipeline := range repository.Pipelines {
+			errs = append(errs, validateHttpsAuth(repositoryPath.Child("pipelines").Index(j).Child("https"), pipeline.Https)...)
+		}
+	}
+
+	for i, pipeline := range k.Spec.Stacks.Pipelines {
+		errs = append(errs, validateHttpsAuth(specPath.Child("stacks", "pipelines").Index(i).Child("https"), pipeline.Https)...)
+	}
+
+	switch k.Spec.Stacks.TektonApiVersion {
+	case "", "v1alpha1", "v1beta1":
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("stacks", "tektonApiVersion"), k.Spec.Stacks.TektonApiVersion, []string{"v1alpha1", "v1beta1"}))
+	}
+
+	switch k.Spec.Stacks.OrphanedAssetPolicy {
+	case "", kabanerov1alpha2.OrphanedAssetPolicyReport, kabanerov1alpha2.OrphanedAssetPolicyDelete:
+	default:
+		errs = append(errs, field.NotSupported(specPath.Child("stacks", "orphanedAssetPolicy"), k.Spec.Stacks.OrphanedAssetPolicy,
+			[]string{kabanerov1alpha2.OrphanedAssetPolicyReport, kabanerov1alpha2.OrphanedAssetPolicyDelete}))
+	}
+
+	imagePolicy := k.Spec.Stacks.ImagePolicy
+	keysPath := specPath.Child("stacks", "imagePolicy", "signatureKeys")
+	if imagePolicy.RequireSignatures && len(imagePolicy.SignatureKeys.Name) == 0 {
+		errs = append(errs, field.Required(keysPath.Child("name"), "must be specified when requireSignatures is true"))
+	} else if len(imagePolicy.SignatureKeys.Key) > 0 && len(imagePolicy.SignatureKeys.Name) == 0 {
+		errs = append(errs, field.Required(keysPath.Child("name"), "must be specified when signatureKeys.key is specified"))
+	}
+
+	errs = append(errs, validateTimeouts(specPath.Child("stacks", "timeouts"), k.Spec.Stacks.Timeouts)...)
+
+	errs = append(errs, validateDurations(specPath.Child("secretRotation"), []durationField{
+		{"ssoDatabase", k.Spec.SecretRotation.SsoDatabase},
+		{"events", k.Spec.SecretRotation.Events},
+		{"cliEncryptionKey", k.Spec.SecretRotation.CliEncryptionKey},
+	})...)
+
+	errs = append(errs, validateIngress(specPath, k)...)
+
+	errs = append(errs, validateConsoleLinks(specPath.Child("landing", "consoleLinks"), k.Spec.Landing.ConsoleLinks)...)
+
+	errs = append(errs, validateEvents(specPath.Child("events"), k.Spec.Events)...)
+
+	errs = append(errs, validateGithubWebhook(specPath.Child("github"), k)...)
+
+	errs = append(errs, v.validateVersions(ctx, k, specPath)...)
+
+	return errs
+}
+
+// Validates the webhook routing configuration of the events service.
+func validateEvents(path *field.Path, events kabanerov1alpha2.EventsCustomizationSpec) field.ErrorList {
+	errs := field.ErrorList{}
+
+	sources := make(map[string]bool)
+	for i, source := range events.WebhookSources {
+		sourcePath := path.Child("webhookSources").Index(i)
+		errs = append(errs, validateEventsName(sourcePath.Child("name"), source.Name, sources)...)
+		if len(source.Organization) == 0 {
+			errs = append(errs, field.Required(sourcePath.Child("organization"), "must be specified"))
+		}
+		if len(source.SecretName) != 0 {
+			// The Secret is mounted as a volume of the same name.
+			for _, msg := range validation.IsDNS1123Label(source.SecretName) {
+				errs = append(errs, field.Invalid(sourcePath.Child("secretName"), source.SecretName, msg))
+			}
+		}
+	}
+
+	destinations := make(map[string]bool)
+	for i, destination := range events.Destinations {
+		destinationPath := path.Child("destinations").Index(i)
+		errs = append(errs, validateEventsName(destinationPath.Child("name"), destination.Name, destinations)...)
+		if u, err := url.Parse(destination.Url); err != nil {
+			errs = append(errs, field.Invalid(destinationPath.Child("url"), destination.Url, err.Error()))
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, field.Invalid(destinationPath.Child("url"), destination.Url, "must be an absolute http or https URL"))
+		}
+	}
+
+	for i, mapping := range events.Mappings {
+		mappingPath := path.Child("mappings").Index(i)
+		if !sources[mapping.Source] {
+			errs = append(errs, field.NotFound(mappingPath.Child("source"), mapping.Source))
+		}
+		if len(mapping.Stack) == 0 {
+			errs = append(errs, field.Required(mappingPath.Child("stack"), "must be specified"))
+		}
+		if len(mapping.Destination) != 0 && !destinations[mapping.Destination] {
+			errs = append(errs, field.NotFound(mappingPath.Child("destination"), mapping.Destination))
+		}
+	}
+
+	return errs
+}
+
+// Validates the webhook registered with GitHub.
+func validateGithubWebhook(path *field.Path, k *kabanerov1alpha2.Kabanero) field.ErrorList {
+	errs := field.ErrorList{}
+	webhook := k.Spec.Github.Webhook
+	if !webhook.Enable {
+		return errs
+	}
+
+	webhookPath := path.Child("webhook")
+	if len(k.Spec.Github.Organization) == 0 {
+		errs = append(errs, field.Required(path.Child("organization"), "must be specified when the webhook is enabled"))
+	}
+	if !k.Spec.Events.Enable {
+		errs = append(errs, field.Invalid(webhookPath.Child("enable"), webhook.Enable, "the events service must be enabled to register its webhook"))
+	}
+	if len(webhook.TokenSecretRef.Name) == 0 {
+		errs = append(errs, field.Required(webhookPath.Child("tokenSecretRef", "name"), "must be specified when the webhook is enabled"))
+	}
+
+	repositories := make(map[string]bool)
+	for i, repository := range webhook.Repositories {
+		repositoryPath := webhookPath.Child("repositories").Index(i)
+		if repositories[repository] {
+			errs = append(errs, field.Duplicate(repositoryPath, repository))
+		}
+		repositories[repository] = true
+		// The webhook of each repository is recorded under a ConfigMap key.
+		for _, msg := range validation.IsConfigMapKey(repository) {
+			errs = append(errs, field.Invalid(repositoryPath, repository, msg))
+		}
+	}
+
+	for i, event := range webhook.Events {
+		if len(event) == 0 {
+			errs = append(errs, field.Required(webhookPath.Child("events").Index(i), "must not be empty"))
+		}
+	}
+
+	return errs
+}
+
+// Validates the name of a webhook source or destination, which must be unique.
+func validateEventsName(path *field.Path, name string, names map[string]bool) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(name) == 0 {
+		errs = append(errs, field.Required(path, "must be specified"))
+	} else if names[name] {
+		errs = append(errs, field.Duplicate(path, name))
+	} else {
+		for _, msg := range validation.IsDNS1123Label(name) {
+			errs = append(errs, field.Invalid(path, name, msg))
+		}
+	}
+	names[name] = true
+	return errs
+}
+
+// Validates the links added to the OpenShift web console.
+func validateConsoleLinks(path *field.Path, links []kabanerov1alpha2.ConsoleLinkSpec) field.ErrorList {
+	errs := field.ErrorList{}
+	locations := []string{
+		kabanerov1alpha2.ConsoleLinkLocationApplicationMenu,
+		kabanerov1alpha2.ConsoleLinkLocationHelpMenu,
+		kabanerov1alpha2.ConsoleLinkLocationUserMenu,
+		kabanerov1alpha2.ConsoleLinkLocationNamespaceDashboard,
+	}
+	names := make(map[string]bool)
+
+	for i, link := range links {
+		linkPath := path.Index(i)
+
+		if len(link.Name) == 0 {
+			errs = append(errs, field.Required(linkPath.Child("name"), "must be specified"))
+		} else if names[link.Name] {
+			errs = append(errs, field.Duplicate(linkPath.Child("name"), link.Name))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(link.Name) {
+				errs = append(errs, field.Invalid(linkPath.Child("name"), link.Name, msg))
+			}
+		}
+		names[link.Name] = true
+
+		switch link.Location {
+		case kabanerov1alpha2.ConsoleLinkLocationApplicationMenu, kabanerov1alpha2.ConsoleLinkLocationHelpMenu,
+			kabanerov1alpha2.ConsoleLinkLocationUserMenu, kabanerov1alpha2.ConsoleLinkLocationNamespaceDashboard:
+		default:
+			errs = append(errs, field.NotSupported(linkPath.Child("location"), link.Location, locations))
+		}
+		if len(link.Text) == 0 {
+			errs = append(errs, field.Required(linkPath.Child("text"), "must be specified"))
+		}
+
+		if len(link.Href) == 0 {
+			errs = append(errs, field.Required(linkPath.Child("href"), "must be specified"))
+		} else if _, err := template.New("href").Parse(link.Href); err != nil {
+			errs = append(errs, field.Invalid(linkPath.Child("href"), link.Href, err.Error()))
+		}
+		if _, err := template.New("imageUrl").Parse(link.ImageUrl); err != nil {
+			errs = append(errs, field.Invalid(linkPath.Child("imageUrl"), link.ImageUrl, err.Error()))
+		}
+
+		if link.Location != kabanerov1alpha2.ConsoleLinkLocationApplicationMenu {
+			if len(link.Section) != 0 {
+				errs = append(errs, field.Forbidden(linkPath.Child("section"), "may only be specified for ApplicationMenu links"))
+			}
+			if len(link.ImageUrl) != 0 {
+				errs = append(errs, field.Forbidden(linkPath.Child("imageUrl"), "may only be specified for ApplicationMenu links"))
+			}
+		}
+		if link.Location != kabanerov1alpha2.ConsoleLinkLocationNamespaceDashboard && len(link.Namespaces) != 0 {
+			errs = append(errs, field.Forbidden(linkPath.Child("namespaces"), "may only be specified for NamespaceDashboard links"))
+		}
+	}
+
+	return errs
+}
+
+// Validates the hosts of the Ingresses used on clusters without OpenShift Routes.
+func validateIngress(specPath *field.Path, k *kabanerov1alpha2.Kabanero) field.ErrorList {
+	errs := field.ErrorList{}
+	hosts := []struct {
+		path *field.Path
+		host string
+	}{
+		{specPath.Child("ingress", "domain"), k.Spec.Ingress.Domain},
+		{specPath.Child("landing", "ingress", "host"), k.Spec.Landing.Ingress.Host},
+		{specPath.Child("cliServices", "ingress", "host"), k.Spec.CliServices.Ingress.Host},
+		{specPath.Child("events", "ingress", "host"), k.Spec.Events.Ingress.Host},
+	}
+	for _, host := range hosts {
+		if len(host.host) == 0 {
+			continue
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(host.host) {
+			errs = append(errs, field.Invalid(host.path, host.host, msg))
+		}
+	}
+	return errs
+}
+
+// Validates the external OpenID Connect provider settings.
+func validateExternalSso(path *field.Path, external *kabanerov1alpha2.ExternalSsoSpec) field.ErrorList {
+	errs := field.ErrorList{}
+
+	issuerPath := path.Child("issuerUrl")
+	if len(external.IssuerUrl) == 0 {
+		errs = append(errs, field.Required(issuerPath, "must be specified when an external provider is used"))
+	} else if u, err := url.Parse(external.IssuerUrl); err != nil {
+		errs = append(errs, field.Invalid(issuerPath, external.IssuerUrl, err.Error()))
+	} else if u.Scheme != "https" || u.Host == "" {
+		errs = append(errs, field.Invalid(issuerPath, external.IssuerUrl, "must be an absolute https URL"))
+	}
+
+	if len(external.ClientSecretName) == 0 {
+		errs = append(errs, field.Required(path.Child("clientSecretName"), "must be specified when an external provider is used"))
+	}
+
+	return errs
+}
+
+// Validates that the stack timeouts are positive durations, such as "90s" or "5m".
+func validateTimeouts(path *field.Path, timeouts kabanerov1alpha2.StackTimeoutsSpec) field.ErrorList {
+	return validateDurations(path, []durationField{
+		{"download", timeouts.Download},
+		{"indexResolution", timeouts.IndexResolution},
+		{"reconcile", timeouts.Reconcile},
+	})
+}
+
+// A named duration field
+type durationField struct {
+	name  string
+	value string
+}
+
+// Validates that the named fields, if specified, are positive durations.  The
+// errors are reported in the order of the fields.
+func validateDurations(path *field.Path, durations []durationField) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for _, duration := range durations {
+		if len(duration.value) == 0 {
+			continue
+		}
+		if d, err := time.ParseDuration(duration.value); err != nil || d <= 0 {
+			errs = append(errs, field.Invalid(path.Child(duration.name), duration.value, "must be a positive duration, such as 90s or 5m"))
+		}
+	}
+
+	return errs
+}
+
+// Validates that a stack repository identifies where the stack index is located.
+func validateRepository(path *field.Path, repository kabanerov1alpha2.RepositoryConfig) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(repository.Https.Url) > 0 {
+		if _, err := url.ParseRequestURI(repository.Https.Url); err != nil {
+			errs = append(errs, field.Invalid(path.Child("https", "url"), repository.Https.Url, err.Error()))
+		}
+		return append(errs, validateHttpsAuth(path.Child("https"), repository.Https)...)
+	}
+
+	gitRelease := repository.GitRelease
+	if sutils.IsGitReleaseUsable(gitRelease) {
+		return append(errs, validateCredentials(path.Child("gitRelease", "credentials"), gitRelease.Credentials)...)
+	}
+
+	if gitRelease == (kabanerov1alpha2.GitReleaseSpec{}) {
+		return append(errs, field.Required(path, "either https.url or a complete gitRelease must be specified"))
+	}
+
+	gitReleasePath := path.Child("gitRelease")
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"hostname", gitRelease.Hostname},
+		{"organization", gitRelease.Organization},
+		{"project", gitRelease.Project},
+		{"release", gitRelease.Release},
+		{"assetName", gitRelease.AssetName},
+	}
+	for _, r := range required {
+		if len(r.value) == 0 {
+			errs = append(errs, field.Required(gitReleasePath.Child(r.name), "must be specified when https.url is not specified"))
+		}
+	}
+
+	return errs
+}
+
+// Validates the credentials and trust used to retrieve a file over HTTPS.
+func validateHttpsAuth(path *field.Path, https kabanerov1alpha2.HttpsProtocolFile) field.ErrorList {
+	errs := validateCredentials(path.Child("credentials"), https.Credentials)
+
+	if https.Auth != (kabanerov1alpha2.HttpsAuthSpec{}) {
+		authPath := path.Child("auth")
+		switch https.Auth.Type {
+		case kabanerov1alpha2.HttpsAuthTypeBasic, kabanerov1alpha2.HttpsAuthTypeBearer, kabanerov1alpha2.HttpsAuthTypeClientCert:
+		default:
+			errs = append(errs, field.NotSupported(authPath.Child("type"), https.Auth.Type,
+				[]string{kabanerov1alpha2.HttpsAuthTypeBasic, kabanerov1alpha2.HttpsAuthTypeBearer, kabanerov1alpha2.HttpsAuthTypeClientCert}))
+		}
+		if len(https.Auth.SecretName) == 0 {
+			errs = append(errs, field.Required(authPath.Child("secretName"), "must be specified when auth is specified"))
+		}
+		if https.Credentials != (kabanerov1alpha2.CredentialsSource{}) {
+			errs = append(errs, field.Forbidden(authPath, "may not be specified together with credentials"))
+		}
+	}
+
+	if len(https.CaBundle.Key) > 0 && len(https.CaBundle.Name) == 0 {
+		errs = append(errs, field.Required(path.Child("caBundle", "name"), "must be specified when caBundle.key is specified"))
+	}
+
+	return errs
+}
+
+// Validates that a Vault secret source names a secret, and only refers to the Vault
+// server and roles configured for the operator.
+func validateVaultSource(path *field.Path, vault kabanerov1alpha2.VaultSecretSource) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(vault.Path) == 0 {
+		errs = append(errs, field.Required(path.Child("path"), "must be specified when vault is specified"))
+	}
+
+	switch {
+	case len(cutils.VaultAddress) == 0:
+		errs = append(errs, field.Forbidden(path, "Vault is not configured for the operator"))
+	case len(vault.Address) != 0 && strings.TrimSuffix(vault.Address, "/") != cutils.VaultAddress:
+		errs = append(errs, field.Invalid(path.Child("address"), vault.Address, "must be the Vault server configured for the operator, "+cutils.VaultAddress))
+	default:
+		if _, _, err := cutils.ResolveVaultSettings(vault.Address, vault.Role); err != nil {
+			errs = append(errs, field.Invalid(path.Child("role"), vault.Role, err.Error()))
+		}
+	}
+
+	return errs
+}
+
+// Validates that at most one credentials source is specified, and that it is complete.
+func validateCredentials(path *field.Path, credentials kabanerov1alpha2.CredentialsSource) field.ErrorList {
+	errs := field.ErrorList{}
+
+	sources := []string{}
+	if credentials.SecretRef != (kabanerov1alpha2.SecretKeyRef{}) {
+		sources = append(sources, "secretRef")
+		if len(credentials.SecretRef.Name) == 0 {
+			errs = append(errs, field.Required(path.Child("secretRef", "name"), "must be specified when secretRef is specified"))
+		}
+	}
+	if len(credentials.File) > 0 {
+		sources = append(sources, "file")
+		if !strings.HasPrefix(credentials.File, "/") {
+			errs = append(errs, field.Invalid(path.Child("file"), credentials.File, "must be an absolute path"))
+		}
+	}
+	if credentials.Vault != (kabanerov1alpha2.VaultSecretSource{}) {
+		sources = append(sources, "vault")
+		errs = append(errs, validateVaultSource(path.Child("vault"), credentials.Vault)...)
+	}
+
+	if len(sources) > 1 {
+		errs = append(errs, field.Forbidden(path, fmt.Sprintf("only one of secretRef, file or vault may be specified, but found %v", strings.Join(sources, ", "))))
+	}
+
+	return errs
+}
+
+// Validates the Kabanero version, and any component version overrides, against the
+// version catalogue.  When the catalogue is retrieved from a URL, or its ConfigMap
+// cannot be read, the versions are not validated here; the Kabanero controller
+// reports problems with them in the instance status.
+func (v *kabaneroValidator) validateVersions(ctx context.Context, k *kabanerov1alpha2.Kabanero, specPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	doc, ok := v.versionCatalog(ctx, k)
+	if !ok {
+		return errs
+	}
+
+	kabaneroVersion := k.Spec.Version
+	if kabaneroVersion == "" {
+		kabaneroVersion = doc.DefaultKabaneroRevision
+	}
+	if doc.KabaneroRevision(kabaneroVersion) == nil {
+		errs = append(errs, field.NotSupported(specPath.Child("version"), k.Spec.Version, doc.Versions()))
+	}
+
+	components := []struct {
+		path      *field.Path
+		component string
+		version   string
+	}{
+		{specPath.Child("cliServices", "version"), "cli-services", k.Spec.CliServices.Version},
+		{specPath.Child("landing", "version"), "landing", k.Spec.Landing.Version},
+		{specPath.Child("events", "version"), "events", k.Spec.Events.Version},
+		{specPath.Child("collectionController", "version"), "collection-controller", k.Spec.CollectionController.Version},
+		{specPath.Child("stackController", "version"), "stack-controller", k.Spec.StackController.Version},
+		{specPath.Child("admissionControllerWebhook", "version"), "admission-webhook", k.Spec.AdmissionControllerWebhook.Version},
+		{specPath.Child("codeReadyWorkspaces", "operator", "customResourceInstance", "devFileRegistryImage", "version"), "codeready-workspaces",
+			k.Spec.CodereadyWorkspaces.Operator.CustomResourceInstance.DevFileRegistryImage.Version},
+	}
+	for _, c := range components {
+		if len(c.version) == 0 {
+			continue
+		}
+
+		var versions []string
+		found := false
+		for _, rev := range doc.RelatedSoftwareRevisions[c.component] {
+			versions = append(versions, rev.Version)
+			found = found || rev.Version == c.version
+		}
+		if !found {
+			errs = append(errs, field.NotSupported(c.path, c.version, versions))
+		}
+	}
+
+	return errs
+}
+
+// Returns the version catalogue which applies to the Kabanero instance: the
+// embedded catalogue, merged with the ConfigMap overlay if one is configured.
+func (v *kabaneroValidator) versionCatalog(ctx context.Context, k *kabanerov1alpha2.Kabanero) (versioning.VersionDocument, bool) {
+	if versioning.LoadError != nil || len(k.Spec.VersionCatalog.Url) > 0 {
+		return versioning.VersionDocument{}, false
+	}
+
+	if len(k.Spec.VersionCatalog.ConfigMapName) == 0 {
+		return versioning.Data, true
+	}
+
+	data, err := versioning.ReadConfigMapOverlay(ctx, v.client, k.Namespace, k.Spec.VersionCatalog.ConfigMapName, k.Spec.VersionCatalog.ConfigMapKey)
+	if err != nil {
+		return versioning.VersionDocument{}, false
+	}
+
+	merged, err := versioning.ApplyOverlay(data)
+	if err != nil {
+		return versioning.VersionDocument{}, false
+	}
+
+	return merged, true
+}
+
 // InjectClient injects the client.
 func (v *kabaneroValidator) InjectClient(c client.Client) error {
 	v.client = c
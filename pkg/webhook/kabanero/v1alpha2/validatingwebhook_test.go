@@ -0,0 +1,295 @@
+package kabanero
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// A Kabanero instance which passes validation
+var validatingKabanero kabanerov1alpha2.Kabanero = kabanerov1alpha2.Kabanero{
+	ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: "kabanero"},
+	Spec: kabanerov1alpha2.KabaneroSpec{
+		Version: "0.6.0",
+		Github: kabanerov1alpha2.GithubConfig{
+			ApiUrl: "https://api.github.com",
+		},
+		CliServices: kabanerov1alpha2.KabaneroCliServicesCustomizationSpec{
+			SessionExpirationSeconds: "1440m",
+		},
+		Stacks: kabanerov1alpha2.InstanceStackConfig{
+			Repositories: []kabanerov1alpha2.RepositoryConfig{{
+				Name:  "central",
+				Https: kabanerov1alpha2.HttpsProtocolFile{Url: "https://github.com/kabanero-io/stacks/releases/download/0.6.0/kabanero-index.yaml"},
+			}},
+		},
+	},
+}
+
+func newValidatorClient(t *testing.T, objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := kabanerov1alpha2.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewFakeClientWithScheme(scheme, objs...)
+}
+
+// A valid spec produces no errors
+func TestValidateSpec(t *testing.T) {
+	v := kabaneroValidator{client: newValidatorClient(t)}
+	errs := v.validateSpecFn(context.Background(), validatingKabanero.DeepCopy())
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, but got: %v", errs)
+	}
+}
+
+// A GitHub API URL without a scheme names a host, which the controller contacts using https
+func TestValidateSpecApiUrlHost(t *testing.T) {
+	v := kabaneroValidator{client: newValidatorClient(t)}
+	k := validatingKabanero.DeepCopy()
+	k.Spec.Github.ApiUrl = "github.example.com"
+	errs := v.validateSpecFn(context.Background(), k)
+	if len(errs) != 0 {
+		t.Fatalf("Expected no errors, but got: %v", errs)
+	}
+}
+
+// Each problem is reported against the field which caused it
+func TestValidateSpecFieldPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(k *kabanerov1alpha2.Kabanero)
+		path   string
+	}{
+		{"apiUrl", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Github.ApiUrl = "ftp://api.github.com" }, "spec.github.apiUrl"},
+		{"apiUrl parse", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Github.ApiUrl = "https://api.github.com:port" }, "spec.github.apiUrl"},
+		{"session expiration", func(k *kabanerov1alpha2.Kabanero) { k.Spec.CliServices.SessionExpirationSeconds = "1440" }, "spec.cliServices.sessionExpirationSeconds"},
+		{"zero session expiration", func(k *kabanerov1alpha2.Kabanero) { k.Spec.CliServices.SessionExpirationSeconds = "0m" }, "spec.cliServices.sessionExpirationSeconds"},
+		{"version", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Version = "9.9.9" }, "spec.version"},
+		{"landing version", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Landing.Version = "9.9.9" }, "spec.landing.version"},
+		{"stack controller version", func(k *kabanerov1alpha2.Kabanero) { k.Spec.StackController.Version = "9.9.9" }, "spec.stackController.version"},
+		{"devfile registry version", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.CodereadyWorkspaces.Operator.CustomResourceInstance.DevFileRegistryImage.Version = "9.9.9"
+		}, "spec.codeReadyWorkspaces.operator.customResourceInstance.devFileRegistryImage.version"},
+		{"repository", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories = append(k.Spec.Stacks.Repositories, kabanerov1alpha2.RepositoryConfig{Name: "empty"})
+		}, "spec.stacks.repositories[1]"},
+		{"repository gitRelease", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0] = kabanerov1alpha2.RepositoryConfig{
+				Name:       "git",
+				GitRelease: kabanerov1alpha2.GitReleaseSpec{Hostname: "github.com", Organization: "kabanero-io", Project: "stacks", Release: "0.6.0"},
+			}
+		}, "spec.stacks.repositories[0].gitRelease.assetName"},
+		{"repository credentials", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0].Https.Credentials = kabanerov1alpha2.CredentialsSource{
+				SecretRef: kabanerov1alpha2.SecretKeyRef{Name: "index-token"},
+				File:      "/etc/kabanero/credentials/index",
+			}
+		}, "spec.stacks.repositories[0].https.credentials"},
+		{"repository vault credentials", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Address: "https://vault:8200"}
+		}, "spec.stacks.repositories[0].https.credentials.vault.path"},
+		{"repository vault address", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Address: "https://attacker.example.com", Path: "secret/data/index"}
+		}, "spec.stacks.repositories[0].https.credentials.vault.address"},
+		{"repository vault role", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Path: "secret/data/index", Role: "admin"}
+		}, "spec.stacks.repositories[0].https.credentials.vault.role"},
+		{"repository auth type", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Repositories[0].Https.Auth = kabanerov1alpha2.HttpsAuthSpec{Type: "digest", SecretName: "index-auth"}
+		}, "spec.stacks.repositories[0].https.auth.type"},
+		{"pipeline auth secret", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Stacks.Pipelines = []kabanerov1alpha2.PipelineSpec{{Id: "default", Https: kabanerov1alpha2.HttpsProtocolFile{
+				Url:  "https://artifactory.example.com/pipelines.tar.gz",
+				Auth: kabanerov1alpha2.HttpsAuthSpec{Type: kabanerov1alpha2.HttpsAuthTypeBasic},
+			}}}
+		}, "spec.stacks.pipelines[0].https.auth.secretName"},
+		{"sso", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Sso.Enable = true }, "spec.sso.adminSecretName"},
+		{"external sso issuer", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Sso.External = &kabanerov1alpha2.ExternalSsoSpec{IssuerUrl: "http://idp.example.com", ClientSecretName: "oidc-client"}
+		}, "spec.sso.external.issuerUrl"},
+		{"external sso client secret", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Sso.Enable = true
+			k.Spec.Sso.External = &kabanerov1alpha2.ExternalSsoSpec{IssuerUrl: "https://idp.example.com/realms/corp"}
+		}, "spec.sso.external.clientSecretName"},
+		{"ingress domain", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Ingress.Domain = "https://apps.example.com" }, "spec.ingress.domain"},
+		{"landing ingress host", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Landing.Ingress.Host = "Kabanero_Landing" }, "spec.landing.ingress.host"},
+		{"console link location", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Landing.ConsoleLinks = []kabanerov1alpha2.ConsoleLinkSpec{{Name: "docs", Location: "Sidebar", Text: "Docs", Href: "{{ .landingURL }}/docs"}}
+		}, "spec.landing.consoleLinks[0].location"},
+		{"console link href", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Landing.ConsoleLinks = []kabanerov1alpha2.ConsoleLinkSpec{{Name: "docs", Location: "HelpMenu", Text: "Docs", Href: "{{ .landingURL /docs"}}
+		}, "spec.landing.consoleLinks[0].href"},
+		{"console link namespaces", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Landing.ConsoleLinks = []kabanerov1alpha2.ConsoleLinkSpec{{Name: "docs", Location: "HelpMenu", Text: "Docs", Href: "https://docs.example.com", Namespaces: []string{"dev"}}}
+		}, "spec.landing.consoleLinks[0].namespaces"},
+		{"events webhook source organization", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Events.WebhookSources = []kabanerov1alpha2.EventsWebhookSourceSpec{{Name: "github"}}
+		}, "spec.events.webhookSources[0].organization"},
+		{"events mapping source", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Events.WebhookSources = []kabanerov1alpha2.EventsWebhookSourceSpec{{Name: "github", Organization: "kabanero-io"}}
+			k.Spec.Events.Mappings = []kabanerov1alpha2.EventsMappingSpec{{Source: "gitlab", Stack: "java-microprofile"}}
+		}, "spec.events.mappings[0].source"},
+		{"events destination url", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Events.Destinations = []kabanerov1alpha2.EventsDestinationSpec{{Name: "listener", Url: "el-listener:8080"}}
+		}, "spec.events.destinations[0].url"},
+		{"github webhook token", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Github.Organization = "kabanero-io"
+			k.Spec.Events.Enable = true
+			k.Spec.Github.Webhook = kabanerov1alpha2.GithubWebhookSpec{Enable: true}
+		}, "spec.github.webhook.tokenSecretRef.name"},
+		{"github webhook events", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Github.Organization = "kabanero-io"
+			k.Spec.Github.Webhook = kabanerov1alpha2.GithubWebhookSpec{Enable: true, TokenSecretRef: kabanerov1alpha2.SecretKeyRef{Name: "github-token"}}
+		}, "spec.github.webhook.enable"},
+		{"github webhook repository", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.Github.Organization = "kabanero-io"
+			k.Spec.Events.Enable = true
+			k.Spec.Github.Webhook = kabanerov1alpha2.GithubWebhookSpec{Enable: true, TokenSecretRef: kabanerov1alpha2.SecretKeyRef{Name: "github-token"}, Repositories: []string{"app/one"}}
+		}, "spec.github.webhook.repositories[0]"},
+		{"tekton api version", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Stacks.TektonApiVersion = "v1" }, "spec.stacks.tektonApiVersion"},
+		{"orphaned asset policy", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Stacks.OrphanedAssetPolicy = "Ignore" }, "spec.stacks.orphanedAssetPolicy"},
+		{"signature keys", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Stacks.ImagePolicy.RequireSignatures = true }, "spec.stacks.imagePolicy.signatureKeys.name"},
+		{"download timeout", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Stacks.Timeouts.Download = "60" }, "spec.stacks.timeouts.download"},
+		{"reconcile timeout", func(k *kabanerov1alpha2.Kabanero) { k.Spec.Stacks.Timeouts.Reconcile = "-5m" }, "spec.stacks.timeouts.reconcile"},
+		{"secret rotation interval", func(k *kabanerov1alpha2.Kabanero) { k.Spec.SecretRotation.SsoDatabase = "30d" }, "spec.secretRotation.ssoDatabase"},
+		{"version catalog", func(k *kabanerov1alpha2.Kabanero) {
+			k.Spec.VersionCatalog.ConfigMapName = "versions"
+			k.Spec.VersionCatalog.Url = "https://example.com/versions.yaml"
+		}, "spec.versionCatalog.url"},
+	}
+
+	defer func(address string, roles []string) { cutils.VaultAddress, cutils.VaultRoles = address, roles }(cutils.VaultAddress, cutils.VaultRoles)
+	cutils.VaultAddress = "https://vault:8200"
+	cutils.VaultRoles = []string{"kabanero"}
+
+	v := kabaneroValidator{client: newValidatorClient(t)}
+	for _, test := range tests {
+		k := validatingKabanero.DeepCopy()
+		test.modify(k)
+		errs := v.validateSpecFn(context.Background(), k)
+		if len(errs) != 1 {
+			t.Fatalf("%v: expected one error, but got: %v", test.name, errs)
+		}
+		if errs[0].Field != test.path {
+			t.Fatalf("%v: expected the error to be reported for %v, but was: %v", test.name, test.path, errs[0])
+		}
+	}
+}
+
+// Invalid durations are reported in the order of their fields
+func TestValidateSpecDurationOrder(t *testing.T) {
+	k := validatingKabanero.DeepCopy()
+	k.Spec.Stacks.Timeouts = kabanerov1alpha2.StackTimeoutsSpec{Download: "1", IndexResolution: "2", Reconcile: "3"}
+	k.Spec.SecretRotation.SsoDatabase = "4"
+	k.Spec.SecretRotation.Events = "5"
+	k.Spec.SecretRotation.CliEncryptionKey = "6"
+
+	expected := []string{
+		"spec.stacks.timeouts.download",
+		"spec.stacks.timeouts.indexResolution",
+		"spec.stacks.timeouts.reconcile",
+		"spec.secretRotation.ssoDatabase",
+		"spec.secretRotation.events",
+		"spec.secretRotation.cliEncryptionKey",
+	}
+
+	v := kabaneroValidator{client: newValidatorClient(t)}
+	for i := 0; i < 10; i++ {
+		errs := v.validateSpecFn(context.Background(), k)
+		if len(errs) != len(expected) {
+			t.Fatalf("Expected %v errors, but got: %v", len(expected), errs)
+		}
+		for j, err := range errs {
+			if err.Field != expected[j] {
+				t.Fatalf("Expected error %v to be reported for %v, but got: %v", j, expected[j], errs)
+			}
+		}
+	}
+}
+
+// Versions added by a version catalogue ConfigMap are accepted
+func TestValidateSpecVersionCatalogConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "versions", Namespace: "kabanero"},
+		Data: map[string]string{"versions.yaml": `
+kabanero:
+- version: "0.6.1"
+  related-versions:
+    cli-services: "0.6.0"
+    landing: "0.6.0"
+    events: "0.1.0"
+    collection-controller: "0.7.0-alpha.1"
+    stack-controller: "0.7.0-alpha.1"
+    admission-webhook: "0.7.0-alpha.1"
+    sso: "7.3.2"
+    codeready-workspaces: "0.6.0"
+`},
+	}
+
+	k := validatingKabanero.DeepCopy()
+	k.Spec.Version = "0.6.1"
+	v := kabaneroValidator{client: newValidatorClient(t)}
+	if errs := v.validateSpecFn(context.Background(), k); len(errs) != 1 {
+		t.Fatalf("Expected the version to be rejected without the catalogue, but got: %v", errs)
+	}
+
+	k.Spec.VersionCatalog.ConfigMapName = "versions"
+	v = kabaneroValidator{client: newValidatorClient(t, cm)}
+	if errs := v.validateSpecFn(context.Background(), k); len(errs) != 0 {
+		t.Fatalf("Expected the version to be accepted, but got: %v", errs)
+	}
+}
+
+// Updates which do not change the spec are not validated, so an existing
+// invalid instance can still be deleted.
+func TestHandleUnchangedSpec(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kabanerov1alpha2.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := validatingKabanero.DeepCopy()
+	invalid.TypeMeta = metav1.TypeMeta{APIVersion: "kabanero.io/v1alpha2", Kind: "Kabanero"}
+	invalid.Spec.Sso.Enable = true
+	raw, err := json.Marshal(invalid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := kabaneroValidator{client: newValidatorClient(t, invalid.DeepCopy()), decoder: decoder}
+
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+	resp := v.Handle(context.Background(), req)
+	if resp.Allowed || !strings.Contains(string(resp.Result.Reason), "spec.sso.adminSecretName") {
+		t.Fatalf("Expected the instance to be rejected: %v", resp.Result)
+	}
+
+	req.Operation = admissionv1beta1.Update
+	req.OldObject = runtime.RawExtension{Raw: raw}
+	resp = v.Handle(context.Background(), req)
+	if !resp.Allowed {
+		t.Fatalf("Expected the unchanged instance to be allowed: %v", resp.Result)
+	}
+}
@@ -5,11 +5,16 @@ package stack
 
 import (
 	"context"
+	"crypto"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	sutils "github.com/kabanero-io/kabanero-operator/pkg/controller/stack/utils"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	"k8s.io/apimachinery/pkg/api/errors"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -23,6 +28,10 @@ func BuildValidatingWebhook(mgr *manager.Manager) *admission.Webhook {
 	return &admission.Webhook{Handler: &stackValidator{}}
 }
 
+// The amount of time allowed to contact image registries while checking the stack image policy.
+// This must fit within the timeout of the webhook configuration.
+const imagePolicyTimeout = 8 * time.Second
+
 // stackValidator validates Stacks
 type stackValidator struct {
 	client  client.Client
@@ -46,6 +55,14 @@ func (v *stackValidator) Handle(ctx context.Context, req admission.Request) admi
 	if err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
 	}
+
+	if allowed {
+		allowed, reason, err = v.validateImagePolicyFn(ctx, stack)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
 	return admission.ValidationResponse(allowed, reason)
 }
 
@@ -113,12 +130,116 @@ func (v *stackValidator) validateStackFn(ctx context.Context, stack *kabanerov1a
 				err = fmt.Errorf(reason)
 				return false, reason, err
 			}
+
+			// Vault credentials may only refer to the Vault server and roles configured for the operator.
+			for _, vault := range []kabanerov1alpha2.VaultSecretSource{pipeline.Https.Credentials.Vault, pipeline.GitRelease.Credentials.Vault} {
+				if vault == (kabanerov1alpha2.VaultSecretSource{}) {
+					continue
+				}
+				if _, _, vaultErr := cutils.ResolveVaultSettings(vault.Address, vault.Role); vaultErr != nil {
+					reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Pipelines[] Vault credentials are not valid. %v. stack: %v", stack.Spec.Name, version.Version, vaultErr, stack)
+					err = fmt.Errorf(reason)
+					return false, reason, err
+				}
+			}
+		}
+
+		if len(version.Requires.Kabanero) != 0 {
+			_, err := semver.ParseRange(version.Requires.Kabanero)
+			if err != nil {
+				reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Requires.Kabanero must be a semver range. %v. stack: %v", stack.Spec.Name, version.Version, err, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+		}
+
+		for _, dependency := range version.Requires.Stacks {
+			if len(dependency.Name) == 0 {
+				reason = fmt.Sprintf("Stack %v %v must set Spec.Versions[].Requires.Stacks[].Name. stack: %v", stack.Spec.Name, version.Version, stack)
+				err = fmt.Errorf(reason)
+				return false, reason, err
+			}
+
+			if len(dependency.Version) != 0 {
+				_, err := semver.ParseRange(dependency.Version)
+				if err != nil {
+					reason = fmt.Sprintf("Stack %v %v Spec.Versions[].Requires.Stacks[].Version must be a semver range. %v. stack: %v", stack.Spec.Name, version.Version, err, stack)
+					err = fmt.Errorf(reason)
+					return false, reason, err
+				}
+			}
 		}
 	}
 
 	return true, reason, nil
 }
 
+// validateImagePolicyFn checks the images of the active stack versions against the stack image
+// policy of the Kabanero instance in the stack's namespace.  Images which the installed stack
+// already uses for the same version are not checked again, so that a stack created before the
+// policy was changed can still be updated or deactivated.
+func (v *stackValidator) validateImagePolicyFn(ctx context.Context, stack *kabanerov1alpha2.Stack) (bool, string, error) {
+	kabaneroList := &kabanerov1alpha2.KabaneroList{}
+	err := v.client.List(ctx, kabaneroList, client.InNamespace(stack.Namespace))
+	if err != nil {
+		return false, fmt.Sprintf("Failed to list Kabaneros in namespace: %s", stack.Namespace), err
+	}
+	if len(kabaneroList.Items) == 0 {
+		return true, "", nil
+	}
+
+	k := kabaneroList.Items[0]
+	policy := k.Spec.Stacks.ImagePolicy
+	if len(policy.AllowedImages) == 0 && !policy.RequireSignatures {
+		return true, "", nil
+	}
+
+	current := &kabanerov1alpha2.Stack{}
+	err = v.client.Get(ctx, client.ObjectKey{Name: stack.Name, Namespace: stack.Namespace}, current)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, fmt.Sprintf("Unable to retrieve installed stack %v", stack.Name), err
+	}
+	installed := make(map[string]bool)
+	for _, version := range current.Spec.Versions {
+		for _, image := range version.Images {
+			installed[version.Version+"/"+image.Image] = true
+		}
+	}
+
+	var keys []crypto.PublicKey
+	var creds cutils.RegistryCredentials
+	if policy.RequireSignatures {
+		keys, err = sutils.GetSignatureKeys(ctx, v.client, stack.Namespace, policy)
+		if err != nil {
+			return false, fmt.Sprintf("Unable to retrieve the keys to verify the signatures of stack %v images: %v", stack.Spec.Name, err), nil
+		}
+		creds, err = cutils.GetRegistryCredentials(v.client, stack.Namespace, k.Spec.DigestPinning.PullSecrets)
+		if err != nil {
+			return false, fmt.Sprintf("Unable to retrieve registry credentials to verify the signatures of stack %v images: %v", stack.Spec.Name, err), nil
+		}
+	}
+
+	policyCtx, cancel := context.WithTimeout(ctx, imagePolicyTimeout)
+	defer cancel()
+
+	var violations []string
+	for _, version := range stack.Spec.Versions {
+		if strings.EqualFold(version.DesiredState, kabanerov1alpha2.StackDesiredStateInactive) {
+			continue
+		}
+		filter := func(image kabanerov1alpha2.Image) bool {
+			return !installed[version.Version+"/"+image.Image]
+		}
+		violations = append(violations, sutils.CheckStackImagePolicy(policyCtx, policy, stack.Spec.Name, version, filter, keys, creds, cutils.RegistryTransport, nil)...)
+	}
+
+	if len(violations) > 0 {
+		return false, fmt.Sprintf("Stack %v violates the stack image policy of Kabanero instance %v: %v", stack.Spec.Name, k.Name, strings.Join(violations, " ")), nil
+	}
+
+	return true, "", nil
+}
+
 // InjectClient injects the client.
 func (v *stackValidator) InjectClient(c client.Client) error {
 	v.client = c
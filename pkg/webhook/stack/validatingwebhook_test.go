@@ -1,10 +1,17 @@
 package stack
 
 import (
+	"context"
+	"strings"
 	"testing"
 
 	kabanerov1alpha2 "github.com/kabanero-io/kabanero-operator/pkg/apis/kabanero/v1alpha2"
+	cutils "github.com/kabanero-io/kabanero-operator/pkg/controller/utils"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 // Base stack with stack.Spec.Versions[0] defined.
@@ -291,3 +298,158 @@ func TestValidatingWebhook12(t *testing.T) {
 		t.Fatal("Validation failed. An error was expected: ", err)
 	}
 }
+
+// Stack requirements with an invalid Kabanero version range and a stack dependency without a name
+func TestValidatingWebhookRequires(t *testing.T) {
+	cv := stackValidator{}
+
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].Requires = kabanerov1alpha2.StackRequirements{
+		Kabanero:          ">=0.7.0",
+		TektonApiVersions: []string{"v1beta1"},
+		Stacks:            []kabanerov1alpha2.StackDependency{{Name: "nodejs", Version: ">=0.3.0 <0.4.0"}},
+	}
+	allowed, _, err := cv.validateStackFn(nil, newStack)
+	if !allowed {
+		t.Fatal("Validation should have passed and the stack update should have been allowed. Error: ", err)
+	}
+
+	newStack.Spec.Versions[0].Requires.Kabanero = "latest"
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+	if allowed || len(msg) == 0 || err == nil {
+		t.Fatal("Validation should have failed because the Kabanero version range is not valid.")
+	}
+
+	newStack.Spec.Versions[0].Requires.Kabanero = ""
+	newStack.Spec.Versions[0].Requires.Stacks[0].Name = ""
+	allowed, msg, err = cv.validateStackFn(nil, newStack)
+	if allowed || len(msg) == 0 || err == nil {
+		t.Fatal("Validation should have failed because the required stack has no name.")
+	}
+}
+
+// Pipeline Vault credentials which refer to another Vault server or role
+func TestValidatingWebhookVaultCredentials(t *testing.T) {
+	defer func(address string, roles []string) { cutils.VaultAddress, cutils.VaultRoles = address, roles }(cutils.VaultAddress, cutils.VaultRoles)
+	cutils.VaultAddress = "https://vault:8200"
+	cutils.VaultRoles = []string{"kabanero"}
+
+	cv := stackValidator{}
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].Pipelines[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Address: "https://attacker.example.com", Path: "secret/data/pipelines"}
+	allowed, msg, _ := cv.validateStackFn(nil, newStack)
+	if allowed || !strings.Contains(msg, "not the Vault server configured") {
+		t.Fatal("Validation should have failed because the Vault address is not the configured one: ", msg)
+	}
+
+	newStack.Spec.Versions[0].Pipelines[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Path: "secret/data/pipelines", Role: "admin"}
+	allowed, msg, _ = cv.validateStackFn(nil, newStack)
+	if allowed || !strings.Contains(msg, "not one of the roles") {
+		t.Fatal("Validation should have failed because the Vault role is not configured: ", msg)
+	}
+
+	newStack.Spec.Versions[0].Pipelines[0].Https.Credentials.Vault = kabanerov1alpha2.VaultSecretSource{Address: "https://vault:8200/", Path: "secret/data/pipelines"}
+	allowed, msg, err := cv.validateStackFn(nil, newStack)
+	if !allowed || err != nil {
+		t.Fatal("Validation should have passed for the configured Vault server: ", msg, err)
+	}
+}
+
+// Creates a client containing a Kabanero instance with the input stack image policy.
+func newImagePolicyClient(t *testing.T, policy kabanerov1alpha2.StackImagePolicySpec, objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	if err := kabanerov1alpha2.SchemeBuilder.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	k := &kabanerov1alpha2.Kabanero{
+		ObjectMeta: metav1.ObjectMeta{Name: "kabanero", Namespace: validatingStack.Namespace},
+		Spec:       kabanerov1alpha2.KabaneroSpec{Stacks: kabanerov1alpha2.InstanceStackConfig{ImagePolicy: policy}},
+	}
+	return fake.NewFakeClientWithScheme(scheme, append(objs, k)...)
+}
+
+// Stack image from a registry the policy does not allow
+func TestValidatingWebhookImagePolicy(t *testing.T) {
+	newStack := validatingStack.DeepCopy()
+	newStack.Spec.Versions[0].Images[0].Image = "quay.io/someone/java-microprofile"
+
+	cv := stackValidator{client: newImagePolicyClient(t, kabanerov1alpha2.StackImagePolicySpec{AllowedImages: []string{"docker.io/kabanero"}})}
+	allowed, msg, err := cv.validateImagePolicyFn(context.Background(), newStack)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if allowed {
+		t.Fatal("Validation should have failed because the stack image is not from an allowed registry. The stack update/create was incorrectly allowed.")
+	}
+
+	if !strings.Contains(msg, "quay.io/someone/java-microprofile") {
+		t.Fatal("Validation failed. A message identifying the image was expected: ", msg)
+	}
+
+	// The default image is allowed
+	newStack = validatingStack.DeepCopy()
+	allowed, msg, err = cv.validateImagePolicyFn(context.Background(), newStack)
+	if !allowed || err != nil {
+		t.Fatal("Validation should have passed and the stack update should have been allowed: ", msg, err)
+	}
+}
+
+// Images already used by the installed stack are not checked again
+func TestValidatingWebhookImagePolicyInstalled(t *testing.T) {
+	installed := validatingStack.DeepCopy()
+	installed.Spec.Versions[0].Images[0].Image = "quay.io/someone/java-microprofile"
+
+	newStack := installed.DeepCopy()
+	newStack.Spec.Versions[0].DesiredState = "inactive"
+	newStack.Spec.Versions = append(newStack.Spec.Versions, *installed.Spec.Versions[0].DeepCopy())
+	newStack.Spec.Versions[1].Version = "1.2.4"
+	newStack.Spec.Versions[1].DesiredState = "active"
+
+	cv := stackValidator{client: newImagePolicyClient(t, kabanerov1alpha2.StackImagePolicySpec{AllowedImages: []string{"docker.io/kabanero"}}, installed)}
+
+	// Deactivating the installed version is allowed, but the new version is not
+	allowed, msg, err := cv.validateImagePolicyFn(context.Background(), newStack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || !strings.Contains(msg, "1.2.4") || strings.Contains(msg, "1.2.3") {
+		t.Fatal("Validation should have failed for the new version only: ", msg)
+	}
+
+	newStack.Spec.Versions = newStack.Spec.Versions[:1]
+	newStack.Spec.Versions[0].DesiredState = "active"
+	allowed, msg, err = cv.validateImagePolicyFn(context.Background(), newStack)
+	if !allowed || err != nil {
+		t.Fatal("Validation should have passed for the installed version: ", msg, err)
+	}
+}
+
+// Signatures cannot be verified without the trusted keys
+func TestValidatingWebhookImagePolicySignatureKeys(t *testing.T) {
+	policy := kabanerov1alpha2.StackImagePolicySpec{RequireSignatures: true, SignatureKeys: kabanerov1alpha2.ConfigMapKeyRef{Name: "cosign-keys"}}
+	cv := stackValidator{client: newImagePolicyClient(t, policy)}
+	allowed, msg, err := cv.validateImagePolicyFn(context.Background(), validatingStack.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || !strings.Contains(msg, "cosign-keys") {
+		t.Fatal("Validation should have failed because the signature keys ConfigMap does not exist: ", msg)
+	}
+
+	keys := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cosign-keys", Namespace: validatingStack.Namespace},
+		Data:       map[string]string{"cosign.pub": "not a key"},
+	}
+	cv = stackValidator{client: newImagePolicyClient(t, policy, keys)}
+	allowed, msg, err = cv.validateImagePolicyFn(context.Background(), validatingStack.DeepCopy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allowed || !strings.Contains(msg, "No PEM encoded public keys") {
+		t.Fatal("Validation should have failed because the signature keys ConfigMap holds no keys: ", msg)
+	}
+}